@@ -2,4 +2,8 @@ package access
 
 type Action string
 
-const AllActions Action = "all"
+const (
+	AllActions Action = "all"
+	// Retrieve is the action of reading a resource.
+	Retrieve Action = "retrieve"
+)
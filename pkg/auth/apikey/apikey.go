@@ -0,0 +1,58 @@
+// Package apikey implements long-lived API keys. API keys let non-interactive clients
+// (such as data acquisition daemons) authenticate without logging in. Each key acts
+// on behalf of a subject (a user or service account), and is limited to a set of
+// scopes.
+package apikey
+
+import (
+	"github.com/arya-analytics/delta/pkg/access"
+	"github.com/arya-analytics/delta/pkg/auth/password"
+	"github.com/arya-analytics/delta/pkg/ontology"
+	"github.com/cockroachdb/errors"
+	"github.com/google/uuid"
+	"time"
+)
+
+var (
+	// Invalid is returned when authenticating with a key that is malformed, doesn't
+	// exist, or has been revoked.
+	Invalid = errors.New("[apikey] - invalid api key")
+	// Expired is returned when authenticating with a key that has expired.
+	Expired = errors.New("[apikey] - api key expired")
+)
+
+// Key is an API key. Only a hash of the key's secret is stored.
+type Key struct {
+	Key uuid.UUID
+	// Name is a human-readable description of the key.
+	Name string
+	// Subject is the user or service account the key acts on behalf of.
+	Subject ontology.ID
+	// Scopes are the actions the key is allowed to perform. A key with the
+	// access.AllActions scope can perform any action its Subject can.
+	Scopes []access.Action
+	// Hash is the hash of the key's secret.
+	Hash      password.Hashed
+	CreatedAt time.Time
+	// ExpiresAt is the time the key expires. A zero value means the key never expires.
+	ExpiresAt time.Time
+}
+
+// GorpKey implements the gorp.Entry interface.
+func (k Key) GorpKey() uuid.UUID { return k.Key }
+
+// SetOptions implements the gorp.Entry interface.
+func (k Key) SetOptions() []interface{} { return nil }
+
+// Expired returns true if the key has expired.
+func (k Key) Expired() bool { return !k.ExpiresAt.IsZero() && time.Now().After(k.ExpiresAt) }
+
+// Allows returns true if the key's scopes include the given action.
+func (k Key) Allows(action access.Action) bool {
+	for _, s := range k.Scopes {
+		if s == access.AllActions || s == action {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package apikey
+
+import (
+	"github.com/arya-analytics/delta/pkg/access"
+	"github.com/arya-analytics/x/query"
+	"github.com/cockroachdb/errors"
+	"github.com/google/uuid"
+)
+
+// Enforcer wraps an access.Enforcer so that it can enforce requests made by API keys.
+// A request whose subject is an API key is denied if the key doesn't exist, has
+// expired, or its scopes don't include the requested action. Otherwise, the request is
+// enforced on behalf of the key's subject. Errors encountered while retrieving the key
+// are returned as is. Requests made by other subjects are passed through unchanged.
+type Enforcer struct {
+	access.Enforcer
+	Keys *Service
+}
+
+var _ access.Enforcer = (*Enforcer)(nil)
+
+// Enforce implements the access.Enforcer interface.
+func (e *Enforcer) Enforce(req access.Request) error {
+	if req.Subject.Type != OntologyType {
+		return e.Enforcer.Enforce(req)
+	}
+	key, err := uuid.Parse(req.Subject.Key)
+	if err != nil {
+		return access.Denied
+	}
+	k, err := e.Keys.Retrieve(key)
+	if errors.Is(err, query.NotFound) {
+		return access.Denied
+	}
+	if err != nil {
+		return err
+	}
+	if k.Expired() || !k.Allows(req.Action) {
+		return access.Denied
+	}
+	req.Subject = k.Subject
+	return e.Enforcer.Enforce(req)
+}
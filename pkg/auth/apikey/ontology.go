@@ -0,0 +1,43 @@
+package apikey
+
+import (
+	"github.com/arya-analytics/delta/pkg/ontology"
+	"github.com/arya-analytics/delta/pkg/ontology/schema"
+	"github.com/google/uuid"
+)
+
+// OntologyType is the type of API keys in the ontology.
+const OntologyType ontology.Type = "apikey"
+
+// OntologyID returns the ID of the key with the given UUID in the ontology.
+func OntologyID(key uuid.UUID) ontology.ID {
+	return ontology.ID{Type: OntologyType, Key: key.String()}
+}
+
+var _schema = &ontology.Schema{
+	Type: OntologyType,
+	Fields: map[string]schema.Field{
+		"key":     {Type: schema.UUID},
+		"name":    {Type: schema.String},
+		"subject": {Type: schema.String},
+	},
+}
+
+var _ ontology.Service = (*Service)(nil)
+
+// Schema implements the ontology.Service interface.
+func (s *Service) Schema() *schema.Schema { return _schema }
+
+// RetrieveEntity implements the ontology.Service interface.
+func (s *Service) RetrieveEntity(key string) (schema.Entity, error) {
+	uuidKey, err := uuid.Parse(key)
+	if err != nil {
+		return schema.Entity{}, err
+	}
+	k, err := s.Retrieve(uuidKey)
+	e := schema.NewEntity(_schema)
+	schema.Set(e, "key", k.Key)
+	schema.Set(e, "name", k.Name)
+	schema.Set(e, "subject", k.Subject.String())
+	return e, err
+}
@@ -0,0 +1,164 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"github.com/arya-analytics/delta/pkg/access"
+	"github.com/arya-analytics/delta/pkg/auth/password"
+	"github.com/arya-analytics/delta/pkg/ontology"
+	"github.com/arya-analytics/x/gorp"
+	"github.com/arya-analytics/x/query"
+	"github.com/cockroachdb/errors"
+	"github.com/google/uuid"
+	"strings"
+	"sync"
+	"time"
+)
+
+// prefix is prepended to every raw key, so that keys are easy to recognize (e.g. by
+// secret scanners).
+const prefix = "delta_"
+
+// Service creates, authenticates and revokes API keys.
+type Service struct {
+	db        *gorp.DB
+	resources *ontology.Ontology
+	// verified caches the digests of raw keys whose secrets have already been
+	// validated, so that the (intentionally slow) password hash is only computed
+	// once per key.
+	verified sync.Map
+}
+
+// New creates a new Service that stores keys in the given database and defines them
+// as resources in the given ontology. The Service must be registered with the
+// ontology before key entities can be retrieved through it.
+func New(db *gorp.DB, resources *ontology.Ontology) *Service {
+	return &Service{db: db, resources: resources}
+}
+
+// BeginTxn begins a transaction for creating and revoking keys, so that changes to
+// their resources are published to ontology Watch subscribers.
+func (s *Service) BeginTxn() gorp.Txn { return s.resources.BeginTxn() }
+
+// Create creates a new key for the subject with the given scopes. The subject must be
+// a resource in the ontology. A zero expiresAt creates a key that never expires, and
+// an expiresAt in the past is rejected. Returns the key along with its raw value. The
+// raw value is the only way to authenticate with the key, and can't be retrieved
+// again.
+func (s *Service) Create(
+	txn gorp.Txn,
+	subject ontology.ID,
+	name string,
+	scopes []access.Action,
+	expiresAt time.Time,
+) (Key, string, error) {
+	k := Key{
+		Key:       uuid.New(),
+		Name:      name,
+		Subject:   subject,
+		Scopes:    scopes,
+		CreatedAt: time.Now(),
+		ExpiresAt: expiresAt,
+	}
+	if err := subject.Validate(); err != nil {
+		return k, "", err
+	}
+	if len(scopes) == 0 {
+		return k, "", errors.New("[apikey] - at least one scope is required")
+	}
+	if !expiresAt.IsZero() && !expiresAt.After(k.CreatedAt) {
+		return k, "", errors.New("[apikey] - expiration must be in the future")
+	}
+	w := s.resources.NewWriter(txn)
+	var res ontology.Resource
+	if err := w.NewRetrieve().WhereIDs(subject).Entry(&res).Exec(); err != nil {
+		if errors.Is(err, query.NotFound) {
+			return k, "", errors.Wrapf(err, "[apikey] - subject %s not found", subject)
+		}
+		return k, "", err
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return k, "", err
+	}
+	raw := password.Raw(base64.RawURLEncoding.EncodeToString(secret))
+	var err error
+	if k.Hash, err = raw.Hash(); err != nil {
+		return k, "", err
+	}
+	if err := w.DefineResource(OntologyID(k.Key)); err != nil {
+		return k, "", err
+	}
+	if err := w.DefineRelationship(OntologyID(k.Key), subject, ontology.Parent); err != nil {
+		return k, "", err
+	}
+	w.InvalidateEntities(OntologyID(k.Key))
+	return k, prefix + k.Key.String() + "." + string(raw), gorp.NewCreate[uuid.UUID, Key]().
+		Entry(&k).
+		Exec(txn)
+}
+
+// Retrieve retrieves the key with the given UUID.
+func (s *Service) Retrieve(key uuid.UUID) (Key, error) {
+	var k Key
+	return k, gorp.NewRetrieve[uuid.UUID, Key]().WhereKeys(key).Entry(&k).Exec(s.db)
+}
+
+// RetrieveBySubject retrieves the keys that act on behalf of the given subject.
+func (s *Service) RetrieveBySubject(subject ontology.ID) ([]Key, error) {
+	var keys []Key
+	err := gorp.NewRetrieve[uuid.UUID, Key]().
+		Where(func(k *Key) bool { return k.Subject == subject }).
+		Entries(&keys).
+		Exec(s.db)
+	if errors.Is(err, query.NotFound) {
+		return keys, nil
+	}
+	return keys, err
+}
+
+// Revoke revokes the key with the given UUID. The key can't be used to authenticate
+// once the transaction is committed.
+func (s *Service) Revoke(txn gorp.Txn, key uuid.UUID) error {
+	w := s.resources.NewWriter(txn)
+	if err := w.DeleteResource(OntologyID(key)); err != nil {
+		return err
+	}
+	// The deletion may be relayed, in which case the Writer doesn't invalidate the
+	// key's entity itself.
+	w.InvalidateEntities(OntologyID(key))
+	return gorp.NewDelete[uuid.UUID, Key]().WhereKeys(key).Exec(txn)
+}
+
+// Authenticate returns the key with the given raw value. Returns Invalid if the key
+// doesn't exist or its secret doesn't match, and Expired if the key has expired.
+func (s *Service) Authenticate(raw string) (Key, error) {
+	id, secret, ok := strings.Cut(strings.TrimPrefix(raw, prefix), ".")
+	if !ok {
+		return Key{}, Invalid
+	}
+	uuidKey, err := uuid.Parse(id)
+	if err != nil {
+		return Key{}, Invalid
+	}
+	k, err := s.Retrieve(uuidKey)
+	if errors.Is(err, query.NotFound) {
+		return k, Invalid
+	}
+	if err != nil {
+		return k, err
+	}
+	if k.Expired() {
+		return k, Expired
+	}
+	digest := sha256.Sum256([]byte(raw))
+	if v, ok := s.verified.Load(digest); ok && v.(uuid.UUID) == k.Key {
+		return k, nil
+	}
+	if err := k.Hash.Validate(password.Raw(secret)); err != nil {
+		return k, Invalid
+	}
+	s.verified.Store(digest, k.Key)
+	return k, nil
+}
@@ -0,0 +1,178 @@
+package apikey_test
+
+import (
+	"github.com/arya-analytics/delta/pkg/access"
+	"github.com/arya-analytics/delta/pkg/auth/apikey"
+	"github.com/arya-analytics/delta/pkg/ontology"
+	"github.com/arya-analytics/delta/pkg/user"
+	"github.com/arya-analytics/x/gorp"
+	"github.com/arya-analytics/x/kv/memkv"
+	"github.com/arya-analytics/x/query"
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"time"
+)
+
+// expiry is how long keys that are expected to expire during a spec are valid for.
+const expiry = 50 * time.Millisecond
+
+var _ = Describe("Service", func() {
+	var (
+		db      *gorp.DB
+		svc     *apikey.Service
+		subject ontology.ID
+		create  func(scopes []access.Action, expiresAt time.Time) (apikey.Key, string)
+	)
+	BeforeEach(func() {
+		db = gorp.Wrap(memkv.New())
+		otg, err := ontology.Open(db)
+		Expect(err).ToNot(HaveOccurred())
+		users := user.New(db, otg)
+		otg.RegisterService(users)
+		svc = apikey.New(db, otg)
+		otg.RegisterService(svc)
+		txn := users.BeginTxn()
+		u := &user.User{Username: "daq"}
+		Expect(users.Create(txn, u)).To(Succeed())
+		Expect(txn.Commit()).To(Succeed())
+		subject = user.OntologyID(u.Key)
+		create = func(scopes []access.Action, expiresAt time.Time) (apikey.Key, string) {
+			txn := svc.BeginTxn()
+			k, raw, err := svc.Create(txn, subject, "daq", scopes, expiresAt)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(txn.Commit()).To(Succeed())
+			return k, raw
+		}
+	})
+	AfterEach(func() { Expect(db.Close()).To(Succeed()) })
+	Describe("Authenticate", func() {
+		It("Should authenticate a key by its raw value", func() {
+			k, raw := create([]access.Action{access.Retrieve}, time.Time{})
+			authed, err := svc.Authenticate(raw)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(authed.Key).To(Equal(k.Key))
+			Expect(authed.Subject).To(Equal(subject))
+			authed, err = svc.Authenticate(raw)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(authed.Key).To(Equal(k.Key))
+		})
+		It("Should not authenticate a key with the wrong secret", func() {
+			_, raw := create([]access.Action{access.Retrieve}, time.Time{})
+			_, err := svc.Authenticate(raw + "x")
+			Expect(err).To(MatchError(apikey.Invalid))
+		})
+		It("Should not authenticate a malformed key", func() {
+			_, err := svc.Authenticate("malformed")
+			Expect(err).To(MatchError(apikey.Invalid))
+		})
+		It("Should not authenticate an expired key", func() {
+			_, raw := create([]access.Action{access.Retrieve}, time.Now().Add(expiry))
+			Eventually(func() error {
+				_, err := svc.Authenticate(raw)
+				return err
+			}).Should(MatchError(apikey.Expired))
+		})
+		It("Should not authenticate a revoked key", func() {
+			k, raw := create([]access.Action{access.Retrieve}, time.Time{})
+			_, err := svc.Authenticate(raw)
+			Expect(err).ToNot(HaveOccurred())
+			txn := svc.BeginTxn()
+			Expect(svc.Revoke(txn, k.Key)).To(Succeed())
+			Expect(txn.Commit()).To(Succeed())
+			_, err = svc.Authenticate(raw)
+			Expect(err).To(MatchError(apikey.Invalid))
+		})
+	})
+	Describe("Create", func() {
+		It("Should require at least one scope", func() {
+			txn := svc.BeginTxn()
+			defer func() { Expect(txn.Close()).To(Succeed()) }()
+			_, _, err := svc.Create(txn, subject, "daq", nil, time.Time{})
+			Expect(err).To(HaveOccurred())
+		})
+		It("Should reject an expiration in the past", func() {
+			txn := svc.BeginTxn()
+			defer func() { Expect(txn.Close()).To(Succeed()) }()
+			_, _, err := svc.Create(
+				txn,
+				subject,
+				"daq",
+				[]access.Action{access.Retrieve},
+				time.Now().Add(-time.Second),
+			)
+			Expect(err).To(HaveOccurred())
+		})
+		It("Should reject a subject that doesn't exist", func() {
+			txn := svc.BeginTxn()
+			defer func() { Expect(txn.Close()).To(Succeed()) }()
+			_, _, err := svc.Create(
+				txn,
+				user.OntologyID(uuid.New()),
+				"daq",
+				[]access.Action{access.Retrieve},
+				time.Time{},
+			)
+			Expect(err).To(MatchError(query.NotFound))
+		})
+		It("Should retrieve the keys of a subject", func() {
+			k, _ := create([]access.Action{access.Retrieve}, time.Time{})
+			keys, err := svc.RetrieveBySubject(subject)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(keys).To(HaveLen(1))
+			Expect(keys[0].Key).To(Equal(k.Key))
+		})
+	})
+	Describe("Enforcer", func() {
+		var (
+			enforcer *apikey.Enforcer
+			object   = ontology.ID{Type: "channel", Key: "1"}
+		)
+		BeforeEach(func() {
+			enforcer = &apikey.Enforcer{Enforcer: subjectEnforcer{subject}, Keys: svc}
+		})
+		It("Should enforce requests on behalf of the key's subject", func() {
+			k, _ := create([]access.Action{access.Retrieve}, time.Time{})
+			Expect(enforcer.Enforce(access.Request{
+				Subject: apikey.OntologyID(k.Key),
+				Object:  object,
+				Action:  access.Retrieve,
+			})).To(Succeed())
+		})
+		It("Should deny actions outside of the key's scopes", func() {
+			k, _ := create([]access.Action{access.Retrieve}, time.Time{})
+			Expect(enforcer.Enforce(access.Request{
+				Subject: apikey.OntologyID(k.Key),
+				Object:  object,
+				Action:  "delete",
+			})).To(MatchError(access.Denied))
+		})
+		It("Should deny requests made by expired keys", func() {
+			k, _ := create([]access.Action{access.AllActions}, time.Now().Add(expiry))
+			Eventually(func() error {
+				return enforcer.Enforce(access.Request{
+					Subject: apikey.OntologyID(k.Key),
+					Object:  object,
+					Action:  access.Retrieve,
+				})
+			}).Should(MatchError(access.Denied))
+		})
+		It("Should deny requests made by keys that don't exist", func() {
+			Expect(enforcer.Enforce(access.Request{
+				Subject: apikey.OntologyID(uuid.New()),
+				Object:  object,
+				Action:  access.Retrieve,
+			})).To(MatchError(access.Denied))
+		})
+	})
+})
+
+// subjectEnforcer grants every request made by a single subject.
+type subjectEnforcer struct{ subject ontology.ID }
+
+func (e subjectEnforcer) Enforce(req access.Request) error {
+	if req.Subject != e.subject {
+		return access.Denied
+	}
+	return access.Granted
+}
@@ -3,14 +3,18 @@ package auth
 import (
 	"github.com/arya-analytics/delta/pkg/auth/password"
 	"github.com/arya-analytics/x/gorp"
+	"github.com/google/uuid"
 )
 
 // Authenticator is an interface for validating the identity of a particular entity (
 // i.e. they are who they say they are).
 type Authenticator interface {
-	// Authenticate validates the identity of the entity with the given credentials.
-	// If the credentials are invalid, an InvalidCredentials error is returned.
-	Authenticate(creds InsecureCredentials) error
+	// Authenticate validates the identity of the entity with the given credentials,
+	// and returns the key of the user they belong to. The user must not be looked up
+	// by the username in the credentials, as it may not match the user's username
+	// exactly. If the credentials are invalid, an InvalidCredentials error is
+	// returned.
+	Authenticate(creds InsecureCredentials) (uuid.UUID, error)
 	// Register registers the given credentials in the authenticator.
 	// If the Authenticator uses the Node's local storage, they can use the provided
 	// txn to perform the registration.
@@ -0,0 +1,168 @@
+package fiber
+
+import (
+	"github.com/arya-analytics/delta/pkg/access"
+	fiberaccess "github.com/arya-analytics/delta/pkg/access/fiber"
+	"github.com/arya-analytics/delta/pkg/auth/apikey"
+	"github.com/arya-analytics/delta/pkg/ontology"
+	"github.com/arya-analytics/x/query"
+	"github.com/cockroachdb/errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"time"
+)
+
+func (s *Service) bindAPIKeys(parent fiber.Router) {
+	router := parent.Group("/keys")
+	router.Use(TokenMiddleware(s.Token, WithAPIKeys(s.APIKeys)))
+	router.Get("/", s.listKeys)
+	router.Post("/", s.createKey)
+	router.Delete("/:key", s.revokeKey)
+}
+
+// keyResponse is the serialized form of an apikey.Key. It omits the hash of the key's
+// secret.
+type keyResponse struct {
+	Key       uuid.UUID       `json:"key"`
+	Name      string          `json:"name"`
+	Subject   string          `json:"subject"`
+	Scopes    []access.Action `json:"scopes"`
+	CreatedAt time.Time       `json:"createdAt"`
+	ExpiresAt *time.Time      `json:"expiresAt,omitempty"`
+}
+
+func newKeyResponse(k apikey.Key) keyResponse {
+	r := keyResponse{
+		Key:       k.Key,
+		Name:      k.Name,
+		Subject:   k.Subject.String(),
+		Scopes:    k.Scopes,
+		CreatedAt: k.CreatedAt,
+	}
+	if !k.ExpiresAt.IsZero() {
+		r.ExpiresAt = &k.ExpiresAt
+	}
+	return r
+}
+
+// listKeys lists the keys of the subject in the 'subject' query parameter, or the
+// keys of the requesting subject if the parameter isn't set.
+func (s *Service) listKeys(c *fiber.Ctx) error {
+	subject, ok, err := s.keySubject(c, c.Query("subject"))
+	if !ok {
+		return err
+	}
+	keys, err := s.APIKeys.RetrieveBySubject(subject)
+	if err != nil {
+		c.Status(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{"error": err.Error()})
+	}
+	res := make([]keyResponse, len(keys))
+	for i, k := range keys {
+		res[i] = newKeyResponse(k)
+	}
+	return c.JSON(res)
+}
+
+type createKeyRequest struct {
+	// Subject is the subject the key acts on behalf of, formatted as returned by
+	// ontology.ID.String. Defaults to the requesting subject.
+	Subject   string          `json:"subject"`
+	Name      string          `json:"name"`
+	Scopes    []access.Action `json:"scopes"`
+	ExpiresAt time.Time       `json:"expiresAt"`
+}
+
+// createKey creates a key and returns its raw value. The raw value can't be
+// retrieved again.
+func (s *Service) createKey(c *fiber.Ctx) error {
+	var req createKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		c.Status(fiber.StatusBadRequest)
+		return c.JSON(fiber.Map{"error": err.Error()})
+	}
+	subject, ok, err := s.keySubject(c, req.Subject)
+	if !ok {
+		return err
+	}
+	txn := s.APIKeys.BeginTxn()
+	k, raw, err := s.APIKeys.Create(txn, subject, req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		c.Status(fiber.StatusBadRequest)
+		return c.JSON(fiber.Map{"error": errors.CombineErrors(err, txn.Close()).Error()})
+	}
+	if err := txn.Commit(); err != nil {
+		c.Status(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{"error": err.Error()})
+	}
+	c.Status(fiber.StatusCreated)
+	return c.JSON(fiber.Map{"key": newKeyResponse(k), "apiKey": raw})
+}
+
+// revokeKey revokes the key in the request path.
+func (s *Service) revokeKey(c *fiber.Ctx) error {
+	key, err := uuid.Parse(c.Params("key"))
+	if err != nil {
+		c.Status(fiber.StatusBadRequest)
+		return c.JSON(fiber.Map{"error": err.Error()})
+	}
+	k, err := s.APIKeys.Retrieve(key)
+	if err != nil {
+		if errors.Is(err, query.NotFound) {
+			c.Status(fiber.StatusNotFound)
+		} else {
+			c.Status(fiber.StatusInternalServerError)
+		}
+		return c.JSON(fiber.Map{"error": err.Error()})
+	}
+	if _, ok, err := s.keySubject(c, k.Subject.String()); !ok {
+		return err
+	}
+	txn := s.APIKeys.BeginTxn()
+	if err := s.APIKeys.Revoke(txn, key); err != nil {
+		c.Status(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{"error": errors.CombineErrors(err, txn.Close()).Error()})
+	}
+	if err := txn.Commit(); err != nil {
+		c.Status(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{"error": err.Error()})
+	}
+	c.Status(fiber.StatusNoContent)
+	return nil
+}
+
+// keySubject resolves the subject whose keys are being managed. Keys can't be
+// managed using an API key, and managing the keys of another subject requires
+// permission to perform all actions on that subject. Returns false if the subject
+// can't be resolved, in which case an error response has been written.
+func (s *Service) keySubject(c *fiber.Ctx, requested string) (ontology.ID, bool, error) {
+	requester, err := fiberaccess.GetSubject(c)
+	if err != nil {
+		return requester, false, err
+	}
+	if requester.Type == apikey.OntologyType {
+		c.Status(fiber.StatusForbidden)
+		return requester, false, c.JSON(fiber.Map{"error": "api keys can't be used to manage api keys"})
+	}
+	if requested == "" || requested == requester.String() {
+		return requester, true, nil
+	}
+	subject, err := ontology.ParseID(requested)
+	if err != nil {
+		c.Status(fiber.StatusBadRequest)
+		return subject, false, c.JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := s.Enforcer.Enforce(access.Request{
+		Subject: requester,
+		Object:  subject,
+		Action:  access.AllActions,
+	}); err != nil {
+		if errors.Is(err, access.Denied) {
+			c.Status(fiber.StatusForbidden)
+		} else {
+			c.Status(fiber.StatusInternalServerError)
+		}
+		return subject, false, c.JSON(fiber.Map{"error": err.Error()})
+	}
+	return subject, true, nil
+}
@@ -0,0 +1,86 @@
+package fiber
+
+import (
+	"crypto/subtle"
+	"github.com/arya-analytics/delta/pkg/auth/oidc"
+	"github.com/cockroachdb/errors"
+	"github.com/gofiber/fiber/v2"
+	"time"
+)
+
+func (s *Service) bindOIDC(parent fiber.Router) {
+	router := parent.Group("/oidc")
+	router.Get("/login", s.oidcLogin)
+	router.Get("/callback", s.oidcCallback)
+}
+
+// oidcStateCookieName is the cookie that binds a login to the browser that began it.
+const oidcStateCookieName = "OIDCState"
+
+// oidcLogin redirects the user to the identity provider to sign in. The state of the
+// login is set as a cookie, so that only the browser that began the login can
+// complete it.
+func (s *Service) oidcLogin(c *fiber.Ctx) error {
+	u, state, err := s.OIDC.Begin()
+	if err != nil {
+		c.Status(fiber.StatusBadGateway)
+		return c.JSON(fiber.Map{"error": err.Error()})
+	}
+	c.Cookie(&fiber.Cookie{
+		Name:     oidcStateCookieName,
+		Value:    state,
+		Path:     "/",
+		Expires:  time.Now().Add(s.OIDC.LoginExpiration),
+		Secure:   c.Protocol() == "https",
+		HTTPOnly: true,
+		// The provider redirects back with a top-level GET, which Lax cookies are
+		// sent with.
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+	return c.Redirect(u, fiber.StatusFound)
+}
+
+// oidcCallback completes a login after the identity provider redirects the user back.
+// The state the provider redirects back with must match the state cookie set by
+// oidcLogin. Responds with a delta token, which is also set as a cookie so that subsequent
+// requests from the browser are authenticated.
+func (s *Service) oidcCallback(c *fiber.Ctx) error {
+	cookie := c.Cookies(oidcStateCookieName)
+	c.ClearCookie(oidcStateCookieName)
+	if e := c.Query("error"); e != "" {
+		c.Status(fiber.StatusUnauthorized)
+		return c.JSON(fiber.Map{"error": e + ": " + c.Query("error_description")})
+	}
+	state := c.Query("state")
+	if cookie == "" || subtle.ConstantTimeCompare([]byte(cookie), []byte(state)) != 1 {
+		c.Status(fiber.StatusUnauthorized)
+		return c.JSON(fiber.Map{"error": "login wasn't started by this browser"})
+	}
+	u, err := s.OIDC.Complete(state, c.Query("code"))
+	if err != nil {
+		switch {
+		case errors.Is(err, oidc.InvalidLogin), errors.Is(err, oidc.InvalidIDToken):
+			c.Status(fiber.StatusUnauthorized)
+		case errors.Is(err, oidc.UsernameTaken):
+			c.Status(fiber.StatusConflict)
+		default:
+			c.Status(fiber.StatusBadGateway)
+		}
+		return c.JSON(fiber.Map{"error": err.Error()})
+	}
+	pair, err := s.issueTokens(u)
+	if err != nil {
+		c.Status(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{"error": err.Error()})
+	}
+	c.Cookie(&fiber.Cookie{
+		Name:     tokenCookieName,
+		Value:    pair.Access,
+		Path:     "/",
+		Expires:  time.Now().Add(s.Token.Expiration),
+		Secure:   c.Protocol() == "https",
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+	return c.JSON(tokenBody(u, pair))
+}
@@ -0,0 +1,119 @@
+package fiber_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	fiberauth "github.com/arya-analytics/delta/pkg/auth/fiber"
+	"github.com/arya-analytics/delta/pkg/auth/oidc"
+	"github.com/arya-analytics/delta/pkg/auth/oidc/mock"
+	"github.com/arya-analytics/delta/pkg/auth/token"
+	"github.com/arya-analytics/delta/pkg/ontology"
+	"github.com/arya-analytics/delta/pkg/user"
+	"github.com/arya-analytics/x/gorp"
+	"github.com/arya-analytics/x/kv/memkv"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+)
+
+const clientID = "delta"
+
+var _ = Describe("OIDC", func() {
+	var (
+		idp *mock.IdentityProvider
+		db  *gorp.DB
+		app *fiber.App
+	)
+	BeforeEach(func() {
+		var err error
+		idp, err = mock.NewIdentityProvider(clientID)
+		Expect(err).ToNot(HaveOccurred())
+		idp.SignIn("alice-sub", jwt.MapClaims{"preferred_username": "alice"})
+		db = gorp.Wrap(memkv.New())
+		otg, err := ontology.Open(db)
+		Expect(err).ToNot(HaveOccurred())
+		users := user.New(db, otg)
+		otg.RegisterService(users)
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).ToNot(HaveOccurred())
+		app = fiber.New()
+		(&fiberauth.Service{
+			User:  users,
+			Token: &token.Service{Secret: key, Expiration: time.Hour},
+			DB:    db,
+			OIDC: oidc.New(oidc.Config{
+				Issuer:      idp.URL,
+				ClientID:    clientID,
+				RedirectURL: "http://delta.example.com/auth/oidc/callback",
+			}, db, users),
+		}).BindTo(app)
+	})
+	AfterEach(func() {
+		idp.Close()
+		Expect(db.Close()).To(Succeed())
+	})
+	// login begins a login and follows the redirect to the identity provider. Returns
+	// the state cookie set by delta and the callback URL the provider redirects back
+	// to.
+	login := func() (*http.Cookie, string) {
+		res, err := app.Test(httptest.NewRequest("GET", "/auth/oidc/login", nil))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.StatusCode).To(Equal(fiber.StatusFound))
+		Expect(res.Cookies()).To(HaveLen(1))
+		cookie := res.Cookies()[0]
+		Expect(cookie.HttpOnly).To(BeTrue())
+		client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}}
+		res, err = client.Get(res.Header.Get("Location"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.Body.Close()).To(Succeed())
+		Expect(res.StatusCode).To(Equal(http.StatusFound))
+		callback, err := url.Parse(res.Header.Get("Location"))
+		Expect(err).ToNot(HaveOccurred())
+		return cookie, callback.RequestURI()
+	}
+	// callback sends the request the provider redirected the browser to, along with
+	// the given state cookie, and returns the status code and body of the response.
+	callback := func(uri string, cookie *http.Cookie) (int, []byte) {
+		req := httptest.NewRequest("GET", uri, nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		res, err := app.Test(req)
+		Expect(err).ToNot(HaveOccurred())
+		b, err := io.ReadAll(res.Body)
+		Expect(err).ToNot(HaveOccurred())
+		return res.StatusCode, b
+	}
+	It("Should complete a login in the browser that began it", func() {
+		cookie, uri := login()
+		status, body := callback(uri, cookie)
+		Expect(status).To(Equal(fiber.StatusOK))
+		var res struct {
+			User  user.User `json:"user"`
+			Token string    `json:"Token"`
+		}
+		Expect(json.Unmarshal(body, &res)).To(Succeed())
+		Expect(res.User.Username).To(Equal("alice"))
+		Expect(res.Token).ToNot(BeEmpty())
+	})
+	It("Should not complete a login without the state cookie", func() {
+		_, uri := login()
+		status, _ := callback(uri, nil)
+		Expect(status).To(Equal(fiber.StatusUnauthorized))
+	})
+	It("Should not complete a login begun by another browser", func() {
+		cookie, _ := login()
+		_, uri := login()
+		status, _ := callback(uri, cookie)
+		Expect(status).To(Equal(fiber.StatusUnauthorized))
+	})
+})
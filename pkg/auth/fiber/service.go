@@ -4,11 +4,14 @@ import (
 	"github.com/arya-analytics/delta/pkg/access"
 	fiberaccess "github.com/arya-analytics/delta/pkg/access/fiber"
 	"github.com/arya-analytics/delta/pkg/auth"
+	"github.com/arya-analytics/delta/pkg/auth/apikey"
+	"github.com/arya-analytics/delta/pkg/auth/oidc"
 	"github.com/arya-analytics/delta/pkg/auth/password"
 	"github.com/arya-analytics/delta/pkg/auth/token"
 	"github.com/arya-analytics/delta/pkg/ontology"
 	"github.com/arya-analytics/delta/pkg/user"
 	"github.com/arya-analytics/x/gorp"
+	"github.com/cockroachdb/errors"
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -18,12 +21,27 @@ type Service struct {
 	DB       *gorp.DB
 	Auth     auth.Authenticator
 	Enforcer access.Enforcer
+	// APIKeys manages API keys. If APIKeys is nil, API keys aren't accepted and their
+	// endpoints aren't bound.
+	APIKeys *apikey.Service
+	// OIDC signs users in through an OpenID Connect identity provider. If OIDC is nil,
+	// its endpoints aren't bound.
+	OIDC *oidc.Service
 }
 
 func (s *Service) BindTo(parent fiber.Router) {
+	parent.Get("/.well-known/jwks.json", s.jwks)
 	router := parent.Group("/auth")
 	router.Post("/login", s.login)
 	router.Post("/register", s.register)
+	router.Post("/refresh", s.refresh)
+	router.Post("/logout", TokenMiddleware(s.Token), s.logout)
+	if s.APIKeys != nil {
+		s.bindAPIKeys(router)
+	}
+	if s.OIDC != nil {
+		s.bindOIDC(router)
+	}
 	protected := parent.Group("/protected")
 	protected.Use(TokenMiddleware(s.Token))
 	protected.Use(fiberaccess.StaticMiddleware(
@@ -41,11 +59,12 @@ func (s *Service) login(c *fiber.Ctx) error {
 		c.Status(fiber.StatusBadRequest)
 		return c.JSON(fiber.Map{"error": err.Error()})
 	}
-	if err := s.Auth.Authenticate(creds); err != nil {
+	key, err := s.Auth.Authenticate(creds)
+	if err != nil {
 		c.Status(fiber.StatusUnauthorized)
 		return c.JSON(fiber.Map{"error": err.Error()})
 	}
-	u, err := s.User.RetrieveByUsername(creds.Username)
+	u, err := s.User.Retrieve(key)
 	if err != nil {
 		c.Status(fiber.StatusNotFound)
 		return c.JSON(fiber.Map{"error": err.Error()})
@@ -60,7 +79,7 @@ type registrationRequest struct {
 
 func (s *Service) register(c *fiber.Ctx) error {
 	var req registrationRequest
-	txn := s.DB.BeginTxn()
+	txn := s.User.BeginTxn()
 	if err := c.BodyParser(&req); err != nil {
 		c.Status(fiber.StatusBadRequest)
 		return c.JSON(fiber.Map{"error": err.Error()})
@@ -138,10 +157,95 @@ func (s *Service) changeUsername(c *fiber.Ctx) error {
 }
 
 func (s *Service) tokenResponse(c *fiber.Ctx, u user.User) error {
-	tk, err := s.Token.New(u.Key)
+	pair, err := s.issueTokens(u)
+	if err != nil {
+		c.Status(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(tokenBody(u, pair))
+}
+
+// issueTokens issues an access token and refresh token for the user. If the token
+// Service has no DB to store refresh tokens in, only an access token is issued.
+func (s *Service) issueTokens(u user.User) (token.Pair, error) {
+	if s.Token.DB == nil {
+		tk, err := s.Token.New(u.Key)
+		return token.Pair{Access: tk}, err
+	}
+	return s.Token.NewPair(u.Key)
+}
+
+func tokenBody(u user.User, pair token.Pair) fiber.Map {
+	body := fiber.Map{"user": u, "Token": pair.Access}
+	if pair.Refresh != "" {
+		body["refreshToken"] = pair.Refresh
+	}
+	return body
+}
+
+// jwks returns the public keys that verify tokens issued by the service.
+func (s *Service) jwks(c *fiber.Ctx) error {
+	set, err := s.Token.JWKS()
+	if err != nil {
+		c.Status(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(set)
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// refresh exchanges a refresh token for a new access token and refresh token. The
+// refresh token in the request can't be used again.
+func (s *Service) refresh(c *fiber.Ctx) error {
+	var req refreshRequest
+	if err := c.BodyParser(&req); err != nil {
+		c.Status(fiber.StatusBadRequest)
+		return c.JSON(fiber.Map{"error": err.Error()})
+	}
+	pair, err := s.Token.Refresh(req.RefreshToken)
 	if err != nil {
+		if errors.Is(err, token.InvalidRefresh) {
+			c.Status(fiber.StatusUnauthorized)
+		} else if errors.Is(err, token.NoDB) {
+			c.Status(fiber.StatusNotImplemented)
+		} else {
+			c.Status(fiber.StatusInternalServerError)
+		}
+		return c.JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"Token": pair.Access, "refreshToken": pair.Refresh})
+}
+
+// logout revokes the access token used to make the request, along with the refresh
+// token in the request body (if any) and every token refreshed from it. Access tokens
+// issued before tokens had IDs can't be revoked, and remain valid until they expire.
+func (s *Service) logout(c *fiber.Ctx) error {
+	var req refreshRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			c.Status(fiber.StatusBadRequest)
+			return c.JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+	tk, err := parseToken(c)
+	if err != nil {
+		return err
+	}
+	if err := s.Token.Revoke(tk); err != nil && !errors.Is(err, token.Irrevocable) {
 		c.Status(fiber.StatusInternalServerError)
 		return c.JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.JSON(fiber.Map{"user": u, "Token": tk})
+	if req.RefreshToken != "" {
+		if err := s.Token.RevokeRefresh(req.RefreshToken); err != nil &&
+			!errors.Is(err, token.InvalidRefresh) {
+			c.Status(fiber.StatusInternalServerError)
+			return c.JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+	c.ClearCookie(tokenCookieName)
+	c.Status(fiber.StatusNoContent)
+	return nil
 }
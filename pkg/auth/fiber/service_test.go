@@ -0,0 +1,111 @@
+package fiber_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"github.com/arya-analytics/delta/pkg/auth"
+	fiberauth "github.com/arya-analytics/delta/pkg/auth/fiber"
+	"github.com/arya-analytics/delta/pkg/auth/token"
+	"github.com/arya-analytics/delta/pkg/ontology"
+	"github.com/arya-analytics/delta/pkg/user"
+	"github.com/arya-analytics/x/gorp"
+	"github.com/arya-analytics/x/kv/memkv"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"net/http/httptest"
+	"strings"
+	"time"
+)
+
+// directoryAuthenticator authenticates every user as a single user, as a directory
+// does for a user whose username differs in case from the one they log in with.
+type directoryAuthenticator struct {
+	auth.Authenticator
+	user uuid.UUID
+}
+
+func (a directoryAuthenticator) Authenticate(auth.InsecureCredentials) (uuid.UUID, error) {
+	return a.user, nil
+}
+
+var _ = Describe("Service", func() {
+	var (
+		db     *gorp.DB
+		app    *fiber.App
+		users  *user.Service
+		tokens *token.Service
+		authn  *directoryAuthenticator
+	)
+	BeforeEach(func() {
+		db = gorp.Wrap(memkv.New())
+		otg, err := ontology.Open(db)
+		Expect(err).ToNot(HaveOccurred())
+		users = user.New(db, otg)
+		otg.RegisterService(users)
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).ToNot(HaveOccurred())
+		tokens = &token.Service{Secret: key, Expiration: time.Hour, DB: db}
+		authn = &directoryAuthenticator{}
+		app = fiber.New()
+		(&fiberauth.Service{User: users, Token: tokens, DB: db, Auth: authn}).BindTo(app)
+	})
+	AfterEach(func() { Expect(db.Close()).To(Succeed()) })
+	Describe("Login", func() {
+		It("Should issue a token for the user the credentials belong to", func() {
+			txn := users.BeginTxn()
+			local := &user.User{Username: "alice"}
+			Expect(users.Create(txn, local)).To(Succeed())
+			directory := &user.User{Username: "Alice"}
+			Expect(users.Create(txn, directory)).To(Succeed())
+			Expect(txn.Commit()).To(Succeed())
+			Expect(txn.Close()).To(Succeed())
+			authn.user = directory.Key
+			req := httptest.NewRequest(
+				"POST",
+				"/auth/login",
+				strings.NewReader(`{"username": "alice", "password": "Alice-pass"}`),
+			)
+			req.Header.Set("Content-Type", "application/json")
+			res, err := app.Test(req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res.StatusCode).To(Equal(fiber.StatusOK))
+			var body struct {
+				User  user.User `json:"user"`
+				Token string    `json:"Token"`
+			}
+			Expect(json.NewDecoder(res.Body).Decode(&body)).To(Succeed())
+			Expect(body.User.Key).To(Equal(directory.Key))
+			issuer, err := tokens.Validate(body.Token)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(issuer).To(Equal(directory.Key))
+		})
+	})
+	Describe("Logout", func() {
+		logout := func(tk string) int {
+			req := httptest.NewRequest("POST", "/auth/logout", nil)
+			req.Header.Set("Authorization", "Bearer "+tk)
+			res, err := app.Test(req)
+			Expect(err).ToNot(HaveOccurred())
+			return res.StatusCode
+		}
+		It("Should revoke the access token", func() {
+			tk, err := tokens.New(uuid.New())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(logout(tk)).To(Equal(fiber.StatusNoContent))
+			_, err = tokens.Validate(tk)
+			Expect(err).To(MatchError(token.Revoked))
+		})
+		It("Should log out with a token issued before tokens had IDs", func() {
+			tk, err := jwt.NewWithClaims(jwt.SigningMethodRS512, jwt.StandardClaims{
+				Issuer:    uuid.New().String(),
+				ExpiresAt: time.Now().Add(time.Hour).Unix(),
+			}).SignedString(tokens.Secret)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(logout(tk)).To(Equal(fiber.StatusNoContent))
+		})
+	})
+})
@@ -2,6 +2,7 @@ package fiber
 
 import (
 	fiberaccess "github.com/arya-analytics/delta/pkg/access/fiber"
+	"github.com/arya-analytics/delta/pkg/auth/apikey"
 	"github.com/arya-analytics/delta/pkg/auth/token"
 	"github.com/arya-analytics/delta/pkg/user"
 	"github.com/cockroachdb/errors"
@@ -11,16 +12,46 @@ import (
 
 const localsUserKey = "userKey"
 
+// MiddlewareOption configures the TokenMiddleware.
+type MiddlewareOption func(o *middlewareOptions)
+
+type middlewareOptions struct {
+	apiKeys *apikey.Service
+}
+
+// WithAPIKeys accepts API keys in the APIKeyHeader in place of a token. The subject of
+// a request authenticated by an API key is the key itself (see apikey.Enforcer).
+func WithAPIKeys(keys *apikey.Service) MiddlewareOption {
+	return func(o *middlewareOptions) { o.apiKeys = keys }
+}
+
+// APIKeyHeader is the header that holds an API key.
+const APIKeyHeader = "X-API-Key"
+
 // TokenMiddleware parses a token from the request and checks if it is valid.
 // If the token is valid, it sets the user's resource key in the request context.
-func TokenMiddleware(svc *token.Service) fiber.Handler {
+func TokenMiddleware(svc *token.Service, opts ...MiddlewareOption) fiber.Handler {
+	o := &middlewareOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
 	return func(c *fiber.Ctx) error {
+		if raw := c.Get(APIKeyHeader); raw != "" && o.apiKeys != nil {
+			k, err := o.apiKeys.Authenticate(raw)
+			if err != nil {
+				c.Status(fiber.StatusUnauthorized)
+				return err
+			}
+			fiberaccess.SetSubject(c, apikey.OntologyID(k.Key))
+			return c.Next()
+		}
 		tk, err := parseToken(c)
 		if err != nil {
 			return err
 		}
 		key, err := svc.Validate(tk)
 		if err != nil {
+			c.Status(fiber.StatusUnauthorized)
 			return err
 		}
 		fiberaccess.SetSubject(c, user.OntologyID(key))
@@ -57,7 +88,7 @@ func parseToken(c *fiber.Ctx) (string, error) {
 
 func typeParseCookieToken(c *fiber.Ctx) (string, bool, error) {
 	tk := c.Cookies(tokenCookieName)
-	return tk, len(tk) == 0, nil
+	return tk, len(tk) != 0, nil
 }
 
 func tryParseHeaderToken(c *fiber.Ctx) (string, bool, error) {
@@ -0,0 +1,70 @@
+package ldap
+
+import (
+	"crypto/tls"
+	"github.com/arya-analytics/delta/pkg/ontology"
+	"time"
+)
+
+// Config configures how an Authenticator connects to and searches a directory.
+type Config struct {
+	// URL is the URL of the directory server (e.g. "ldaps://dc.example.com:636").
+	// Provisioned users are linked to the URL and DN of their entries, so users
+	// provisioned from one URL fail to log in through another with UsernameTaken.
+	URL string
+	// TLS configures TLS for ldaps:// URLs and StartTLS.
+	TLS *tls.Config
+	// StartTLS upgrades ldap:// connections to TLS before binding.
+	StartTLS bool
+	// Timeout is the timeout for connecting to the directory and for each request.
+	// Defaults to 10 seconds.
+	Timeout time.Duration
+	// UserDN is a template for the DN of a user, where %s is replaced with the escaped
+	// username (e.g. "uid=%s,ou=people,dc=example,dc=com"). If UserDN is empty, the DN
+	// of the user is found by binding as BindDN and searching UserBaseDN with
+	// UserFilter, which is the usual setup for Active Directory.
+	UserDN string
+	// BindDN and BindPassword are the credentials of the service account used to
+	// search for users.
+	BindDN       string
+	BindPassword string
+	// UserBaseDN is the DN to search for users under.
+	UserBaseDN string
+	// UserFilter is a template for the filter that matches a user, where %s is
+	// replaced with the escaped username. Defaults to "(uid=%s)". Active Directory
+	// uses "(sAMAccountName=%s)".
+	UserFilter string
+	// GroupBaseDN is the DN to search for the groups of a user under. If GroupBaseDN
+	// is empty, group memberships aren't retrieved.
+	GroupBaseDN string
+	// GroupFilter is a template for the filter that matches the groups of a user,
+	// where %s is replaced with the escaped DN of the user. Defaults to
+	// "(member=%s)".
+	GroupFilter string
+	// GroupAttribute is the attribute holding the name of a group. Defaults to "cn".
+	GroupAttribute string
+	// Groups maps the names of directory groups to delta groups. On each login, the
+	// user is made a child of the delta groups of the directory groups they're a
+	// member of, and removed from the rest.
+	Groups map[string]ontology.ID
+	// RequiredGroups restricts login to the members of at least one of the given
+	// directory groups. If RequiredGroups is empty, any user in the directory can
+	// log in.
+	RequiredGroups []string
+}
+
+func (c Config) withDefaults() Config {
+	if c.Timeout == 0 {
+		c.Timeout = 10 * time.Second
+	}
+	if c.UserFilter == "" {
+		c.UserFilter = "(uid=%s)"
+	}
+	if c.GroupFilter == "" {
+		c.GroupFilter = "(member=%s)"
+	}
+	if c.GroupAttribute == "" {
+		c.GroupAttribute = "cn"
+	}
+	return c
+}
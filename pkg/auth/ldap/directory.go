@@ -0,0 +1,159 @@
+package ldap
+
+import (
+	"fmt"
+	"github.com/arya-analytics/delta/pkg/auth"
+	"github.com/cockroachdb/errors"
+	goldap "github.com/go-ldap/ldap/v3"
+	"net"
+	"strings"
+)
+
+// entry is a user found in the directory.
+type entry struct {
+	dn     string
+	groups []string
+}
+
+// lookup binds to the directory with the given credentials, and returns the user's
+// entry. Returns auth.InvalidCredentials if the directory rejects the credentials.
+func (c Config) lookup(creds auth.InsecureCredentials) (entry, error) {
+	c = c.withDefaults()
+	var e entry
+	if creds.Username == "" || creds.Password == "" {
+		return e, auth.InvalidCredentials
+	}
+	conn, err := c.dial()
+	if err != nil {
+		return e, err
+	}
+	defer conn.Close()
+	if e.dn, err = c.userDN(conn, creds.Username); err != nil {
+		return e, err
+	}
+	if err := conn.Bind(e.dn, string(creds.Password)); err != nil {
+		return e, translateErr(err)
+	}
+	if e.groups, err = c.groups(conn, e.dn); err != nil {
+		return e, err
+	}
+	if !c.permitted(e.groups) {
+		return e, errors.Wrap(auth.InvalidCredentials, "[ldap] - user isn't a member of a required group")
+	}
+	return e, nil
+}
+
+func (c Config) dial() (*goldap.Conn, error) {
+	conn, err := goldap.DialURL(
+		c.URL,
+		goldap.DialWithDialer(&net.Dialer{Timeout: c.Timeout}),
+		goldap.DialWithTLSConfig(c.TLS),
+	)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(c.Timeout)
+	if c.StartTLS {
+		if err := conn.StartTLS(c.TLS); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+func (c Config) userDN(conn *goldap.Conn, username string) (string, error) {
+	if c.UserDN != "" {
+		return fmt.Sprintf(c.UserDN, escapeDN(username)), nil
+	}
+	if err := conn.Bind(c.BindDN, c.BindPassword); err != nil {
+		return "", errors.Wrap(err, "[ldap] - failed to bind service account")
+	}
+	res, err := conn.Search(goldap.NewSearchRequest(
+		c.UserBaseDN,
+		goldap.ScopeWholeSubtree,
+		goldap.NeverDerefAliases,
+		2,
+		0,
+		false,
+		fmt.Sprintf(c.UserFilter, goldap.EscapeFilter(username)),
+		[]string{"dn"},
+		nil,
+	))
+	if err != nil {
+		return "", err
+	}
+	if len(res.Entries) != 1 {
+		return "", errors.Wrapf(
+			auth.InvalidCredentials,
+			"[ldap] - expected one user named %s, found %d",
+			username, len(res.Entries),
+		)
+	}
+	return res.Entries[0].DN, nil
+}
+
+func (c Config) groups(conn *goldap.Conn, dn string) ([]string, error) {
+	if c.GroupBaseDN == "" {
+		return nil, nil
+	}
+	res, err := conn.Search(goldap.NewSearchRequest(
+		c.GroupBaseDN,
+		goldap.ScopeWholeSubtree,
+		goldap.NeverDerefAliases,
+		0,
+		0,
+		false,
+		fmt.Sprintf(c.GroupFilter, goldap.EscapeFilter(dn)),
+		[]string{c.GroupAttribute},
+		nil,
+	))
+	if err != nil {
+		return nil, err
+	}
+	groups := make([]string, 0, len(res.Entries))
+	for _, e := range res.Entries {
+		if name := e.GetAttributeValue(c.GroupAttribute); name != "" {
+			groups = append(groups, name)
+		}
+	}
+	return groups, nil
+}
+
+func (c Config) permitted(groups []string) bool {
+	if len(c.RequiredGroups) == 0 {
+		return true
+	}
+	for _, r := range c.RequiredGroups {
+		if contains(groups, r) {
+			return true
+		}
+	}
+	return false
+}
+
+func translateErr(err error) error {
+	if goldap.IsErrorWithCode(err, goldap.LDAPResultInvalidCredentials) {
+		return errors.Wrap(auth.InvalidCredentials, err.Error())
+	}
+	return err
+}
+
+// escapeDN escapes the special characters of an attribute value in a DN (RFC 4514).
+func escapeDN(v string) string {
+	var b strings.Builder
+	for i, r := range v {
+		switch {
+		case strings.ContainsRune(`,+"\<>;=`, r),
+			(r == ' ' || r == '#') && i == 0,
+			r == ' ' && i == len(v)-1:
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == 0:
+			b.WriteString(`\00`)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
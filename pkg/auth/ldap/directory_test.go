@@ -0,0 +1,181 @@
+package ldap_test
+
+import (
+	ber "github.com/go-asn1-ber/asn1-ber"
+	goldap "github.com/go-ldap/ldap/v3"
+	"net"
+	"strings"
+	"sync"
+)
+
+// directory is an in-process stand-in for an LDAP server. It supports simple binds
+// and searches with equality, presence, and, or and not filters, which is enough
+// to exercise the Authenticator.
+type directory struct {
+	lis  net.Listener
+	mu   sync.Mutex
+	dirs map[string]dirEntry
+}
+
+type dirEntry struct {
+	password string
+	attrs    map[string][]string
+}
+
+func newDirectory() (*directory, error) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	d := &directory{lis: lis, dirs: make(map[string]dirEntry)}
+	go d.serve()
+	return d, nil
+}
+
+func (d *directory) URL() string { return "ldap://" + d.lis.Addr().String() }
+
+func (d *directory) Close() error { return d.lis.Close() }
+
+// add adds an entry with the given DN. Entries with a password can be bound to.
+func (d *directory) add(dn, password string, attrs map[string][]string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.dirs[strings.ToLower(dn)] = dirEntry{password: password, attrs: attrs}
+}
+
+func (d *directory) serve() {
+	for {
+		conn, err := d.lis.Accept()
+		if err != nil {
+			return
+		}
+		go d.handle(conn)
+	}
+}
+
+func (d *directory) handle(conn net.Conn) {
+	defer conn.Close()
+	for {
+		req, err := ber.ReadPacket(conn)
+		if err != nil || len(req.Children) < 2 {
+			return
+		}
+		id := req.Children[0].Value.(int64)
+		op := req.Children[1]
+		switch op.Tag {
+		case goldap.ApplicationBindRequest:
+			code := d.bind(op.Children[1].Value.(string), op.Children[2].Data.String())
+			err = write(conn, id, result(goldap.ApplicationBindResponse, code))
+		case goldap.ApplicationSearchRequest:
+			err = d.search(conn, id, op)
+		case goldap.ApplicationUnbindRequest:
+			return
+		default:
+			err = write(conn, id, result(goldap.ApplicationExtendedResponse, goldap.LDAPResultUnwillingToPerform))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (d *directory) bind(dn, password string) uint16 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	e, ok := d.dirs[strings.ToLower(dn)]
+	if !ok || e.password == "" || e.password != password {
+		return goldap.LDAPResultInvalidCredentials
+	}
+	return goldap.LDAPResultSuccess
+}
+
+func (d *directory) search(conn net.Conn, id int64, op *ber.Packet) error {
+	base := strings.ToLower(op.Children[0].Value.(string))
+	filter := op.Children[6]
+	d.mu.Lock()
+	var matches []*ber.Packet
+	for dn, e := range d.dirs {
+		if !strings.HasSuffix(dn, base) || !match(filter, dn, e) {
+			continue
+		}
+		res := ber.Encode(ber.ClassApplication, ber.TypeConstructed, goldap.ApplicationSearchResultEntry, nil, "Entry")
+		res.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, dn, "DN"))
+		attrs := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+		for name, vals := range e.attrs {
+			attr := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attribute")
+			attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "Name"))
+			set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+			for _, v := range vals {
+				set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, "Value"))
+			}
+			attr.AppendChild(set)
+			attrs.AppendChild(attr)
+		}
+		res.AppendChild(attrs)
+		matches = append(matches, res)
+	}
+	d.mu.Unlock()
+	for _, m := range matches {
+		if err := write(conn, id, m); err != nil {
+			return err
+		}
+	}
+	return write(conn, id, result(goldap.ApplicationSearchResultDone, goldap.LDAPResultSuccess))
+}
+
+func match(filter *ber.Packet, dn string, e dirEntry) bool {
+	switch filter.Tag {
+	case goldap.FilterAnd:
+		for _, f := range filter.Children {
+			if !match(f, dn, e) {
+				return false
+			}
+		}
+		return true
+	case goldap.FilterOr:
+		for _, f := range filter.Children {
+			if match(f, dn, e) {
+				return true
+			}
+		}
+		return false
+	case goldap.FilterNot:
+		return !match(filter.Children[0], dn, e)
+	case goldap.FilterPresent:
+		_, ok := attr(e, filter.Data.String())
+		return ok
+	case goldap.FilterEqualityMatch:
+		vals, _ := attr(e, filter.Children[0].Data.String())
+		for _, v := range vals {
+			if strings.EqualFold(v, filter.Children[1].Data.String()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func attr(e dirEntry, name string) ([]string, bool) {
+	for k, v := range e.attrs {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+func result(tag ber.Tag, code uint16) *ber.Packet {
+	res := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Result")
+	res.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), "Code"))
+	res.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	res.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Message"))
+	return res
+}
+
+func write(conn net.Conn, id int64, op *ber.Packet) error {
+	p := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Response")
+	p.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "Message ID"))
+	p.AppendChild(op)
+	_, err := conn.Write(p.Bytes())
+	return err
+}
@@ -0,0 +1,168 @@
+// Package ldap implements an auth.Authenticator backed by an LDAP directory (such as
+// OpenLDAP or Active Directory).
+package ldap
+
+import (
+	"github.com/arya-analytics/delta/pkg/auth"
+	"github.com/arya-analytics/delta/pkg/auth/password"
+	"github.com/arya-analytics/delta/pkg/ontology"
+	"github.com/arya-analytics/delta/pkg/user"
+	"github.com/arya-analytics/x/gorp"
+	"github.com/arya-analytics/x/query"
+	"github.com/cockroachdb/errors"
+	"github.com/google/uuid"
+	"strings"
+)
+
+// Authenticator authenticates users by binding to an LDAP directory with their
+// credentials. The first time a user logs in, a user.User with the same username is
+// provisioned and linked to their directory entry. Later logins are resolved through
+// the link, so directory users can never log in as users they weren't provisioned
+// as. On every login, the user's memberships in delta groups are synced with
+// their directory groups (see Config.Groups).
+//
+// Users are managed by the directory, so Authenticator can't register users or update
+// their credentials. Chain it with other Authenticators using auth.MultiAuthenticator
+// to support local users.
+type Authenticator struct {
+	Config
+	// DB is the DB users and their group memberships are provisioned in.
+	DB *gorp.DB
+	// Users provisions users on their first login.
+	Users *user.Service
+	// Resources is the ontology that holds the relationships between users and groups.
+	Resources *ontology.Ontology
+}
+
+var _ auth.Authenticator = (*Authenticator)(nil)
+
+var (
+	// ManagedByDirectory is returned when attempting to register or update the
+	// credentials of a user through the Authenticator.
+	ManagedByDirectory = errors.New("[ldap] - users are managed by the directory")
+	// UsernameTaken is returned when a directory user logs in for the first time with
+	// the username of an existing user that wasn't provisioned for their entry. Users
+	// are never linked to a directory entry implicitly, as the existing user may be a
+	// local user with different permissions.
+	UsernameTaken = errors.New("[ldap] - username belongs to another user")
+)
+
+// Authenticate implements the auth.Authenticator interface. Returns the key of the
+// user linked to the directory entry the credentials bind as. Directories match
+// usernames case-insensitively, so the user's username may differ from the one in the
+// credentials.
+func (a *Authenticator) Authenticate(creds auth.InsecureCredentials) (uuid.UUID, error) {
+	e, err := a.lookup(creds)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	txn := a.Resources.BeginTxn()
+	u, err := a.provision(txn, creds.Username, e)
+	if err != nil {
+		return uuid.Nil, errors.CombineErrors(err, txn.Close())
+	}
+	return u.Key, errors.CombineErrors(txn.Commit(), txn.Close())
+}
+
+// Register implements the auth.Authenticator interface. Always returns an error, as
+// users are managed by the directory.
+func (a *Authenticator) Register(gorp.Txn, auth.InsecureCredentials) error {
+	return errors.Wrap(auth.RegistrationFailed, ManagedByDirectory.Error())
+}
+
+// UpdateUsername implements the auth.Authenticator interface. Always returns an
+// error, as users are managed by the directory.
+func (a *Authenticator) UpdateUsername(gorp.Txn, auth.InsecureCredentials, string) error {
+	return ManagedByDirectory
+}
+
+// UpdatePassword implements the auth.Authenticator interface. Always returns an
+// error, as users are managed by the directory.
+func (a *Authenticator) UpdatePassword(gorp.Txn, auth.InsecureCredentials, password.Raw) error {
+	return ManagedByDirectory
+}
+
+// provision resolves the user linked to the directory entry, and syncs its group
+// memberships.
+func (a *Authenticator) provision(txn gorp.Txn, username string, e entry) (user.User, error) {
+	u, err := a.resolve(txn, username, e.dn)
+	if err != nil {
+		return u, err
+	}
+	member := make(map[ontology.ID]bool, len(a.Groups))
+	for name, g := range a.Groups {
+		member[g] = member[g] || contains(e.groups, name)
+	}
+	w := a.Resources.NewWriter(txn)
+	for g, ok := range member {
+		if !ok {
+			if err := w.DeleteRelationship(user.OntologyID(u.Key), g, ontology.Parent); err != nil {
+				return u, err
+			}
+			continue
+		}
+		if err := w.DefineResource(g); err != nil {
+			return u, err
+		}
+		if err := w.DefineRelationship(user.OntologyID(u.Key), g, ontology.Parent); err != nil {
+			return u, err
+		}
+	}
+	return u, nil
+}
+
+// resolve returns the user linked to the directory entry with the given DN,
+// provisioning a new user if the entry isn't linked to one.
+func (a *Authenticator) resolve(txn gorp.Txn, username, dn string) (user.User, error) {
+	var id identity
+	err := gorp.NewRetrieve[string, identity]().
+		WhereKeys(a.identityKey(dn)).
+		Entry(&id).
+		Exec(a.DB)
+	if err == nil {
+		return a.Users.Retrieve(id.User)
+	}
+	if !errors.Is(err, query.NotFound) {
+		return user.User{}, err
+	}
+	if _, err := a.Users.RetrieveByUsername(username); err == nil {
+		return user.User{}, errors.Wrapf(UsernameTaken, "[ldap] - %s", username)
+	} else if !errors.Is(err, query.NotFound) {
+		return user.User{}, err
+	}
+	u := user.User{Username: username}
+	if err := a.Users.Create(txn, &u); err != nil {
+		return u, err
+	}
+	id = identity{Key: a.identityKey(dn), User: u.Key}
+	return u, gorp.NewCreate[string, identity]().Entry(&id).Exec(txn)
+}
+
+// identityKey returns the key of the identity of the entry with the given DN. DNs are
+// case-insensitive, so they're lowercased.
+func (a *Authenticator) identityKey(dn string) string {
+	return a.URL + " " + strings.ToLower(dn)
+}
+
+// identity links a directory entry to the user provisioned for it.
+type identity struct {
+	// Key is the URL of the directory and the DN of the entry.
+	Key  string
+	User uuid.UUID
+}
+
+// GorpKey implements the gorp.Entry interface.
+func (i identity) GorpKey() string { return i.Key }
+
+// SetOptions implements the gorp.Entry interface.
+func (i identity) SetOptions() []interface{} { return nil }
+
+// contains returns true if groups contains name. Group names are case-insensitive.
+func contains(groups []string, name string) bool {
+	for _, g := range groups {
+		if strings.EqualFold(g, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,239 @@
+package ldap_test
+
+import (
+	"github.com/arya-analytics/delta/pkg/auth"
+	"github.com/arya-analytics/delta/pkg/auth/ldap"
+	"github.com/arya-analytics/delta/pkg/ontology"
+	"github.com/arya-analytics/delta/pkg/ontology/schema"
+	"github.com/arya-analytics/delta/pkg/user"
+	"github.com/arya-analytics/x/gorp"
+	"github.com/arya-analytics/x/kv/memkv"
+	"github.com/arya-analytics/x/query"
+	"github.com/cockroachdb/errors"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Authenticator", func() {
+	var (
+		dir           *directory
+		db            *gorp.DB
+		otg           *ontology.Ontology
+		users         *user.Service
+		authenticator *ldap.Authenticator
+		operators     = ontology.ID{Type: "group", Key: "operators"}
+		engineers     = ontology.ID{Type: "group", Key: "engineers"}
+	)
+	BeforeEach(func() {
+		var err error
+		dir, err = newDirectory()
+		Expect(err).ToNot(HaveOccurred())
+		dir.add("uid=alice,ou=people,dc=example,dc=com", "alice-pass", map[string][]string{
+			"uid": {"alice"},
+		})
+		dir.add("uid=bob,ou=people,dc=example,dc=com", "bob-pass", map[string][]string{
+			"uid": {"bob"},
+		})
+		dir.add("cn=svc,dc=example,dc=com", "svc-pass", nil)
+		dir.add("cn=operators,ou=groups,dc=example,dc=com", "", map[string][]string{
+			"cn":     {"operators"},
+			"member": {"uid=alice,ou=people,dc=example,dc=com"},
+		})
+		db = gorp.Wrap(memkv.New())
+		otg, err = ontology.Open(db)
+		Expect(err).ToNot(HaveOccurred())
+		users = user.New(db, otg)
+		otg.RegisterService(users)
+		otg.RegisterService(groupService{})
+		authenticator = &ldap.Authenticator{
+			Config: ldap.Config{
+				URL:         dir.URL(),
+				UserDN:      "uid=%s,ou=people,dc=example,dc=com",
+				GroupBaseDN: "ou=groups,dc=example,dc=com",
+				Groups: map[string]ontology.ID{
+					"operators": operators,
+					"engineers": engineers,
+				},
+			},
+			DB:        db,
+			Users:     users,
+			Resources: otg,
+		}
+	})
+	AfterEach(func() {
+		Expect(db.Close()).To(Succeed())
+		Expect(dir.Close()).To(Succeed())
+	})
+	// authenticate authenticates the credentials, returning only the error.
+	authenticate := func(creds auth.InsecureCredentials) error {
+		_, err := authenticator.Authenticate(creds)
+		return err
+	}
+	groupsOf := func(username string) []ontology.ID {
+		u, err := users.RetrieveByUsername(username)
+		Expect(err).ToNot(HaveOccurred())
+		var groups []ontology.Resource
+		err = otg.NewRetrieve().
+			WhereIDs(user.OntologyID(u.Key)).
+			TraverseTo(ontology.Parent.Forward()).
+			Entries(&groups).
+			Exec()
+		if !errors.Is(err, query.NotFound) {
+			Expect(err).ToNot(HaveOccurred())
+		}
+		ids := make([]ontology.ID, len(groups))
+		for i, g := range groups {
+			ids[i] = g.ID
+		}
+		return ids
+	}
+	Describe("Authenticate", func() {
+		It("Should provision a user on their first login", func() {
+			Expect(authenticate(auth.InsecureCredentials{
+				Username: "alice",
+				Password: "alice-pass",
+			})).To(Succeed())
+			u, err := users.RetrieveByUsername("alice")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(u.Username).To(Equal("alice"))
+		})
+		It("Should reject invalid credentials", func() {
+			Expect(authenticate(auth.InsecureCredentials{
+				Username: "alice",
+				Password: "wrong",
+			})).To(MatchError(auth.InvalidCredentials))
+			_, err := users.RetrieveByUsername("alice")
+			Expect(err).To(HaveOccurred())
+		})
+		It("Should reject an empty password", func() {
+			Expect(authenticate(auth.InsecureCredentials{
+				Username: "alice",
+			})).To(MatchError(auth.InvalidCredentials))
+		})
+		It("Should find users by searching the directory", func() {
+			authenticator.UserDN = ""
+			authenticator.BindDN = "cn=svc,dc=example,dc=com"
+			authenticator.BindPassword = "svc-pass"
+			authenticator.UserBaseDN = "ou=people,dc=example,dc=com"
+			Expect(authenticate(auth.InsecureCredentials{
+				Username: "bob",
+				Password: "bob-pass",
+			})).To(Succeed())
+			Expect(authenticate(auth.InsecureCredentials{
+				Username: "carol",
+				Password: "carol-pass",
+			})).To(MatchError(auth.InvalidCredentials))
+		})
+		It("Should restrict login to members of the required groups", func() {
+			authenticator.RequiredGroups = []string{"operators"}
+			Expect(authenticate(auth.InsecureCredentials{
+				Username: "alice",
+				Password: "alice-pass",
+			})).To(Succeed())
+			Expect(authenticate(auth.InsecureCredentials{
+				Username: "bob",
+				Password: "bob-pass",
+			})).To(MatchError(auth.InvalidCredentials))
+		})
+	})
+	Describe("Groups", func() {
+		It("Should make the user a child of the delta groups they're a member of", func() {
+			Expect(authenticate(auth.InsecureCredentials{
+				Username: "alice",
+				Password: "alice-pass",
+			})).To(Succeed())
+			Expect(groupsOf("alice")).To(ConsistOf(operators))
+		})
+		It("Should remove the user from groups they've left in the directory", func() {
+			creds := auth.InsecureCredentials{Username: "alice", Password: "alice-pass"}
+			Expect(authenticate(creds)).To(Succeed())
+			dir.add("cn=operators,ou=groups,dc=example,dc=com", "", map[string][]string{
+				"cn": {"operators"},
+			})
+			dir.add("cn=engineers,ou=groups,dc=example,dc=com", "", map[string][]string{
+				"cn":     {"engineers"},
+				"member": {"uid=alice,ou=people,dc=example,dc=com"},
+			})
+			Expect(authenticate(creds)).To(Succeed())
+			Expect(groupsOf("alice")).To(ConsistOf(engineers))
+		})
+	})
+	Describe("Provisioned Users", func() {
+		creds := auth.InsecureCredentials{Username: "alice", Password: "alice-pass"}
+		It("Should log in as the same user on later logins", func() {
+			key, err := authenticator.Authenticate(creds)
+			Expect(err).ToNot(HaveOccurred())
+			u, err := users.RetrieveByUsername("alice")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(key).To(Equal(u.Key))
+			key, err = authenticator.Authenticate(creds)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(key).To(Equal(u.Key))
+		})
+		It("Should return the provisioned user when the username differs in case", func() {
+			dir.add("uid=Alice,ou=people,dc=example,dc=com", "Alice-pass", map[string][]string{
+				"uid": {"Alice"},
+			})
+			txn := users.BeginTxn()
+			local := &user.User{Username: "alice"}
+			Expect(users.Create(txn, local)).To(Succeed())
+			Expect(txn.Commit()).To(Succeed())
+			Expect(txn.Close()).To(Succeed())
+			directory, err := authenticator.Authenticate(auth.InsecureCredentials{
+				Username: "Alice",
+				Password: "Alice-pass",
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(directory).ToNot(Equal(local.Key))
+			// The directory matches usernames case-insensitively, so the directory user
+			// can bind as "alice", but must still be resolved as themselves.
+			key, err := authenticator.Authenticate(auth.InsecureCredentials{
+				Username: "alice",
+				Password: "Alice-pass",
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(key).To(Equal(directory))
+		})
+		It("Should not log in as a user that wasn't provisioned from the directory", func() {
+			txn := users.BeginTxn()
+			Expect(users.Create(txn, &user.User{Username: "alice"})).To(Succeed())
+			Expect(txn.Commit()).To(Succeed())
+			Expect(txn.Close()).To(Succeed())
+			Expect(authenticate(creds)).To(MatchError(ldap.UsernameTaken))
+			Expect(groupsOf("alice")).To(BeEmpty())
+		})
+	})
+	Describe("Managed Users", func() {
+		It("Should not register users", func() {
+			txn := db.BeginTxn()
+			defer func() { Expect(txn.Close()).To(Succeed()) }()
+			Expect(authenticator.Register(txn, auth.InsecureCredentials{
+				Username: "carol",
+				Password: "carol-pass",
+			})).To(MatchError(auth.RegistrationFailed))
+		})
+	})
+	Describe("Multiple Authenticators", func() {
+		It("Should not authenticate when the directory is unreachable", func() {
+			unreachable := *authenticator
+			unreachable.URL = "ldap://127.0.0.1:1"
+			multi := auth.MultiAuthenticator{&unreachable}
+			_, err := multi.Authenticate(auth.InsecureCredentials{
+				Username: "alice",
+				Password: "alice-pass",
+			})
+			Expect(err).To(MatchError(auth.InvalidCredentials))
+		})
+	})
+})
+
+var groupSchema = &ontology.Schema{Type: "group", Fields: map[string]schema.Field{}}
+
+// groupService is a stand-in for the ontology.Service of delta groups.
+type groupService struct{}
+
+func (groupService) Schema() *schema.Schema { return groupSchema }
+
+func (groupService) RetrieveEntity(string) (schema.Entity, error) {
+	return schema.NewEntity(groupSchema), nil
+}
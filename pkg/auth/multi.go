@@ -4,6 +4,7 @@ import (
 	"github.com/arya-analytics/delta/pkg/auth/password"
 	"github.com/arya-analytics/x/gorp"
 	"github.com/cockroachdb/errors"
+	"github.com/google/uuid"
 )
 
 // MultiAuthenticator implements the Authenticator interface by wrapping a set of
@@ -13,13 +14,13 @@ import (
 type MultiAuthenticator []Authenticator
 
 // Authenticate implements the Authenticator interface.
-func (a MultiAuthenticator) Authenticate(creds InsecureCredentials) error {
+func (a MultiAuthenticator) Authenticate(creds InsecureCredentials) (uuid.UUID, error) {
 	for _, auth := range a {
-		if err := auth.Authenticate(creds); !errors.Is(err, InvalidCredentials) {
-			return nil
+		if key, err := auth.Authenticate(creds); err == nil {
+			return key, nil
 		}
 	}
-	return InvalidCredentials
+	return uuid.Nil, InvalidCredentials
 }
 
 // Register implements the Authenticator interface.
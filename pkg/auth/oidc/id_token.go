@@ -0,0 +1,165 @@
+package oidc
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/json"
+	"github.com/cockroachdb/errors"
+	"github.com/golang-jwt/jwt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// tokenResponse is the response of the provider's token endpoint.
+type tokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// exchange exchanges an authorization code for an ID token.
+func (s *Service) exchange(code, verifier string) (string, error) {
+	m, err := s.provider.metadata()
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {s.RedirectURL},
+		"client_id":     {s.ClientID},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequest(http.MethodPost, m.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if s.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(s.ClientID), url.QueryEscape(s.ClientSecret))
+	}
+	res, err := s.HTTPClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	var tr tokenResponse
+	if err := json.NewDecoder(res.Body).Decode(&tr); err != nil {
+		return "", errors.Wrapf(err, "[oidc] - unexpected response from token endpoint (%s)", res.Status)
+	}
+	if res.StatusCode != http.StatusOK || tr.Error != "" {
+		return "", errors.Newf("[oidc] - token exchange failed: %s %s", tr.Error, tr.ErrorDescription)
+	}
+	if tr.IDToken == "" {
+		return "", errors.Wrap(InvalidIDToken, "[oidc] - token response has no id token")
+	}
+	return tr.IDToken, nil
+}
+
+// claims are the claims of an ID token.
+type claims struct {
+	jwt.MapClaims
+	Issuer  string
+	Subject string
+}
+
+// username returns the value of the claim used as a username.
+func (c claims) username(claim string) string {
+	if claim != "" {
+		v, _ := c.MapClaims[claim].(string)
+		return v
+	}
+	for _, name := range []string{"preferred_username", "email"} {
+		if v, _ := c.MapClaims[name].(string); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// validate validates the ID token as required by OpenID Connect Core 1.0 (section
+// 3.1.3.7), and returns its claims.
+func (s *Service) validate(idToken, nonce string) (claims, error) {
+	m, err := s.provider.metadata()
+	if err != nil {
+		return claims{}, err
+	}
+	mc := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(idToken, mc, s.keyfunc); err != nil {
+		return claims{}, errors.Wrap(InvalidIDToken, err.Error())
+	}
+	c := claims{MapClaims: mc}
+	c.Issuer, _ = mc["iss"].(string)
+	c.Subject, _ = mc["sub"].(string)
+	if c.Issuer != m.Issuer {
+		return c, errors.Wrapf(InvalidIDToken, "[oidc] - unexpected issuer %s", c.Issuer)
+	}
+	if c.Subject == "" {
+		return c, errors.Wrap(InvalidIDToken, "[oidc] - id token has no subject")
+	}
+	aud := audience(mc["aud"])
+	if !contains(aud, s.ClientID) {
+		return c, errors.Wrap(InvalidIDToken, "[oidc] - id token wasn't issued to this client")
+	}
+	if azp, ok := mc["azp"].(string); (ok || len(aud) > 1) && azp != s.ClientID {
+		return c, errors.Wrap(InvalidIDToken, "[oidc] - id token wasn't authorized for this client")
+	}
+	if _, ok := mc["exp"]; !ok {
+		return c, errors.Wrap(InvalidIDToken, "[oidc] - id token has no expiration")
+	}
+	if n, _ := mc["nonce"].(string); n != nonce {
+		return c, errors.Wrap(InvalidIDToken, "[oidc] - nonce mismatch")
+	}
+	return c, nil
+}
+
+// keyfunc returns the provider's key that verifies the token, checking that the key
+// matches the token's signing method.
+func (s *Service) keyfunc(tk *jwt.Token) (interface{}, error) {
+	id, _ := tk.Header["kid"].(string)
+	k, err := s.provider.key(id)
+	if err != nil {
+		return nil, err
+	}
+	var ok bool
+	switch k.(type) {
+	case *rsa.PublicKey:
+		_, ok = tk.Method.(*jwt.SigningMethodRSA)
+	case *ecdsa.PublicKey:
+		_, ok = tk.Method.(*jwt.SigningMethodECDSA)
+	case ed25519.PublicKey:
+		_, ok = tk.Method.(*jwt.SigningMethodEd25519)
+	}
+	if !ok {
+		return nil, errors.Newf("[oidc] - unexpected signing method %s", tk.Method.Alg())
+	}
+	return k, nil
+}
+
+func audience(v interface{}) []string {
+	switch aud := v.(type) {
+	case string:
+		return []string{aud}
+	case []interface{}:
+		s := make([]string, 0, len(aud))
+		for _, a := range aud {
+			if str, ok := a.(string); ok {
+				s = append(s, str)
+			}
+		}
+		return s
+	}
+	return nil
+}
+
+func contains(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,181 @@
+package mock
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/arya-analytics/delta/pkg/auth/token"
+	"github.com/golang-jwt/jwt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// IdentityProvider is a stub OpenID Connect identity provider. It signs in every
+// user that is redirected to it as the identity set by SignIn.
+type IdentityProvider struct {
+	*httptest.Server
+	key      token.Key
+	clientID string
+	mu       sync.Mutex
+	// subject and claims are the identity of the next user to sign in.
+	subject string
+	claims  jwt.MapClaims
+	// signer, if set, signs ID tokens in place of the provider's key.
+	signer *rsa.PrivateKey
+	codes  map[string]authorization
+}
+
+// authorization is an authorization code issued by the provider.
+type authorization struct {
+	nonce       string
+	challenge   string
+	redirectURI string
+	subject     string
+	claims      jwt.MapClaims
+}
+
+// NewIdentityProvider starts an IdentityProvider that issues ID tokens to the client
+// with the given ID. The issuer of the provider is its URL.
+func NewIdentityProvider(clientID string) (*IdentityProvider, error) {
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	k, err := token.NewKey(private)
+	if err != nil {
+		return nil, err
+	}
+	p := &IdentityProvider{key: k, clientID: clientID, codes: make(map[string]authorization)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/authorize", p.authorize)
+	mux.HandleFunc("/token", p.token)
+	mux.HandleFunc("/jwks", p.jwks)
+	p.Server = httptest.NewServer(mux)
+	return p, nil
+}
+
+// SignIn sets the identity of the next user to sign in.
+func (p *IdentityProvider) SignIn(subject string, claims jwt.MapClaims) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.subject, p.claims = subject, claims
+}
+
+// SignWith signs ID tokens with the given key in place of the provider's key.
+func (p *IdentityProvider) SignWith(k *rsa.PrivateKey) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.signer = k
+}
+
+func (p *IdentityProvider) discovery(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"issuer":                 p.URL,
+		"authorization_endpoint": p.URL + "/authorize",
+		"token_endpoint":         p.URL + "/token",
+		"jwks_uri":               p.URL + "/jwks",
+	})
+}
+
+func (p *IdentityProvider) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("client_id") != p.clientID || q.Get("response_type") != "code" ||
+		q.Get("code_challenge_method") != "S256" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	code := randomCode()
+	p.mu.Lock()
+	p.codes[code] = authorization{
+		nonce:       q.Get("nonce"),
+		challenge:   q.Get("code_challenge"),
+		redirectURI: q.Get("redirect_uri"),
+		subject:     p.subject,
+		claims:      p.claims,
+	}
+	p.mu.Unlock()
+	redirect, _ := url.Parse(q.Get("redirect_uri"))
+	rq := redirect.Query()
+	rq.Set("code", code)
+	rq.Set("state", q.Get("state"))
+	redirect.RawQuery = rq.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (p *IdentityProvider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	p.mu.Lock()
+	a, ok := p.codes[r.PostForm.Get("code")]
+	delete(p.codes, r.PostForm.Get("code"))
+	signer := p.key.Private
+	if p.signer != nil {
+		signer = p.signer
+	}
+	p.mu.Unlock()
+	challenge := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok ||
+		r.PostForm.Get("redirect_uri") != a.redirectURI ||
+		base64.RawURLEncoding.EncodeToString(challenge[:]) != a.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+	claims := jwt.MapClaims{
+		"iss":   p.URL,
+		"sub":   a.subject,
+		"aud":   p.clientID,
+		"exp":   time.Now().Add(time.Minute).Unix(),
+		"iat":   time.Now().Unix(),
+		"nonce": a.nonce,
+	}
+	for k, v := range a.claims {
+		claims[k] = v
+	}
+	tk := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	tk.Header["kid"] = p.key.ID
+	idToken, err := tk.SignedString(signer)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{
+		"access_token": randomCode(),
+		"token_type":   "Bearer",
+		"id_token":     idToken,
+	})
+}
+
+func (p *IdentityProvider) jwks(w http.ResponseWriter, _ *http.Request) {
+	ks, err := token.NewKeySet(p.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	set, err := ks.JWKS()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	set.Keys[0].Algorithm = jwt.SigningMethodRS256.Alg()
+	writeJSON(w, http.StatusOK, set)
+}
+
+func randomCode() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,278 @@
+// Package oidc implements login through an OpenID Connect identity provider using the
+// authorization code flow with PKCE (RFC 7636).
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"github.com/arya-analytics/delta/pkg/user"
+	"github.com/arya-analytics/x/gorp"
+	"github.com/arya-analytics/x/query"
+	"github.com/cockroachdb/errors"
+	"github.com/google/uuid"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Config configures the identity provider and the delta client registered with it.
+type Config struct {
+	// Issuer is the issuer URL of the identity provider. The provider's endpoints
+	// and keys are discovered from Issuer + "/.well-known/openid-configuration".
+	Issuer string
+	// ClientID and ClientSecret are the credentials of the client registered with the
+	// provider. ClientSecret is optional for public clients.
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is the URL the provider redirects to after the user signs in. It
+	// must be registered with the provider, and point to the callback endpoint.
+	RedirectURL string
+	// Scopes are the scopes to request in addition to "openid". Defaults to
+	// "profile" and "email".
+	Scopes []string
+	// UsernameClaim is the ID token claim used as the username of a provisioned user.
+	// Defaults to "preferred_username", falling back to "email".
+	UsernameClaim string
+	// LoginExpiration is how long a user has to sign in with the provider. Defaults to
+	// 10 minutes.
+	LoginExpiration time.Duration
+	// HTTPClient is the client used to communicate with the provider. Defaults to a
+	// client with a timeout of DefaultHTTPTimeout.
+	HTTPClient *http.Client
+}
+
+// DefaultHTTPTimeout is the timeout of requests to the provider if the Config has no
+// HTTPClient.
+const DefaultHTTPTimeout = 10 * time.Second
+
+var (
+	// InvalidLogin is returned when completing a login that doesn't exist, has
+	// expired, or has already been completed.
+	InvalidLogin = errors.New("[oidc] - invalid or expired login")
+	// InvalidIDToken is returned when the ID token issued by the provider fails
+	// validation.
+	InvalidIDToken = errors.New("[oidc] - invalid id token")
+	// UsernameTaken is returned when the username of a new identity belongs to an
+	// existing user that isn't linked to the identity. Users are never linked to an
+	// identity implicitly, as the provider may let users choose their username.
+	UsernameTaken = errors.New("[oidc] - username belongs to another user")
+)
+
+// Service runs the login flow. Logins in progress are stored in the DB, so a login
+// can be completed by any node in the cluster. Logins that are never completed are
+// kept until they're purged (see Purge).
+type Service struct {
+	Config
+	DB    *gorp.DB
+	Users *user.Service
+	// provider caches the provider's metadata and keys.
+	provider *provider
+}
+
+// New creates a new Service.
+func New(cfg Config, db *gorp.DB, users *user.Service) *Service {
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"profile", "email"}
+	}
+	if cfg.LoginExpiration == 0 {
+		cfg.LoginExpiration = 10 * time.Minute
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: DefaultHTTPTimeout}
+	}
+	return &Service{
+		Config:   cfg,
+		DB:       db,
+		Users:    users,
+		provider: &provider{issuer: cfg.Issuer, client: cfg.HTTPClient},
+	}
+}
+
+// Begin starts a login. Returns the URL of the provider's authorization endpoint to
+// redirect the user to, and the state that identifies the login. The state should be
+// bound to the user's browser (e.g. in a cookie) and checked against the state the
+// provider redirects back with, so that an attacker can't complete a login they
+// started in the user's browser.
+func (s *Service) Begin() (authURL, state string, err error) {
+	m, err := s.provider.metadata()
+	if err != nil {
+		return "", "", err
+	}
+	l := login{ExpiresAt: time.Now().Add(s.LoginExpiration).Unix()}
+	if l.State, err = randomString(); err != nil {
+		return "", "", err
+	}
+	if l.Nonce, err = randomString(); err != nil {
+		return "", "", err
+	}
+	if l.Verifier, err = randomString(); err != nil {
+		return "", "", err
+	}
+	if err := gorp.NewCreate[string, login]().Entry(&l).Exec(s.DB); err != nil {
+		return "", "", err
+	}
+	challenge := sha256.Sum256([]byte(l.Verifier))
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {s.ClientID},
+		"redirect_uri":          {s.RedirectURL},
+		"scope":                 {strings.Join(append([]string{"openid"}, s.Scopes...), " ")},
+		"state":                 {l.State},
+		"nonce":                 {l.Nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(m.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return m.AuthorizationEndpoint + sep + q.Encode(), l.State, nil
+}
+
+// Complete completes the login with the given state using the authorization code
+// returned by the provider. Returns the user linked to the identity in the ID token,
+// provisioning a new user if the identity hasn't logged in before.
+func (s *Service) Complete(state, code string) (user.User, error) {
+	l, err := s.consume(state)
+	if err != nil {
+		return user.User{}, err
+	}
+	idToken, err := s.exchange(code, l.Verifier)
+	if err != nil {
+		return user.User{}, err
+	}
+	claims, err := s.validate(idToken, l.Nonce)
+	if err != nil {
+		return user.User{}, err
+	}
+	return s.resolve(claims)
+}
+
+// consume retrieves and deletes the login with the given state, so that it can only
+// be completed once.
+func (s *Service) consume(state string) (login, error) {
+	var l login
+	if state == "" {
+		return l, InvalidLogin
+	}
+	err := gorp.NewRetrieve[string, login]().WhereKeys(state).Entry(&l).Exec(s.DB)
+	if errors.Is(err, query.NotFound) {
+		return l, InvalidLogin
+	}
+	if err != nil {
+		return l, err
+	}
+	if err := gorp.NewDelete[string, login]().WhereKeys(state).Exec(s.DB); err != nil {
+		return l, err
+	}
+	if time.Now().Unix() > l.ExpiresAt {
+		return l, InvalidLogin
+	}
+	return l, nil
+}
+
+// Purge deletes logins that have expired without being completed. Purge should be
+// called periodically (see SchedulePurge) to keep the DB from growing without bound.
+func (s *Service) Purge() error {
+	now := time.Now().Unix()
+	return gorp.NewDelete[string, login]().
+		Where(func(l *login) bool { return l.ExpiresAt < now }).
+		Exec(s.DB)
+}
+
+// SchedulePurge calls Purge every interval until the context is cancelled. onError
+// is called with any errors returned by Purge. Optional.
+func (s *Service) SchedulePurge(ctx context.Context, interval time.Duration, onError func(error)) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.Purge(); err != nil && onError != nil {
+					onError(err)
+				}
+			}
+		}
+	}()
+}
+
+// resolve returns the user linked to the identity in the claims, provisioning a new
+// user if the identity isn't linked to one.
+func (s *Service) resolve(c claims) (user.User, error) {
+	var id identity
+	err := gorp.NewRetrieve[string, identity]().
+		WhereKeys(identityKey(c.Issuer, c.Subject)).
+		Entry(&id).
+		Exec(s.DB)
+	if err == nil {
+		return s.Users.Retrieve(id.User)
+	}
+	if !errors.Is(err, query.NotFound) {
+		return user.User{}, err
+	}
+	username := c.username(s.UsernameClaim)
+	if username == "" {
+		return user.User{}, errors.Wrap(InvalidIDToken, "[oidc] - id token has no username claim")
+	}
+	if _, err := s.Users.RetrieveByUsername(username); err == nil {
+		return user.User{}, errors.Wrapf(UsernameTaken, "[oidc] - %s", username)
+	} else if !errors.Is(err, query.NotFound) {
+		return user.User{}, err
+	}
+	txn := s.Users.BeginTxn()
+	u := user.User{Username: username}
+	if err := s.Users.Create(txn, &u); err != nil {
+		return u, errors.CombineErrors(err, txn.Close())
+	}
+	id = identity{Key: identityKey(c.Issuer, c.Subject), User: u.Key}
+	if err := gorp.NewCreate[string, identity]().Entry(&id).Exec(txn); err != nil {
+		return u, errors.CombineErrors(err, txn.Close())
+	}
+	return u, errors.CombineErrors(txn.Commit(), txn.Close())
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// login is a login in progress.
+type login struct {
+	// State is the value of the state parameter that identifies the login.
+	State string
+	// Nonce binds the ID token to the login.
+	Nonce string
+	// Verifier is the PKCE code verifier.
+	Verifier  string
+	ExpiresAt int64
+}
+
+// GorpKey implements the gorp.Entry interface.
+func (l login) GorpKey() string { return l.State }
+
+// SetOptions implements the gorp.Entry interface.
+func (l login) SetOptions() []interface{} { return nil }
+
+// identity links an identity at a provider to a user.
+type identity struct {
+	// Key is the issuer and subject of the identity.
+	Key  string
+	User uuid.UUID
+}
+
+func identityKey(issuer, subject string) string { return issuer + " " + subject }
+
+// GorpKey implements the gorp.Entry interface.
+func (i identity) GorpKey() string { return i.Key }
+
+// SetOptions implements the gorp.Entry interface.
+func (i identity) SetOptions() []interface{} { return nil }
@@ -0,0 +1,212 @@
+package oidc_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"github.com/arya-analytics/delta/pkg/auth/oidc"
+	"github.com/arya-analytics/delta/pkg/auth/oidc/mock"
+	"github.com/arya-analytics/delta/pkg/ontology"
+	"github.com/arya-analytics/delta/pkg/user"
+	"github.com/arya-analytics/x/gorp"
+	"github.com/arya-analytics/x/kv/memkv"
+	"github.com/golang-jwt/jwt"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const clientID = "delta"
+
+var _ = Describe("OIDC", func() {
+	var (
+		idp   *mock.IdentityProvider
+		db    *gorp.DB
+		users *user.Service
+		svc   *oidc.Service
+	)
+	BeforeEach(func() {
+		var err error
+		idp, err = mock.NewIdentityProvider(clientID)
+		Expect(err).ToNot(HaveOccurred())
+		db = gorp.Wrap(memkv.New())
+		otg, err := ontology.Open(db)
+		Expect(err).ToNot(HaveOccurred())
+		users = user.New(db, otg)
+		otg.RegisterService(users)
+		svc = oidc.New(oidc.Config{
+			Issuer:      idp.URL,
+			ClientID:    clientID,
+			RedirectURL: "http://delta.example.com/auth/oidc/callback",
+		}, db, users)
+		idp.SignIn("alice-sub", jwt.MapClaims{"preferred_username": "alice"})
+	})
+	AfterEach(func() {
+		idp.Close()
+		Expect(db.Close()).To(Succeed())
+	})
+	// authorize begins a login and follows the redirect to the identity provider,
+	// returning the state and code the provider redirects back with.
+	authorize := func() (state, code string) {
+		u, _, err := svc.Begin()
+		Expect(err).ToNot(HaveOccurred())
+		client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}}
+		res, err := client.Get(u)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.Body.Close()).To(Succeed())
+		Expect(res.StatusCode).To(Equal(http.StatusFound))
+		loc, err := url.Parse(res.Header.Get("Location"))
+		Expect(err).ToNot(HaveOccurred())
+		return loc.Query().Get("state"), loc.Query().Get("code")
+	}
+	login := func() (user.User, error) { return svc.Complete(authorize()) }
+	Describe("Login", func() {
+		It("Should provision a user on their first login", func() {
+			u, err := login()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(u.Username).To(Equal("alice"))
+			stored, err := users.RetrieveByUsername("alice")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stored.Key).To(Equal(u.Key))
+		})
+		It("Should resolve later logins to the same user by subject", func() {
+			first, err := login()
+			Expect(err).ToNot(HaveOccurred())
+			idp.SignIn("alice-sub", jwt.MapClaims{"preferred_username": "alice.smith"})
+			second, err := login()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(second.Key).To(Equal(first.Key))
+		})
+		It("Should fall back to the email claim as the username", func() {
+			idp.SignIn("bob-sub", jwt.MapClaims{"email": "bob@example.com"})
+			u, err := login()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(u.Username).To(Equal("bob@example.com"))
+		})
+		It("Should not link an identity to an existing user with the same username", func() {
+			txn := users.BeginTxn()
+			Expect(users.Create(txn, &user.User{Username: "alice"})).To(Succeed())
+			Expect(txn.Commit()).To(Succeed())
+			_, err := login()
+			Expect(err).To(MatchError(oidc.UsernameTaken))
+		})
+	})
+	Describe("Login Validation", func() {
+		It("Should not complete a login twice", func() {
+			state, code := authorize()
+			_, err := svc.Complete(state, code)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = svc.Complete(state, code)
+			Expect(err).To(MatchError(oidc.InvalidLogin))
+		})
+		It("Should not complete a login that wasn't started", func() {
+			_, err := svc.Complete("unknown", "code")
+			Expect(err).To(MatchError(oidc.InvalidLogin))
+		})
+		It("Should not exchange a code issued to another login", func() {
+			state, _ := authorize()
+			_, code := authorize()
+			_, err := svc.Complete(state, code)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Describe("Purge", func() {
+		It("Should keep logins in progress", func() {
+			state, code := authorize()
+			Expect(svc.Purge()).To(Succeed())
+			_, err := svc.Complete(state, code)
+			Expect(err).ToNot(HaveOccurred())
+		})
+		It("Should purge expired logins", func() {
+			svc.LoginExpiration = -time.Second
+			state, code := authorize()
+			Expect(svc.Purge()).To(Succeed())
+			_, err := svc.Complete(state, code)
+			Expect(err).To(MatchError(oidc.InvalidLogin))
+		})
+	})
+	Describe("Provider", func() {
+		It("Should time out requests to the provider by default", func() {
+			Expect(svc.HTTPClient.Timeout).To(Equal(oidc.DefaultHTTPTimeout))
+		})
+		It("Should not block logins while fetching the provider's keys", func() {
+			transport := &blockingTransport{path: "/jwks", release: make(chan struct{})}
+			svc = oidc.New(oidc.Config{
+				Issuer:      idp.URL,
+				ClientID:    clientID,
+				RedirectURL: "http://delta.example.com/auth/oidc/callback",
+				HTTPClient:  &http.Client{Transport: transport},
+			}, db, users)
+			completed := make(chan error, 1)
+			go func() {
+				_, err := login()
+				completed <- err
+			}()
+			Eventually(transport.blocked).Should(BeTrue())
+			_, _, err := svc.Begin()
+			Expect(err).ToNot(HaveOccurred())
+			close(transport.release)
+			Eventually(completed).Should(Receive(BeNil()))
+		})
+	})
+	Describe("ID Token Validation", func() {
+		It("Should reject an ID token signed by an unknown key", func() {
+			k, err := rsa.GenerateKey(rand.Reader, 2048)
+			Expect(err).ToNot(HaveOccurred())
+			idp.SignWith(k)
+			_, err = login()
+			Expect(err).To(MatchError(oidc.InvalidIDToken))
+		})
+		It("Should reject an ID token issued to another client", func() {
+			idp.SignIn("alice-sub", jwt.MapClaims{"preferred_username": "alice", "aud": "other"})
+			_, err := login()
+			Expect(err).To(MatchError(oidc.InvalidIDToken))
+		})
+		It("Should reject an ID token issued by another provider", func() {
+			idp.SignIn("alice-sub", jwt.MapClaims{"iss": "http://evil.example.com"})
+			_, err := login()
+			Expect(err).To(MatchError(oidc.InvalidIDToken))
+		})
+		It("Should reject an ID token with the wrong nonce", func() {
+			idp.SignIn("alice-sub", jwt.MapClaims{"preferred_username": "alice", "nonce": "replayed"})
+			_, err := login()
+			Expect(err).To(MatchError(oidc.InvalidIDToken))
+		})
+		It("Should reject an expired ID token", func() {
+			idp.SignIn("alice-sub", jwt.MapClaims{
+				"preferred_username": "alice",
+				"exp":                time.Now().Add(-time.Minute).Unix(),
+			})
+			_, err := login()
+			Expect(err).To(MatchError(oidc.InvalidIDToken))
+		})
+	})
+})
+
+// blockingTransport blocks requests to a path until it's released.
+type blockingTransport struct {
+	path      string
+	release   chan struct{}
+	mu        sync.Mutex
+	isBlocked bool
+}
+
+func (t *blockingTransport) blocked() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.isBlocked
+}
+
+func (t *blockingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Path == t.path {
+		t.mu.Lock()
+		t.isBlocked = true
+		t.mu.Unlock()
+		<-t.release
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
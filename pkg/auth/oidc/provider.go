@@ -0,0 +1,142 @@
+package oidc
+
+import (
+	"crypto"
+	"encoding/json"
+	"github.com/arya-analytics/delta/pkg/auth/token"
+	"github.com/cockroachdb/errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// jwksRefreshInterval is the minimum time between fetches of the provider's JWKS
+// when an ID token is signed by an unknown key.
+const jwksRefreshInterval = time.Minute
+
+// metadata is the subset of the provider's discovery document (OpenID Connect
+// Discovery 1.0) used by the login flow.
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// provider caches the metadata and signing keys of an identity provider.
+type provider struct {
+	issuer string
+	client *http.Client
+	// fetchMu serializes fetches from the provider, so that concurrent lookups of an
+	// uncached value make a single request. It's never held along with mu.
+	fetchMu sync.Mutex
+	// mu protects the fields below. It's never held during a request, so lookups of
+	// cached values don't wait on the provider.
+	mu        sync.Mutex
+	meta      *metadata
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+func (p *provider) cachedMetadata() (metadata, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.meta == nil {
+		return metadata{}, false
+	}
+	return *p.meta, true
+}
+
+func (p *provider) metadata() (metadata, error) {
+	if m, ok := p.cachedMetadata(); ok {
+		return m, nil
+	}
+	p.fetchMu.Lock()
+	defer p.fetchMu.Unlock()
+	// Another caller may have fetched the metadata while we waited.
+	if m, ok := p.cachedMetadata(); ok {
+		return m, nil
+	}
+	var m metadata
+	url := strings.TrimSuffix(p.issuer, "/") + "/.well-known/openid-configuration"
+	if err := p.get(url, &m); err != nil {
+		return m, errors.Wrap(err, "[oidc] - discovery failed")
+	}
+	if m.Issuer != p.issuer {
+		return m, errors.Newf("[oidc] - provider issuer %s doesn't match %s", m.Issuer, p.issuer)
+	}
+	if m.AuthorizationEndpoint == "" || m.TokenEndpoint == "" || m.JWKSURI == "" {
+		return m, errors.New("[oidc] - incomplete discovery document")
+	}
+	p.mu.Lock()
+	p.meta = &m
+	p.mu.Unlock()
+	return m, nil
+}
+
+// cachedKey returns the cached key with the given ID, and whether the JWKS was fetched
+// too recently to be fetched again.
+func (p *provider) cachedKey(id string) (k crypto.PublicKey, ok bool, recent bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	k, ok = p.keys[id]
+	return k, ok, time.Since(p.fetchedAt) < jwksRefreshInterval
+}
+
+// key returns the provider's signing key with the given ID. The provider's JWKS is
+// re-fetched if the key is unknown, so that keys rotated in by the provider are
+// picked up.
+func (p *provider) key(id string) (crypto.PublicKey, error) {
+	m, err := p.metadata()
+	if err != nil {
+		return nil, err
+	}
+	if k, ok, _ := p.cachedKey(id); ok {
+		return k, nil
+	}
+	p.fetchMu.Lock()
+	defer p.fetchMu.Unlock()
+	// Another caller may have fetched the JWKS while we waited.
+	k, ok, recent := p.cachedKey(id)
+	if ok {
+		return k, nil
+	}
+	if recent {
+		return nil, errors.Newf("[oidc] - unknown signing key %s", id)
+	}
+	var set token.JWKS
+	if err := p.get(m.JWKSURI, &set); err != nil {
+		return nil, errors.Wrap(err, "[oidc] - failed to fetch jwks")
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		// Keys of unsupported types are skipped, as they can't have signed a token
+		// we'd accept.
+		if k, err := jwk.PublicKey(); err == nil {
+			keys[jwk.ID] = k
+		}
+	}
+	p.mu.Lock()
+	p.keys, p.fetchedAt = keys, time.Now()
+	p.mu.Unlock()
+	if k, ok := keys[id]; ok {
+		return k, nil
+	}
+	return nil, errors.Newf("[oidc] - unknown signing key %s", id)
+}
+
+func (p *provider) get(url string, v interface{}) error {
+	res, err := p.client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return errors.Newf("[oidc] - unexpected status %s from %s", res.Status, url)
+	}
+	return json.NewDecoder(res.Body).Decode(v)
+}
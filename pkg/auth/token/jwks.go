@@ -0,0 +1,162 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/cockroachdb/errors"
+	"math/big"
+)
+
+// JWK is the JSON Web Key (RFC 7517) representation of the public half of a Key.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	ID        string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	// N and E are the modulus and exponent of an RSA key.
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Curve, X and Y are the curve and coordinates of an ECDSA key. Ed25519 keys
+	// set Curve and X.
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+	Y     string `json:"y,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set. Services that verify tokens issued by delta can fetch
+// the JWKS to do so without the private keys.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys in the set.
+func (ks *KeySet) JWKS() (JWKS, error) {
+	keys := ks.Keys()
+	set := JWKS{Keys: make([]JWK, len(keys))}
+	for i, k := range keys {
+		m, err := k.method()
+		if err != nil {
+			return set, err
+		}
+		jwk, err := newJWK(k.Private.Public())
+		if err != nil {
+			return set, err
+		}
+		jwk.ID, jwk.Use, jwk.Algorithm = k.ID, "sig", m.Alg()
+		set.Keys[i] = jwk
+	}
+	return set, nil
+}
+
+// PublicKey returns the public key described by the JWK.
+func (j JWK) PublicKey() (crypto.PublicKey, error) {
+	switch j.KeyType {
+	case "RSA":
+		n, err := decodeBase64Int(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBase64Int(j.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch j.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, errors.Wrapf(UnsupportedKey, "[token] - curve %s", j.Curve)
+		}
+		x, err := decodeBase64Int(j.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBase64Int(j.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("[token] - invalid ec key")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil {
+			return nil, err
+		}
+		if j.Curve != "Ed25519" || len(x) != ed25519.PublicKeySize {
+			return nil, errors.Wrapf(UnsupportedKey, "[token] - curve %s", j.Curve)
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, errors.Wrapf(UnsupportedKey, "[token] - key type %s", j.KeyType)
+}
+
+func newJWK(pub crypto.PublicKey) (JWK, error) {
+	switch p := pub.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			KeyType: "RSA",
+			N:       encodeBase64(p.N.Bytes()),
+			E:       encodeBase64(big.NewInt(int64(p.E)).Bytes()),
+		}, nil
+	case *ecdsa.PublicKey:
+		size := (p.Curve.Params().BitSize + 7) / 8
+		return JWK{
+			KeyType: "EC",
+			Curve:   p.Curve.Params().Name,
+			X:       encodeBase64(p.X.FillBytes(make([]byte, size))),
+			Y:       encodeBase64(p.Y.FillBytes(make([]byte, size))),
+		}, nil
+	case ed25519.PublicKey:
+		return JWK{KeyType: "OKP", Curve: "Ed25519", X: encodeBase64(p)}, nil
+	}
+	return JWK{}, errors.Wrapf(UnsupportedKey, "[token] - key type %T", pub)
+}
+
+// thumbprint returns the JWK thumbprint (RFC 7638) of the public key.
+func thumbprint(pub crypto.PublicKey) (string, error) {
+	jwk, err := newJWK(pub)
+	if err != nil {
+		return "", err
+	}
+	// The thumbprint is computed over the required members of the JWK, in
+	// lexicographic order. json.Marshal sorts map keys.
+	members := map[string]string{"kty": jwk.KeyType}
+	switch jwk.KeyType {
+	case "RSA":
+		members["n"], members["e"] = jwk.N, jwk.E
+	case "EC":
+		members["crv"], members["x"], members["y"] = jwk.Curve, jwk.X, jwk.Y
+	default:
+		members["crv"], members["x"] = jwk.Curve, jwk.X
+	}
+	b, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.Sum256(b)
+	return encodeBase64(h[:]), nil
+}
+
+func encodeBase64(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+func decodeBase64Int(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
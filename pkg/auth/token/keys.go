@@ -0,0 +1,421 @@
+package token
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"github.com/arya-analytics/x/gorp"
+	"github.com/cockroachdb/errors"
+	"github.com/golang-jwt/jwt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Key is a key used to sign and verify tokens.
+type Key struct {
+	// ID identifies the key in the 'kid' header of the tokens it signs.
+	ID string
+	// Private is the private key. Must be an *rsa.PrivateKey (signs with RS512), an
+	// *ecdsa.PrivateKey on P-256, P-384 or P-521 (ES256, ES384 or ES512), or an
+	// ed25519.PrivateKey (EdDSA).
+	Private crypto.Signer
+	// ActivatedAt is the time the key started signing tokens.
+	ActivatedAt time.Time
+	// RetiredAt is the time the key stopped signing tokens. Zero if the key is active.
+	RetiredAt time.Time
+}
+
+// NewKey creates a new Key from the given private key. The ID of the key is its
+// JWK thumbprint (RFC 7638).
+func NewKey(private crypto.Signer) (Key, error) {
+	k := Key{Private: private}
+	if _, err := k.method(); err != nil {
+		return k, err
+	}
+	id, err := thumbprint(private.Public())
+	k.ID = id
+	return k, err
+}
+
+// UnsupportedKey is returned when creating a Key from a private key that can't sign
+// tokens.
+var UnsupportedKey = errors.New("[token] - unsupported key")
+
+func (k Key) method() (jwt.SigningMethod, error) {
+	switch p := k.Private.(type) {
+	case *rsa.PrivateKey:
+		return jwt.SigningMethodRS512, nil
+	case *ecdsa.PrivateKey:
+		switch p.Curve {
+		case elliptic.P256():
+			return jwt.SigningMethodES256, nil
+		case elliptic.P384():
+			return jwt.SigningMethodES384, nil
+		case elliptic.P521():
+			return jwt.SigningMethodES512, nil
+		}
+		return nil, errors.Wrapf(UnsupportedKey, "[token] - curve %s", p.Curve.Params().Name)
+	case ed25519.PrivateKey:
+		return jwt.SigningMethodEdDSA, nil
+	}
+	return nil, errors.Wrapf(UnsupportedKey, "[token] - key type %T", k.Private)
+}
+
+// KeySet is the set of keys used by a Service. The most recently added key signs new
+// tokens, while keys that have been rotated out continue to verify the tokens they
+// signed until they're pruned. A KeySet is safe for concurrent use.
+//
+// A KeySet created with NewKeySet is held in memory. A KeySet opened with OpenKeySet
+// is stored in a DB: rotations and prunes are written to the DB, and if the DB is
+// backed by aspen, every node sharing it picks them up when it reloads the KeySet (see
+// Reload and Service.ScheduleRotation).
+type KeySet struct {
+	mu sync.RWMutex
+	// keys are ordered by the time they were added. The last key is active.
+	keys []Key
+	// db stores the keys. Nil if the KeySet is held in memory.
+	db *gorp.DB
+}
+
+// NewKeySet creates a KeySet from the given keys. The last key is active, and all
+// other keys are retired.
+func NewKeySet(keys ...Key) (*KeySet, error) {
+	ks := &KeySet{}
+	for _, k := range keys {
+		if err := ks.Rotate(k); err != nil {
+			return nil, err
+		}
+	}
+	return ks, nil
+}
+
+// OpenKeySet opens the KeySet stored in db. If db holds no keys, the given keys are
+// stored in it, with the last key active.
+func OpenKeySet(db *gorp.DB, initial ...Key) (*KeySet, error) {
+	ks := &KeySet{db: db}
+	if err := ks.Reload(); err != nil {
+		return nil, err
+	}
+	if len(ks.Keys()) > 0 {
+		return ks, nil
+	}
+	for _, k := range initial {
+		if err := ks.Rotate(k); err != nil {
+			return nil, err
+		}
+	}
+	return ks, nil
+}
+
+// Reload replaces the keys in the set with those stored in its DB, picking up the
+// rotations and prunes made by other nodes. Reload does nothing if the KeySet is held
+// in memory.
+func (ks *KeySet) Reload() error {
+	if ks.db == nil {
+		return nil
+	}
+	var stored []storedKey
+	if err := gorp.NewRetrieve[string, storedKey]().Entries(&stored).Exec(ks.db); err != nil {
+		return err
+	}
+	sort.Slice(stored, func(i, j int) bool {
+		if stored[i].ActivatedAt == stored[j].ActivatedAt {
+			return stored[i].ID < stored[j].ID
+		}
+		return stored[i].ActivatedAt < stored[j].ActivatedAt
+	})
+	keys := make([]Key, len(stored))
+	for i, sk := range stored {
+		k, err := sk.key()
+		if err != nil {
+			return err
+		}
+		keys[i] = k
+	}
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.keys = keys
+	return nil
+}
+
+// Active returns the key that signs new tokens. Returns false if the KeySet is empty.
+func (ks *KeySet) Active() (Key, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	if len(ks.keys) == 0 {
+		return Key{}, false
+	}
+	return ks.keys[len(ks.keys)-1], true
+}
+
+// Get returns the key with the given ID. Returns false if the key isn't in the set.
+func (ks *KeySet) Get(id string) (Key, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	for _, k := range ks.keys {
+		if k.ID == id {
+			return k, true
+		}
+	}
+	return Key{}, false
+}
+
+// Keys returns every key in the set, ordered from oldest to newest.
+func (ks *KeySet) Keys() []Key {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return append([]Key{}, ks.keys...)
+}
+
+// Rotate retires the active key and makes next the active key. Tokens signed by the
+// retired key remain valid until the key is pruned. If the KeySet is stored in a DB,
+// the rotation is written to it.
+func (ks *KeySet) Rotate(next Key) error {
+	if next.ID == "" {
+		return errors.New("[token] - key id is required")
+	}
+	if _, err := next.method(); err != nil {
+		return err
+	}
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	for _, k := range ks.keys {
+		if k.ID == next.ID {
+			return errors.Newf("[token] - key %s already exists", next.ID)
+		}
+	}
+	now := time.Now()
+	next.ActivatedAt, next.RetiredAt = now, time.Time{}
+	changed := []Key{next}
+	if n := len(ks.keys); n > 0 {
+		retired := ks.keys[n-1]
+		retired.RetiredAt = now
+		changed = append(changed, retired)
+	}
+	if err := ks.store(changed...); err != nil {
+		return err
+	}
+	if n := len(ks.keys); n > 0 {
+		ks.keys[n-1].RetiredAt = now
+	}
+	ks.keys = append(ks.keys, next)
+	return nil
+}
+
+// Prune removes keys that were retired more than retention ago. retention should be
+// at least the expiration of the tokens signed by the keys; otherwise, tokens are
+// invalidated before they expire. If the KeySet is stored in a DB, the pruned keys are
+// deleted from it.
+func (ks *KeySet) Prune(retention time.Duration) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	cutoff := time.Now().Add(-retention)
+	var (
+		kept   []Key
+		pruned []string
+	)
+	for _, k := range ks.keys {
+		if k.RetiredAt.IsZero() || k.RetiredAt.After(cutoff) {
+			kept = append(kept, k)
+		} else {
+			pruned = append(pruned, k.ID)
+		}
+	}
+	if ks.db != nil && len(pruned) > 0 {
+		if err := gorp.NewDelete[string, storedKey]().
+			WhereKeys(pruned...).
+			Exec(ks.db); err != nil {
+			return err
+		}
+	}
+	ks.keys = kept
+	return nil
+}
+
+// store writes the keys to the DB of the KeySet in a single transaction.
+func (ks *KeySet) store(keys ...Key) error {
+	if ks.db == nil {
+		return nil
+	}
+	entries := make([]storedKey, len(keys))
+	for i, k := range keys {
+		private, err := x509.MarshalPKCS8PrivateKey(k.Private)
+		if err != nil {
+			return err
+		}
+		entries[i] = storedKey{
+			ID:          k.ID,
+			Private:     private,
+			ActivatedAt: k.ActivatedAt.UnixNano(),
+		}
+		if !k.RetiredAt.IsZero() {
+			entries[i].RetiredAt = k.RetiredAt.UnixNano()
+		}
+	}
+	txn := ks.db.BeginTxn()
+	if err := gorp.NewCreate[string, storedKey]().Entries(&entries).Exec(txn); err != nil {
+		return errors.CombineErrors(err, txn.Close())
+	}
+	return errors.CombineErrors(txn.Commit(), txn.Close())
+}
+
+// Rotation configures the scheduled rotation of a Service's KeySet (see
+// Service.ScheduleRotation).
+type Rotation struct {
+	// Interval is the time between rotations.
+	Interval time.Duration
+	// Retention is how long a retired key continues to verify tokens (see
+	// KeySet.Prune).
+	Retention time.Duration
+	// Generate generates the private key of the next active key.
+	Generate func() (crypto.Signer, error)
+	// Lease returns true if the host holds the lease on rotation. Keys are only
+	// rotated and pruned by the host holding the lease, so a KeySet shared by several
+	// nodes is rotated once per Interval. Defaults to always holding the lease, which
+	// is only safe for a single node. Optional.
+	Lease func() bool
+	// OnError is called with any errors encountered while rotating. The active key
+	// is kept if a rotation fails. Optional.
+	OnError func(error)
+}
+
+// ScheduleRotation rotates the Service's KeySet every Rotation.Interval and prunes
+// retired keys until the context is cancelled. Every node sharing the KeySet should
+// schedule its rotation, as nodes that don't hold the lease reload the KeySet on each
+// tick to pick up the active key.
+//
+// Retention is raised to at least the Service's Expiration plus the Interval, so that
+// keys aren't pruned before the tokens they signed expire, even when signed by a node
+// that hasn't reloaded the KeySet since the key was retired.
+func (s *Service) ScheduleRotation(ctx context.Context, r Rotation) error {
+	ks, err := s.keys()
+	if err != nil {
+		return err
+	}
+	if r.Interval <= 0 {
+		return errors.New("[token] - rotation interval must be positive")
+	}
+	if floor := s.Expiration + r.Interval; r.Retention < floor {
+		r.Retention = floor
+	}
+	if r.Lease == nil {
+		r.Lease = func() bool { return true }
+	}
+	go func() {
+		ticker := time.NewTicker(r.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := ks.rotateScheduled(r); err != nil && r.OnError != nil {
+					r.OnError(err)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// rotateScheduled reloads the KeySet and, if the host holds the lease, rotates the
+// active key once it's been active for the Rotation's Interval and prunes retired
+// keys. Checking the age of the active key keeps a node that has just taken over the
+// lease from rotating a key that was rotated moments ago.
+func (ks *KeySet) rotateScheduled(r Rotation) error {
+	if err := ks.Reload(); err != nil {
+		return err
+	}
+	if !r.Lease() {
+		return nil
+	}
+	if active, ok := ks.Active(); !ok || time.Since(active.ActivatedAt) >= r.Interval {
+		if err := ks.rotateGenerated(r.Generate); err != nil {
+			return err
+		}
+	}
+	return ks.Prune(r.Retention)
+}
+
+func (ks *KeySet) rotateGenerated(generate func() (crypto.Signer, error)) error {
+	private, err := generate()
+	if err != nil {
+		return err
+	}
+	k, err := NewKey(private)
+	if err != nil {
+		return err
+	}
+	return ks.Rotate(k)
+}
+
+// keyfunc returns the public key that verifies the given token, using the key named
+// by its 'kid' header.
+func (ks *KeySet) keyfunc(tk *jwt.Token) (interface{}, error) {
+	id, _ := tk.Header["kid"].(string)
+	if id == "" {
+		return nil, errors.New("[token] - token has no key id")
+	}
+	k, ok := ks.Get(id)
+	if !ok && ks.db != nil {
+		// The key may have been added by another node since the KeySet was loaded.
+		if err := ks.Reload(); err != nil {
+			return nil, err
+		}
+		k, ok = ks.Get(id)
+	}
+	if !ok {
+		return nil, errors.Newf("[token] - unknown key %s", id)
+	}
+	return k.verify(tk)
+}
+
+// verify returns the public key of k if it signs tokens with the token's method.
+func (k Key) verify(tk *jwt.Token) (interface{}, error) {
+	m, err := k.method()
+	if err != nil {
+		return nil, err
+	}
+	if m.Alg() != tk.Method.Alg() {
+		return nil, errors.Newf("[token] - unexpected signing method %s", tk.Method.Alg())
+	}
+	return k.Private.Public(), nil
+}
+
+// storedKey is a Key stored in the DB of a KeySet.
+type storedKey struct {
+	ID string
+	// Private is the private key in PKCS #8 form.
+	Private []byte
+	// ActivatedAt and RetiredAt are Unix times in nanoseconds. RetiredAt is zero if
+	// the key is active.
+	ActivatedAt int64
+	RetiredAt   int64
+}
+
+func (sk storedKey) key() (Key, error) {
+	private, err := x509.ParsePKCS8PrivateKey(sk.Private)
+	if err != nil {
+		return Key{}, err
+	}
+	signer, ok := private.(crypto.Signer)
+	if !ok {
+		return Key{}, errors.Wrapf(UnsupportedKey, "[token] - key type %T", private)
+	}
+	k := Key{ID: sk.ID, Private: signer, ActivatedAt: time.Unix(0, sk.ActivatedAt)}
+	if sk.RetiredAt != 0 {
+		k.RetiredAt = time.Unix(0, sk.RetiredAt)
+	}
+	return k, nil
+}
+
+// GorpKey implements the gorp.Entry interface.
+func (sk storedKey) GorpKey() string { return sk.ID }
+
+// SetOptions implements the gorp.Entry interface.
+func (sk storedKey) SetOptions() []interface{} { return nil }
@@ -0,0 +1,273 @@
+package token_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"github.com/arya-analytics/delta/pkg/auth/token"
+	"github.com/arya-analytics/x/gorp"
+	"github.com/arya-analytics/x/kv/memkv"
+	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"time"
+)
+
+func newKey(generate func() (crypto.Signer, error)) token.Key {
+	private, err := generate()
+	Expect(err).ToNot(HaveOccurred())
+	k, err := token.NewKey(private)
+	Expect(err).ToNot(HaveOccurred())
+	return k
+}
+
+func generateRSA() (crypto.Signer, error) { return rsa.GenerateKey(rand.Reader, 2048) }
+
+func generateECDSA() (crypto.Signer, error) {
+	return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+}
+
+func generateEd25519() (crypto.Signer, error) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	return private, err
+}
+
+var _ = Describe("Keys", func() {
+	DescribeTable("Signing Algorithms", func(generate func() (crypto.Signer, error), alg string) {
+		ks, err := token.NewKeySet(newKey(generate))
+		Expect(err).ToNot(HaveOccurred())
+		svc := &token.Service{Keys: ks, Expiration: 5 * time.Second}
+		issuer := uuid.New()
+		tk, err := svc.New(issuer)
+		Expect(err).ToNot(HaveOccurred())
+		key, err := svc.Validate(tk)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(key).To(Equal(issuer))
+		jwks, err := svc.JWKS()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(jwks.Keys).To(HaveLen(1))
+		Expect(jwks.Keys[0].Algorithm).To(Equal(alg))
+	},
+		Entry("RSA", generateRSA, "RS512"),
+		Entry("ECDSA", generateECDSA, "ES256"),
+		Entry("Ed25519", generateEd25519, "EdDSA"),
+	)
+	DescribeTable("JWK Round Trip", func(generate func() (crypto.Signer, error)) {
+		k := newKey(generate)
+		ks, err := token.NewKeySet(k)
+		Expect(err).ToNot(HaveOccurred())
+		jwks, err := ks.JWKS()
+		Expect(err).ToNot(HaveOccurred())
+		pub, err := jwks.Keys[0].PublicKey()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pub).To(Equal(k.Private.Public()))
+	},
+		Entry("RSA", generateRSA),
+		Entry("ECDSA", generateECDSA),
+		Entry("Ed25519", generateEd25519),
+	)
+	It("Should reject unsupported keys", func() {
+		private, err := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = token.NewKey(private)
+		Expect(err).To(MatchError(token.UnsupportedKey))
+	})
+	Describe("Rotation", func() {
+		var (
+			ks  *token.KeySet
+			svc *token.Service
+		)
+		BeforeEach(func() {
+			var err error
+			ks, err = token.NewKeySet(newKey(generateEd25519))
+			Expect(err).ToNot(HaveOccurred())
+			svc = &token.Service{Keys: ks, Expiration: 5 * time.Second}
+		})
+		It("Should continue to verify tokens signed by retired keys", func() {
+			tk, err := svc.New(uuid.New())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ks.Rotate(newKey(generateECDSA))).To(Succeed())
+			_, err = svc.Validate(tk)
+			Expect(err).ToNot(HaveOccurred())
+			jwks, err := svc.JWKS()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(jwks.Keys).To(HaveLen(2))
+		})
+		It("Should stop verifying tokens once their key is pruned", func() {
+			tk, err := svc.New(uuid.New())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ks.Rotate(newKey(generateEd25519))).To(Succeed())
+			Expect(ks.Prune(0)).To(Succeed())
+			Expect(ks.Keys()).To(HaveLen(1))
+			_, err = svc.Validate(tk)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Describe("Stored Keys", func() {
+		var db *gorp.DB
+		BeforeEach(func() { db = gorp.Wrap(memkv.New()) })
+		AfterEach(func() { Expect(db.Close()).To(Succeed()) })
+		// open opens a Service on a node sharing the DB.
+		open := func() (*token.KeySet, *token.Service) {
+			ks, err := token.OpenKeySet(db, newKey(generateEd25519))
+			Expect(err).ToNot(HaveOccurred())
+			return ks, &token.Service{Keys: ks, DB: db, Expiration: 5 * time.Second}
+		}
+		It("Should share the keys stored by the first node", func() {
+			_, first := open()
+			_, second := open()
+			tk, err := first.New(uuid.New())
+			Expect(err).ToNot(HaveOccurred())
+			_, err = second.Validate(tk)
+			Expect(err).ToNot(HaveOccurred())
+		})
+		It("Should verify tokens signed by a key rotated on another node", func() {
+			ks, first := open()
+			_, second := open()
+			Expect(ks.Rotate(newKey(generateECDSA))).To(Succeed())
+			tk, err := first.New(uuid.New())
+			Expect(err).ToNot(HaveOccurred())
+			_, err = second.Validate(tk)
+			Expect(err).ToNot(HaveOccurred())
+		})
+		It("Should open the stored keys of a Service without a KeySet", func() {
+			k := newKey(generateRSA)
+			svc := &token.Service{
+				Secret:     k.Private.(*rsa.PrivateKey),
+				DB:         db,
+				Expiration: 5 * time.Second,
+			}
+			tk, err := svc.New(uuid.New())
+			Expect(err).ToNot(HaveOccurred())
+			ks, err := token.OpenKeySet(db)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ks.Keys()).To(HaveLen(1))
+			Expect(ks.Keys()[0].ID).To(Equal(k.ID))
+			_, err = (&token.Service{Keys: ks, Expiration: 5 * time.Second}).Validate(tk)
+			Expect(err).ToNot(HaveOccurred())
+		})
+		It("Should delete pruned keys", func() {
+			ks, _ := open()
+			Expect(ks.Rotate(newKey(generateEd25519))).To(Succeed())
+			Expect(ks.Prune(0)).To(Succeed())
+			reopened, _ := open()
+			Expect(reopened.Keys()).To(HaveLen(1))
+			active, _ := ks.Active()
+			Expect(reopened.Keys()[0].ID).To(Equal(active.ID))
+		})
+	})
+	Describe("Scheduled Rotation", func() {
+		var (
+			db     *gorp.DB
+			ctx    context.Context
+			cancel context.CancelFunc
+		)
+		BeforeEach(func() {
+			db = gorp.Wrap(memkv.New())
+			ctx, cancel = context.WithCancel(context.Background())
+		})
+		AfterEach(func() {
+			cancel()
+			Expect(db.Close()).To(Succeed())
+		})
+		rotation := func(lease bool) token.Rotation {
+			return token.Rotation{
+				Interval: 10 * time.Millisecond,
+				Generate: generateEd25519,
+				Lease:    func() bool { return lease },
+				OnError: func(err error) {
+					defer GinkgoRecover()
+					Fail(err.Error())
+				},
+			}
+		}
+		It("Should rotate keys on the node holding the lease", func() {
+			leaseholder, err := token.OpenKeySet(db, newKey(generateEd25519))
+			Expect(err).ToNot(HaveOccurred())
+			other, err := token.OpenKeySet(db)
+			Expect(err).ToNot(HaveOccurred())
+			initial, _ := leaseholder.Active()
+			svc := &token.Service{Keys: leaseholder, DB: db, Expiration: time.Second}
+			Expect(svc.ScheduleRotation(ctx, rotation(true))).To(Succeed())
+			otherSvc := &token.Service{Keys: other, DB: db, Expiration: time.Second}
+			Expect(otherSvc.ScheduleRotation(ctx, rotation(false))).To(Succeed())
+			Eventually(func() string {
+				k, _ := other.Active()
+				return k.ID
+			}).ShouldNot(Equal(initial.ID))
+		})
+		It("Should keep verifying tokens until they expire", func() {
+			ks, err := token.OpenKeySet(db, newKey(generateEd25519))
+			Expect(err).ToNot(HaveOccurred())
+			svc := &token.Service{Keys: ks, DB: db, Expiration: 200 * time.Millisecond}
+			tk, err := svc.New(uuid.New())
+			Expect(err).ToNot(HaveOccurred())
+			r := rotation(true)
+			r.Retention = time.Millisecond
+			Expect(svc.ScheduleRotation(ctx, r)).To(Succeed())
+			Consistently(func() error {
+				_, err := svc.Validate(tk)
+				return err
+			}, 150*time.Millisecond, 10*time.Millisecond).Should(Succeed())
+			Eventually(func() int { return len(ks.Keys()) }).Should(BeNumerically("<", 40))
+		})
+		It("Should not rotate keys on nodes without the lease", func() {
+			ks, err := token.OpenKeySet(db, newKey(generateEd25519))
+			Expect(err).ToNot(HaveOccurred())
+			svc := &token.Service{Keys: ks, DB: db, Expiration: time.Second}
+			Expect(svc.ScheduleRotation(ctx, rotation(false))).To(Succeed())
+			Consistently(func() []token.Key { return ks.Keys() }, 50*time.Millisecond).
+				Should(HaveLen(1))
+		})
+	})
+	Describe("Tokens Without Key IDs", func() {
+		var secret *rsa.PrivateKey
+		// legacy signs a token without a key id, as tokens were issued before keys had
+		// IDs.
+		legacy := func(private crypto.Signer, m jwt.SigningMethod) string {
+			tk, err := jwt.NewWithClaims(m, jwt.StandardClaims{
+				Issuer:    uuid.New().String(),
+				ExpiresAt: time.Now().Add(5 * time.Second).Unix(),
+			}).SignedString(private)
+			Expect(err).ToNot(HaveOccurred())
+			return tk
+		}
+		BeforeEach(func() {
+			private, err := generateRSA()
+			Expect(err).ToNot(HaveOccurred())
+			secret = private.(*rsa.PrivateKey)
+		})
+		It("Should verify them with the secret after the keys are rotated", func() {
+			ks, err := token.NewKeySet(newKey(generateEd25519))
+			Expect(err).ToNot(HaveOccurred())
+			svc := &token.Service{Secret: secret, Keys: ks, Expiration: 5 * time.Second}
+			_, err = svc.Validate(legacy(secret, jwt.SigningMethodRS512))
+			Expect(err).ToNot(HaveOccurred())
+		})
+		It("Should not verify them with the active key", func() {
+			k := newKey(generateRSA)
+			ks, err := token.NewKeySet(k)
+			Expect(err).ToNot(HaveOccurred())
+			svc := &token.Service{Secret: secret, Keys: ks, Expiration: 5 * time.Second}
+			_, err = svc.Validate(legacy(k.Private, jwt.SigningMethodRS512))
+			Expect(err).To(HaveOccurred())
+			svc = &token.Service{Keys: ks, Expiration: 5 * time.Second}
+			_, err = svc.Validate(legacy(k.Private, jwt.SigningMethodRS512))
+			Expect(err).To(HaveOccurred())
+		})
+		It("Should return Irrevocable when revoking them", func() {
+			db := gorp.Wrap(memkv.New())
+			defer func() { Expect(db.Close()).To(Succeed()) }()
+			svc := &token.Service{Secret: secret, Expiration: 5 * time.Second, DB: db}
+			tk := legacy(secret, jwt.SigningMethodRS512)
+			Expect(svc.Revoke(tk)).To(MatchError(token.Irrevocable))
+			_, err := svc.Validate(tk)
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,229 @@
+package token
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"github.com/arya-analytics/x/gorp"
+	"github.com/arya-analytics/x/query"
+	"github.com/cockroachdb/errors"
+	"github.com/google/uuid"
+	"time"
+)
+
+// Pair is an access token along with the refresh token used to obtain a new one
+// once it expires.
+type Pair struct {
+	Access  string `json:"token"`
+	Refresh string `json:"refreshToken"`
+}
+
+// NewPair issues a new access token and refresh token for the given issuer. The
+// refresh token starts a new family: every refresh token obtained by refreshing it
+// belongs to the same family.
+func (s *Service) NewPair(issuer uuid.UUID) (Pair, error) {
+	if s.DB == nil {
+		return Pair{}, NoDB
+	}
+	return s.newPair(s.DB, issuer, uuid.New())
+}
+
+// Refresh exchanges a refresh token for a new Pair. Refresh tokens can only be used
+// once. If a refresh token is used a second time, it has likely been stolen, so its
+// entire family is revoked and InvalidRefresh is returned.
+//
+// Refresh sends the refresh token through the Service's Relay if it has one, and
+// otherwise exchanges it on the host (see Exchange).
+func (s *Service) Refresh(refresh string) (Pair, error) {
+	if s.Relay != nil {
+		return s.Relay(refresh)
+	}
+	return s.Exchange(refresh)
+}
+
+// Exchange exchanges a refresh token for a new Pair on the host. Exchanges are
+// serialized within the Service, so a refresh token can't be exchanged twice on the
+// same node. The DB has no conditional writes, so if the DB is shared by several
+// nodes, every node must relay its refreshes to a single node (the leaseholder), which
+// calls Exchange on their behalf.
+func (s *Service) Exchange(refresh string) (Pair, error) {
+	if s.DB == nil {
+		return Pair{}, NoDB
+	}
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+	txn := s.DB.BeginTxn()
+	pair, err := s.refresh(txn, refresh)
+	if err != nil && !errors.Is(err, InvalidRefresh) {
+		return Pair{}, errors.CombineErrors(err, txn.Close())
+	}
+	// The family of a reused refresh token is revoked in txn, so it's committed even
+	// if the refresh token is invalid.
+	return pair, errors.CombineErrors(err, errors.CombineErrors(txn.Commit(), txn.Close()))
+}
+
+func (s *Service) refresh(txn gorp.Txn, refresh string) (Pair, error) {
+	rt, err := retrieveRefresh(txn, refresh)
+	if err != nil {
+		return Pair{}, err
+	}
+	if rt.Used {
+		return Pair{}, errors.CombineErrors(
+			errors.Wrap(InvalidRefresh, "[token] - refresh token reused"),
+			revokeFamily(txn, rt.Family),
+		)
+	}
+	if time.Now().Unix() > rt.ExpiresAt {
+		return Pair{}, errors.Wrap(InvalidRefresh, "[token] - refresh token expired")
+	}
+	rt.Used = true
+	if err := gorp.NewCreate[string, refreshToken]().Entry(&rt).Exec(txn); err != nil {
+		return Pair{}, err
+	}
+	return s.newPair(txn, rt.Subject, rt.Family)
+}
+
+// RevokeRefresh revokes the refresh token along with every other token in its family,
+// and the access tokens issued with them.
+func (s *Service) RevokeRefresh(refresh string) error {
+	if s.DB == nil {
+		return NoDB
+	}
+	txn := s.DB.BeginTxn()
+	rt, err := retrieveRefresh(txn, refresh)
+	if err != nil {
+		return errors.CombineErrors(err, txn.Close())
+	}
+	if err := revokeFamily(txn, rt.Family); err != nil {
+		return errors.CombineErrors(err, txn.Close())
+	}
+	return errors.CombineErrors(txn.Commit(), txn.Close())
+}
+
+// Purge deletes revocations and refresh tokens that have expired. Purge should be
+// called periodically (see SchedulePurge) to keep the DB from growing without bound.
+func (s *Service) Purge() error {
+	if s.DB == nil {
+		return NoDB
+	}
+	now := time.Now().Unix()
+	if err := gorp.NewDelete[string, revocation]().
+		Where(func(r *revocation) bool { return r.ExpiresAt < now }).
+		Exec(s.DB); err != nil {
+		return err
+	}
+	return gorp.NewDelete[string, refreshToken]().
+		Where(func(rt *refreshToken) bool { return rt.ExpiresAt < now }).
+		Exec(s.DB)
+}
+
+// SchedulePurge calls Purge every interval until the context is cancelled. onError
+// is called with any errors returned by Purge. Optional.
+func (s *Service) SchedulePurge(ctx context.Context, interval time.Duration, onError func(error)) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.Purge(); err != nil && onError != nil {
+					onError(err)
+				}
+			}
+		}
+	}()
+}
+
+func (s *Service) newPair(txn gorp.Txn, issuer uuid.UUID, family uuid.UUID) (Pair, error) {
+	access, claims, err := s.issue(issuer)
+	if err != nil {
+		return Pair{}, err
+	}
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return Pair{}, err
+	}
+	expiration := s.RefreshExpiration
+	if expiration == 0 {
+		expiration = DefaultRefreshExpiration
+	}
+	refresh := base64.RawURLEncoding.EncodeToString(raw)
+	rt := refreshToken{
+		Hash:            hashRefresh(refresh),
+		Subject:         issuer,
+		Family:          family,
+		ExpiresAt:       time.Now().Add(expiration).Unix(),
+		Access:          claims.Id,
+		AccessExpiresAt: claims.ExpiresAt,
+	}
+	return Pair{Access: access, Refresh: refresh}, gorp.NewCreate[string, refreshToken]().
+		Entry(&rt).
+		Exec(txn)
+}
+
+func retrieveRefresh(txn gorp.Txn, refresh string) (refreshToken, error) {
+	var rt refreshToken
+	err := gorp.NewRetrieve[string, refreshToken]().
+		WhereKeys(hashRefresh(refresh)).
+		Entry(&rt).
+		Exec(txn)
+	if errors.Is(err, query.NotFound) {
+		return rt, InvalidRefresh
+	}
+	return rt, err
+}
+
+// revokeFamily deletes every refresh token in the family, and revokes the access
+// tokens issued with them that haven't expired.
+func revokeFamily(txn gorp.Txn, family uuid.UUID) error {
+	var members []refreshToken
+	if err := gorp.NewRetrieve[string, refreshToken]().
+		Where(func(rt *refreshToken) bool { return rt.Family == family }).
+		Entries(&members).
+		Exec(txn); err != nil {
+		return err
+	}
+	now := time.Now().Unix()
+	for _, rt := range members {
+		if rt.Access == "" || rt.AccessExpiresAt < now {
+			continue
+		}
+		if err := revoke(txn, rt.Access, rt.AccessExpiresAt); err != nil {
+			return err
+		}
+	}
+	return gorp.NewDelete[string, refreshToken]().
+		Where(func(rt *refreshToken) bool { return rt.Family == family }).
+		Exec(txn)
+}
+
+// refreshToken is a refresh token stored server side. Only the hash of the token is
+// stored, so that tokens can't be recovered from the DB.
+type refreshToken struct {
+	Hash      string
+	Subject   uuid.UUID
+	Family    uuid.UUID
+	ExpiresAt int64
+	// Used is true once the token has been exchanged for a new Pair. Used tokens are
+	// kept so that their reuse can be detected.
+	Used bool
+	// Access is the ID (jti) of the access token issued with the refresh token, which
+	// is revoked along with the token's family.
+	Access          string
+	AccessExpiresAt int64
+}
+
+func hashRefresh(refresh string) string {
+	h := sha256.Sum256([]byte(refresh))
+	return hex.EncodeToString(h[:])
+}
+
+// GorpKey implements the gorp.Entry interface.
+func (rt refreshToken) GorpKey() string { return rt.Hash }
+
+// SetOptions implements the gorp.Entry interface.
+func (rt refreshToken) SetOptions() []interface{} { return nil }
@@ -0,0 +1,223 @@
+package token_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"github.com/arya-analytics/delta/pkg/auth/token"
+	"github.com/arya-analytics/x/gorp"
+	"github.com/arya-analytics/x/kv/memkv"
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"sync"
+	"time"
+)
+
+var _ = Describe("Refresh", func() {
+	var (
+		db     *gorp.DB
+		svc    *token.Service
+		issuer uuid.UUID
+	)
+	BeforeEach(func() {
+		k, err := rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).ToNot(HaveOccurred())
+		db = gorp.Wrap(memkv.New())
+		svc = &token.Service{
+			Secret:            k,
+			Expiration:        5 * time.Second,
+			RefreshExpiration: time.Hour,
+			DB:                db,
+		}
+		issuer = uuid.New()
+	})
+	AfterEach(func() { Expect(db.Close()).To(Succeed()) })
+	Describe("Refresh", func() {
+		It("Should exchange a refresh token for a new pair", func() {
+			pair, err := svc.NewPair(issuer)
+			Expect(err).ToNot(HaveOccurred())
+			next, err := svc.Refresh(pair.Refresh)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(next.Refresh).ToNot(Equal(pair.Refresh))
+			key, err := svc.Validate(next.Access)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(key).To(Equal(issuer))
+		})
+		It("Should not accept an unknown refresh token", func() {
+			_, err := svc.Refresh("unknown")
+			Expect(err).To(MatchError(token.InvalidRefresh))
+		})
+		It("Should revoke the token family when a refresh token is reused", func() {
+			pair, err := svc.NewPair(issuer)
+			Expect(err).ToNot(HaveOccurred())
+			next, err := svc.Refresh(pair.Refresh)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = svc.Refresh(pair.Refresh)
+			Expect(err).To(MatchError(token.InvalidRefresh))
+			_, err = svc.Refresh(next.Refresh)
+			Expect(err).To(MatchError(token.InvalidRefresh))
+		})
+		It("Should not accept an expired refresh token", func() {
+			svc.RefreshExpiration = -time.Second
+			pair, err := svc.NewPair(issuer)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = svc.Refresh(pair.Refresh)
+			Expect(err).To(MatchError(token.InvalidRefresh))
+		})
+		It("Should default the refresh token expiration", func() {
+			svc.RefreshExpiration = 0
+			pair, err := svc.NewPair(issuer)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = svc.Refresh(pair.Refresh)
+			Expect(err).ToNot(HaveOccurred())
+		})
+		It("Should only exchange a refresh token once when refreshed concurrently", func() {
+			pair, err := svc.NewPair(issuer)
+			Expect(err).ToNot(HaveOccurred())
+			var (
+				wg   sync.WaitGroup
+				errs = make([]error, 10)
+			)
+			wg.Add(len(errs))
+			for i := range errs {
+				go func(i int) {
+					defer wg.Done()
+					_, errs[i] = svc.Refresh(pair.Refresh)
+				}(i)
+			}
+			wg.Wait()
+			succeeded := 0
+			for _, err := range errs {
+				if err == nil {
+					succeeded++
+				} else {
+					Expect(err).To(MatchError(token.InvalidRefresh))
+				}
+			}
+			Expect(succeeded).To(Equal(1))
+		})
+		It("Should only exchange a refresh token once when relayed from other nodes", func() {
+			pair, err := svc.NewPair(issuer)
+			Expect(err).ToNot(HaveOccurred())
+			// Each Service is a node sharing the DB, and the first holds the lease.
+			nodes := make([]*token.Service, 5)
+			for i := range nodes {
+				nodes[i] = &token.Service{
+					Secret:     svc.Secret,
+					Expiration: svc.Expiration,
+					DB:         db,
+					Relay:      svc.Exchange,
+				}
+			}
+			var (
+				wg   sync.WaitGroup
+				errs = make([]error, len(nodes))
+			)
+			wg.Add(len(errs))
+			for i, n := range nodes {
+				go func(i int, n *token.Service) {
+					defer wg.Done()
+					_, errs[i] = n.Refresh(pair.Refresh)
+				}(i, n)
+			}
+			wg.Wait()
+			succeeded := 0
+			for _, err := range errs {
+				if err == nil {
+					succeeded++
+				} else {
+					Expect(err).To(MatchError(token.InvalidRefresh))
+				}
+			}
+			Expect(succeeded).To(Equal(1))
+		})
+	})
+	Describe("No DB", func() {
+		BeforeEach(func() { svc.DB = nil })
+		It("Should return NoDB when issuing a pair", func() {
+			_, err := svc.NewPair(issuer)
+			Expect(err).To(MatchError(token.NoDB))
+		})
+		It("Should return NoDB when refreshing or revoking", func() {
+			tk, err := svc.New(issuer)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = svc.Refresh("refresh")
+			Expect(err).To(MatchError(token.NoDB))
+			Expect(svc.Revoke(tk)).To(MatchError(token.NoDB))
+			Expect(svc.RevokeRefresh("refresh")).To(MatchError(token.NoDB))
+			Expect(svc.Purge()).To(MatchError(token.NoDB))
+		})
+	})
+	Describe("Revoke", func() {
+		It("Should fail to validate a revoked token", func() {
+			tk, err := svc.New(issuer)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(svc.Revoke(tk)).To(Succeed())
+			_, err = svc.Validate(tk)
+			Expect(err).To(MatchError(token.Revoked))
+		})
+		It("Should not affect other tokens for the same issuer", func() {
+			tk, err := svc.New(issuer)
+			Expect(err).ToNot(HaveOccurred())
+			other, err := svc.New(issuer)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(svc.Revoke(tk)).To(Succeed())
+			_, err = svc.Validate(other)
+			Expect(err).ToNot(HaveOccurred())
+		})
+		It("Should revoke a refresh token and its family", func() {
+			pair, err := svc.NewPair(issuer)
+			Expect(err).ToNot(HaveOccurred())
+			next, err := svc.Refresh(pair.Refresh)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(svc.RevokeRefresh(next.Refresh)).To(Succeed())
+			_, err = svc.Refresh(next.Refresh)
+			Expect(err).To(MatchError(token.InvalidRefresh))
+		})
+		It("Should revoke the access tokens issued with a revoked family", func() {
+			pair, err := svc.NewPair(issuer)
+			Expect(err).ToNot(HaveOccurred())
+			next, err := svc.Refresh(pair.Refresh)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(svc.RevokeRefresh(next.Refresh)).To(Succeed())
+			for _, access := range []string{pair.Access, next.Access} {
+				_, err = svc.Validate(access)
+				Expect(err).To(MatchError(token.Revoked))
+			}
+		})
+		It("Should revoke the access tokens of a family when a refresh token is reused", func() {
+			pair, err := svc.NewPair(issuer)
+			Expect(err).ToNot(HaveOccurred())
+			next, err := svc.Refresh(pair.Refresh)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = svc.Refresh(pair.Refresh)
+			Expect(err).To(MatchError(token.InvalidRefresh))
+			_, err = svc.Validate(next.Access)
+			Expect(err).To(MatchError(token.Revoked))
+		})
+	})
+	Describe("Purge", func() {
+		It("Should remove expired refresh tokens", func() {
+			svc.RefreshExpiration = -time.Second
+			pair, err := svc.NewPair(issuer)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(svc.Purge()).To(Succeed())
+			Expect(svc.RevokeRefresh(pair.Refresh)).To(MatchError(token.InvalidRefresh))
+		})
+		It("Should purge on a schedule", func() {
+			svc.RefreshExpiration = -time.Second
+			pair, err := svc.NewPair(issuer)
+			Expect(err).ToNot(HaveOccurred())
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			svc.SchedulePurge(ctx, 10*time.Millisecond, func(err error) {
+				defer GinkgoRecover()
+				Fail(err.Error())
+			})
+			Eventually(func() error {
+				return svc.RevokeRefresh(pair.Refresh)
+			}).Should(MatchError(token.InvalidRefresh))
+		})
+	})
+})
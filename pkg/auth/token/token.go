@@ -2,38 +2,201 @@ package token
 
 import (
 	"crypto/rsa"
+	"github.com/arya-analytics/x/gorp"
+	"github.com/cockroachdb/errors"
 	"github.com/golang-jwt/jwt"
 	"github.com/google/uuid"
+	"sync"
 	"time"
 )
 
 // Service is a service for generating and validating tokens with UUID issuers.
 type Service struct {
-	// Secret is the secret used to sign the token.
+	// Secret is the secret used to sign the token if Keys is nil. Tokens without a
+	// key id, issued before keys had IDs, are always verified with Secret.
 	Secret *rsa.PrivateKey
+	// Keys is the set of keys used to sign and verify tokens. If Keys is nil, the
+	// KeySet stored in the DB is opened, holding only Secret until it's rotated (see
+	// OpenKeySet and ScheduleRotation). If the Service has no DB either, a KeySet
+	// holding only Secret is used.
+	Keys *KeySet
 	// Expiration is the duration that the token will be valid for.
 	Expiration time.Duration
+	// RefreshExpiration is the duration that a refresh token will be valid for.
+	// Defaults to DefaultRefreshExpiration.
+	RefreshExpiration time.Duration
+	// DB stores refresh tokens and revoked tokens. If the DB is backed by aspen,
+	// revocations are replicated to every node in the cluster. If DB is nil, tokens
+	// can't be refreshed or revoked, and NoDB is returned when attempting to.
+	DB *gorp.DB
+	// Relay, if set, sends refresh tokens to the node that holds the lease on
+	// refreshes, which exchanges them by calling Exchange on its own Service. Relay
+	// must be set if the DB is shared by several nodes (see Refresh). Optional.
+	Relay func(refresh string) (Pair, error)
+	// once initializes Keys from Secret.
+	once    sync.Once
+	keysErr error
+	// refreshMu serializes exchanges, so that a refresh token can't be exchanged
+	// twice by concurrent refreshes on the same node.
+	refreshMu sync.Mutex
 }
 
+// DefaultRefreshExpiration is the duration that a refresh token will be valid for if
+// the Service's RefreshExpiration isn't set.
+const DefaultRefreshExpiration = 7 * 24 * time.Hour
+
+var (
+	// Revoked is returned when validating a token that has been revoked.
+	Revoked = errors.New("[token] - token revoked")
+	// InvalidRefresh is returned when refreshing with a refresh token that doesn't
+	// exist, has expired, or has already been used.
+	InvalidRefresh = errors.New("[token] - invalid refresh token")
+	// NoDB is returned when refreshing or revoking tokens with a Service that has no
+	// DB.
+	NoDB = errors.New("[token] - refreshing and revoking tokens requires a DB")
+	// Irrevocable is returned when revoking a token issued before tokens had IDs.
+	// These tokens remain valid until they expire.
+	Irrevocable = errors.New("[token] - token has no id and can't be revoked")
+)
+
 // New issues a new token for the given issuer. Returns the token as a string, and
 // any errors encountered during signing.
 func (s *Service) New(issuer uuid.UUID) (string, error) {
-	claims := jwt.NewWithClaims(jwt.SigningMethodRS512, jwt.StandardClaims{
+	tk, _, err := s.issue(issuer)
+	return tk, err
+}
+
+// issue issues a new token for the given issuer, and returns it along with its claims.
+func (s *Service) issue(issuer uuid.UUID) (string, jwt.StandardClaims, error) {
+	claims := jwt.StandardClaims{
+		Id:        uuid.New().String(),
 		Issuer:    issuer.String(),
 		ExpiresAt: time.Now().Add(s.Expiration).Unix(),
+	}
+	ks, err := s.keys()
+	if err != nil {
+		return "", claims, err
+	}
+	k, ok := ks.Active()
+	if !ok {
+		return "", claims, errors.New("[token] - no active signing key")
+	}
+	m, err := k.method()
+	if err != nil {
+		return "", claims, err
+	}
+	tk := jwt.NewWithClaims(m, claims)
+	tk.Header["kid"] = k.ID
+	signed, err := tk.SignedString(k.Private)
+	return signed, claims, err
+}
+
+// JWKS returns the public keys that verify tokens issued by the Service.
+func (s *Service) JWKS() (JWKS, error) {
+	ks, err := s.keys()
+	if err != nil {
+		return JWKS{}, err
+	}
+	return ks.JWKS()
+}
+
+func (s *Service) keys() (*KeySet, error) {
+	s.once.Do(func() {
+		if s.Keys != nil {
+			return
+		}
+		if s.Secret == nil {
+			s.keysErr = errors.New("[token] - either keys or a secret are required")
+			return
+		}
+		var k Key
+		if k, s.keysErr = NewKey(s.Secret); s.keysErr != nil {
+			return
+		}
+		if s.DB != nil {
+			s.Keys, s.keysErr = OpenKeySet(s.DB, k)
+			return
+		}
+		s.Keys, s.keysErr = NewKeySet(k)
 	})
-	return claims.SignedString(s.Secret)
+	return s.Keys, s.keysErr
 }
 
 // Validate validates the given token. Returns the UUID of the issuer along with any
-// errors encountered.
+// errors encountered. Returns Revoked if the token has been revoked.
 func (s *Service) Validate(token string) (uuid.UUID, error) {
-	claims := &jwt.StandardClaims{}
-	_, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
-		return s.Secret.Public(), nil
-	})
+	claims, err := s.parse(token)
 	if err != nil {
 		return uuid.Nil, err
 	}
+	if err := s.checkRevoked(claims.Id); err != nil {
+		return uuid.Nil, err
+	}
 	return uuid.Parse(claims.Issuer)
 }
+
+// Revoke revokes the given token, so that it fails validation on every node until it
+// expires. Returns Irrevocable if the token was issued before tokens had IDs.
+func (s *Service) Revoke(token string) error {
+	if s.DB == nil {
+		return NoDB
+	}
+	claims, err := s.parse(token)
+	if err != nil {
+		return err
+	}
+	if claims.Id == "" {
+		return Irrevocable
+	}
+	return revoke(s.DB, claims.Id, claims.ExpiresAt)
+}
+
+func revoke(txn gorp.Txn, id string, expiresAt int64) error {
+	return gorp.NewCreate[string, revocation]().
+		Entry(&revocation{ID: id, ExpiresAt: expiresAt}).
+		Exec(txn)
+}
+
+func (s *Service) parse(token string) (*jwt.StandardClaims, error) {
+	claims := &jwt.StandardClaims{}
+	ks, err := s.keys()
+	if err != nil {
+		return claims, err
+	}
+	_, err = jwt.ParseWithClaims(token, claims, func(tk *jwt.Token) (interface{}, error) {
+		if id, _ := tk.Header["kid"].(string); id == "" && s.Secret != nil {
+			// Tokens issued before keys had IDs were signed with Secret, so they're
+			// verified with it rather than with whichever key is active.
+			return Key{Private: s.Secret}.verify(tk)
+		}
+		return ks.keyfunc(tk)
+	})
+	return claims, err
+}
+
+func (s *Service) checkRevoked(id string) error {
+	if s.DB == nil || id == "" {
+		return nil
+	}
+	revoked, err := gorp.NewRetrieve[string, revocation]().WhereKeys(id).Exists(s.DB)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return Revoked
+	}
+	return nil
+}
+
+// revocation records a revoked token. Revocations are kept until the token expires.
+type revocation struct {
+	// ID is the ID (jti) of the revoked token.
+	ID        string
+	ExpiresAt int64
+}
+
+// GorpKey implements the gorp.Entry interface.
+func (r revocation) GorpKey() string { return r.ID }
+
+// SetOptions implements the gorp.Entry interface.
+func (r revocation) SetOptions() []interface{} { return nil }
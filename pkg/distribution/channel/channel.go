@@ -82,7 +82,10 @@ func (k Keys) Nodes() (ids []node.ID) {
 type Channel struct {
 	Name   string
 	NodeID node.ID
-	Cesium cesium.Channel
+	// SampleType is the type of the samples the Channel records. Channels created
+	// without a SampleType can't be aggregated or transformed.
+	SampleType SampleType
+	Cesium     cesium.Channel
 }
 
 // Key returns the key for the Channel.
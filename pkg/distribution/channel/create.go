@@ -7,6 +7,7 @@ import (
 	"github.com/arya-analytics/x/gorp"
 	"github.com/arya-analytics/x/query"
 	"github.com/arya-analytics/x/telem"
+	"github.com/cockroachdb/errors"
 )
 
 type Create struct {
@@ -26,6 +27,10 @@ func (c Create) WithDataRate(dr telem.DataRate) Create { telem.SetDataRate(c, dr
 
 func (c Create) WithDataType(dt telem.DataType) Create { telem.SetDataType(c, dt); return c }
 
+// WithSampleType sets the SampleType of the channel. If no data type is set, the
+// channel's data type is set to the one of the SampleType.
+func (c Create) WithSampleType(st SampleType) Create { setSampleType(c, st); return c }
+
 func (c Create) WithTxn(txn gorp.Txn) Create { gorp.SetTxn(c, txn); return c }
 
 func (c Create) Exec(ctx context.Context) (Channel, error) {
@@ -51,6 +56,21 @@ func assembleFromQuery(q query.Query, n int) ([]Channel, error) {
 		return channels, err
 	}
 	dt, err := telem.GetDataType(q)
+	st := getSampleType(q)
+	if st != UnknownSampleType {
+		stDT, ok := st.DataType()
+		if !ok {
+			return channels, errors.Newf("[channel] - unknown sample type %v", st)
+		}
+		if err == nil && dt != stDT {
+			return channels, errors.Newf(
+				"[channel] - data type %v doesn't match sample type %v",
+				dt,
+				st,
+			)
+		}
+		dt, err = stDT, nil
+	}
 	if err != nil {
 		return channels, err
 	}
@@ -58,9 +78,10 @@ func assembleFromQuery(q query.Query, n int) ([]Channel, error) {
 	nodeID := getNodeID(q)
 	for i := 0; i < n; i++ {
 		channels[i] = Channel{
-			Name:   name,
-			NodeID: nodeID,
-			Cesium: cesium.Channel{DataRate: dr, DataType: dt},
+			Name:       name,
+			NodeID:     nodeID,
+			SampleType: st,
+			Cesium:     cesium.Channel{DataRate: dr, DataType: dt},
 		}
 	}
 	return channels, nil
@@ -79,6 +100,19 @@ func getNodeID(q query.Query) aspen.NodeID {
 	return 0
 }
 
+// |||||| SAMPLE TYPE ||||||
+
+const sampleTypeKey query.OptionKey = "sampleType"
+
+func setSampleType(q query.Query, st SampleType) { q.Set(sampleTypeKey, st) }
+
+func getSampleType(q query.Query) SampleType {
+	if v, ok := q.Get(sampleTypeKey); ok {
+		return v.(SampleType)
+	}
+	return UnknownSampleType
+}
+
 // |||||| NAME ||||||
 
 const nameKey query.OptionKey = "name"
@@ -108,4 +108,27 @@ var _ = Describe("Create", Ordered, func() {
 		})
 
 	})
+	Context("Sample Type", func() {
+		It("Should set the data type from the sample type", func() {
+			ch, err := services[1].NewCreate().
+				WithDataRate(5 * telem.Hz).
+				WithSampleType(channel.Int64).
+				WithName("SG02").
+				WithNodeID(1).
+				Exec(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ch.SampleType).To(Equal(channel.Int64))
+			Expect(ch.Cesium.DataType).To(Equal(telem.DataType(8)))
+		})
+		It("Should reject a data type that doesn't match the sample type", func() {
+			_, err := services[1].NewCreate().
+				WithDataRate(5 * telem.Hz).
+				WithDataType(telem.Float64).
+				WithSampleType(channel.Int16).
+				WithName("SG03").
+				WithNodeID(1).
+				Exec(ctx)
+			Expect(err).To(HaveOccurred())
+		})
+	})
 })
@@ -37,7 +37,7 @@ func newLeaseProxy(
 }
 
 func (lp *leaseProxy) handle(ctx context.Context, msg CreateMessage) (CreateMessage, error) {
-	txn := lp.db.BeginTxn()
+	txn := lp.beginTxn()
 	channels, err := lp.create(ctx, txn, msg.Channels)
 	if err != nil {
 		return CreateMessage{}, err
@@ -45,6 +45,16 @@ func (lp *leaseProxy) handle(ctx context.Context, msg CreateMessage) (CreateMess
 	return CreateMessage{Channels: channels}, txn.Commit()
 }
 
+// beginTxn begins a transaction for creating channels. If the proxy defines channels
+// as resources, the transaction is opened on the ontology so that they're published
+// to its Watch subscribers.
+func (lp *leaseProxy) beginTxn() gorp.Txn {
+	if lp.resources != nil {
+		return lp.resources.BeginTxn()
+	}
+	return lp.db.BeginTxn()
+}
+
 func (lp *leaseProxy) create(
 	ctx context.Context,
 	txn gorp.Txn,
@@ -97,9 +107,11 @@ func (lp *leaseProxy) maybeSetResources(
 			if err := w.DefineResource(rtk); err != nil {
 				return err
 			}
+			w.InvalidateEntities(rtk)
 			if err := w.DefineRelationship(
-				node.ResourceKey(channel.NodeID),
 				rtk,
+				node.ResourceKey(channel.NodeID),
+				ontology.Parent,
 			); err != nil {
 				return err
 			}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"github.com/arya-analytics/delta/pkg/ontology"
 	"github.com/arya-analytics/delta/pkg/ontology/schema"
+	"github.com/arya-analytics/x/query"
+	"github.com/cockroachdb/errors"
 )
 
 const ontologyType ontology.Type = "channel"
@@ -11,15 +13,16 @@ const ontologyType ontology.Type = "channel"
 var _schema = &ontology.Schema{
 	Type: ontologyType,
 	Fields: map[string]schema.Field{
-		"key":      {Type: schema.String},
-		"name":     {Type: schema.String},
-		"nodeID":   {Type: schema.Uint32},
-		"dataRate": {Type: schema.Float64},
-		"dataType": {Type: schema.Uint16},
+		"key":        {Type: schema.String},
+		"name":       {Type: schema.String},
+		"nodeID":     {Type: schema.Uint32},
+		"dataRate":   {Type: schema.Float64},
+		"dataType":   {Type: schema.Uint16},
+		"sampleType": {Type: schema.Uint8},
 	},
 }
 
-var _ ontology.Service = (*Service)(nil)
+var _ ontology.BatchService = (*Service)(nil)
 
 func (s *Service) Schema() *schema.Schema { return _schema }
 
@@ -29,15 +32,48 @@ func (s *Service) RetrieveEntity(key string) (schema.Entity, error) {
 		return schema.Entity{}, err
 	}
 	var ch Channel
-	return newEntity(ch), s.NewRetrieve().WhereKeys(k).Entry(&ch).Exec(context.TODO())
+	if err := s.NewRetrieve().WhereKeys(k).Entry(&ch).Exec(context.TODO()); err != nil {
+		return schema.Entity{}, err
+	}
+	return newEntity(ch), nil
+}
+
+// RetrieveEntities implements the ontology.BatchService interface.
+func (s *Service) RetrieveEntities(keys []string) ([]schema.Entity, error) {
+	ks := make([]Key, len(keys))
+	for i, key := range keys {
+		k, err := ParseKey(key)
+		if err != nil {
+			return nil, err
+		}
+		ks[i] = k
+	}
+	var channels []Channel
+	if err := s.NewRetrieve().WhereKeys(ks...).Entries(&channels).Exec(context.TODO()); err != nil {
+		return nil, err
+	}
+	byKey := make(map[Key]Channel, len(channels))
+	for _, ch := range channels {
+		byKey[ch.Key()] = ch
+	}
+	entities := make([]schema.Entity, len(ks))
+	for i, k := range ks {
+		ch, ok := byKey[k]
+		if !ok {
+			return nil, errors.Wrapf(query.NotFound, "[channel] - channel %s not found", k)
+		}
+		entities[i] = newEntity(ch)
+	}
+	return entities, nil
 }
 
 func newEntity(c Channel) schema.Entity {
 	e := schema.NewEntity(_schema)
 	schema.Set(e, "key", c.Key().String())
 	schema.Set(e, "name", c.Name)
-	schema.Set(e, "nodeID", int32(c.NodeID))
+	schema.Set(e, "nodeID", uint32(c.NodeID))
 	schema.Set(e, "dataRate", float64(c.Cesium.DataRate))
-	schema.Set(e, "dataType", float32(c.Cesium.DataType))
+	schema.Set(e, "dataType", uint16(c.Cesium.DataType))
+	schema.Set(e, "sampleType", uint8(c.SampleType))
 	return e
 }
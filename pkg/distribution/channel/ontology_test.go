@@ -0,0 +1,66 @@
+package channel_test
+
+import (
+	"github.com/arya-analytics/delta/pkg/distribution/channel"
+	"github.com/arya-analytics/delta/pkg/distribution/mock"
+	"github.com/arya-analytics/delta/pkg/ontology/schema"
+	"github.com/arya-analytics/x/gorp"
+	"github.com/arya-analytics/x/telem"
+	tmock "github.com/arya-analytics/x/transport/mock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+)
+
+var _ = Describe("Ontology", Ordered, func() {
+	var (
+		svc     *channel.Service
+		builder *mock.StorageBuilder
+	)
+	BeforeAll(func() {
+		net := tmock.NewNetwork[channel.CreateMessage, channel.CreateMessage]()
+		builder = mock.NewStorage()
+		store, err := builder.New(zap.NewNop())
+		Expect(err).ToNot(HaveOccurred())
+		svc = channel.New(store.Aspen, gorp.Wrap(store.Aspen), store.Cesium, net.RouteUnary(""))
+	})
+	AfterAll(func() { Expect(builder.Close()).To(Succeed()) })
+	It("Should return entities that conform to the service's schema", func() {
+		ch, err := svc.NewCreate().
+			WithName("SG01").
+			WithDataRate(25 * telem.Hz).
+			WithDataType(telem.Float64).
+			WithNodeID(1).
+			Exec(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		e, err := svc.RetrieveEntity(ch.Key().String())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(e.Validate(svc.Schema())).To(Succeed())
+		name, ok := schema.Get[string](e, "name")
+		Expect(ok).To(BeTrue())
+		Expect(name).To(Equal("SG01"))
+		nodeID, ok := schema.Get[uint32](e, "nodeID")
+		Expect(ok).To(BeTrue())
+		Expect(nodeID).To(Equal(uint32(1)))
+	})
+	It("Should retrieve entities in a batch in the order of their keys", func() {
+		var keys []string
+		for _, name := range []string{"SG02", "SG03"} {
+			ch, err := svc.NewCreate().
+				WithName(name).
+				WithDataRate(25 * telem.Hz).
+				WithDataType(telem.Float64).
+				WithNodeID(1).
+				Exec(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			keys = append([]string{ch.Key().String()}, keys...)
+		}
+		entities, err := svc.RetrieveEntities(keys)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(entities).To(HaveLen(2))
+		name, _ := schema.Get[string](entities[0], "name")
+		Expect(name).To(Equal("SG03"))
+		name, _ = schema.Get[string](entities[1], "name")
+		Expect(name).To(Equal("SG02"))
+	})
+})
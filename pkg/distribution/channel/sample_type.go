@@ -0,0 +1,48 @@
+package channel
+
+import (
+	"github.com/arya-analytics/x/telem"
+)
+
+// SampleType is the type of the samples a Channel records. A telem.DataType only
+// records the density of a sample, so channels whose samples have the same density
+// (e.g. float64, int64 and timestamps) can only be told apart by their SampleType.
+type SampleType uint8
+
+const (
+	// UnknownSampleType is the SampleType of channels whose samples are opaque.
+	UnknownSampleType SampleType = iota
+	Float64
+	Float32
+	Int64
+	Int32
+	Int16
+	Int8
+	Uint64
+	Uint32
+	Uint16
+	Uint8
+	// TimeStamp samples are int64 nanosecond telem.TimeStamps.
+	TimeStamp
+)
+
+var sampleDensities = map[SampleType]int{
+	Float64:   8,
+	Float32:   4,
+	Int64:     8,
+	Int32:     4,
+	Int16:     2,
+	Int8:      1,
+	Uint64:    8,
+	Uint32:    4,
+	Uint16:    2,
+	Uint8:     1,
+	TimeStamp: 8,
+}
+
+// DataType returns the telem.DataType of samples of the SampleType. Returns false if
+// the SampleType is unknown.
+func (st SampleType) DataType() (telem.DataType, bool) {
+	d, ok := sampleDensities[st]
+	return telem.DataType(d), ok
+}
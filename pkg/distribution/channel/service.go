@@ -34,7 +34,9 @@ func (s *Service) NewRetrieve() Retrieve { return newRetrieve(s.metadataDB) }
 
 func (s *Service) Resolve(key Key) (address.Address, error) { return s.resolver.Resolve(key) }
 
+// BindResources registers the Service with the ontology, and defines a resource for
+// each channel created from then on as a child of its leaseholder node.
 func (s *Service) BindResources(svc *ontology.Ontology) {
-	svc.RegisterService(ontologyType, &ResourceProvider{svc: s})
+	svc.RegisterService(s)
 	s.proxy.resources = svc
 }
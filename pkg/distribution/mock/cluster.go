@@ -0,0 +1,101 @@
+package mock
+
+import (
+	"github.com/arya-analytics/delta/pkg/distribution/channel"
+	"github.com/arya-analytics/delta/pkg/distribution/node"
+	"github.com/arya-analytics/delta/pkg/distribution/resource"
+	"github.com/arya-analytics/delta/pkg/distribution/segment"
+	"github.com/arya-analytics/delta/pkg/distribution/transport/mem"
+	"github.com/arya-analytics/x/address"
+	"github.com/arya-analytics/x/gorp"
+	"go.uber.org/zap"
+)
+
+// ClusterBuilder builds in-process delta clusters whose nodes communicate over an
+// in-memory network. Faults can be injected through Network.
+type ClusterBuilder struct {
+	*StorageBuilder
+	Network *mem.Network
+	Nodes   map[node.ID]Node
+}
+
+// Node is a single node in a cluster built by ClusterBuilder.
+type Node struct {
+	Store
+	Address   address.Address
+	Resources *resource.Service
+	Channel   *channel.Service
+	Segment   *segment.Service
+}
+
+// ID returns the ID of the node.
+func (n Node) ID() node.ID { return n.Aspen.HostID() }
+
+func NewCluster() *ClusterBuilder {
+	return &ClusterBuilder{
+		StorageBuilder: NewStorage(),
+		Network:        mem.NewNetwork(),
+		Nodes:          make(map[node.ID]Node),
+	}
+}
+
+// New adds a node to the cluster.
+func (cb *ClusterBuilder) New(logger *zap.Logger) (Node, error) {
+	store, err := cb.StorageBuilder.New(logger)
+	if err != nil {
+		return Node{}, err
+	}
+	addr, err := store.Aspen.Resolve(store.Aspen.HostID())
+	if err != nil {
+		return Node{}, err
+	}
+	n := Node{Store: store, Address: addr}
+	db := gorp.Wrap(store.Aspen)
+	n.Resources, err = resource.Open(store.Aspen, db, mem.ResourceTransport(cb.Network, addr))
+	if err != nil {
+		return Node{}, err
+	}
+	n.Channel = channel.New(
+		store.Aspen,
+		db,
+		store.Cesium,
+		mem.ChannelTransport(cb.Network, addr),
+	)
+	n.Channel.BindResources(n.Resources.Ontology)
+	n.Segment = segment.New(
+		n.Channel,
+		store.Cesium,
+		mem.NewSegmentTransport(cb.Network, addr),
+		store.Aspen,
+	)
+	cb.Nodes[n.ID()] = n
+	return n, nil
+}
+
+// NewN adds count nodes to the cluster.
+func (cb *ClusterBuilder) NewN(logger *zap.Logger, count int) ([]Node, error) {
+	nodes := make([]Node, count)
+	for i := range nodes {
+		var err error
+		if nodes[i], err = cb.New(logger); err != nil {
+			return nodes, err
+		}
+	}
+	return nodes, nil
+}
+
+// Kill makes the node unreachable and severs all of its open streams.
+func (cb *ClusterBuilder) Kill(id node.ID) { cb.Network.Kill(cb.Nodes[id].Address) }
+
+// Revive makes a killed node reachable again.
+func (cb *ClusterBuilder) Revive(id node.ID) { cb.Network.Revive(cb.Nodes[id].Address) }
+
+// Partition splits the cluster so that the given nodes can only communicate with
+// each other.
+func (cb *ClusterBuilder) Partition(ids ...node.ID) {
+	addrs := make([]address.Address, len(ids))
+	for i, id := range ids {
+		addrs[i] = cb.Nodes[id].Address
+	}
+	cb.Network.Partition(addrs...)
+}
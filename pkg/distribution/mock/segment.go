@@ -0,0 +1,49 @@
+package mock
+
+import (
+	"context"
+	"encoding/binary"
+	"github.com/arya-analytics/cesium"
+	"github.com/cockroachdb/errors"
+	"math"
+)
+
+// Float64Data encodes the given values as little-endian float64 samples.
+func Float64Data(values ...float64) []byte {
+	b := make([]byte, len(values)*8)
+	for i, v := range values {
+		binary.LittleEndian.PutUint64(b[i*8:], math.Float64bits(v))
+	}
+	return b
+}
+
+// Int64Data encodes the given values as little-endian int64 samples.
+func Int64Data(values ...int64) []byte {
+	b := make([]byte, len(values)*8)
+	for i, v := range values {
+		binary.LittleEndian.PutUint64(b[i*8:], uint64(v))
+	}
+	return b
+}
+
+// WriteSegments writes the given segments directly to db. All segments must belong
+// to channels that already exist in db.
+func WriteSegments(ctx context.Context, db cesium.DB, segments ...cesium.Segment) error {
+	keys := make([]cesium.ChannelKey, len(segments))
+	for i, seg := range segments {
+		keys[i] = seg.ChannelKey
+	}
+	req, res, err := db.NewCreate().WhereChannels(keys...).Stream(ctx)
+	if err != nil {
+		return err
+	}
+	req <- cesium.CreateRequest{Segments: segments}
+	close(req)
+	var errs error
+	for r := range res {
+		if r.Error != nil {
+			errs = errors.CombineErrors(errs, r.Error)
+		}
+	}
+	return errs
+}
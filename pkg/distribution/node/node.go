@@ -7,8 +7,9 @@ import (
 )
 
 type (
-	Node = aspen.Node
-	ID   = aspen.NodeID
+	Node  = aspen.Node
+	ID    = aspen.NodeID
+	Group = aspen.NodeGroup
 )
 
 func ResourceKey(id ID) ontology.ID {
@@ -0,0 +1,50 @@
+package node
+
+import (
+	"github.com/arya-analytics/aspen"
+	"github.com/arya-analytics/delta/pkg/ontology"
+	"github.com/arya-analytics/delta/pkg/ontology/schema"
+	"strconv"
+)
+
+var _schema = &ontology.Schema{
+	Type: ResourceType,
+	Fields: map[string]schema.Field{
+		"id":      {Type: schema.Uint32},
+		"address": {Type: schema.String},
+		"state":   {Type: schema.String},
+	},
+}
+
+// Service exposes the nodes in the cluster as ontology resources.
+type Service struct {
+	cluster aspen.Cluster
+}
+
+var _ ontology.Service = (*Service)(nil)
+
+func NewService(cluster aspen.Cluster) *Service { return &Service{cluster: cluster} }
+
+// Schema implements the ontology.Service interface.
+func (s *Service) Schema() *schema.Schema { return _schema }
+
+// RetrieveEntity implements the ontology.Service interface.
+func (s *Service) RetrieveEntity(key string) (schema.Entity, error) {
+	id, err := strconv.Atoi(key)
+	if err != nil {
+		return schema.Entity{}, err
+	}
+	n, err := s.cluster.Node(ID(id))
+	if err != nil {
+		return schema.Entity{}, err
+	}
+	return newEntity(n), nil
+}
+
+func newEntity(n Node) schema.Entity {
+	e := schema.NewEntity(_schema)
+	schema.Set(e, "id", uint32(n.ID))
+	schema.Set(e, "address", n.Address.String())
+	schema.Set(e, "state", n.State.String())
+	return e
+}
@@ -1,3 +1,5 @@
 package node
 
-const ResourceType = "node"
+import "github.com/arya-analytics/delta/pkg/ontology"
+
+const ResourceType ontology.Type = "node"
@@ -0,0 +1,40 @@
+package resource_test
+
+import (
+	"github.com/arya-analytics/delta/pkg/ontology"
+	"github.com/arya-analytics/delta/pkg/ontology/schema"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type emptyService struct{}
+
+const emptyType ontology.Type = "empty"
+
+func newEmptyID(key string) ontology.ID {
+	return ontology.ID{Key: key, Type: emptyType}
+}
+
+func (s *emptyService) Schema() *ontology.Schema {
+	return &ontology.Schema{
+		Type: emptyType,
+		Fields: map[string]schema.Field{
+			"key": {Type: schema.String},
+		},
+	}
+}
+
+func (s *emptyService) RetrieveEntity(key string) (ontology.Entity, error) {
+	e := schema.NewEntity(s.Schema())
+	if err := schema.Set(e, "key", key); err != nil {
+		return ontology.Entity{}, err
+	}
+	return e, nil
+}
+
+func TestResource(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Resource Suite")
+}
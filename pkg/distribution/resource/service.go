@@ -0,0 +1,258 @@
+package resource
+
+import (
+	"context"
+	"github.com/arya-analytics/aspen"
+	"github.com/arya-analytics/delta/pkg/distribution/node"
+	"github.com/arya-analytics/delta/pkg/ontology"
+	"github.com/arya-analytics/x/gorp"
+	"github.com/cockroachdb/errors"
+	"sync"
+	"time"
+)
+
+// RelayTimeout is the maximum amount of time to wait for another node to accept a
+// message.
+const RelayTimeout = 5 * time.Second
+
+// NotLeaseholder is returned when a node that doesn't hold the lease on the ontology
+// is asked to apply changes.
+var NotLeaseholder = errors.New("[resource] - node is not the leaseholder")
+
+// Leaseholder returns the node that holds the lease on the ontology as seen by host:
+// the healthy node in the group with the lowest ID. The host always considers itself
+// healthy. When the leaseholder fails, the lease passes to the next node once the
+// cluster's failure detector marks it as unhealthy.
+func Leaseholder(host node.ID, nodes node.Group) node.ID {
+	lh := host
+	for id, n := range nodes {
+		if id < lh && n.State == aspen.Healthy {
+			lh = id
+		}
+	}
+	return lh
+}
+
+// Service is an ontology shared by every node in the cluster. The ontology is stored in
+// aspen, so every node sees every resource and relationship once it has been gossiped.
+//
+// Graph changes (defining and deleting resources and relationships) are relayed to the
+// Leaseholder, which applies them one batch at a time in their own transaction. This
+// serializes the constraint checks for relationship types, so concurrent writes on
+// different nodes can't jointly create a cycle or violate a type's cardinality. Graph
+// changes made on a Txn are relayed as a single batch when it commits, and the Txn is
+// only committed if the Leaseholder applies them. Constraint violations are returned
+// by the commit instead of the Writer. Nodes reject changes relayed to them while they
+// don't hold the lease.
+//
+// Once the Leaseholder applies a batch, it sends the changes to every other healthy
+// node, which publishes them to its Watch subscribers and updates its search index and
+// entity cache as soon as the changes have been gossiped to it. A node that can't be
+// reached while a batch is sent misses its changes.
+//
+// The lease is decided by each node from its own view of the cluster's health, so
+// while nodes disagree on whether the Leaseholder has failed (or during a partition),
+// two nodes may apply changes at once.
+type Service struct {
+	*ontology.Ontology
+	cluster   aspen.Cluster
+	db        *gorp.DB
+	transport Transport
+	mu        sync.Mutex
+	applied   chan []ontology.Change
+}
+
+// Open opens the cluster-wide ontology stored in db, which must be backed by aspen.
+// The Leaseholder must be reachable for Open to succeed. Every node in the cluster is
+// defined as a child of Root, and the Leaseholder defines nodes as they join.
+func Open(cluster aspen.Cluster, db *gorp.DB, transport Transport) (*Service, error) {
+	s := &Service{
+		cluster:   cluster,
+		db:        db,
+		transport: transport,
+		applied:   make(chan []ontology.Change, 1024),
+	}
+	var err error
+	s.Ontology, err = ontology.Open(db, ontology.WithRelay(s.relay))
+	if err != nil {
+		return nil, err
+	}
+	s.RegisterService(node.NewService(cluster))
+	s.transport.Handle(s.handle)
+	go s.observeApplied()
+	// Errors are dropped here, as the nodes will be defined again on the next change
+	// to the cluster. The change may also be a failure of the Leaseholder, after which
+	// the host may have taken over the lease.
+	cluster.OnChange(func(_ context.Context, state aspen.ClusterState) {
+		if s.leaseholder() == s.cluster.HostID() {
+			_ = s.defineNodes(nodeIDs(state.Nodes)...)
+		}
+	})
+	if s.leaseholder() == cluster.HostID() {
+		if err := s.apply([]ontology.Change{{
+			Variant: ontology.ResourceDefined,
+			ID:      ontology.Root,
+		}}); err != nil {
+			return nil, err
+		}
+		return s, s.defineNodes(nodeIDs(cluster.Nodes())...)
+	}
+	// The leaseholder may not have learned that the host joined the cluster yet, so
+	// the host defines itself.
+	return s, s.defineNodes(cluster.HostID())
+}
+
+// defineNodes defines a resource for each node as a child of Root.
+func (s *Service) defineNodes(ids ...node.ID) error {
+	changes := make([]ontology.Change, 0, 2*len(ids))
+	for _, id := range ids {
+		rk := node.ResourceKey(id)
+		changes = append(changes,
+			ontology.Change{Variant: ontology.ResourceDefined, ID: rk},
+			ontology.Change{
+				Variant: ontology.RelationshipDefined,
+				Relationship: ontology.Relationship{
+					From: rk,
+					To:   ontology.Root,
+					Type: ontology.Parent,
+				},
+			},
+		)
+	}
+	return s.relay(changes)
+}
+
+func (s *Service) leaseholder() node.ID {
+	return Leaseholder(s.cluster.HostID(), s.cluster.Nodes())
+}
+
+func (s *Service) handle(_ context.Context, msg Message) (Message, error) {
+	switch msg.Variant {
+	case Relay:
+		host := s.cluster.HostID()
+		if lh := s.leaseholder(); lh != host {
+			return Message{}, errors.Wrapf(
+				NotLeaseholder,
+				"[resource] - node %v received changes, but node %v holds the lease",
+				host,
+				lh,
+			)
+		}
+		return Message{}, s.apply(msg.Changes)
+	case Applied:
+		s.applied <- msg.Changes
+		return Message{}, nil
+	default:
+		return Message{}, errors.Newf("[resource] - unknown message variant %d", msg.Variant)
+	}
+}
+
+// relay applies the changes locally if the host is the Leaseholder, and otherwise
+// sends them to the Leaseholder.
+func (s *Service) relay(changes []ontology.Change) error {
+	lh := s.leaseholder()
+	if lh == s.cluster.HostID() {
+		return s.apply(changes)
+	}
+	return s.send(lh, Message{Variant: Relay, Changes: changes})
+}
+
+func (s *Service) send(target node.ID, msg Message) error {
+	addr, err := s.cluster.Resolve(target)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), RelayTimeout)
+	defer cancel()
+	_, err = s.transport.Send(ctx, addr, msg)
+	return err
+}
+
+// apply applies and commits the changes, and then sends the changes that were made to
+// the other nodes. Changes are applied one batch at a time, so each batch is validated
+// against the outcome of the last, and every node receives batches in order.
+func (s *Service) apply(changes []ontology.Change) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	txn := s.BeginTxn()
+	if err := s.Apply(txn, changes...); err != nil {
+		return errors.CombineErrors(err, txn.Close())
+	}
+	applied := txn.Changes()
+	if err := txn.Commit(); err != nil {
+		return err
+	}
+	s.broadcast(applied)
+	return nil
+}
+
+// broadcast sends changes applied by the host to every other healthy node. Errors are
+// dropped, as the changes have already been committed.
+func (s *Service) broadcast(changes []ontology.Change) {
+	if len(changes) == 0 {
+		return
+	}
+	host := s.cluster.HostID()
+	var wg sync.WaitGroup
+	for id, n := range s.cluster.Nodes() {
+		if id == host || n.State != aspen.Healthy {
+			continue
+		}
+		wg.Add(1)
+		go func(id node.ID) {
+			defer wg.Done()
+			_ = s.send(id, Message{Variant: Applied, Changes: changes})
+		}(id)
+	}
+	wg.Wait()
+}
+
+// observeApplied observes the batches of changes applied by the Leaseholder in the
+// order they were applied.
+func (s *Service) observeApplied() {
+	for changes := range s.applied {
+		s.awaitGossip(changes)
+		s.Observe(changes...)
+	}
+}
+
+// awaitGossipInterval is how often awaitGossip checks whether changes are visible.
+const awaitGossipInterval = 10 * time.Millisecond
+
+// awaitGossip waits until the changes applied by the Leaseholder are visible in the
+// host's copy of the database, or until RelayTimeout elapses.
+func (s *Service) awaitGossip(changes []ontology.Change) {
+	deadline := time.Now().Add(RelayTimeout)
+	for _, c := range changes {
+		for time.Now().Before(deadline) {
+			if ok, err := s.visible(c); ok || err != nil {
+				break
+			}
+			time.Sleep(awaitGossipInterval)
+		}
+	}
+}
+
+// visible returns true if the outcome of the change is visible in the database.
+func (s *Service) visible(c ontology.Change) (bool, error) {
+	switch c.Variant {
+	case ontology.ResourceDefined, ontology.ResourceDeleted:
+		exists, err := gorp.NewRetrieve[ontology.ID, ontology.Resource]().
+			WhereKeys(c.ID).
+			Exists(s.db)
+		return exists == (c.Variant == ontology.ResourceDefined), err
+	default:
+		exists, err := gorp.NewRetrieve[string, ontology.Relationship]().
+			WhereKeys(c.Relationship.GorpKey()).
+			Exists(s.db)
+		return exists == (c.Variant == ontology.RelationshipDefined), err
+	}
+}
+
+func nodeIDs(g node.Group) []node.ID {
+	ids := make([]node.ID, 0, len(g))
+	for id := range g {
+		ids = append(ids, id)
+	}
+	return ids
+}
@@ -0,0 +1,187 @@
+package resource_test
+
+import (
+	"context"
+	"github.com/arya-analytics/aspen"
+	"github.com/arya-analytics/delta/pkg/distribution/mock"
+	"github.com/arya-analytics/delta/pkg/distribution/node"
+	"github.com/arya-analytics/delta/pkg/distribution/resource"
+	"github.com/arya-analytics/delta/pkg/distribution/transport/mem"
+	"github.com/arya-analytics/delta/pkg/ontology"
+	"github.com/arya-analytics/delta/pkg/ontology/schema"
+	"github.com/arya-analytics/x/query"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+var _ = Describe("Service", func() {
+	var (
+		cluster *mock.ClusterBuilder
+		nodes   []mock.Node
+	)
+	BeforeEach(func() {
+		cluster = mock.NewCluster()
+		var err error
+		nodes, err = cluster.NewN(zap.NewNop(), 2)
+		Expect(err).ToNot(HaveOccurred())
+		for _, n := range nodes {
+			n.Resources.RegisterService(&emptyService{})
+		}
+	})
+	AfterEach(func() { Expect(cluster.Close()).To(Succeed()) })
+	It("Should make resources defined on one node visible on every node", func() {
+		a, b := newEmptyID("A"), newEmptyID("B")
+		txn := nodes[1].Resources.BeginTxn()
+		w := nodes[1].Resources.NewWriter(txn)
+		Expect(w.DefineResource(a)).To(Succeed())
+		Expect(w.DefineResource(b)).To(Succeed())
+		Expect(w.DefineRelationship(a, b, ontology.Parent)).To(Succeed())
+		Expect(txn.Commit()).To(Succeed())
+		for _, n := range nodes {
+			Eventually(func() ([]ontology.Resource, error) {
+				var res []ontology.Resource
+				err := n.Resources.NewRetrieve().
+					WhereIDs(a).
+					TraverseTo(ontology.Parents).
+					Entries(&res).
+					Exec()
+				return res, err
+			}, time.Second).Should(HaveLen(1))
+		}
+	})
+	It("Should prevent concurrent writes on different nodes from creating a cycle", func() {
+		a, b := newEmptyID("A"), newEmptyID("B")
+		txn := nodes[0].Resources.BeginTxn()
+		w := nodes[0].Resources.NewWriter(txn)
+		Expect(w.DefineResource(a)).To(Succeed())
+		Expect(w.DefineResource(b)).To(Succeed())
+		Expect(txn.Commit()).To(Succeed())
+		var (
+			wg   sync.WaitGroup
+			errs = make([]error, 2)
+		)
+		wg.Add(2)
+		for i, rel := range []ontology.Relationship{
+			{From: a, To: b, Type: ontology.Parent},
+			{From: b, To: a, Type: ontology.Parent},
+		} {
+			go func(i int, rel ontology.Relationship) {
+				defer GinkgoRecover()
+				defer wg.Done()
+				r := nodes[i].Resources
+				txn := r.BeginTxn()
+				errs[i] = r.NewWriter(txn).DefineRelationship(rel.From, rel.To, rel.Type)
+				if errs[i] == nil {
+					errs[i] = txn.Commit()
+				}
+			}(i, rel)
+		}
+		wg.Wait()
+		failed := 0
+		for _, err := range errs {
+			if err != nil {
+				failed++
+			}
+		}
+		Expect(failed).To(Equal(1))
+	})
+	It("Should not commit graph changes made on a transaction until it commits", func() {
+		a := newEmptyID("A")
+		txn := nodes[1].Resources.BeginTxn()
+		Expect(nodes[1].Resources.NewWriter(txn).DefineResource(a)).To(Succeed())
+		Consistently(func() error {
+			var r ontology.Resource
+			return nodes[0].Resources.NewRetrieve().WhereIDs(a).Entry(&r).Exec()
+		}, 100*time.Millisecond).Should(HaveOccurred())
+		Expect(txn.Commit()).To(Succeed())
+		Eventually(func() error {
+			var r ontology.Resource
+			return nodes[1].Resources.NewRetrieve().WhereIDs(a).Entry(&r).Exec()
+		}, time.Second).Should(Succeed())
+	})
+	It("Should fail to commit graph changes while the leaseholder is unreachable", func() {
+		lh := resource.Leaseholder(nodes[1].ID(), nodes[1].Aspen.Nodes())
+		Expect(lh).To(Equal(nodes[0].ID()))
+		a := newEmptyID("A")
+		cluster.Kill(lh)
+		txn := nodes[1].Resources.BeginTxn()
+		Expect(nodes[1].Resources.NewWriter(txn).DefineResource(a)).To(Succeed())
+		Expect(txn.Commit()).To(MatchError(mem.Unreachable))
+		Expect(txn.Close()).To(Succeed())
+		cluster.Revive(lh)
+		var r ontology.Resource
+		Expect(nodes[0].Resources.NewRetrieve().WhereIDs(a).Entry(&r).Exec()).
+			To(MatchError(query.NotFound))
+		txn = nodes[1].Resources.BeginTxn()
+		Expect(nodes[1].Resources.NewWriter(txn).DefineResource(a)).To(Succeed())
+		Expect(txn.Commit()).To(Succeed())
+	})
+	It("Should define every node in the cluster as a child of the root", func() {
+		Eventually(func() ([]ontology.Resource, error) {
+			var res []ontology.Resource
+			err := nodes[1].Resources.NewRetrieve().
+				WhereIDs(ontology.Root).
+				TraverseTo(ontology.Children).
+				Entries(&res).
+				Exec()
+			return res, err
+		}, time.Second).Should(HaveLen(2))
+		var r ontology.Resource
+		Expect(nodes[1].Resources.NewRetrieve().
+			WhereIDs(node.ResourceKey(nodes[1].ID())).
+			Entry(&r).
+			Exec(),
+		).To(Succeed())
+		id, ok := schema.Get[uint32](r.Entity(), "id")
+		Expect(ok).To(BeTrue())
+		Expect(node.ID(id)).To(Equal(nodes[1].ID()))
+	})
+	It("Should publish graph changes to Watch subscribers on every node", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		subs := make([]ontology.Subscription, len(nodes))
+		for i, n := range nodes {
+			var err error
+			subs[i], err = n.Resources.Watch(ctx, ontology.WatchFilter{Types: []ontology.Type{emptyType}})
+			Expect(err).ToNot(HaveOccurred())
+		}
+		a := newEmptyID("A")
+		txn := nodes[1].Resources.BeginTxn()
+		Expect(nodes[1].Resources.NewWriter(txn).DefineResource(a)).To(Succeed())
+		Expect(txn.Commit()).To(Succeed())
+		for _, sub := range subs {
+			var c ontology.Change
+			Eventually(sub.Changes, time.Second).Should(Receive(&c))
+			Expect(c.Variant).To(Equal(ontology.ResourceDefined))
+			Expect(c.ID).To(Equal(a))
+		}
+	})
+	It("Should reject changes relayed to a node that doesn't hold the lease", func() {
+		t := mem.ResourceTransport(cluster.Network, "localhost:99")
+		_, err := t.Send(context.Background(), nodes[1].Address, resource.Message{
+			Variant: resource.Relay,
+			Changes: []ontology.Change{{Variant: ontology.ResourceDefined, ID: newEmptyID("A")}},
+		})
+		Expect(err).To(MatchError(resource.NotLeaseholder))
+		var r ontology.Resource
+		Expect(nodes[1].Resources.NewRetrieve().WhereIDs(newEmptyID("A")).Entry(&r).Exec()).
+			To(MatchError(query.NotFound))
+	})
+	Describe("Leaseholder", func() {
+		It("Should be the healthy node with the lowest ID", func() {
+			nodes := node.Group{
+				1: {ID: 1, State: aspen.Dead},
+				2: {ID: 2, State: aspen.Suspect},
+				3: {ID: 3, State: aspen.Healthy},
+				4: {ID: 4, State: aspen.Healthy},
+			}
+			Expect(resource.Leaseholder(4, nodes)).To(Equal(node.ID(3)))
+			Expect(resource.Leaseholder(2, nodes)).To(Equal(node.ID(2)))
+			nodes[1] = node.Node{ID: 1, State: aspen.Healthy}
+			Expect(resource.Leaseholder(4, nodes)).To(Equal(node.ID(1)))
+		})
+	})
+})
@@ -0,0 +1,25 @@
+package resource
+
+import (
+	"github.com/arya-analytics/delta/pkg/ontology"
+	"github.com/arya-analytics/x/transport"
+)
+
+// Transport relays ontology changes to the leaseholder, and sends the changes the
+// leaseholder applies to the other nodes.
+type Transport = transport.Unary[Message, Message]
+
+// MessageVariant is the kind of Message sent between nodes.
+type MessageVariant uint8
+
+const (
+	// Relay carries changes to be applied by the leaseholder.
+	Relay MessageVariant = iota + 1
+	// Applied carries changes the leaseholder has applied.
+	Applied
+)
+
+type Message struct {
+	Variant MessageVariant
+	Changes []ontology.Change
+}
@@ -0,0 +1,67 @@
+package compress_test
+
+import (
+	"github.com/arya-analytics/delta/pkg/distribution/segment/compress"
+	"testing"
+)
+
+const benchSamples = 100_000
+
+func benchmarkCompress(b *testing.B, alg compress.Algorithm, data []byte) {
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	var out []byte
+	for i := 0; i < b.N; i++ {
+		var err error
+		if out, err = compress.Compress(alg, 8, data); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(len(data))/float64(len(out)), "ratio")
+}
+
+func benchmarkDecompress(b *testing.B, alg compress.Algorithm, data []byte) {
+	c, err := compress.Compress(alg, 8, data)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := compress.Decompress(alg, c); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCompressFloat64Zstd(b *testing.B) {
+	benchmarkCompress(b, compress.Zstd, float64Series(benchSamples))
+}
+
+func BenchmarkCompressFloat64DeltaOfDelta(b *testing.B) {
+	benchmarkCompress(b, compress.DeltaOfDelta, float64Series(benchSamples))
+}
+
+func BenchmarkCompressInt64Zstd(b *testing.B) {
+	benchmarkCompress(b, compress.Zstd, int64Series(benchSamples))
+}
+
+func BenchmarkCompressInt64DeltaOfDelta(b *testing.B) {
+	benchmarkCompress(b, compress.DeltaOfDelta, int64Series(benchSamples))
+}
+
+func BenchmarkDecompressFloat64Zstd(b *testing.B) {
+	benchmarkDecompress(b, compress.Zstd, float64Series(benchSamples))
+}
+
+func BenchmarkDecompressFloat64DeltaOfDelta(b *testing.B) {
+	benchmarkDecompress(b, compress.DeltaOfDelta, float64Series(benchSamples))
+}
+
+func BenchmarkDecompressInt64Zstd(b *testing.B) {
+	benchmarkDecompress(b, compress.Zstd, int64Series(benchSamples))
+}
+
+func BenchmarkDecompressInt64DeltaOfDelta(b *testing.B) {
+	benchmarkDecompress(b, compress.DeltaOfDelta, int64Series(benchSamples))
+}
@@ -0,0 +1,95 @@
+// Package compress implements lossless compression codecs for segment data sent
+// over the network. Codecs operate on raw, fixed-width samples and are selected on a
+// per-stream basis when an iterator or writer stream is opened.
+package compress
+
+import (
+	"github.com/cockroachdb/errors"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Algorithm is a compression algorithm that can be negotiated for a segment stream.
+type Algorithm uint8
+
+const (
+	// None sends segment data uncompressed.
+	None Algorithm = iota
+	// Zstd compresses segment data using the zstd algorithm. Zstd is a good general
+	// purpose choice, and works with samples of any data type.
+	Zstd
+	// DeltaOfDelta encodes the delta-of-delta between the bit representations of
+	// consecutive samples as zig-zag varints. It works best for slowly changing or
+	// regularly incrementing 4 and 8 byte integer and float series.
+	DeltaOfDelta
+)
+
+// String implements fmt.Stringer.
+func (a Algorithm) String() string {
+	switch a {
+	case None:
+		return "none"
+	case Zstd:
+		return "zstd"
+	case DeltaOfDelta:
+		return "delta-of-delta"
+	default:
+		return "unknown"
+	}
+}
+
+// Supported returns true if the Algorithm is implemented by this package.
+func Supported(a Algorithm) bool { return a <= DeltaOfDelta }
+
+// Unsupported is returned when an Algorithm isn't supported.
+var Unsupported = errors.New("[compress] - unsupported algorithm")
+
+// TooLarge is returned when decompressed data would exceed MaxSegmentSize.
+var TooLarge = errors.New("[compress] - decompressed data exceeds max segment size")
+
+// MaxSegmentSize is the largest amount of segment data, in bytes, that Decompress
+// will produce. It bounds the memory a peer can make a node allocate with a small,
+// highly compressible payload, and sits well above the size of the segments written
+// by cesium.
+const MaxSegmentSize = 64 << 20
+
+// Compress compresses data containing samples that are density bytes wide using the
+// given Algorithm.
+func Compress(a Algorithm, density int, data []byte) ([]byte, error) {
+	switch a {
+	case None:
+		return data, nil
+	case Zstd:
+		return zstdEncoder.EncodeAll(data, make([]byte, 0, len(data)/2)), nil
+	case DeltaOfDelta:
+		return encodeDeltaOfDelta(density, data), nil
+	default:
+		return nil, errors.Wrapf(Unsupported, "[compress] - algorithm %v", a)
+	}
+}
+
+// Decompress decompresses data that was compressed using the given Algorithm.
+func Decompress(a Algorithm, data []byte) ([]byte, error) {
+	switch a {
+	case None:
+		return data, nil
+	case Zstd:
+		out, err := zstdDecoder.DecodeAll(data, nil)
+		if errors.Is(err, zstd.ErrDecoderSizeExceeded) {
+			return nil, TooLarge
+		}
+		return out, err
+	case DeltaOfDelta:
+		return decodeDeltaOfDelta(data)
+	default:
+		return nil, errors.Wrapf(Unsupported, "[compress] - algorithm %v", a)
+	}
+}
+
+var (
+	zstdEncoder, _ = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedFastest))
+	zstdDecoder, _ = zstd.NewReader(
+		nil,
+		zstd.WithDecoderMaxMemory(MaxSegmentSize),
+		zstd.WithDecoderMaxWindow(MaxSegmentSize),
+	)
+)
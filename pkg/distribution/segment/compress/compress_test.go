@@ -0,0 +1,104 @@
+package compress_test
+
+import (
+	"encoding/binary"
+	"github.com/arya-analytics/delta/pkg/distribution/segment/compress"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"math"
+	"math/rand"
+)
+
+func float64Series(n int) []byte {
+	b := make([]byte, n*8)
+	for i := 0; i < n; i++ {
+		v := math.Sin(float64(i)/100) * 100
+		binary.LittleEndian.PutUint64(b[i*8:], math.Float64bits(v))
+	}
+	return b
+}
+
+func int64Series(n int) []byte {
+	b := make([]byte, n*8)
+	for i := 0; i < n; i++ {
+		binary.LittleEndian.PutUint64(b[i*8:], uint64(1_000_000+i*10))
+	}
+	return b
+}
+
+func randomSeries(n, density int) []byte {
+	b := make([]byte, n*density)
+	rand.New(rand.NewSource(0)).Read(b)
+	return b
+}
+
+var _ = Describe("Compress", func() {
+	algorithms := []compress.Algorithm{compress.None, compress.Zstd, compress.DeltaOfDelta}
+	for _, alg := range algorithms {
+		alg := alg
+		Describe(alg.String(), func() {
+			DescribeTable("Round Trip", func(density int, data []byte) {
+				c, err := compress.Compress(alg, density, data)
+				Expect(err).ToNot(HaveOccurred())
+				d, err := compress.Decompress(alg, c)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(d).To(Equal(data))
+			},
+				Entry("float64", 8, float64Series(1000)),
+				Entry("int64", 8, int64Series(1000)),
+				Entry("random 2 byte", 2, randomSeries(1000, 2)),
+				Entry("random 4 byte", 4, randomSeries(1000, 4)),
+				Entry("random 8 byte", 8, randomSeries(1000, 8)),
+				Entry("partial trailing sample", 8, append(int64Series(10), 1, 2, 3)),
+				Entry("unusual density", 3, randomSeries(100, 3)),
+				Entry("empty", 8, []byte{}),
+			)
+		})
+	}
+	Describe("DeltaOfDelta", func() {
+		It("Should compress a regularly incrementing integer series", func() {
+			data := int64Series(1000)
+			c, err := compress.Compress(compress.DeltaOfDelta, 8, data)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(c)).To(BeNumerically("<", len(data)/4))
+		})
+		It("Should return an error for corrupted data", func() {
+			_, err := compress.Decompress(compress.DeltaOfDelta, []byte{7})
+			Expect(err).To(MatchError(compress.Corrupted))
+		})
+		It("Should return an error when the sample count exceeds the data", func() {
+			header := func(n uint64) []byte {
+				b := make([]byte, 1+binary.MaxVarintLen64)
+				b[0] = 8
+				return b[:1+binary.PutUvarint(b[1:], n)]
+			}
+			_, err := compress.Decompress(compress.DeltaOfDelta, header(math.MaxUint64))
+			Expect(err).To(MatchError(compress.Corrupted))
+			_, err = compress.Decompress(compress.DeltaOfDelta, append(header(10), 1, 2, 3))
+			Expect(err).To(MatchError(compress.Corrupted))
+		})
+	})
+	Describe("Max Segment Size", func() {
+		It("Should return an error when zstd data decompresses beyond the limit", func() {
+			c, err := compress.Compress(compress.Zstd, 8, make([]byte, compress.MaxSegmentSize+1))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(c)).To(BeNumerically("<", 1<<20))
+			_, err = compress.Decompress(compress.Zstd, c)
+			Expect(err).To(MatchError(compress.TooLarge))
+		})
+		It("Should return an error when delta-of-delta data decodes beyond the limit", func() {
+			n := compress.MaxSegmentSize/8 + 1
+			c, err := compress.Compress(compress.DeltaOfDelta, 8, make([]byte, n*8))
+			Expect(err).ToNot(HaveOccurred())
+			_, err = compress.Decompress(compress.DeltaOfDelta, c)
+			Expect(err).To(MatchError(compress.TooLarge))
+		})
+	})
+	Describe("Unsupported", func() {
+		It("Should return an error for an unsupported algorithm", func() {
+			_, err := compress.Compress(compress.Algorithm(42), 8, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(compress.Supported(compress.Algorithm(42))).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,121 @@
+package compress
+
+import (
+	"encoding/binary"
+	"github.com/cockroachdb/errors"
+)
+
+// Corrupted is returned when compressed data can't be decoded.
+var Corrupted = errors.New("[compress] - corrupted data")
+
+// encodeDeltaOfDelta encodes data as follows:
+//
+//	[density (1 byte)][sample count (uvarint)][delta-of-deltas (varint)...][remainder]
+//
+// Samples are read as little endian unsigned integers of the given density, and all
+// arithmetic wraps so that the encoding is lossless for any bit pattern. Densities
+// other than 1, 2, 4, and 8 bytes are stored as is.
+func encodeDeltaOfDelta(density int, data []byte) []byte {
+	if !validDensity(density) {
+		density = 1
+	}
+	var (
+		n   = len(data) / density
+		out = make([]byte, 0, 1+binary.MaxVarintLen64+n*2)
+		buf [binary.MaxVarintLen64]byte
+	)
+	out = append(out, byte(density))
+	out = append(out, buf[:binary.PutUvarint(buf[:], uint64(n))]...)
+	var prev, prevDelta uint64
+	for i := 0; i < n; i++ {
+		v := readSample(density, data[i*density:])
+		delta := v - prev
+		dod := delta - prevDelta
+		out = append(out, buf[:binary.PutVarint(buf[:], signExtend(density, dod))]...)
+		prev, prevDelta = v, delta
+	}
+	return append(out, data[n*density:]...)
+}
+
+func decodeDeltaOfDelta(data []byte) ([]byte, error) {
+	if len(data) < 1 {
+		return nil, Corrupted
+	}
+	density := int(data[0])
+	if !validDensity(density) {
+		return nil, Corrupted
+	}
+	n, read := binary.Uvarint(data[1:])
+	// Every sample occupies at least one byte, so a count larger than the remaining
+	// data can only come from corrupted input.
+	if read <= 0 || n > uint64(len(data)-1-read) {
+		return nil, Corrupted
+	}
+	if n*uint64(density) > MaxSegmentSize {
+		return nil, TooLarge
+	}
+	var (
+		pos            = 1 + read
+		out            = make([]byte, 0, int(n)*density)
+		buf            [8]byte
+		prev, prevDiff uint64
+	)
+	for i := uint64(0); i < n; i++ {
+		dod, read := binary.Varint(data[pos:])
+		if read <= 0 {
+			return nil, Corrupted
+		}
+		pos += read
+		delta := prevDiff + uint64(dod)
+		v := prev + delta
+		writeSample(density, buf[:], v)
+		out = append(out, buf[:density]...)
+		prev, prevDiff = truncate(density, v), truncate(density, delta)
+	}
+	return append(out, data[pos:]...), nil
+}
+
+func validDensity(density int) bool {
+	return density == 1 || density == 2 || density == 4 || density == 8
+}
+
+func readSample(density int, b []byte) uint64 {
+	switch density {
+	case 1:
+		return uint64(b[0])
+	case 2:
+		return uint64(binary.LittleEndian.Uint16(b))
+	case 4:
+		return uint64(binary.LittleEndian.Uint32(b))
+	default:
+		return binary.LittleEndian.Uint64(b)
+	}
+}
+
+func writeSample(density int, b []byte, v uint64) {
+	switch density {
+	case 1:
+		b[0] = byte(v)
+	case 2:
+		binary.LittleEndian.PutUint16(b, uint16(v))
+	case 4:
+		binary.LittleEndian.PutUint32(b, uint32(v))
+	default:
+		binary.LittleEndian.PutUint64(b, v)
+	}
+}
+
+// truncate discards the bits of v that don't fit in a sample of the given density.
+func truncate(density int, v uint64) uint64 {
+	if density == 8 {
+		return v
+	}
+	return v & (1<<(uint(density)*8) - 1)
+}
+
+// signExtend interprets the low density bytes of v as a two's complement integer, so
+// that small negative delta-of-deltas encode as small varints.
+func signExtend(density int, v uint64) int64 {
+	shift := 64 - uint(density)*8
+	return int64(v<<shift) >> shift
+}
@@ -0,0 +1,51 @@
+package core
+
+import (
+	"github.com/arya-analytics/cesium"
+	"github.com/arya-analytics/delta/pkg/distribution/channel"
+	"github.com/arya-analytics/delta/pkg/distribution/segment/compress"
+)
+
+// Densities returns a map of channel keys to the density (bytes per sample) of each
+// channel. Used to compress segment data.
+func Densities(channels []cesium.Channel, keyMap map[cesium.ChannelKey]channel.Key) map[channel.Key]int {
+	densities := make(map[channel.Key]int, len(channels))
+	for _, ch := range channels {
+		densities[keyMap[ch.Key]] = int(ch.DataType)
+	}
+	return densities
+}
+
+// CompressSegments returns a copy of segments with their data compressed using the
+// given algorithm. densities must contain the density of every channel in segments.
+func CompressSegments(
+	alg compress.Algorithm,
+	densities map[channel.Key]int,
+	segments []Segment,
+) ([]Segment, error) {
+	out := make([]Segment, len(segments))
+	for i, seg := range segments {
+		data, err := compress.Compress(alg, densities[seg.ChannelKey], seg.Segment.Data)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = seg
+		out[i].Segment.Data = data
+	}
+	return out, nil
+}
+
+// DecompressSegments returns a copy of segments with their data decompressed using
+// the given algorithm.
+func DecompressSegments(alg compress.Algorithm, segments []Segment) ([]Segment, error) {
+	out := make([]Segment, len(segments))
+	for i, seg := range segments {
+		data, err := compress.Decompress(alg, seg.Segment.Data)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = seg
+		out[i].Segment.Data = data
+	}
+	return out, nil
+}
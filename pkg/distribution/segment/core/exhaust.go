@@ -0,0 +1,48 @@
+package core
+
+import (
+	"github.com/arya-analytics/cesium"
+	"github.com/arya-analytics/delta/pkg/distribution/channel"
+	"github.com/arya-analytics/x/confluence"
+	"github.com/arya-analytics/x/telem"
+	"github.com/cockroachdb/errors"
+)
+
+// Exhaust iterates through every segment for the given channels in the local
+// cesium.DB over the provided time range, calling f with each batch of segments read.
+// All keys must be leased to the node the cesium.DB belongs to. If f returns an error,
+// Exhaust stops calling f, closes the iterator, and returns the error.
+func Exhaust(
+	db cesium.DB,
+	rng telem.TimeRange,
+	keys channel.Keys,
+	f func(segments []Segment) error,
+) error {
+	iter := db.NewRetrieve().WhereTimeRange(rng).WhereChannels(keys.Cesium()...).Iterate()
+	if iter.Error() != nil {
+		return errors.Wrap(iter.Error(), "[segment] - failed to open cesium iterator")
+	}
+
+	responses := confluence.NewStream[cesium.RetrieveResponse](1)
+	iter.OutTo(responses)
+
+	closeErr := make(chan error, 1)
+	go func() {
+		for ok := iter.First(); ok; ok = iter.Next() {
+		}
+		closeErr <- iter.Close()
+		responses.Close()
+	}()
+
+	var (
+		wrapper = &CesiumWrapper{KeyMap: keys.CesiumMap()}
+		err     error
+	)
+	for res := range responses.Outlet() {
+		// Keep draining the iterator after an error so that it can close cleanly.
+		if err == nil {
+			err = f(wrapper.Wrap(res.Segments))
+		}
+	}
+	return errors.CombineErrors(err, <-closeErr)
+}
@@ -0,0 +1,120 @@
+package core
+
+import (
+	"encoding/binary"
+	"github.com/arya-analytics/delta/pkg/distribution/channel"
+	"github.com/arya-analytics/x/telem"
+	"github.com/cockroachdb/errors"
+	"math"
+)
+
+type sampleCodec struct {
+	density int
+	decode  func(b []byte) float64
+	encode  func(b []byte, v float64)
+}
+
+var sampleCodecs = map[channel.SampleType]sampleCodec{
+	channel.Float64: {
+		density: 8,
+		decode: func(b []byte) float64 {
+			return math.Float64frombits(binary.LittleEndian.Uint64(b))
+		},
+		encode: func(b []byte, v float64) {
+			binary.LittleEndian.PutUint64(b, math.Float64bits(v))
+		},
+	},
+	channel.Float32: {
+		density: 4,
+		decode: func(b []byte) float64 {
+			return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+		},
+		encode: func(b []byte, v float64) {
+			binary.LittleEndian.PutUint32(b, math.Float32bits(float32(v)))
+		},
+	},
+	channel.Int64:     int64Codec,
+	channel.TimeStamp: int64Codec,
+	channel.Int32: {
+		density: 4,
+		decode:  func(b []byte) float64 { return float64(int32(binary.LittleEndian.Uint32(b))) },
+		encode:  func(b []byte, v float64) { binary.LittleEndian.PutUint32(b, uint32(int32(math.Round(v)))) },
+	},
+	channel.Int16: {
+		density: 2,
+		decode:  func(b []byte) float64 { return float64(int16(binary.LittleEndian.Uint16(b))) },
+		encode:  func(b []byte, v float64) { binary.LittleEndian.PutUint16(b, uint16(int16(math.Round(v)))) },
+	},
+	channel.Int8: {
+		density: 1,
+		decode:  func(b []byte) float64 { return float64(int8(b[0])) },
+		encode:  func(b []byte, v float64) { b[0] = uint8(int8(math.Round(v))) },
+	},
+	channel.Uint64: {
+		density: 8,
+		decode:  func(b []byte) float64 { return float64(binary.LittleEndian.Uint64(b)) },
+		encode:  func(b []byte, v float64) { binary.LittleEndian.PutUint64(b, uint64(math.Round(v))) },
+	},
+	channel.Uint32: {
+		density: 4,
+		decode:  func(b []byte) float64 { return float64(binary.LittleEndian.Uint32(b)) },
+		encode:  func(b []byte, v float64) { binary.LittleEndian.PutUint32(b, uint32(math.Round(v))) },
+	},
+	channel.Uint16: {
+		density: 2,
+		decode:  func(b []byte) float64 { return float64(binary.LittleEndian.Uint16(b)) },
+		encode:  func(b []byte, v float64) { binary.LittleEndian.PutUint16(b, uint16(math.Round(v))) },
+	},
+	channel.Uint8: {
+		density: 1,
+		decode:  func(b []byte) float64 { return float64(b[0]) },
+		encode:  func(b []byte, v float64) { b[0] = uint8(math.Round(v)) },
+	},
+}
+
+// int64Codec decodes int64 samples and timestamps. Values beyond 2^53 lose precision
+// when decoded.
+var int64Codec = sampleCodec{
+	density: 8,
+	decode:  func(b []byte) float64 { return float64(int64(binary.LittleEndian.Uint64(b))) },
+	encode:  func(b []byte, v float64) { binary.LittleEndian.PutUint64(b, uint64(int64(math.Round(v)))) },
+}
+
+// Numeric returns true if samples of the given type can be decoded into and encoded
+// from numeric values.
+func Numeric(st channel.SampleType) bool { _, ok := sampleCodecs[st]; return ok }
+
+// DecodeSamples decodes the samples in data into float64 values according to the
+// given sample type. Returns an error if the sample type is not Numeric.
+func DecodeSamples(st channel.SampleType, data []byte) ([]float64, error) {
+	codec, ok := sampleCodecs[st]
+	if !ok {
+		return nil, errors.Newf("[segment] - sample type %v is not numeric", st)
+	}
+	values := make([]float64, len(data)/codec.density)
+	for i := range values {
+		values[i] = codec.decode(data[i*codec.density : (i+1)*codec.density])
+	}
+	return values, nil
+}
+
+// EncodeSamples encodes the given values into the binary format of the provided
+// sample type. Integer sample types round values to the nearest integer. Returns an
+// error if the sample type is not Numeric.
+func EncodeSamples(st channel.SampleType, values []float64) ([]byte, error) {
+	codec, ok := sampleCodecs[st]
+	if !ok {
+		return nil, errors.Newf("[segment] - sample type %v is not numeric", st)
+	}
+	data := make([]byte, len(values)*codec.density)
+	for i, v := range values {
+		codec.encode(data[i*codec.density:(i+1)*codec.density], v)
+	}
+	return data, nil
+}
+
+// SamplePeriod returns the time span between two consecutive samples of a channel
+// with the given data rate.
+func SamplePeriod(dr telem.DataRate) telem.TimeSpan {
+	return telem.TimeSpan(float64(telem.Second) / float64(dr))
+}
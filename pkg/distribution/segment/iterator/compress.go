@@ -0,0 +1,42 @@
+package iterator
+
+import (
+	"github.com/arya-analytics/delta/pkg/distribution/channel"
+	"github.com/arya-analytics/delta/pkg/distribution/segment/compress"
+	"github.com/arya-analytics/delta/pkg/distribution/segment/core"
+	"github.com/arya-analytics/x/transport"
+)
+
+// compressingSender compresses the segments of outgoing data responses using the
+// algorithm negotiated when the stream was opened.
+type compressingSender struct {
+	transport.StreamSender[Response]
+	alg       compress.Algorithm
+	densities map[channel.Key]int
+}
+
+// Send implements transport.StreamSender.
+func (s compressingSender) Send(res Response) error {
+	if res.Variant == DataResponse && s.alg != compress.None {
+		var err error
+		if res.Segments, err = core.CompressSegments(s.alg, s.densities, res.Segments); err != nil {
+			return err
+		}
+		res.Compression = s.alg
+	}
+	return s.StreamSender.Send(res)
+}
+
+// decompressingClient decompresses the segments of incoming data responses.
+type decompressingClient struct{ Client }
+
+// Receive implements transport.StreamReceiver.
+func (c decompressingClient) Receive() (Response, error) {
+	res, err := c.Client.Receive()
+	if err != nil || res.Compression == compress.None {
+		return res, err
+	}
+	res.Segments, err = core.DecompressSegments(res.Compression, res.Segments)
+	res.Compression = compress.None
+	return res, err
+}
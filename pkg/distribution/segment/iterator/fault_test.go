@@ -0,0 +1,67 @@
+package iterator_test
+
+import (
+	"github.com/arya-analytics/cesium"
+	"github.com/arya-analytics/delta/pkg/distribution/channel"
+	"github.com/arya-analytics/delta/pkg/distribution/mock"
+	"github.com/arya-analytics/x/telem"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+	"time"
+)
+
+var _ = Describe("Fault", func() {
+	var (
+		cluster *mock.ClusterBuilder
+		nodes   []mock.Node
+		key     channel.Key
+	)
+	BeforeEach(func() {
+		cluster = mock.NewCluster()
+		var err error
+		nodes, err = cluster.NewN(zap.NewNop(), 2)
+		Expect(err).ToNot(HaveOccurred())
+		ch, err := nodes[1].Channel.NewCreate().
+			WithName("SG01").
+			WithDataRate(1 * telem.Hz).
+			WithDataType(telem.Float64).
+			WithNodeID(nodes[1].ID()).
+			Exec(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		key = ch.Key()
+		req, res, err := nodes[1].Cesium.NewCreate().WhereChannels(key.Cesium()).Stream(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		req <- cesium.CreateRequest{Segments: []cesium.Segment{{
+			ChannelKey: key.Cesium(),
+			Start:      0,
+			Data:       make([]byte, 80),
+		}}}
+		close(req)
+		for r := range res {
+			Expect(r.Error).ToNot(HaveOccurred())
+		}
+		// Wait for the channel to propagate to the first node.
+		time.Sleep(100 * time.Millisecond)
+	})
+	AfterEach(func() { Expect(cluster.Close()).To(Succeed()) })
+	It("Should read from a remote node over the in-memory network", func() {
+		iter, err := nodes[0].Segment.NewRetrieve().WhereChannels(key).Iterate(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(iter.First()).To(BeTrue())
+		Expect(assertResponse(1, 1, iter, 20*time.Millisecond)).To(Succeed())
+		Expect(iter.Close()).To(Succeed())
+	})
+	It("Should fail to open an iterator when the leaseholder is partitioned", func() {
+		cluster.Partition(nodes[1].ID())
+		_, err := nodes[0].Segment.NewRetrieve().WhereChannels(key).Iterate(ctx)
+		Expect(err).To(HaveOccurred())
+	})
+	It("Should return false when the leaseholder is killed mid-iteration", func() {
+		iter, err := nodes[0].Segment.NewRetrieve().WhereChannels(key).Iterate(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(iter.First()).To(BeTrue())
+		cluster.Kill(nodes[1].ID())
+		Expect(iter.Next()).To(BeFalse())
+	})
+})
@@ -0,0 +1,291 @@
+package iterator
+
+import (
+	"context"
+	"github.com/arya-analytics/delta/pkg/distribution/node"
+	"github.com/arya-analytics/x/confluence"
+	"github.com/arya-analytics/x/signal"
+	"github.com/arya-analytics/x/transport"
+	"sync"
+)
+
+// FlowControl sets limits on the amount of segment data an iterator holds in memory.
+// A zero value for any field disables the corresponding limit.
+//
+// When flow control is enabled, remote nodes stop sending data once they run out of
+// credits, and a node only acknowledges a command after it has sent all data for the
+// command. Consumers must read from Iterator.Responses while calling iterator
+// methods, otherwise methods may return false after timing out.
+type FlowControl struct {
+	// MaxBytesInFlight is the maximum number of segment data bytes received by the
+	// iterator but not yet read from Iterator.Responses. A single response larger
+	// than the limit is still admitted when nothing else is in flight.
+	MaxBytesInFlight int
+	// MaxSegmentsPerResponse is the maximum number of segments in a single data
+	// response. Larger responses are split.
+	MaxSegmentsPerResponse int
+	// CreditWindow is the number of data responses each remote node may send before
+	// it must wait for the iterator to read them from Iterator.Responses.
+	CreditWindow int
+}
+
+// Enabled returns true if any limit is set.
+func (fc FlowControl) Enabled() bool {
+	return fc.MaxBytesInFlight > 0 || fc.MaxSegmentsPerResponse > 0 || fc.CreditWindow > 0
+}
+
+// chunk splits res into responses with at most max segments. Returns res unmodified
+// if max is zero.
+func chunk(res Response, max int) []Response {
+	if max <= 0 || len(res.Segments) <= max {
+		return []Response{res}
+	}
+	chunks := make([]Response, 0, (len(res.Segments)+max-1)/max)
+	for i := 0; i < len(res.Segments); i += max {
+		end := i + max
+		if end > len(res.Segments) {
+			end = len(res.Segments)
+		}
+		c := res
+		c.Segments = res.Segments[i:end]
+		chunks = append(chunks, c)
+	}
+	return chunks
+}
+
+func responseSize(res Response) (size int) {
+	for _, seg := range res.Segments {
+		size += len(seg.Segment.Data)
+	}
+	return size
+}
+
+// |||||| BUDGET ||||||
+
+// budget tracks the number of bytes in flight against a maximum.
+type budget struct {
+	mu      sync.Mutex
+	max     int
+	used    int
+	changed chan struct{}
+}
+
+func newBudget(max int) *budget { return &budget{max: max, changed: make(chan struct{})} }
+
+// acquire blocks until n bytes fit within the budget or the context is cancelled.
+func (b *budget) acquire(ctx context.Context, n int) error {
+	for {
+		b.mu.Lock()
+		if b.max <= 0 || b.used == 0 || b.used+n <= b.max {
+			b.used += n
+			b.mu.Unlock()
+			return nil
+		}
+		changed := b.changed
+		b.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// release returns n bytes to the budget.
+func (b *budget) release(n int) {
+	b.mu.Lock()
+	b.used -= n
+	close(b.changed)
+	b.changed = make(chan struct{})
+	b.mu.Unlock()
+}
+
+// |||||| CREDITS ||||||
+
+// creditGate limits the number of data responses a server sends before the client
+// grants it more credits.
+type creditGate struct {
+	mu        sync.Mutex
+	unlimited bool
+	credits   int
+	notify    chan struct{}
+}
+
+func newCreditGate(initial int) *creditGate {
+	return &creditGate{unlimited: initial <= 0, credits: initial, notify: make(chan struct{}, 1)}
+}
+
+// wait blocks until a credit is available, consumes it, and returns.
+func (g *creditGate) wait(ctx context.Context) error {
+	for {
+		g.mu.Lock()
+		if g.unlimited || g.credits > 0 {
+			g.credits--
+			g.mu.Unlock()
+			return nil
+		}
+		g.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-g.notify:
+		}
+	}
+}
+
+func (g *creditGate) grant(n int) {
+	g.mu.Lock()
+	g.credits += n
+	g.mu.Unlock()
+	select {
+	case g.notify <- struct{}{}:
+	default:
+	}
+}
+
+// |||||| SERVER ||||||
+
+// flowSender splits outgoing data responses into chunks and waits for a credit from
+// the client before sending each chunk.
+type flowSender struct {
+	transport.StreamSender[Response]
+	ctx         context.Context
+	gate        *creditGate
+	maxSegments int
+}
+
+// Send implements transport.StreamSender.
+func (s flowSender) Send(res Response) error {
+	if res.Variant != DataResponse {
+		return s.StreamSender.Send(res)
+	}
+	for _, c := range chunk(res, s.maxSegments) {
+		if err := s.gate.wait(s.ctx); err != nil {
+			return err
+		}
+		if err := s.StreamSender.Send(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// creditReceiver intercepts Credit requests from the client and grants them to the
+// server's creditGate.
+type creditReceiver struct {
+	Server
+	gate *creditGate
+}
+
+// Receive implements transport.StreamReceiver.
+func (r creditReceiver) Receive() (Request, error) {
+	for {
+		req, err := r.Server.Receive()
+		if err != nil || req.Command != Credit {
+			return req, err
+		}
+		r.gate.grant(req.Credit)
+	}
+}
+
+// |||||| CLIENT ||||||
+
+// lockedClient serializes sends to a Client so that credits can be granted
+// concurrently with commands sent by the iterator.
+type lockedClient struct {
+	Client
+	mu *sync.Mutex
+}
+
+// Send implements transport.StreamSender.
+func (c lockedClient) Send(req Request) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.Client.Send(req)
+}
+
+// flowClient reserves space in the iterator's byte budget for every data response
+// received from a remote node.
+type flowClient struct {
+	Client
+	ctx    context.Context
+	budget *budget
+}
+
+// Receive implements transport.StreamReceiver.
+func (c flowClient) Receive() (Response, error) {
+	res, err := c.Client.Receive()
+	if err != nil || res.Variant != DataResponse {
+		return res, err
+	}
+	return res, c.budget.acquire(c.ctx, responseSize(res))
+}
+
+// admitter reserves space in the iterator's byte budget for every data response
+// emitted by the local iterator.
+type admitter struct {
+	budget *budget
+	confluence.LinearTransform[Response, Response]
+}
+
+func newAdmitter(b *budget) confluence.Segment[Response, Response] {
+	a := &admitter{budget: b}
+	a.LinearTransform.ApplyTransform = a.admit
+	return a
+}
+
+func (a *admitter) admit(ctx signal.Context, res Response) (Response, bool, error) {
+	if res.Variant != DataResponse {
+		return res, true, nil
+	}
+	return res, true, a.budget.acquire(ctx, responseSize(res))
+}
+
+// flow delivers data responses to the consumer of an iterator, releasing bytes from
+// the budget and granting credits to remote nodes as responses are read.
+type flow struct {
+	FlowControl
+	host    node.ID
+	budget  *budget
+	clients map[node.ID]Client
+}
+
+func newFlow(host node.ID, fc FlowControl) *flow {
+	return &flow{
+		FlowControl: fc,
+		host:        host,
+		budget:      newBudget(fc.MaxBytesInFlight),
+		clients:     make(map[node.ID]Client),
+	}
+}
+
+// deliver forwards responses from in to the returned channel until in is closed or
+// the context is cancelled.
+func (f *flow) deliver(ctx context.Context, in <-chan Response) <-chan Response {
+	out := make(chan Response)
+	go func() {
+		defer close(out)
+		for res := range in {
+			for _, c := range chunk(res, f.MaxSegmentsPerResponse) {
+				select {
+				case <-ctx.Done():
+					return
+				case out <- c:
+				}
+				f.budget.release(responseSize(c))
+			}
+			f.grant(res.NodeID)
+		}
+	}()
+	return out
+}
+
+func (f *flow) grant(nodeID node.ID) {
+	client, ok := f.clients[nodeID]
+	if !ok || f.CreditWindow <= 0 {
+		return
+	}
+	// If the grant fails, the stream is broken, and the error will surface through
+	// the response pipeline.
+	_ = client.Send(Request{Command: Credit, Credit: 1})
+}
@@ -85,7 +85,9 @@ func New(
 	tran Transport,
 	rng telem.TimeRange,
 	keys channel.Keys,
+	opts ...Option,
 ) (Iterator, error) {
+	o := newOptions(opts)
 	sCtx, cancel := signal.WithCancel(ctx)
 
 	// First we need to check if all the channels exist and are retrievable in the
@@ -106,11 +108,25 @@ func New(
 		receiverAddresses []address.Address
 	)
 
+	// f bounds the amount of data we hold in memory. Nil if flow control is disabled.
+	var f *flow
+	if o.flow.Enabled() {
+		f = newFlow(resolver.HostID(), o.flow)
+	}
+
 	if needRemote {
 		numSenders += 1
 		numReceivers += len(batch.Remote)
 
-		sender, receivers, err := openRemoteIterators(sCtx, tran, batch.Remote, rng, resolver)
+		sender, receivers, err := openRemoteIterators(
+			sCtx,
+			tran,
+			batch.Remote,
+			rng,
+			resolver,
+			o.compression,
+			f,
+		)
 		if err != nil {
 			cancel()
 			return nil, err
@@ -138,6 +154,17 @@ func New(
 		}
 		addr := address.Address("local")
 		plumber.SetSegment[Request, Response](pipe, addr, localIter)
+		if f != nil {
+			// Reserve budget for local data before it enters the response pipeline.
+			plumber.SetSegment[Response, Response](pipe, "admitter", newAdmitter(f.budget))
+			if err := (plumber.UnaryRouter[Response]{
+				SourceTarget: addr,
+				SinkTarget:   "admitter",
+			}).PreRoute(pipe); err != nil {
+				panic(err)
+			}
+			addr = "admitter"
+		}
 		receiverAddresses = append(receiverAddresses, addr)
 	}
 
@@ -219,12 +246,17 @@ func New(
 	seg.OutTo(res)
 	seg.Flow(sCtx, confluence.CloseInletsOnExit())
 
+	responses := res.Outlet()
+	if f != nil {
+		responses = f.deliver(sCtx, responses)
+	}
+
 	return &iterator{
 		emitter:   emit,
 		sync:      sync,
 		wg:        sCtx,
 		cancel:    cancel,
-		responses: res.Outlet(),
+		responses: responses,
 	}, nil
 }
 
@@ -36,7 +36,7 @@ func newLocalIterator(
 
 	// translator translates cesium res from the iterator source into
 	// res transportable over the network.
-	ts := newCesiumResponseTranslator(keys.CesiumMap())
+	ts := newCesiumResponseTranslator(host, keys.CesiumMap())
 	plumber.SetSegment[cesium.RetrieveResponse, Response](pipe, "translator", ts)
 
 	c := errutil.NewCatchSimple()
@@ -85,15 +85,17 @@ func (te *requestExecutor) execute(ctx signal.Context, req Request) (Response, b
 }
 
 type cesiumResponseTranslator struct {
+	host    node.ID
 	wrapper *core.CesiumWrapper
 	confluence.LinearTransform[cesium.RetrieveResponse, Response]
 }
 
 func newCesiumResponseTranslator(
+	host node.ID,
 	keyMap map[cesium.ChannelKey]channel.Key,
 ) confluence.Segment[cesium.RetrieveResponse, Response] {
 	wrapper := &core.CesiumWrapper{KeyMap: keyMap}
-	ts := &cesiumResponseTranslator{wrapper: wrapper}
+	ts := &cesiumResponseTranslator{host: host, wrapper: wrapper}
 	ts.LinearTransform.ApplyTransform = ts.translate
 	return ts
 }
@@ -110,5 +112,9 @@ func (te *cesiumResponseTranslator) translate(
 	ctx signal.Context,
 	res cesium.RetrieveResponse,
 ) (Response, bool, error) {
-	return Response{Variant: DataResponse, Segments: te.wrapper.Wrap(res.Segments)}, true, nil
+	return Response{
+		Variant:  DataResponse,
+		NodeID:   te.host,
+		Segments: te.wrapper.Wrap(res.Segments),
+	}, true, nil
 }
@@ -0,0 +1,31 @@
+package iterator
+
+import "github.com/arya-analytics/delta/pkg/distribution/segment/compress"
+
+type Option func(*options)
+
+type options struct {
+	compression compress.Algorithm
+	flow        FlowControl
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{compression: compress.None}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithCompression sets the algorithm remote nodes use to compress segment data
+// before sending it over the network. If a remote node doesn't support the
+// algorithm, it sends data uncompressed.
+func WithCompression(alg compress.Algorithm) Option {
+	return func(o *options) { o.compression = alg }
+}
+
+// WithFlowControl bounds the amount of segment data the iterator holds in memory.
+// See FlowControl for details.
+func WithFlowControl(fc FlowControl) Option {
+	return func(o *options) { o.flow = fc }
+}
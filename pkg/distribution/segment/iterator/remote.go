@@ -4,10 +4,12 @@ import (
 	"github.com/arya-analytics/aspen"
 	"github.com/arya-analytics/delta/pkg/distribution/channel"
 	"github.com/arya-analytics/delta/pkg/distribution/node"
+	"github.com/arya-analytics/delta/pkg/distribution/segment/compress"
 	"github.com/arya-analytics/x/address"
 	"github.com/arya-analytics/x/confluence/transfluence"
 	"github.com/arya-analytics/x/signal"
 	"github.com/arya-analytics/x/telem"
+	"sync"
 )
 
 func openRemoteIterators(
@@ -16,6 +18,8 @@ func openRemoteIterators(
 	targets map[node.ID][]channel.Key,
 	rng telem.TimeRange,
 	resolver aspen.HostResolver,
+	compression compress.Algorithm,
+	f *flow,
 ) (*transfluence.MultiSender[Request], []*transfluence.Receiver[Response], error) {
 	sender := &transfluence.MultiSender[Request]{}
 	receivers := make([]*transfluence.Receiver[Response], 0, len(targets))
@@ -24,10 +28,13 @@ func openRemoteIterators(
 		if err != nil {
 			return sender, receivers, err
 		}
-		client, err := openRemoteClient(ctx, tran, targetAddr, keys, rng)
+		client, err := openRemoteClient(ctx, tran, targetAddr, keys, rng, compression, f)
 		if err != nil {
 			return sender, receivers, err
 		}
+		if f != nil {
+			f.clients[nodeID] = client
+		}
 		sender.Senders = append(sender.Senders, client)
 		receivers = append(receivers, &transfluence.Receiver[Response]{Receiver: client})
 	}
@@ -40,17 +47,31 @@ func openRemoteClient(
 	target address.Address,
 	keys channel.Keys,
 	rng telem.TimeRange,
+	compression compress.Algorithm,
+	f *flow,
 ) (Client, error) {
 	client, err := tran.Stream(ctx, target)
 	if err != nil {
 		return nil, err
 	}
+	open := Request{
+		Command:     Open,
+		Keys:        keys,
+		Range:       rng,
+		Compression: compression,
+	}
+	wrapped := Client(decompressingClient{Client: client})
+	if f != nil {
+		open.Credit = f.CreditWindow
+		open.MaxSegments = f.MaxSegmentsPerResponse
+		wrapped = flowClient{
+			Client: lockedClient{Client: wrapped, mu: &sync.Mutex{}},
+			ctx:    ctx,
+			budget: f.budget,
+		}
+	}
 
 	// Send an open request to the transport. This will open a localIterator  on the
 	// target node.
-	return client, client.Send(Request{
-		Command: Open,
-		Keys:    keys,
-		Range:   rng,
-	})
+	return wrapped, client.Send(open)
 }
@@ -6,6 +6,7 @@ import (
 	"github.com/arya-analytics/delta/pkg/distribution/channel"
 	"github.com/arya-analytics/delta/pkg/distribution/mock"
 	"github.com/arya-analytics/delta/pkg/distribution/node"
+	"github.com/arya-analytics/delta/pkg/distribution/segment/compress"
 	"github.com/arya-analytics/delta/pkg/distribution/segment/iterator"
 	"github.com/arya-analytics/x/address"
 	"github.com/arya-analytics/x/gorp"
@@ -24,6 +25,7 @@ var _ = Describe("Remote", Ordered, func() {
 		iter      iterator.Iterator
 		builder   *mock.StorageBuilder
 		nChannels int
+		openIter  func(opts ...iterator.Option) (iterator.Iterator, error)
 	)
 	BeforeAll(func() {
 		log = zap.NewNop()
@@ -118,15 +120,19 @@ var _ = Describe("Remote", Ordered, func() {
 
 		time.Sleep(100 * time.Millisecond)
 
-		iter, err = iterator.New(
-			ctx,
-			store3.Cesium,
-			store3ChannelSvc,
-			store3.Aspen,
-			node3Transport,
-			telem.TimeRangeMax,
-			keys,
-		)
+		openIter = func(opts ...iterator.Option) (iterator.Iterator, error) {
+			return iterator.New(
+				ctx,
+				store3.Cesium,
+				store3ChannelSvc,
+				store3.Aspen,
+				node3Transport,
+				telem.TimeRangeMax,
+				keys,
+				opts...,
+			)
+		}
+		iter, err = openIter()
 		Expect(err).ToNot(HaveOccurred())
 	})
 	AfterAll(func() {
@@ -135,6 +141,47 @@ var _ = Describe("Remote", Ordered, func() {
 		Expect(ok).To(BeFalse())
 		Expect(builder.Close()).To(Succeed())
 	})
+	Describe("Compression", func() {
+		It("Should transparently decompress segments from remote nodes", func() {
+			cIter, err := openIter(iterator.WithCompression(compress.Zstd))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cIter.First()).To(BeTrue())
+			for i := 0; i < nChannels; i++ {
+				var res iterator.Response
+				Eventually(cIter.Responses()).Should(Receive(&res))
+				Expect(res.Compression).To(Equal(compress.None))
+				for _, s := range res.Segments {
+					Expect(s.Segment.Data).To(HaveLen(80))
+				}
+			}
+			Expect(cIter.Close()).To(Succeed())
+		})
+	})
+	Describe("Flow Control", func() {
+		It("Should split responses and bound data in flight", func() {
+			fIter, err := openIter(iterator.WithFlowControl(iterator.FlowControl{
+				MaxBytesInFlight:       80,
+				MaxSegmentsPerResponse: 1,
+				CreditWindow:           1,
+			}))
+			Expect(err).ToNot(HaveOccurred())
+			var (
+				nSegments int
+				done      = make(chan struct{})
+			)
+			go func() {
+				defer close(done)
+				for res := range fIter.Responses() {
+					Expect(res.Segments).To(HaveLen(1))
+					nSegments++
+				}
+			}()
+			Expect(fIter.First()).To(BeTrue())
+			Expect(fIter.Close()).To(Succeed())
+			<-done
+			Expect(nSegments).To(Equal(nChannels))
+		})
+	})
 	Context("Behavioral Accuracy", func() {
 		Describe("First", func() {
 			It("Should return the first segment in the iterator", func() {
@@ -3,7 +3,10 @@ package iterator
 import (
 	"context"
 	"github.com/arya-analytics/cesium"
+	"github.com/arya-analytics/delta/pkg/distribution/channel"
 	"github.com/arya-analytics/delta/pkg/distribution/node"
+	"github.com/arya-analytics/delta/pkg/distribution/segment/compress"
+	"github.com/arya-analytics/delta/pkg/distribution/segment/core"
 	"github.com/arya-analytics/x/confluence"
 	"github.com/arya-analytics/x/confluence/plumber"
 	"github.com/arya-analytics/x/confluence/transfluence"
@@ -41,14 +44,44 @@ func (sf *server) Handle(_ctx context.Context, server Server) error {
 		return errors.New("[segment.iterator] - server expected Open command")
 	}
 
+	// gate limits the number of data responses we send before the client reads
+	// them. See FlowControl.
+	gate := newCreditGate(req.Credit)
+
 	// receiver receives requests from the client and pipes them into the
-	// requestPipeline.
-	receiver := &transfluence.Receiver[Request]{Receiver: server}
+	// requestPipeline, intercepting credit grants.
+	receiver := &transfluence.Receiver[Request]{
+		Receiver: creditReceiver{Server: server, gate: gate},
+	}
+
+	// Fall back to sending uncompressed data if we don't support the requested
+	// compression algorithm.
+	compression := req.Compression
+	if !compress.Supported(compression) {
+		compression = compress.None
+	}
+	var densities map[channel.Key]int
+	if compression != compress.None {
+		channels, err := sf.db.RetrieveChannel(req.Keys.Cesium()...)
+		if err != nil {
+			return errors.Wrap(err, "[segment.iterator] - failed to retrieve cesium channels")
+		}
+		densities = core.Densities(channels, req.Keys.CesiumMap())
+	}
 
-	// sender receives responses from the pipeline and sends them
-	// over the network.
+	// sender receives responses from the pipeline, splits and compresses them, and
+	// sends them over the network.
 	sender := &transfluence.Sender[Response]{
-		Sender: transport.SenderEmptyCloser[Response]{StreamSender: server},
+		Sender: transport.SenderEmptyCloser[Response]{StreamSender: flowSender{
+			StreamSender: compressingSender{
+				StreamSender: server,
+				alg:          compression,
+				densities:    densities,
+			},
+			ctx:         ctx,
+			gate:        gate,
+			maxSegments: req.MaxSegments,
+		}},
 	}
 
 	iter, err := newLocalIterator(sf.db, sf.host, req.Range, req.Keys)
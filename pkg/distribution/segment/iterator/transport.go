@@ -3,6 +3,7 @@ package iterator
 import (
 	"github.com/arya-analytics/delta/pkg/distribution/channel"
 	"github.com/arya-analytics/delta/pkg/distribution/node"
+	"github.com/arya-analytics/delta/pkg/distribution/segment/compress"
 	"github.com/arya-analytics/delta/pkg/distribution/segment/core"
 	"github.com/arya-analytics/x/telem"
 	"github.com/arya-analytics/x/transport"
@@ -27,6 +28,9 @@ const (
 	Error
 	Close
 	Exhaust
+	// Credit grants a remote iterator server additional credits to send data
+	// responses. See FlowControl.
+	Credit
 )
 
 type Request struct {
@@ -35,6 +39,17 @@ type Request struct {
 	Range   telem.TimeRange
 	Stamp   telem.TimeStamp
 	Keys    channel.Keys
+	// Compression is the algorithm the client would like segment data to be
+	// compressed with. Only read from the Open request.
+	Compression compress.Algorithm
+	// Credit is the initial number of data responses the server may send when set
+	// on the Open request, and the number of additional responses it may send when
+	// set on a Credit request. A zero value on Open disables credit-based flow
+	// control.
+	Credit int
+	// MaxSegments is the maximum number of segments the server may send in a single
+	// data response. Only read from the Open request.
+	MaxSegments int
 }
 
 type ResponseVariant uint8
@@ -51,6 +66,8 @@ type Response struct {
 	Command  Command
 	Segments []core.Segment
 	Error    error
+	// Compression is the algorithm that the data in Segments is compressed with.
+	Compression compress.Algorithm
 }
 
 func newAck(host node.ID, cmd Command, ok bool) Response {
@@ -0,0 +1,235 @@
+package plan
+
+import (
+	"context"
+	"github.com/arya-analytics/aspen"
+	"github.com/arya-analytics/cesium"
+	"github.com/arya-analytics/delta/pkg/distribution/channel"
+	"github.com/arya-analytics/delta/pkg/distribution/segment/core"
+	"github.com/arya-analytics/delta/pkg/distribution/segment/stat"
+	"github.com/arya-analytics/x/address"
+	"github.com/arya-analytics/x/confluence"
+	"github.com/arya-analytics/x/confluence/plumber"
+	"github.com/arya-analytics/x/confluence/transfluence"
+	"github.com/arya-analytics/x/signal"
+	"github.com/cockroachdb/errors"
+)
+
+// Result is the result of executing a Plan. Segments is populated for plans that
+// don't aggregate, and Statistics (ordered by the Query's keys) for plans that do.
+type Result struct {
+	Segments   []core.Segment
+	Statistics []stat.Statistics
+}
+
+// Exec executes the Plan. Stages for the host node are executed against db, and
+// stages for remote nodes are sent over the transport to the leaseholder.
+func Exec(
+	ctx context.Context,
+	p Plan,
+	db cesium.DB,
+	svc *channel.Service,
+	resolver aspen.HostResolver,
+	tran Transport,
+) (Result, error) {
+	if err := core.ValidateChannelKeys(ctx, svc, p.Query.Keys); err != nil {
+		return Result{}, err
+	}
+
+	var chs []channel.Channel
+	if err := svc.NewRetrieve().WhereKeys(p.Query.Keys...).Entries(&chs).Exec(ctx); err != nil {
+		return Result{}, err
+	}
+	channels := make(map[channel.Key]channel.Channel, len(chs))
+	for _, ch := range chs {
+		if p.Query.Aggregates() && !core.Numeric(ch.SampleType) {
+			return Result{}, errors.Wrapf(
+				stat.UnsupportedDataType,
+				"[segment.plan] - cannot aggregate channel %v with sample type %v",
+				ch.Key(),
+				ch.SampleType,
+			)
+		}
+		channels[ch.Key()] = ch
+	}
+
+	sCtx, cancel := signal.WithCancel(ctx)
+	defer cancel()
+
+	responses, locals, err := open(sCtx, p, db, svc, resolver, tran, channels)
+	if err != nil {
+		return Result{}, err
+	}
+	for _, ls := range locals {
+		go ls.run(sCtx)
+	}
+
+	var (
+		res        Result
+		errs       error
+		stats      = make(map[channel.Key]stat.Statistics, len(p.Query.Keys))
+		aggregates = p.Query.Aggregates()
+		// If no stage aggregates, the coordinator is responsible for computing the
+		// statistics from raw segments.
+		aggregateHere = aggregates && !coordinatorMerges(p)
+	)
+	for r := range responses {
+		if r.Error != nil {
+			errs = errors.CombineErrors(errs, r.Error)
+			continue
+		}
+		for _, s := range r.Statistics {
+			stats[s.ChannelKey] = stat.Merge(stats[s.ChannelKey], s)
+		}
+		if !aggregateHere {
+			res.Segments = append(res.Segments, r.Segments...)
+			continue
+		}
+		for _, seg := range r.Segments {
+			s, err := stat.OfSegment(channels[seg.ChannelKey], p.Query.Range, seg.Segment)
+			if err != nil {
+				errs = errors.CombineErrors(errs, err)
+				continue
+			}
+			stats[seg.ChannelKey] = stat.Merge(stats[seg.ChannelKey], s)
+		}
+	}
+	if err := sCtx.Wait(); err != nil && !errors.Is(err, context.Canceled) {
+		errs = errors.CombineErrors(errs, err)
+	}
+	if errs != nil {
+		return res, errors.Wrap(errs, "[segment.plan] - failed to execute plan")
+	}
+	if aggregates {
+		res.Statistics = make([]stat.Statistics, len(p.Query.Keys))
+		for i, key := range p.Query.Keys {
+			s, ok := stats[key]
+			if !ok {
+				s = stat.Statistics{ChannelKey: key, DataType: channels[key].Cesium.DataType}
+			}
+			res.Statistics[i] = s
+		}
+	}
+	return res, nil
+}
+
+func coordinatorMerges(p Plan) bool {
+	for _, op := range p.Coordinator {
+		if op.Type == MergeOperator {
+			return true
+		}
+	}
+	return false
+}
+
+// open assembles the pipeline for a Plan. Each node stage is a source that feeds
+// into a coordinator segment, which applies the coordinator operators that can be
+// applied to segments. The returned local stages must be run after the pipeline
+// is opened. If the pipeline can't be assembled, the streams already opened to
+// remote nodes are closed.
+func open(
+	ctx signal.Context,
+	p Plan,
+	db cesium.DB,
+	svc *channel.Service,
+	resolver aspen.HostResolver,
+	tran Transport,
+	channels map[channel.Key]channel.Channel,
+) (_ <-chan Response, _ []*localStage, err error) {
+	var (
+		pipe    = plumber.New()
+		sources = make([]address.Address, 0, len(p.Nodes))
+		locals  []*localStage
+		clients []Client
+	)
+	defer func() {
+		if err == nil {
+			return
+		}
+		for _, client := range clients {
+			err = errors.CombineErrors(err, client.CloseSend())
+		}
+	}()
+
+	for _, stage := range p.Nodes {
+		if stage.Node == p.Host {
+			ls := newLocalStage(db, svc, stage)
+			addr := address.Newf("local-%v", stage.Node)
+			plumber.SetSource[Response](pipe, addr, ls)
+			sources = append(sources, addr)
+			locals = append(locals, ls)
+			continue
+		}
+		target, err := resolver.Resolve(stage.Node)
+		if err != nil {
+			return nil, nil, err
+		}
+		client, err := tran.Stream(ctx, target)
+		if err != nil {
+			return nil, nil, err
+		}
+		clients = append(clients, client)
+		if err := client.Send(Request{Stage: stage}); err != nil {
+			return nil, nil, err
+		}
+		addr := address.Newf("remote-%v", stage.Node)
+		plumber.SetSource[Response](pipe, addr, &transfluence.Receiver[Response]{Receiver: client})
+		sources = append(sources, addr)
+	}
+
+	plumber.SetSegment[Response, Response](pipe, "coordinator", newCoordinator(channels, p.Coordinator))
+
+	if err := (plumber.MultiRouter[Response]{
+		SourceTargets: sources,
+		SinkTargets:   []address.Address{"coordinator"},
+		Stitch:        plumber.StitchUnary,
+		Capacity:      len(sources),
+	}).PreRoute(pipe); err != nil {
+		return nil, nil, errors.Wrap(err, "[segment.plan] - failed to route pipeline")
+	}
+
+	seg := &plumber.Segment[Request, Response]{Pipeline: pipe}
+	if err := seg.RouteOutletFrom("coordinator"); err != nil {
+		return nil, nil, errors.Wrap(err, "[segment.plan] - failed to route pipeline")
+	}
+
+	res := confluence.NewStream[Response](len(sources))
+	seg.OutTo(res)
+	seg.Flow(ctx, confluence.CloseInletsOnExit())
+	return res.Outlet(), locals, nil
+}
+
+// coordinator applies the coordinator operators of a Plan to incoming segments.
+// Statistics and errors are passed through untouched.
+type coordinator struct {
+	channels map[channel.Key]channel.Channel
+	ops      []Operator
+	confluence.LinearTransform[Response, Response]
+}
+
+func newCoordinator(
+	channels map[channel.Key]channel.Channel,
+	ops []Operator,
+) confluence.Segment[Response, Response] {
+	c := &coordinator{channels: channels}
+	for _, op := range ops {
+		if op.Type == FilterOperator || op.Type == TransformOperator {
+			c.ops = append(c.ops, op)
+		}
+	}
+	c.LinearTransform.ApplyTransform = c.apply
+	return c
+}
+
+func (c *coordinator) apply(_ signal.Context, res Response) (Response, bool, error) {
+	if res.Error != nil || len(res.Segments) == 0 {
+		return res, true, nil
+	}
+	var err error
+	for _, op := range c.ops {
+		if res.Segments, err = op.apply(c.channels, res.Segments); err != nil {
+			return Response{Error: err}, true, nil
+		}
+	}
+	return res, len(res.Segments) > 0, nil
+}
@@ -0,0 +1,153 @@
+package plan_test
+
+import (
+	"github.com/arya-analytics/cesium"
+	"github.com/arya-analytics/delta/pkg/distribution/channel"
+	"github.com/arya-analytics/delta/pkg/distribution/mock"
+	"github.com/arya-analytics/delta/pkg/distribution/segment/core"
+	"github.com/arya-analytics/delta/pkg/distribution/segment/plan"
+	"github.com/arya-analytics/x/address"
+	"github.com/arya-analytics/x/gorp"
+	"github.com/arya-analytics/x/telem"
+	tmock "github.com/arya-analytics/x/transport/mock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+)
+
+var _ = Describe("Exec", Ordered, func() {
+	var (
+		builder *mock.StorageBuilder
+		keys    channel.Keys
+		exec    func(ops []plan.Operator, pushDown bool) (plan.Result, error)
+		// run sends the stage to the first node, and returns the error it responds
+		// with.
+		run func(stage plan.Stage) error
+	)
+	BeforeAll(func() {
+		log := zap.NewNop()
+		builder = mock.NewStorage()
+		net := tmock.NewNetwork[plan.Request, plan.Response]()
+		channelNet := tmock.NewNetwork[channel.CreateMessage, channel.CreateMessage]()
+
+		node1Addr := address.Address("localhost:0")
+		node2Addr := address.Address("localhost:1")
+
+		store1, err := builder.New(log)
+		Expect(err).ToNot(HaveOccurred())
+		store2, err := builder.New(log)
+		Expect(err).ToNot(HaveOccurred())
+
+		store1ChannelSvc := channel.New(
+			store1.Aspen,
+			gorp.Wrap(store1.Aspen),
+			store1.Cesium,
+			channelNet.RouteUnary(node1Addr),
+		)
+		store2ChannelSvc := channel.New(
+			store2.Aspen,
+			gorp.Wrap(store2.Aspen),
+			store2.Cesium,
+			channelNet.RouteUnary(node2Addr),
+		)
+
+		plan.NewServer(store1.Cesium, store1ChannelSvc, store1.Aspen.HostID(), net.RouteStream(node1Addr, 0))
+		node2Transport := net.RouteStream(node2Addr, 0)
+		plan.NewServer(store2.Cesium, store2ChannelSvc, store2.Aspen.HostID(), node2Transport)
+
+		ch1, err := store1ChannelSvc.NewCreate().
+			WithName("SG01").
+			WithDataRate(1 * telem.Hz).
+			WithSampleType(channel.Float64).
+			WithNodeID(1).
+			Exec(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(mock.WriteSegments(ctx, store1.Cesium, cesium.Segment{
+			ChannelKey: ch1.Key().Cesium(),
+			Start:      0,
+			Data:       mock.Float64Data(1, 2, 3, 4, 5, 6, 7, 8, 9, 10),
+		})).To(Succeed())
+
+		ch2, err := store2ChannelSvc.NewCreate().
+			WithName("SG02").
+			WithDataRate(1 * telem.Hz).
+			WithSampleType(channel.Float64).
+			WithNodeID(2).
+			Exec(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(mock.WriteSegments(ctx, store2.Cesium, cesium.Segment{
+			ChannelKey: ch2.Key().Cesium(),
+			Start:      0,
+			Data:       mock.Float64Data(-5, 5),
+		})).To(Succeed())
+
+		keys = channel.Keys{ch1.Key(), ch2.Key()}
+
+		// Wait for the channels to propagate to the second node.
+		Eventually(func() error {
+			return core.ValidateChannelKeys(ctx, store2ChannelSvc, keys)
+		}).Should(Succeed())
+
+		exec = func(ops []plan.Operator, pushDown bool) (plan.Result, error) {
+			p, err := plan.Planner{Host: store2.Aspen.HostID(), PushDown: pushDown}.
+				Plan(plan.Query{Keys: keys, Range: telem.TimeRangeMax, Operators: ops})
+			Expect(err).ToNot(HaveOccurred())
+			return plan.Exec(ctx, p, store2.Cesium, store2ChannelSvc, store2.Aspen, node2Transport)
+		}
+		run = func(stage plan.Stage) error {
+			client, err := node2Transport.Stream(ctx, node1Addr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(client.Send(plan.Request{Stage: stage})).To(Succeed())
+			for {
+				res, err := client.Receive()
+				if err != nil {
+					return err
+				}
+				if res.Error != nil {
+					return res.Error
+				}
+			}
+		}
+	})
+	AfterAll(func() { Expect(builder.Close()).To(Succeed()) })
+	It("Should not execute a stage for channels leased to another node", func() {
+		err := run(plan.Stage{Node: 1, Keys: channel.Keys{keys[1]}, Range: telem.TimeRangeMax})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("not the leaseholder"))
+	})
+	for _, pushDown := range []bool{true, false} {
+		pushDown := pushDown
+		Context("PushDown", func() {
+			It("Should return raw segments when no operators are provided", func() {
+				res, err := exec(nil, pushDown)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(res.Segments).To(HaveLen(2))
+			})
+			It("Should filter and transform segments", func() {
+				res, err := exec([]plan.Operator{plan.Filter(4, 6), plan.Transform(2, 0)}, pushDown)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(res.Segments).To(HaveLen(2))
+				for _, seg := range res.Segments {
+					values, err := core.DecodeSamples(channel.Float64, seg.Segment.Data)
+					Expect(err).ToNot(HaveOccurred())
+					if seg.ChannelKey == keys[0] {
+						Expect(values).To(Equal([]float64{8, 10, 12}))
+						Expect(seg.Segment.Start).To(Equal(telem.TimeStamp(3 * telem.Second)))
+					} else {
+						Expect(values).To(Equal([]float64{10}))
+						Expect(seg.Segment.Start).To(Equal(telem.TimeStamp(1 * telem.Second)))
+					}
+				}
+			})
+			It("Should aggregate the results", func() {
+				res, err := exec([]plan.Operator{plan.Filter(0, 100), plan.Aggregate()}, pushDown)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(res.Statistics).To(HaveLen(2))
+				Expect(res.Statistics[0].Count).To(Equal(int64(10)))
+				Expect(res.Statistics[0].Mean()).To(Equal(5.5))
+				Expect(res.Statistics[1].Count).To(Equal(int64(1)))
+				Expect(res.Statistics[1].Max).To(Equal(5.0))
+			})
+		})
+	}
+})
@@ -0,0 +1,113 @@
+package plan
+
+import (
+	"context"
+	"github.com/arya-analytics/cesium"
+	"github.com/arya-analytics/delta/pkg/distribution/channel"
+	"github.com/arya-analytics/delta/pkg/distribution/segment/core"
+	"github.com/arya-analytics/delta/pkg/distribution/segment/stat"
+	"github.com/arya-analytics/x/confluence"
+	"github.com/cockroachdb/errors"
+)
+
+// execStage executes a Stage against the local cesium.DB, calling send with each
+// Response produced.
+func execStage(
+	ctx context.Context,
+	db cesium.DB,
+	svc *channel.Service,
+	stage Stage,
+	send func(Response) error,
+) error {
+	var chs []channel.Channel
+	if err := svc.NewRetrieve().WhereKeys(stage.Keys...).Entries(&chs).Exec(ctx); err != nil {
+		return errors.Wrap(err, "[segment.plan] - failed to retrieve channels")
+	}
+	var (
+		channels   = make(map[channel.Key]channel.Channel, len(chs))
+		aggregates = len(stage.Operators) > 0 &&
+			stage.Operators[len(stage.Operators)-1].Type == AggregateOperator
+		ops     = stage.Operators
+		partial = make(map[channel.Key]stat.Statistics, len(stage.Keys))
+	)
+	for _, ch := range chs {
+		channels[ch.Key()] = ch
+	}
+	if aggregates {
+		ops = ops[:len(ops)-1]
+	}
+
+	if err := core.Exhaust(db, stage.Range, stage.Keys, func(segments []core.Segment) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		for _, op := range ops {
+			var err error
+			if segments, err = op.apply(channels, segments); err != nil {
+				return err
+			}
+		}
+		if !aggregates {
+			if len(segments) == 0 {
+				return nil
+			}
+			return send(Response{Segments: segments})
+		}
+		for _, seg := range segments {
+			s, err := stat.OfSegment(channels[seg.ChannelKey], stage.Range, seg.Segment)
+			if err != nil {
+				return err
+			}
+			partial[seg.ChannelKey] = stat.Merge(partial[seg.ChannelKey], s)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if !aggregates {
+		return nil
+	}
+	res := Response{Statistics: make([]stat.Statistics, 0, len(stage.Keys))}
+	for _, key := range stage.Keys {
+		s, ok := partial[key]
+		if !ok {
+			s = stat.Statistics{ChannelKey: key, DataType: channels[key].Cesium.DataType}
+		}
+		res.Statistics = append(res.Statistics, s)
+	}
+	return send(res)
+}
+
+// localStage is a confluence.Source that executes a Stage on the host node and
+// emits its results.
+type localStage struct {
+	db    cesium.DB
+	svc   *channel.Service
+	stage Stage
+	confluence.AbstractUnarySource[Response]
+	confluence.EmptyFlow
+}
+
+func newLocalStage(db cesium.DB, svc *channel.Service, stage Stage) *localStage {
+	return &localStage{db: db, svc: svc, stage: stage}
+}
+
+// run executes the Stage, closing the outlet of the source when done. run should
+// be called after the pipeline the localStage belongs to begins flowing.
+func (ls *localStage) run(ctx context.Context) {
+	defer ls.Out.Close()
+	if err := execStage(ctx, ls.db, ls.svc, ls.stage, func(res Response) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case ls.Out.Inlet() <- res:
+			return nil
+		}
+	}); err != nil {
+		select {
+		case <-ctx.Done():
+		case ls.Out.Inlet() <- Response{Error: err}:
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package plan
+
+import (
+	"fmt"
+	"github.com/arya-analytics/cesium"
+	"github.com/arya-analytics/delta/pkg/distribution/channel"
+	"github.com/arya-analytics/delta/pkg/distribution/segment/core"
+	"github.com/arya-analytics/x/telem"
+	"github.com/cockroachdb/errors"
+)
+
+type OperatorType uint8
+
+const (
+	// FilterOperator keeps only the samples whose value lies within [Lower, Upper].
+	// Segments are split into contiguous runs of matching samples.
+	FilterOperator OperatorType = iota + 1
+	// TransformOperator applies the linear transformation v*Scale + Offset to every
+	// sample.
+	TransformOperator
+	// AggregateOperator computes stat.Statistics for every channel. When placed on a
+	// leaseholder node, it computes partial statistics that are merged by a
+	// MergeOperator on the coordinator.
+	AggregateOperator
+	// MergeOperator merges partial stat.Statistics computed on leaseholder nodes.
+	// MergeOperator is only ever placed by the Planner.
+	MergeOperator
+)
+
+// Operator is a single step in a query plan. Operators are plain data so that they
+// can be shipped to the leaseholder nodes that execute them.
+type Operator struct {
+	Type OperatorType
+	// Lower is the inclusive lower bound for a FilterOperator.
+	Lower float64
+	// Upper is the inclusive upper bound for a FilterOperator.
+	Upper float64
+	// Scale is the multiplier for a TransformOperator.
+	Scale float64
+	// Offset is the offset for a TransformOperator.
+	Offset float64
+}
+
+// Filter returns an Operator that keeps samples whose value lies within
+// [lower, upper].
+func Filter(lower, upper float64) Operator {
+	return Operator{Type: FilterOperator, Lower: lower, Upper: upper}
+}
+
+// Transform returns an Operator that applies v*scale + offset to every sample.
+func Transform(scale, offset float64) Operator {
+	return Operator{Type: TransformOperator, Scale: scale, Offset: offset}
+}
+
+// Aggregate returns an Operator that reduces the data for each channel to a set
+// of stat.Statistics. Aggregate must be the last Operator in a Query.
+func Aggregate() Operator { return Operator{Type: AggregateOperator} }
+
+// String implements fmt.Stringer.
+func (o Operator) String() string {
+	switch o.Type {
+	case FilterOperator:
+		return fmt.Sprintf("filter [%v, %v]", o.Lower, o.Upper)
+	case TransformOperator:
+		return fmt.Sprintf("transform (v * %v + %v)", o.Scale, o.Offset)
+	case AggregateOperator:
+		return "aggregate"
+	case MergeOperator:
+		return "merge"
+	default:
+		return "unknown"
+	}
+}
+
+// Distributable returns true if the Operator can be executed on the leaseholder
+// node for a channel.
+func (o Operator) Distributable() bool { return o.Type != MergeOperator }
+
+func (o Operator) apply(
+	channels map[channel.Key]channel.Channel,
+	segments []core.Segment,
+) ([]core.Segment, error) {
+	switch o.Type {
+	case FilterOperator:
+		return mapSegments(channels, segments, o.filter)
+	case TransformOperator:
+		return mapSegments(channels, segments, o.transform)
+	default:
+		return nil, errors.Newf("[segment.plan] - operator %s can't be applied to segments", o)
+	}
+}
+
+func mapSegments(
+	channels map[channel.Key]channel.Channel,
+	segments []core.Segment,
+	f func(ch channel.Channel, seg core.Segment) ([]core.Segment, error),
+) ([]core.Segment, error) {
+	out := make([]core.Segment, 0, len(segments))
+	for _, seg := range segments {
+		ch, ok := channels[seg.ChannelKey]
+		if !ok {
+			return nil, errors.Newf("[segment.plan] - channel %s not found", seg.ChannelKey)
+		}
+		mapped, err := f(ch, seg)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, mapped...)
+	}
+	return out, nil
+}
+
+func (o Operator) filter(ch channel.Channel, seg core.Segment) ([]core.Segment, error) {
+	values, err := core.DecodeSamples(ch.SampleType, seg.Segment.Data)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		density = int(ch.Cesium.DataType)
+		period  = core.SamplePeriod(ch.Cesium.DataRate)
+		out     []core.Segment
+		start   = -1
+	)
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		out = append(out, core.Segment{
+			ChannelKey: seg.ChannelKey,
+			Segment: cesium.Segment{
+				ChannelKey: seg.Segment.ChannelKey,
+				Start:      seg.Segment.Start + telem.TimeStamp(telem.TimeSpan(start)*period),
+				Data:       seg.Segment.Data[start*density : end*density],
+			},
+		})
+		start = -1
+	}
+	for i, v := range values {
+		if v >= o.Lower && v <= o.Upper {
+			if start < 0 {
+				start = i
+			}
+		} else {
+			flush(i)
+		}
+	}
+	flush(len(values))
+	return out, nil
+}
+
+func (o Operator) transform(ch channel.Channel, seg core.Segment) ([]core.Segment, error) {
+	values, err := core.DecodeSamples(ch.SampleType, seg.Segment.Data)
+	if err != nil {
+		return nil, err
+	}
+	for i, v := range values {
+		values[i] = v*o.Scale + o.Offset
+	}
+	seg.Segment.Data, err = core.EncodeSamples(ch.SampleType, values)
+	return []core.Segment{seg}, err
+}
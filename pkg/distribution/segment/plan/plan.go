@@ -0,0 +1,160 @@
+package plan
+
+import (
+	"fmt"
+	"github.com/arya-analytics/delta/pkg/distribution/channel"
+	"github.com/arya-analytics/delta/pkg/distribution/node"
+	"github.com/arya-analytics/delta/pkg/distribution/proxy"
+	"github.com/arya-analytics/x/telem"
+	"github.com/cockroachdb/errors"
+	"sort"
+	"strings"
+)
+
+// Query is a logical description of a segment retrieval. Operators are applied in
+// order to the segments of every channel in Keys over Range.
+type Query struct {
+	Keys      channel.Keys
+	Range     telem.TimeRange
+	Operators []Operator
+}
+
+// Aggregates returns true if the Query reduces its results to statistics.
+func (q Query) Aggregates() bool {
+	return len(q.Operators) > 0 && q.Operators[len(q.Operators)-1].Type == AggregateOperator
+}
+
+// Validate validates the Query.
+func (q Query) Validate() error {
+	if len(q.Keys) == 0 {
+		return errors.New("[segment.plan] - no channels provided")
+	}
+	for i, op := range q.Operators {
+		switch op.Type {
+		case FilterOperator:
+			if op.Lower > op.Upper {
+				return errors.Newf("[segment.plan] - filter lower bound exceeds upper bound")
+			}
+		case TransformOperator:
+		case AggregateOperator:
+			if i != len(q.Operators)-1 {
+				return errors.New("[segment.plan] - aggregate must be the last operator")
+			}
+		default:
+			return errors.Newf("[segment.plan] - operator %s is not allowed in a query", op)
+		}
+	}
+	return nil
+}
+
+// Stage is a set of Operators executed on a single node. A Stage on a leaseholder
+// node reads segments for Keys from its cesium.DB before applying Operators.
+type Stage struct {
+	Node      node.ID
+	Keys      channel.Keys
+	Range     telem.TimeRange
+	Operators []Operator
+}
+
+// Plan is a physical plan for a Query. Nodes holds one Stage for each leaseholder
+// node, and Coordinator holds the Operators applied to the combined results of all
+// node stages on the node that executes the Plan.
+type Plan struct {
+	Query       Query
+	Host        node.ID
+	Nodes       []Stage
+	Coordinator []Operator
+}
+
+// Explain returns a human-readable description of the Plan for debugging.
+func (p Plan) Explain() string {
+	b := new(strings.Builder)
+	_, _ = fmt.Fprintf(
+		b,
+		"plan (coordinator: node %v, range: [%v, %v))\n",
+		p.Host,
+		p.Query.Range.Start,
+		p.Query.Range.End,
+	)
+	for _, stage := range p.Nodes {
+		locality := "remote"
+		if stage.Node == p.Host {
+			locality = "local"
+		}
+		_, _ = fmt.Fprintf(
+			b,
+			"├── node %v (%s): %s\n",
+			stage.Node,
+			locality,
+			explainOperators(fmt.Sprintf("retrieve %d channels", len(stage.Keys)), stage.Operators),
+		)
+	}
+	_, _ = fmt.Fprintf(b, "└── coordinator: %s\n", explainOperators("collect", p.Coordinator))
+	return b.String()
+}
+
+func explainOperators(source string, ops []Operator) string {
+	steps := []string{source}
+	for _, op := range ops {
+		steps = append(steps, op.String())
+	}
+	return strings.Join(steps, " → ")
+}
+
+// Planner turns a Query into a Plan.
+type Planner struct {
+	// Host is the ID of the node that executes the Plan.
+	Host node.ID
+	// PushDown sets whether Operators are placed on the leaseholder nodes for each
+	// channel. If false, leaseholder nodes only serve raw segments and all Operators
+	// are executed on the coordinator.
+	PushDown bool
+}
+
+// Plan builds a Plan for the given Query.
+func (p Planner) Plan(q Query) (Plan, error) {
+	if err := q.Validate(); err != nil {
+		return Plan{}, err
+	}
+	plan := Plan{Query: q, Host: p.Host}
+	nodeOps, coordinatorOps := p.place(q)
+	batch := proxy.NewBatchFactory[channel.Key](p.Host).Batch(q.Keys)
+	if len(batch.Local) > 0 {
+		plan.Nodes = append(plan.Nodes, Stage{
+			Node:      p.Host,
+			Keys:      batch.Local,
+			Range:     q.Range,
+			Operators: nodeOps,
+		})
+	}
+	remote := make([]Stage, 0, len(batch.Remote))
+	for nodeID, keys := range batch.Remote {
+		remote = append(remote, Stage{
+			Node:      nodeID,
+			Keys:      keys,
+			Range:     q.Range,
+			Operators: nodeOps,
+		})
+	}
+	// Keep plans deterministic so Explain output is stable.
+	sort.Slice(remote, func(i, j int) bool { return remote[i].Node < remote[j].Node })
+	plan.Nodes = append(plan.Nodes, remote...)
+	plan.Coordinator = coordinatorOps
+	return plan, nil
+}
+
+func (p Planner) place(q Query) (nodeOps []Operator, coordinatorOps []Operator) {
+	if !p.PushDown {
+		return nil, q.Operators
+	}
+	for i, op := range q.Operators {
+		if !op.Distributable() {
+			return nodeOps, q.Operators[i:]
+		}
+		nodeOps = append(nodeOps, op)
+	}
+	if q.Aggregates() {
+		coordinatorOps = append(coordinatorOps, Operator{Type: MergeOperator})
+	}
+	return nodeOps, coordinatorOps
+}
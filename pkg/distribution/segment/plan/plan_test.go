@@ -0,0 +1,78 @@
+package plan_test
+
+import (
+	"github.com/arya-analytics/delta/pkg/distribution/channel"
+	"github.com/arya-analytics/delta/pkg/distribution/segment/plan"
+	"github.com/arya-analytics/x/telem"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Planner", func() {
+	var q plan.Query
+	BeforeEach(func() {
+		q = plan.Query{
+			Keys:  channel.Keys{channel.NewKey(1, 1), channel.NewKey(2, 1), channel.NewKey(3, 1)},
+			Range: telem.TimeRangeMax,
+			Operators: []plan.Operator{
+				plan.Filter(0, 10),
+				plan.Transform(2, 1),
+				plan.Aggregate(),
+			},
+		}
+	})
+	Describe("PushDown", func() {
+		It("Should place operators on the leaseholder nodes", func() {
+			p, err := plan.Planner{Host: 1, PushDown: true}.Plan(q)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(p.Nodes).To(HaveLen(3))
+			Expect(p.Nodes[0].Node).To(BeEquivalentTo(1))
+			Expect(p.Nodes[1].Node).To(BeEquivalentTo(2))
+			Expect(p.Nodes[2].Node).To(BeEquivalentTo(3))
+			for _, stage := range p.Nodes {
+				Expect(stage.Keys).To(HaveLen(1))
+				Expect(stage.Operators).To(Equal(q.Operators))
+			}
+			Expect(p.Coordinator).To(HaveLen(1))
+			Expect(p.Coordinator[0].Type).To(Equal(plan.MergeOperator))
+		})
+		It("Should not add a merge operator when the query doesn't aggregate", func() {
+			q.Operators = q.Operators[:2]
+			p, err := plan.Planner{Host: 1, PushDown: true}.Plan(q)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(p.Coordinator).To(BeEmpty())
+		})
+	})
+	Describe("No PushDown", func() {
+		It("Should place all operators on the coordinator", func() {
+			p, err := plan.Planner{Host: 1}.Plan(q)
+			Expect(err).ToNot(HaveOccurred())
+			for _, stage := range p.Nodes {
+				Expect(stage.Operators).To(BeEmpty())
+			}
+			Expect(p.Coordinator).To(Equal(q.Operators))
+		})
+	})
+	Describe("Validation", func() {
+		It("Should return an error if aggregate is not the last operator", func() {
+			q.Operators = []plan.Operator{plan.Aggregate(), plan.Filter(0, 1)}
+			_, err := plan.Planner{Host: 1}.Plan(q)
+			Expect(err).To(HaveOccurred())
+		})
+		It("Should return an error if no channels are provided", func() {
+			q.Keys = nil
+			_, err := plan.Planner{Host: 1}.Plan(q)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Describe("Explain", func() {
+		It("Should describe the placement of each operator", func() {
+			p, err := plan.Planner{Host: 1, PushDown: true}.Plan(q)
+			Expect(err).ToNot(HaveOccurred())
+			explain := p.Explain()
+			Expect(explain).To(ContainSubstring("node 1 (local): retrieve 1 channels → filter [0, 10] → transform (v * 2 + 1) → aggregate"))
+			Expect(explain).To(ContainSubstring("node 2 (remote)"))
+			Expect(explain).To(ContainSubstring("coordinator: collect → merge"))
+		})
+	})
+})
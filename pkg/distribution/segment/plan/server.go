@@ -0,0 +1,55 @@
+package plan
+
+import (
+	"context"
+	"github.com/arya-analytics/cesium"
+	"github.com/arya-analytics/delta/pkg/distribution/channel"
+	"github.com/arya-analytics/delta/pkg/distribution/node"
+	"github.com/cockroachdb/errors"
+)
+
+type server struct {
+	host    node.ID
+	db      cesium.DB
+	channel *channel.Service
+}
+
+func NewServer(
+	db cesium.DB,
+	channel *channel.Service,
+	host node.ID,
+	transport Transport,
+) *server {
+	sf := &server{db: db, channel: channel, host: host}
+	transport.Handle(sf.Handle)
+	return sf
+}
+
+// Handle handles incoming stage execution requests from the transport. The stream
+// is closed once the stage has been executed.
+func (sf *server) Handle(ctx context.Context, server Server) error {
+	req, err := server.Receive()
+	if err != nil {
+		return err
+	}
+	if req.Stage.Node != sf.host {
+		return errors.Newf(
+			"[segment.plan] - stage for node %v sent to node %v",
+			req.Stage.Node,
+			sf.host,
+		)
+	}
+	for _, key := range req.Stage.Keys {
+		if key.NodeID() != sf.host {
+			return errors.Newf(
+				"[segment.plan] - node %v is not the leaseholder for channel %v",
+				sf.host,
+				key,
+			)
+		}
+	}
+	if err := execStage(ctx, sf.db, sf.channel, req.Stage, server.Send); err != nil {
+		return server.Send(Response{Error: err})
+	}
+	return nil
+}
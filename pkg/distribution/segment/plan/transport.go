@@ -0,0 +1,26 @@
+package plan
+
+import (
+	"github.com/arya-analytics/delta/pkg/distribution/segment/core"
+	"github.com/arya-analytics/delta/pkg/distribution/segment/stat"
+	"github.com/arya-analytics/x/transport"
+)
+
+// Request asks a leaseholder node to execute a Stage.
+type Request struct {
+	Stage Stage
+}
+
+// Response carries the results of a Stage. Stages that end in an AggregateOperator
+// emit a single Response containing Statistics. All other stages emit Segments.
+type Response struct {
+	Segments   []core.Segment
+	Statistics []stat.Statistics
+	Error      error
+}
+
+type (
+	Server    = transport.StreamServer[Request, Response]
+	Client    = transport.StreamClient[Request, Response]
+	Transport = transport.Stream[Request, Response]
+)
@@ -3,11 +3,15 @@ package segment
 import (
 	"github.com/arya-analytics/delta/pkg/distribution/segment/core"
 	"github.com/arya-analytics/delta/pkg/distribution/segment/iterator"
+	"github.com/arya-analytics/delta/pkg/distribution/segment/stat"
 	"github.com/arya-analytics/delta/pkg/distribution/segment/writer"
 )
 
 type (
-	Segment  = core.Segment
-	Iterator = iterator.Iterator
-	Writer   = writer.Writer
+	Segment    = core.Segment
+	Iterator   = iterator.Iterator
+	Writer     = writer.Writer
+	Statistics = stat.Statistics
+	// FlowControl bounds the memory used by an Iterator.
+	FlowControl = iterator.FlowControl
 )
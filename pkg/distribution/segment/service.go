@@ -5,7 +5,10 @@ import (
 	"github.com/arya-analytics/aspen"
 	"github.com/arya-analytics/cesium"
 	"github.com/arya-analytics/delta/pkg/distribution/channel"
+	"github.com/arya-analytics/delta/pkg/distribution/segment/compress"
 	"github.com/arya-analytics/delta/pkg/distribution/segment/iterator"
+	"github.com/arya-analytics/delta/pkg/distribution/segment/plan"
+	"github.com/arya-analytics/delta/pkg/distribution/segment/stat"
 	"github.com/arya-analytics/delta/pkg/distribution/segment/writer"
 	"github.com/arya-analytics/x/query"
 	"github.com/arya-analytics/x/telem"
@@ -32,6 +35,8 @@ func New(
 	}
 	iterator.NewServer(db, resolver.HostID(), transport.Iterator())
 	writer.NewServer(db, resolver.HostID(), transport.Writer())
+	stat.NewServer(db, channel, resolver.HostID(), transport.Stat())
+	plan.NewServer(db, channel, resolver.HostID(), transport.Plan())
 	return s
 }
 
@@ -53,6 +58,13 @@ func (c Create) WhereChannels(keys ...channel.Key) Create {
 	return c
 }
 
+// WithCompression sets the algorithm used to compress segment data sent to remote
+// nodes.
+func (c Create) WithCompression(alg compress.Algorithm) Create {
+	setCompression(c, alg)
+	return c
+}
+
 func (c Create) Write(ctx context.Context) (Writer, error) {
 	return writer.New(
 		ctx,
@@ -61,6 +73,7 @@ func (c Create) Write(ctx context.Context) (Writer, error) {
 		c.svc.resolver,
 		c.svc.transport.Writer(),
 		getKeys(c),
+		writer.WithCompression(getCompression(c)),
 	)
 }
 
@@ -83,6 +96,19 @@ func (r Retrieve) WhereTimeRange(rng telem.TimeRange) Retrieve {
 	return r
 }
 
+// WithCompression sets the algorithm remote nodes use to compress segment data
+// before sending it to the iterator.
+func (r Retrieve) WithCompression(alg compress.Algorithm) Retrieve {
+	setCompression(r, alg)
+	return r
+}
+
+// WithFlowControl bounds the amount of segment data the iterator holds in memory.
+func (r Retrieve) WithFlowControl(fc iterator.FlowControl) Retrieve {
+	setFlowControl(r, fc)
+	return r
+}
+
 func (r Retrieve) Iterate(ctx context.Context) (Iterator, error) {
 	tr, err := telem.GetTimeRange(r)
 	if err != nil {
@@ -96,6 +122,74 @@ func (r Retrieve) Iterate(ctx context.Context) (Iterator, error) {
 		r.svc.transport.Iterator(),
 		tr,
 		getKeys(r),
+		iterator.WithCompression(getCompression(r)),
+		iterator.WithFlowControl(getFlowControl(r)),
+	)
+}
+
+// Stats computes summary statistics for each channel over the query's time range
+// without retrieving the raw segment data. Statistics are returned in the same
+// order as the channel keys.
+func (r Retrieve) Stats(ctx context.Context) ([]Statistics, error) {
+	tr, err := telem.GetTimeRange(r)
+	if err != nil {
+		tr = telem.TimeRangeMax
+	}
+	return stat.Retrieve(
+		ctx,
+		r.svc.db,
+		r.svc.channel,
+		r.svc.resolver,
+		r.svc.transport.Stat(),
+		tr,
+		getKeys(r),
+	)
+}
+
+// WithOperators sets the operators (filters, transforms, and aggregations) to apply
+// to the retrieved data. Queries with operators are executed using Exec.
+func (r Retrieve) WithOperators(ops ...plan.Operator) Retrieve {
+	setOperators(r, ops)
+	return r
+}
+
+// Plan builds a physical plan for the query. Operators are pushed down to the
+// leaseholder nodes for each channel wherever possible.
+func (r Retrieve) Plan() (plan.Plan, error) {
+	tr, err := telem.GetTimeRange(r)
+	if err != nil {
+		tr = telem.TimeRangeMax
+	}
+	return plan.Planner{Host: r.svc.resolver.HostID(), PushDown: true}.Plan(plan.Query{
+		Keys:      getKeys(r),
+		Range:     tr,
+		Operators: getOperators(r),
+	})
+}
+
+// Explain returns a human-readable description of the query's physical plan.
+func (r Retrieve) Explain() (string, error) {
+	p, err := r.Plan()
+	if err != nil {
+		return "", err
+	}
+	return p.Explain(), nil
+}
+
+// Exec plans and executes the query, returning the resulting segments or, if the
+// query aggregates, statistics.
+func (r Retrieve) Exec(ctx context.Context) (plan.Result, error) {
+	p, err := r.Plan()
+	if err != nil {
+		return plan.Result{}, err
+	}
+	return plan.Exec(
+		ctx,
+		p,
+		r.svc.db,
+		r.svc.channel,
+		r.svc.resolver,
+		r.svc.transport.Plan(),
 	)
 }
 
@@ -106,3 +200,42 @@ const keysKey = "keys"
 func setKeys(q query.Query, keys channel.Keys) { q.Set(keysKey, keys) }
 
 func getKeys(q query.Query) channel.Keys { return q.GetRequired(keysKey).(channel.Keys) }
+
+// |||||| OPERATORS ||||||
+
+const operatorsKey = "operators"
+
+func setOperators(q query.Query, ops []plan.Operator) { q.Set(operatorsKey, ops) }
+
+func getOperators(q query.Query) []plan.Operator {
+	if v, ok := q.Get(operatorsKey); ok {
+		return v.([]plan.Operator)
+	}
+	return nil
+}
+
+// |||||| COMPRESSION ||||||
+
+const compressionKey = "compression"
+
+func setCompression(q query.Query, alg compress.Algorithm) { q.Set(compressionKey, alg) }
+
+func getCompression(q query.Query) compress.Algorithm {
+	if v, ok := q.Get(compressionKey); ok {
+		return v.(compress.Algorithm)
+	}
+	return compress.None
+}
+
+// |||||| FLOW CONTROL ||||||
+
+const flowControlKey = "flowControl"
+
+func setFlowControl(q query.Query, fc iterator.FlowControl) { q.Set(flowControlKey, fc) }
+
+func getFlowControl(q query.Query) iterator.FlowControl {
+	if v, ok := q.Get(flowControlKey); ok {
+		return v.(iterator.FlowControl)
+	}
+	return iterator.FlowControl{}
+}
@@ -0,0 +1,131 @@
+package stat
+
+import (
+	"context"
+	"github.com/arya-analytics/cesium"
+	"github.com/arya-analytics/delta/pkg/distribution/channel"
+	"github.com/arya-analytics/delta/pkg/distribution/segment/core"
+	"github.com/arya-analytics/x/telem"
+	"github.com/cockroachdb/errors"
+)
+
+// compute computes Statistics for the channels with the given keys by iterating
+// through their segments in the local cesium.DB. All keys must be leased to the
+// host node.
+func compute(
+	ctx context.Context,
+	db cesium.DB,
+	svc *channel.Service,
+	rng telem.TimeRange,
+	keys channel.Keys,
+) ([]Statistics, error) {
+	var channels []channel.Channel
+	if err := svc.NewRetrieve().WhereKeys(keys...).Entries(&channels).Exec(ctx); err != nil {
+		return nil, errors.Wrap(err, "[segment.stat] - failed to retrieve channels")
+	}
+	for _, ch := range channels {
+		if !core.Numeric(ch.SampleType) {
+			return nil, errors.Wrapf(
+				UnsupportedDataType,
+				"[segment.stat] - channel %v has sample type %v",
+				ch.Key(),
+				ch.SampleType,
+			)
+		}
+	}
+	acc := newAccumulator(rng, channels)
+	err := core.Exhaust(db, rng, keys, acc.accumulate)
+	return acc.statistics(keys), err
+}
+
+type accumulator struct {
+	rng      telem.TimeRange
+	channels map[channel.Key]channel.Channel
+	stats    map[channel.Key]Statistics
+}
+
+func newAccumulator(rng telem.TimeRange, channels []channel.Channel) *accumulator {
+	acc := &accumulator{
+		rng:      rng,
+		channels: make(map[channel.Key]channel.Channel, len(channels)),
+		stats:    make(map[channel.Key]Statistics, len(channels)),
+	}
+	for _, ch := range channels {
+		acc.channels[ch.Key()] = ch
+	}
+	return acc
+}
+
+func (a *accumulator) accumulate(segments []core.Segment) error {
+	for _, seg := range segments {
+		ch, ok := a.channels[seg.ChannelKey]
+		if !ok {
+			return errors.Newf(
+				"[segment.stat] - received segment for unknown channel %v",
+				seg.ChannelKey,
+			)
+		}
+		s, err := OfSegment(ch, a.rng, seg.Segment)
+		if err != nil {
+			return err
+		}
+		a.stats[seg.ChannelKey] = Merge(a.stats[seg.ChannelKey], s)
+	}
+	return nil
+}
+
+// OfSegment computes Statistics for the samples in the segment that fall within the
+// given time range. ch must be the Channel the segment belongs to. Returns
+// UnsupportedDataType if the channel's sample type is not core.Numeric.
+func OfSegment(ch channel.Channel, rng telem.TimeRange, seg cesium.Segment) (Statistics, error) {
+	s := Statistics{ChannelKey: ch.Key(), DataType: ch.Cesium.DataType}
+	values, err := core.DecodeSamples(ch.SampleType, seg.Data)
+	if err != nil {
+		return s, errors.Wrapf(
+			UnsupportedDataType,
+			"[segment.stat] - channel %v has sample type %v",
+			ch.Key(),
+			ch.SampleType,
+		)
+	}
+	if ch.Cesium.DataRate <= 0 {
+		return s, nil
+	}
+	density := int(ch.Cesium.DataType)
+	period := core.SamplePeriod(ch.Cesium.DataRate)
+	for i := 0; i+density <= len(seg.Data); i += density {
+		ts := seg.Start + telem.TimeStamp(telem.TimeSpan(i/density)*period)
+		if ts < rng.Start || ts >= rng.End {
+			continue
+		}
+		if s.Count == 0 || ts < s.First {
+			s.First = ts
+		}
+		if s.Count == 0 || ts > s.Last {
+			s.Last = ts
+		}
+		v := values[i/density]
+		if s.Count == 0 || v < s.Min {
+			s.Min = v
+		}
+		if s.Count == 0 || v > s.Max {
+			s.Max = v
+		}
+		s.Sum += v
+		s.Count++
+		s.Bytes += int64(density)
+	}
+	return s, nil
+}
+
+func (a *accumulator) statistics(keys channel.Keys) []Statistics {
+	stats := make([]Statistics, len(keys))
+	for i, key := range keys {
+		s, ok := a.stats[key]
+		if !ok {
+			s = Statistics{ChannelKey: key, DataType: a.channels[key].Cesium.DataType}
+		}
+		stats[i] = s
+	}
+	return stats
+}
@@ -0,0 +1,41 @@
+package stat
+
+import (
+	"context"
+	"github.com/arya-analytics/cesium"
+	"github.com/arya-analytics/delta/pkg/distribution/channel"
+	"github.com/arya-analytics/delta/pkg/distribution/node"
+	"github.com/cockroachdb/errors"
+)
+
+type server struct {
+	host    node.ID
+	db      cesium.DB
+	channel *channel.Service
+}
+
+func NewServer(
+	db cesium.DB,
+	channel *channel.Service,
+	host node.ID,
+	transport Transport,
+) *server {
+	sf := &server{db: db, channel: channel, host: host}
+	transport.Handle(sf.Handle)
+	return sf
+}
+
+// Handle handles incoming statistics requests from the transport.
+func (sf *server) Handle(ctx context.Context, req Request) (Response, error) {
+	for _, key := range req.Keys {
+		if key.NodeID() != sf.host {
+			return Response{}, errors.Newf(
+				"[segment.stat] - node %v is not the leaseholder for channel %v",
+				sf.host,
+				key,
+			)
+		}
+	}
+	stats, err := compute(ctx, sf.db, sf.channel, req.Range, req.Keys)
+	return Response{Statistics: stats}, err
+}
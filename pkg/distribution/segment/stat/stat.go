@@ -0,0 +1,116 @@
+package stat
+
+import (
+	"context"
+	"github.com/arya-analytics/aspen"
+	"github.com/arya-analytics/cesium"
+	"github.com/arya-analytics/delta/pkg/distribution/channel"
+	"github.com/arya-analytics/delta/pkg/distribution/node"
+	"github.com/arya-analytics/delta/pkg/distribution/proxy"
+	"github.com/arya-analytics/delta/pkg/distribution/segment/core"
+	"github.com/arya-analytics/x/telem"
+	"github.com/cockroachdb/errors"
+	"sync"
+)
+
+// Retrieve computes Statistics for the channels with the given keys over the provided
+// time range. Statistics are computed on the leaseholder node for each channel, and
+// partial results are merged on the calling node. The returned Statistics are in the
+// same order as keys.
+func Retrieve(
+	ctx context.Context,
+	db cesium.DB,
+	svc *channel.Service,
+	resolver aspen.HostResolver,
+	tran Transport,
+	rng telem.TimeRange,
+	keys channel.Keys,
+) ([]Statistics, error) {
+	// First we need to check if all the channels exist and are retrievable in the
+	// database.
+	if err := core.ValidateChannelKeys(ctx, svc, keys); err != nil {
+		return nil, err
+	}
+
+	batch := proxy.NewBatchFactory[channel.Key](resolver.HostID()).Batch(keys)
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		partial []Statistics
+		errs    []error
+	)
+
+	collect := func(stats []Statistics, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			errs = append(errs, err)
+			return
+		}
+		partial = append(partial, stats...)
+	}
+
+	// Fan out to the remote leaseholders while we compute the local statistics.
+	for nodeID, remoteKeys := range batch.Remote {
+		wg.Add(1)
+		go func(nodeID node.ID, remoteKeys channel.Keys) {
+			defer wg.Done()
+			collect(retrieveRemote(ctx, tran, resolver, nodeID, rng, remoteKeys))
+		}(nodeID, remoteKeys)
+	}
+
+	if len(batch.Local) > 0 {
+		collect(compute(ctx, db, svc, rng, batch.Local))
+	}
+
+	wg.Wait()
+
+	if len(errs) > 0 {
+		var err error
+		for _, e := range errs {
+			err = errors.CombineErrors(err, e)
+		}
+		return nil, errors.Wrap(err, "[segment.stat] - failed to retrieve statistics")
+	}
+
+	return mergeInOrder(keys, partial), nil
+}
+
+func retrieveRemote(
+	ctx context.Context,
+	tran Transport,
+	resolver aspen.HostResolver,
+	target node.ID,
+	rng telem.TimeRange,
+	keys channel.Keys,
+) ([]Statistics, error) {
+	addr, err := resolver.Resolve(target)
+	if err != nil {
+		return nil, err
+	}
+	res, err := tran.Send(ctx, addr, Request{Keys: keys, Range: rng})
+	return res.Statistics, err
+}
+
+// mergeInOrder merges partial Statistics for the same channel, and returns them in
+// the order of the provided keys.
+func mergeInOrder(keys channel.Keys, partial []Statistics) []Statistics {
+	merged := make(map[channel.Key]Statistics, len(keys))
+	for _, s := range partial {
+		if existing, ok := merged[s.ChannelKey]; ok {
+			merged[s.ChannelKey] = Merge(existing, s)
+		} else {
+			merged[s.ChannelKey] = s
+		}
+	}
+	out := make([]Statistics, len(keys))
+	for i, key := range keys {
+		s, ok := merged[key]
+		if !ok {
+			s = Statistics{ChannelKey: key}
+		}
+		out[i] = s
+	}
+	return out
+}
@@ -0,0 +1,183 @@
+package stat_test
+
+import (
+	"github.com/arya-analytics/cesium"
+	"github.com/arya-analytics/delta/pkg/distribution/channel"
+	"github.com/arya-analytics/delta/pkg/distribution/mock"
+	"github.com/arya-analytics/delta/pkg/distribution/segment/core"
+	"github.com/arya-analytics/delta/pkg/distribution/segment/stat"
+	"github.com/arya-analytics/x/address"
+	"github.com/arya-analytics/x/gorp"
+	"github.com/arya-analytics/x/telem"
+	tmock "github.com/arya-analytics/x/transport/mock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+)
+
+var _ = Describe("Stat", Ordered, func() {
+	var (
+		builder    *mock.StorageBuilder
+		keys       channel.Keys
+		channelSvc *channel.Service
+		retrieve   func(rng telem.TimeRange, keys channel.Keys) ([]stat.Statistics, error)
+	)
+	BeforeAll(func() {
+		log := zap.NewNop()
+		builder = mock.NewStorage()
+		net := tmock.NewNetwork[stat.Request, stat.Response]()
+		channelNet := tmock.NewNetwork[channel.CreateMessage, channel.CreateMessage]()
+
+		node1Addr := address.Address("localhost:0")
+		node2Addr := address.Address("localhost:1")
+
+		store1, err := builder.New(log)
+		Expect(err).ToNot(HaveOccurred())
+		store2, err := builder.New(log)
+		Expect(err).ToNot(HaveOccurred())
+
+		store1ChannelSvc := channel.New(
+			store1.Aspen,
+			gorp.Wrap(store1.Aspen),
+			store1.Cesium,
+			channelNet.RouteUnary(node1Addr),
+		)
+		store2ChannelSvc := channel.New(
+			store2.Aspen,
+			gorp.Wrap(store2.Aspen),
+			store2.Cesium,
+			channelNet.RouteUnary(node2Addr),
+		)
+
+		stat.NewServer(store1.Cesium, store1ChannelSvc, store1.Aspen.HostID(), net.RouteUnary(node1Addr))
+		node2Transport := net.RouteUnary(node2Addr)
+		stat.NewServer(store2.Cesium, store2ChannelSvc, store2.Aspen.HostID(), node2Transport)
+
+		ch1, err := store1ChannelSvc.NewCreate().
+			WithName("SG01").
+			WithDataRate(1 * telem.Hz).
+			WithSampleType(channel.Float64).
+			WithNodeID(1).
+			Exec(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(mock.WriteSegments(ctx, store1.Cesium, cesium.Segment{
+			ChannelKey: ch1.Key().Cesium(),
+			Start:      0,
+			Data:       mock.Float64Data(1, 2, 3, 4, 5, 6, 7, 8, 9, 10),
+		})).To(Succeed())
+
+		ch2, err := store2ChannelSvc.NewCreate().
+			WithName("SG02").
+			WithDataRate(1 * telem.Hz).
+			WithSampleType(channel.Float64).
+			WithNodeID(2).
+			Exec(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(mock.WriteSegments(ctx, store2.Cesium, cesium.Segment{
+			ChannelKey: ch2.Key().Cesium(),
+			Start:      0,
+			Data:       mock.Float64Data(-5, 5),
+		})).To(Succeed())
+
+		keys = channel.Keys{ch1.Key(), ch2.Key()}
+
+		// Wait for the channels to propagate to the second node.
+		Eventually(func() error {
+			return core.ValidateChannelKeys(ctx, store2ChannelSvc, keys)
+		}).Should(Succeed())
+
+		channelSvc = store2ChannelSvc
+		retrieve = func(rng telem.TimeRange, keys channel.Keys) ([]stat.Statistics, error) {
+			return stat.Retrieve(
+				ctx,
+				store2.Cesium,
+				store2ChannelSvc,
+				store2.Aspen,
+				node2Transport,
+				rng,
+				keys,
+			)
+		}
+	})
+	AfterAll(func() { Expect(builder.Close()).To(Succeed()) })
+	It("Should compute statistics for local and remote channels", func() {
+		stats, err := retrieve(telem.TimeRangeMax, keys)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stats).To(HaveLen(2))
+
+		Expect(stats[0].ChannelKey).To(Equal(keys[0]))
+		Expect(stats[0].DataType).To(Equal(telem.Float64))
+		Expect(stats[0].Count).To(Equal(int64(10)))
+		Expect(stats[0].Bytes).To(Equal(int64(80)))
+		Expect(stats[0].First).To(Equal(telem.TimeStamp(0)))
+		Expect(stats[0].Last).To(Equal(telem.TimeStamp(9 * telem.Second)))
+		Expect(stats[0].Min).To(Equal(1.0))
+		Expect(stats[0].Max).To(Equal(10.0))
+		Expect(stats[0].Mean()).To(Equal(5.5))
+
+		Expect(stats[1].ChannelKey).To(Equal(keys[1]))
+		Expect(stats[1].Count).To(Equal(int64(2)))
+		Expect(stats[1].Min).To(Equal(-5.0))
+		Expect(stats[1].Max).To(Equal(5.0))
+		Expect(stats[1].Mean()).To(Equal(0.0))
+	})
+	It("Should only include samples within the time range", func() {
+		stats, err := retrieve(telem.TimeRange{
+			Start: telem.TimeStamp(2 * telem.Second),
+			End:   telem.TimeStamp(5 * telem.Second),
+		}, keys)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stats[0].Count).To(Equal(int64(3)))
+		Expect(stats[0].First).To(Equal(telem.TimeStamp(2 * telem.Second)))
+		Expect(stats[0].Last).To(Equal(telem.TimeStamp(4 * telem.Second)))
+		Expect(stats[0].Min).To(Equal(3.0))
+		Expect(stats[0].Max).To(Equal(5.0))
+		Expect(stats[1].Empty()).To(BeTrue())
+	})
+	It("Should return an error for channels with a non-numeric data type", func() {
+		ch, err := channelSvc.NewCreate().
+			WithName("SG03").
+			WithDataRate(1 * telem.Hz).
+			WithDataType(telem.DataType(2)).
+			WithNodeID(2).
+			Exec(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = retrieve(telem.TimeRangeMax, channel.Keys{ch.Key()})
+		Expect(err).To(MatchError(stat.UnsupportedDataType))
+	})
+	It("Should decode samples according to the channel's sample type", func() {
+		ch, err := channelSvc.NewCreate().
+			WithName("SG04").
+			WithDataRate(1 * telem.Hz).
+			WithSampleType(channel.Int64).
+			WithNodeID(2).
+			Exec(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(mock.WriteSegments(ctx, builder.Stores[2].Cesium, cesium.Segment{
+			ChannelKey: ch.Key().Cesium(),
+			Start:      0,
+			Data:       mock.Int64Data(-3, 1, 8),
+		})).To(Succeed())
+		stats, err := retrieve(telem.TimeRangeMax, channel.Keys{ch.Key()})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stats[0].Count).To(Equal(int64(3)))
+		Expect(stats[0].Min).To(Equal(-3.0))
+		Expect(stats[0].Max).To(Equal(8.0))
+		Expect(stats[0].Mean()).To(Equal(2.0))
+	})
+	Describe("Merge", func() {
+		It("Should merge partial statistics", func() {
+			merged := stat.Merge(
+				stat.Statistics{Count: 2, Bytes: 16, First: 0, Last: 1, Min: 1, Max: 2, Sum: 3},
+				stat.Statistics{Count: 2, Bytes: 16, First: 2, Last: 3, Min: 0, Max: 1, Sum: 1},
+			)
+			Expect(merged.Count).To(Equal(int64(4)))
+			Expect(merged.Bytes).To(Equal(int64(32)))
+			Expect(merged.First).To(Equal(telem.TimeStamp(0)))
+			Expect(merged.Last).To(Equal(telem.TimeStamp(3)))
+			Expect(merged.Min).To(Equal(0.0))
+			Expect(merged.Max).To(Equal(2.0))
+			Expect(merged.Mean()).To(Equal(1.0))
+		})
+	})
+})
@@ -0,0 +1,73 @@
+package stat
+
+import (
+	"github.com/arya-analytics/delta/pkg/distribution/channel"
+	"github.com/arya-analytics/x/telem"
+	"github.com/cockroachdb/errors"
+)
+
+// UnsupportedDataType is returned when statistics are requested for a channel whose
+// sample type is not core.Numeric.
+var UnsupportedDataType = errors.New("[segment.stat] - unsupported data type")
+
+// Statistics is a set of summary statistics for a channel's data over a time range.
+type Statistics struct {
+	// ChannelKey is the key of the channel the statistics were computed for.
+	ChannelKey channel.Key
+	// DataType is the data type of the channel. Statistics can only be computed for
+	// channels whose sample type is core.Numeric.
+	DataType telem.DataType
+	// Count is the number of samples in the range.
+	Count int64
+	// Bytes is the number of bytes occupied by the samples in the range.
+	Bytes int64
+	// First is the timestamp of the first sample in the range.
+	First telem.TimeStamp
+	// Last is the timestamp of the last sample in the range.
+	Last telem.TimeStamp
+	// Min is the smallest sample value in the range.
+	Min float64
+	// Max is the largest sample value in the range.
+	Max float64
+	// Sum is the sum of all sample values in the range. Sum is kept instead of the
+	// mean so that partial results from multiple nodes can be merged without loss.
+	Sum float64
+}
+
+// Mean returns the mean sample value in the range. Returns 0 if the range is empty.
+func (s Statistics) Mean() float64 {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Sum / float64(s.Count)
+}
+
+// Empty returns true if no samples were found in the range.
+func (s Statistics) Empty() bool { return s.Count == 0 }
+
+// Merge merges two sets of Statistics for the same channel.
+func Merge(a, b Statistics) Statistics {
+	if a.Empty() {
+		return b
+	}
+	if b.Empty() {
+		return a
+	}
+	m := a
+	m.Count += b.Count
+	m.Bytes += b.Bytes
+	m.Sum += b.Sum
+	if b.First < m.First {
+		m.First = b.First
+	}
+	if b.Last > m.Last {
+		m.Last = b.Last
+	}
+	if b.Min < m.Min {
+		m.Min = b.Min
+	}
+	if b.Max > m.Max {
+		m.Max = b.Max
+	}
+	return m
+}
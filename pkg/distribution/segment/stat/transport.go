@@ -0,0 +1,18 @@
+package stat
+
+import (
+	"github.com/arya-analytics/delta/pkg/distribution/channel"
+	"github.com/arya-analytics/x/telem"
+	"github.com/arya-analytics/x/transport"
+)
+
+type Request struct {
+	Keys  channel.Keys
+	Range telem.TimeRange
+}
+
+type Response struct {
+	Statistics []Statistics
+}
+
+type Transport = transport.Unary[Request, Response]
@@ -2,10 +2,14 @@ package segment
 
 import (
 	"github.com/arya-analytics/delta/pkg/distribution/segment/iterator"
+	"github.com/arya-analytics/delta/pkg/distribution/segment/plan"
+	"github.com/arya-analytics/delta/pkg/distribution/segment/stat"
 	"github.com/arya-analytics/delta/pkg/distribution/segment/writer"
 )
 
 type Transport interface {
 	Iterator() iterator.Transport
 	Writer() writer.Transport
+	Stat() stat.Transport
+	Plan() plan.Transport
 }
@@ -0,0 +1,41 @@
+package writer
+
+import (
+	"github.com/arya-analytics/delta/pkg/distribution/channel"
+	"github.com/arya-analytics/delta/pkg/distribution/segment/compress"
+	"github.com/arya-analytics/delta/pkg/distribution/segment/core"
+)
+
+// compressingClient compresses the segments of outgoing requests using the
+// algorithm negotiated when the stream was opened.
+type compressingClient struct {
+	Client
+	alg       compress.Algorithm
+	densities map[channel.Key]int
+}
+
+// Send implements transport.StreamSender.
+func (c compressingClient) Send(req Request) error {
+	if c.alg != compress.None && len(req.Segments) > 0 {
+		var err error
+		if req.Segments, err = core.CompressSegments(c.alg, c.densities, req.Segments); err != nil {
+			return err
+		}
+		req.Compression = c.alg
+	}
+	return c.Client.Send(req)
+}
+
+// decompressingServer decompresses the segments of incoming requests.
+type decompressingServer struct{ Server }
+
+// Receive implements transport.StreamReceiver.
+func (s decompressingServer) Receive() (Request, error) {
+	req, err := s.Server.Receive()
+	if err != nil || req.Compression == compress.None {
+		return req, err
+	}
+	req.Segments, err = core.DecompressSegments(req.Compression, req.Segments)
+	req.Compression = compress.None
+	return req, err
+}
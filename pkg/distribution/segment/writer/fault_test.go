@@ -0,0 +1,106 @@
+package writer_test
+
+import (
+	"github.com/arya-analytics/cesium"
+	"github.com/arya-analytics/delta/pkg/distribution/channel"
+	"github.com/arya-analytics/delta/pkg/distribution/mock"
+	"github.com/arya-analytics/delta/pkg/distribution/segment/core"
+	"github.com/arya-analytics/delta/pkg/distribution/segment/writer"
+	"github.com/arya-analytics/x/telem"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+	"time"
+)
+
+var _ = Describe("Fault", func() {
+	var (
+		cluster *mock.ClusterBuilder
+		nodes   []mock.Node
+		key     channel.Key
+		data    = mock.Float64Data(1, 2, 3, 4, 5, 6, 7, 8, 9, 10)
+	)
+	request := func(start telem.TimeStamp) writer.Request {
+		return writer.Request{Segments: []core.Segment{{
+			ChannelKey: key,
+			Segment:    cesium.Segment{ChannelKey: key.Cesium(), Start: start, Data: data},
+		}}}
+	}
+	// closeWriter closes the writer and returns every error it reported.
+	closeWriter := func(w writer.Writer) (errs []error) {
+		close(w.Requests())
+		for res := range w.Responses() {
+			if res.Error != nil {
+				errs = append(errs, res.Error)
+			}
+		}
+		if err := w.Close(); err != nil {
+			errs = append(errs, err)
+		}
+		return errs
+	}
+	written := func() (segments []core.Segment) {
+		Expect(core.Exhaust(
+			nodes[1].Cesium,
+			telem.TimeRangeMax,
+			channel.Keys{key},
+			func(s []core.Segment) error {
+				segments = append(segments, s...)
+				return nil
+			},
+		)).To(Succeed())
+		return segments
+	}
+	BeforeEach(func() {
+		cluster = mock.NewCluster()
+		var err error
+		nodes, err = cluster.NewN(zap.NewNop(), 2)
+		Expect(err).ToNot(HaveOccurred())
+		ch, err := nodes[1].Channel.NewCreate().
+			WithName("SG01").
+			WithDataRate(1 * telem.Hz).
+			WithDataType(telem.Float64).
+			WithNodeID(nodes[1].ID()).
+			Exec(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		key = ch.Key()
+		// Wait for the channel to propagate to the first node.
+		time.Sleep(100 * time.Millisecond)
+	})
+	AfterEach(func() { Expect(cluster.Close()).To(Succeed()) })
+	It("Should write to a remote node over a slow network", func() {
+		cluster.Network.Delay(5 * time.Millisecond)
+		w, err := nodes[0].Segment.NewCreate().WhereChannels(key).Write(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		w.Requests() <- request(0)
+		Expect(closeWriter(w)).To(BeEmpty())
+		segments := written()
+		Expect(segments).To(HaveLen(1))
+		Expect(segments[0].Segment.Data).To(Equal(data))
+	})
+	It("Should discard segments dropped by the network", func() {
+		w, err := nodes[0].Segment.NewCreate().WhereChannels(key).Write(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		cluster.Network.Drop(nodes[0].Address, nodes[1].Address, 1)
+		w.Requests() <- request(0)
+		w.Requests() <- request(telem.TimeStamp(10 * telem.Second))
+		Expect(closeWriter(w)).To(BeEmpty())
+		segments := written()
+		Expect(segments).To(HaveLen(1))
+		Expect(segments[0].Segment.Start).To(Equal(telem.TimeStamp(10 * telem.Second)))
+	})
+	It("Should fail to open a writer when the leaseholder is partitioned", func() {
+		cluster.Partition(nodes[1].ID())
+		_, err := nodes[0].Segment.NewCreate().WhereChannels(key).Write(ctx)
+		Expect(err).To(HaveOccurred())
+	})
+	It("Should return an error when the leaseholder is killed mid-write", func() {
+		w, err := nodes[0].Segment.NewCreate().WhereChannels(key).Write(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		cluster.Kill(nodes[1].ID())
+		Eventually(w.Requests()).Should(BeSent(request(0)))
+		Expect(closeWriter(w)).ToNot(BeEmpty())
+		cluster.Revive(nodes[1].ID())
+		Expect(written()).To(BeEmpty())
+	})
+})
@@ -0,0 +1,24 @@
+package writer
+
+import "github.com/arya-analytics/delta/pkg/distribution/segment/compress"
+
+type Option func(*options)
+
+type options struct {
+	compression compress.Algorithm
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{compression: compress.None}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithCompression sets the algorithm used to compress segment data before sending
+// it to remote nodes. Opening the writer fails if a remote node doesn't support the
+// algorithm.
+func WithCompression(alg compress.Algorithm) Option {
+	return func(o *options) { o.compression = alg }
+}
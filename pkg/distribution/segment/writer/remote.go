@@ -5,6 +5,7 @@ import (
 	"github.com/arya-analytics/delta/pkg/distribution/channel"
 	"github.com/arya-analytics/delta/pkg/distribution/node"
 	"github.com/arya-analytics/delta/pkg/distribution/proxy"
+	"github.com/arya-analytics/delta/pkg/distribution/segment/compress"
 	"github.com/arya-analytics/x/address"
 	"github.com/arya-analytics/x/confluence/transfluence"
 	"github.com/arya-analytics/x/signal"
@@ -15,6 +16,8 @@ func openRemoteWriters(
 	tran Transport,
 	targets map[node.ID][]channel.Key,
 	resolver aspen.HostResolver,
+	compression compress.Algorithm,
+	densities map[channel.Key]int,
 ) (*requestSwitchSender,
 	[]*transfluence.Receiver[Response], error) {
 	receivers := make([]*transfluence.Receiver[Response], 0, len(targets))
@@ -26,7 +29,7 @@ func openRemoteWriters(
 			return sender, receivers, err
 		}
 		addrMap[nodeID] = targetAddr
-		client, err := openRemoteClient(ctx, tran, targetAddr, keys)
+		client, err := openRemoteClient(ctx, tran, targetAddr, keys, compression, densities)
 		if err != nil {
 			return sender, receivers, err
 		}
@@ -41,10 +44,16 @@ func openRemoteClient(
 	tran Transport,
 	target address.Address,
 	keys channel.Keys,
+	compression compress.Algorithm,
+	densities map[channel.Key]int,
 ) (Client, error) {
 	client, err := tran.Stream(ctx, target)
 	if err != nil {
 		return nil, err
 	}
-	return client, client.Send(Request{OpenKeys: keys})
+	return compressingClient{
+		Client:    client,
+		alg:       compression,
+		densities: densities,
+	}, client.Send(Request{OpenKeys: keys, Compression: compression})
 }
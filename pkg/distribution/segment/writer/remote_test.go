@@ -5,6 +5,7 @@ import (
 	"github.com/arya-analytics/cesium/testutil/seg"
 	"github.com/arya-analytics/delta/pkg/distribution/channel"
 	"github.com/arya-analytics/delta/pkg/distribution/mock"
+	"github.com/arya-analytics/delta/pkg/distribution/segment/compress"
 	"github.com/arya-analytics/delta/pkg/distribution/segment/core"
 	"github.com/arya-analytics/delta/pkg/distribution/segment/writer"
 	"github.com/arya-analytics/x/address"
@@ -26,8 +27,9 @@ var _ = Describe("Remote", Ordered, func() {
 		factory   seg.SequentialFactory
 		wrapper   *core.CesiumWrapper
 		keys      channel.Keys
-		newWriter func() (writer.Writer, error)
+		newWriter func(opts ...writer.Option) (writer.Writer, error)
 		channels  []channel.Channel
+		stores    []mock.Store
 	)
 	BeforeAll(func() {
 		log = zap.NewNop()
@@ -107,12 +109,13 @@ var _ = Describe("Remote", Ordered, func() {
 		}}
 
 		keys = channel.Keys{channels[0].Key(), channels[1].Key()}
+		stores = []mock.Store{store1, store2}
 
 		node1Transport = net.RouteStream("", 0)
 
 		time.Sleep(150 * time.Millisecond)
 
-		newWriter = func() (writer.Writer, error) {
+		newWriter = func(opts ...writer.Option) (writer.Writer, error) {
 			return writer.New(
 				ctx,
 				store3.Cesium,
@@ -120,6 +123,7 @@ var _ = Describe("Remote", Ordered, func() {
 				store3.Aspen,
 				node3Transport,
 				keys,
+				opts...,
 			)
 		}
 	})
@@ -142,4 +146,51 @@ var _ = Describe("Remote", Ordered, func() {
 			Expect(w.Close()).To(Succeed())
 		})
 	})
+	Describe("Compression", func() {
+		// The writer opened in BeforeEach isn't used by these specs.
+		BeforeEach(func() {
+			close(w.Requests())
+			for res := range w.Responses() {
+				Expect(res.Error).ToNot(HaveOccurred())
+			}
+			Expect(w.Close()).To(Succeed())
+		})
+		It("Should decompress segments before writing them on remote nodes", func() {
+			cw, err := newWriter(writer.WithCompression(compress.DeltaOfDelta))
+			Expect(err).ToNot(HaveOccurred())
+			start := telem.TimeStamp(telem.Hour)
+			data := mock.Float64Data(1, 2, 3, 4, 5, 6, 7, 8, 9, 10)
+			var segments []core.Segment
+			for _, ch := range channels {
+				segments = append(segments, core.Segment{
+					ChannelKey: ch.Key(),
+					Segment:    cesium.Segment{ChannelKey: ch.Key().Cesium(), Start: start, Data: data},
+				})
+			}
+			cw.Requests() <- writer.Request{Segments: segments}
+			close(cw.Requests())
+			for res := range cw.Responses() {
+				Expect(res.Error).ToNot(HaveOccurred())
+			}
+			Expect(cw.Close()).To(Succeed())
+			for i, ch := range channels {
+				var written []core.Segment
+				Expect(core.Exhaust(
+					stores[i].Cesium,
+					telem.TimeRange{Start: start, End: telem.TimeRangeMax.End},
+					channel.Keys{ch.Key()},
+					func(segments []core.Segment) error {
+						written = append(written, segments...)
+						return nil
+					},
+				)).To(Succeed())
+				Expect(written).To(HaveLen(1))
+				Expect(written[0].Segment.Data).To(Equal(data))
+			}
+		})
+		It("Should fail to open a writer with an unsupported algorithm", func() {
+			_, err := newWriter(writer.WithCompression(compress.Algorithm(42)))
+			Expect(err).To(MatchError(compress.Unsupported))
+		})
+	})
 })
@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/arya-analytics/cesium"
 	"github.com/arya-analytics/delta/pkg/distribution/node"
+	"github.com/arya-analytics/delta/pkg/distribution/segment/compress"
 	"github.com/arya-analytics/x/confluence"
 	"github.com/arya-analytics/x/confluence/plumber"
 	"github.com/arya-analytics/x/confluence/transfluence"
@@ -46,8 +47,15 @@ func (sf *server) Handle(_ctx context.Context, server Server) error {
 	if len(req.OpenKeys) == 0 {
 		return errors.New("[segment.w] - server expected OpenKeys to be defined")
 	}
+	if !compress.Supported(req.Compression) {
+		return errors.Wrapf(
+			compress.Unsupported,
+			"[segment.w] - server can't decompress %s",
+			req.Compression,
+		)
+	}
 
-	receiver := &transfluence.Receiver[Request]{Receiver: server}
+	receiver := &transfluence.Receiver[Request]{Receiver: decompressingServer{Server: server}}
 	sender := &transfluence.Sender[Response]{
 		Sender: transport.SenderEmptyCloser[Response]{StreamSender: server},
 	}
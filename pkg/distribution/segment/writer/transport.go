@@ -2,6 +2,7 @@ package writer
 
 import (
 	"github.com/arya-analytics/delta/pkg/distribution/channel"
+	"github.com/arya-analytics/delta/pkg/distribution/segment/compress"
 	"github.com/arya-analytics/delta/pkg/distribution/segment/core"
 	"github.com/arya-analytics/x/transport"
 )
@@ -9,6 +10,10 @@ import (
 type Request struct {
 	OpenKeys channel.Keys
 	Segments []core.Segment
+	// Compression is the algorithm that the data in Segments is compressed with.
+	// When set on the OpenKeys request, the server verifies that it supports the
+	// algorithm before accepting writes.
+	Compression compress.Algorithm
 }
 
 type Response struct {
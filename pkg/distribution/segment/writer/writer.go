@@ -6,6 +6,7 @@ import (
 	"github.com/arya-analytics/cesium"
 	"github.com/arya-analytics/delta/pkg/distribution/channel"
 	"github.com/arya-analytics/delta/pkg/distribution/proxy"
+	"github.com/arya-analytics/delta/pkg/distribution/segment/compress"
 	"github.com/arya-analytics/delta/pkg/distribution/segment/core"
 	"github.com/arya-analytics/x/address"
 	"github.com/arya-analytics/x/confluence"
@@ -38,7 +39,9 @@ func New(
 	resolver aspen.HostResolver,
 	tran Transport,
 	keys channel.Keys,
+	opts ...Option,
 ) (Writer, error) {
+	o := newOptions(opts)
 	sCtx, cancel := signal.WithCancel(ctx)
 
 	// First we need to check if all the channels exist and are retrievable in the
@@ -59,7 +62,19 @@ func New(
 	)
 
 	if needRemote {
-		sender, receivers, err := openRemoteWriters(sCtx, tran, batch.Remote, resolver)
+		densities, err := retrieveDensities(sCtx, svc, keys, o.compression)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		sender, receivers, err := openRemoteWriters(
+			sCtx,
+			tran,
+			batch.Remote,
+			resolver,
+			o.compression,
+			densities,
+		)
 		if err != nil {
 			cancel()
 			return nil, err
@@ -125,3 +140,28 @@ func New(
 
 	return &writer{responses: output.Outlet(), requests: input.Inlet(), wg: sCtx}, nil
 }
+
+// retrieveDensities returns the density of each channel, which is needed to compress
+// segment data. Returns nil if no compression is used.
+func retrieveDensities(
+	ctx context.Context,
+	svc *channel.Service,
+	keys channel.Keys,
+	compression compress.Algorithm,
+) (map[channel.Key]int, error) {
+	if compression == compress.None {
+		return nil, nil
+	}
+	if !compress.Supported(compression) {
+		return nil, compress.Unsupported
+	}
+	var chs []channel.Channel
+	if err := svc.NewRetrieve().WhereKeys(keys...).Entries(&chs).Exec(ctx); err != nil {
+		return nil, err
+	}
+	densities := make(map[channel.Key]int, len(chs))
+	for _, ch := range chs {
+		densities[ch.Key()] = int(ch.Cesium.DataType)
+	}
+	return densities, nil
+}
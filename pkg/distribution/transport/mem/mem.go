@@ -0,0 +1,190 @@
+// Package mem implements the transports used by the distribution layer in memory.
+// It's intended for running multi-node clusters in a single process, and supports
+// injecting faults (dropped messages, delays, partitions, and killed nodes) so that
+// failure paths can be tested deterministically.
+package mem
+
+import (
+	"github.com/arya-analytics/x/address"
+	"github.com/cockroachdb/errors"
+	"sync"
+	"time"
+)
+
+var (
+	// Unreachable is returned when a message is sent to or from a node that is
+	// killed or on the other side of a partition.
+	Unreachable = errors.New("[transport.mem] - target unreachable")
+	// Dropped is returned when a unary request or response is dropped.
+	Dropped = errors.New("[transport.mem] - message dropped")
+)
+
+// DefaultBuffer is the default number of messages buffered in each direction of a
+// stream.
+const DefaultBuffer = 10
+
+// Network is an in-memory network that nodes communicate over.
+type Network struct {
+	// Buffer is the number of messages buffered in each direction of a stream.
+	Buffer int
+	mu     sync.RWMutex
+	routes map[route]any
+	faults faults
+	// killed is closed and replaced whenever a node is killed, so that open
+	// streams can check whether they've been severed.
+	killed chan struct{}
+}
+
+type route struct {
+	service string
+	host    address.Address
+}
+
+type link struct {
+	from, to address.Address
+}
+
+type faults struct {
+	delay      time.Duration
+	drops      map[link]int
+	dead       map[address.Address]bool
+	partitions []map[address.Address]bool
+}
+
+// NewNetwork creates a new, fault-free Network.
+func NewNetwork() *Network {
+	n := &Network{
+		Buffer: DefaultBuffer,
+		routes: make(map[route]any),
+		killed: make(chan struct{}),
+	}
+	n.Heal()
+	return n
+}
+
+// Delay delays every message sent over the network by d.
+func (n *Network) Delay(d time.Duration) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.faults.delay = d
+}
+
+// Drop drops the next count messages sent from one node to another. Dropped unary
+// requests and responses return Dropped to the caller, while dropped stream
+// messages are silently discarded.
+func (n *Network) Drop(from, to address.Address, count int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.faults.drops[link{from, to}] += count
+}
+
+// Partition splits the network so that nodes in group can only communicate with
+// each other. Nodes outside of any partition can only communicate with other nodes
+// outside of any partition.
+func (n *Network) Partition(group ...address.Address) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	p := make(map[address.Address]bool, len(group))
+	for _, addr := range group {
+		p[addr] = true
+	}
+	n.faults.partitions = append(n.faults.partitions, p)
+	n.severLocked()
+}
+
+// Kill makes the node at addr unreachable and severs all of its open streams.
+func (n *Network) Kill(addr address.Address) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.faults.dead[addr] = true
+	n.severLocked()
+}
+
+// Revive makes a killed node reachable again. Streams severed when the node was
+// killed stay closed.
+func (n *Network) Revive(addr address.Address) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delete(n.faults.dead, addr)
+}
+
+// Heal removes all faults from the network.
+func (n *Network) Heal() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.faults = faults{
+		drops: make(map[link]int),
+		dead:  make(map[address.Address]bool),
+	}
+}
+
+func (n *Network) severLocked() {
+	close(n.killed)
+	n.killed = make(chan struct{})
+}
+
+func (n *Network) register(service string, host address.Address, handler any) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.routes[route{service: service, host: host}] = handler
+}
+
+func (n *Network) handler(service string, target address.Address) (any, error) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	h, ok := n.routes[route{service: service, host: target}]
+	if !ok {
+		return nil, errors.Wrapf(Unreachable, "no %s handler at %s", service, target)
+	}
+	return h, nil
+}
+
+// reachable returns nil if a message can be sent from one node to another.
+func (n *Network) reachable(from, to address.Address) error {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.reachableLocked(from, to)
+}
+
+func (n *Network) reachableLocked(from, to address.Address) error {
+	if n.faults.dead[from] || n.faults.dead[to] {
+		return Unreachable
+	}
+	for _, p := range n.faults.partitions {
+		if p[from] != p[to] {
+			return Unreachable
+		}
+	}
+	return nil
+}
+
+// transmit applies faults to a single message sent from one node to another. It
+// returns dropped = true if the message should be discarded, and an error if the
+// target is unreachable.
+func (n *Network) transmit(from, to address.Address) (dropped bool, err error) {
+	n.mu.Lock()
+	if err := n.reachableLocked(from, to); err != nil {
+		n.mu.Unlock()
+		return false, err
+	}
+	l := link{from, to}
+	if n.faults.drops[l] > 0 {
+		n.faults.drops[l]--
+		n.mu.Unlock()
+		return true, nil
+	}
+	delay := n.faults.delay
+	n.mu.Unlock()
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	return false, nil
+}
+
+// severed returns a channel that is closed the next time a node is killed or the
+// network is partitioned.
+func (n *Network) severed() <-chan struct{} {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.killed
+}
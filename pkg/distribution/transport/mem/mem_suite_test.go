@@ -0,0 +1,18 @@
+package mem_test
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var (
+	ctx = context.Background()
+)
+
+func TestMem(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Mem Suite")
+}
@@ -0,0 +1,113 @@
+package mem_test
+
+import (
+	"context"
+	"github.com/arya-analytics/delta/pkg/distribution/transport/mem"
+	"github.com/arya-analytics/x/address"
+	"github.com/arya-analytics/x/transport"
+	"github.com/cockroachdb/errors"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"io"
+	"time"
+)
+
+const (
+	node1 address.Address = "localhost:0"
+	node2 address.Address = "localhost:1"
+)
+
+func echo(_ context.Context, srv transport.StreamServer[int, int]) error {
+	for {
+		req, err := srv.Receive()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := srv.Send(req); err != nil {
+			return err
+		}
+	}
+}
+
+var _ = Describe("Mem", func() {
+	var net *mem.Network
+	BeforeEach(func() { net = mem.NewNetwork() })
+	Describe("Unary", func() {
+		var client *mem.Unary[int, int]
+		BeforeEach(func() {
+			mem.NewUnary[int, int](net, "double", node2).Handle(
+				func(_ context.Context, req int) (int, error) { return req * 2, nil },
+			)
+			client = mem.NewUnary[int, int](net, "double", node1)
+		})
+		It("Should send a request and receive a response", func() {
+			Expect(client.Send(ctx, node2, 2)).To(Equal(4))
+		})
+		It("Should drop the next n messages on a link", func() {
+			net.Drop(node1, node2, 1)
+			_, err := client.Send(ctx, node2, 2)
+			Expect(err).To(MatchError(mem.Dropped))
+			Expect(client.Send(ctx, node2, 2)).To(Equal(4))
+		})
+		It("Should return an error when the target is killed", func() {
+			net.Kill(node2)
+			_, err := client.Send(ctx, node2, 2)
+			Expect(err).To(MatchError(mem.Unreachable))
+			net.Revive(node2)
+			Expect(client.Send(ctx, node2, 2)).To(Equal(4))
+		})
+		It("Should return an error when the target is partitioned", func() {
+			net.Partition(node1)
+			_, err := client.Send(ctx, node2, 2)
+			Expect(err).To(MatchError(mem.Unreachable))
+			net.Heal()
+			Expect(client.Send(ctx, node2, 2)).To(Equal(4))
+		})
+		It("Should delay messages", func() {
+			net.Delay(10 * time.Millisecond)
+			start := time.Now()
+			Expect(client.Send(ctx, node2, 2)).To(Equal(4))
+			Expect(time.Since(start)).To(BeNumerically(">=", 20*time.Millisecond))
+		})
+	})
+	Describe("Stream", func() {
+		var client transport.StreamClient[int, int]
+		BeforeEach(func() {
+			mem.NewStream[int, int](net, "echo", node2).Handle(echo)
+			var err error
+			client, err = mem.NewStream[int, int](net, "echo", node1).Stream(ctx, node2)
+			Expect(err).ToNot(HaveOccurred())
+		})
+		It("Should exchange messages until the client closes the stream", func() {
+			for i := 0; i < 5; i++ {
+				Expect(client.Send(i)).To(Succeed())
+				Expect(client.Receive()).To(Equal(i))
+			}
+			Expect(client.CloseSend()).To(Succeed())
+			_, err := client.Receive()
+			Expect(err).To(MatchError(io.EOF))
+		})
+		It("Should silently drop messages", func() {
+			net.Drop(node1, node2, 1)
+			Expect(client.Send(1)).To(Succeed())
+			Expect(client.Send(2)).To(Succeed())
+			Expect(client.Receive()).To(Equal(2))
+		})
+		It("Should sever the stream when a node is killed", func() {
+			Expect(client.Send(1)).To(Succeed())
+			Expect(client.Receive()).To(Equal(1))
+			net.Kill(node2)
+			_, err := client.Receive()
+			Expect(err).To(MatchError(mem.Unreachable))
+			Expect(client.Send(1)).To(MatchError(mem.Unreachable))
+		})
+		It("Should not open a stream to a killed node", func() {
+			net.Kill(node2)
+			_, err := mem.NewStream[int, int](net, "echo", node1).Stream(ctx, node2)
+			Expect(err).To(MatchError(mem.Unreachable))
+		})
+	})
+})
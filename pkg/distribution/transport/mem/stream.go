@@ -0,0 +1,176 @@
+package mem
+
+import (
+	"context"
+	"fmt"
+	"github.com/arya-analytics/x/address"
+	"github.com/arya-analytics/x/transport"
+	"io"
+	"sync"
+)
+
+// Stream is an in-memory transport.Stream for a single node.
+type Stream[RQ, RS any] struct {
+	net     *Network
+	service string
+	host    address.Address
+}
+
+var _ transport.Stream[any, any] = (*Stream[any, any])(nil)
+
+// NewStream opens a streaming transport for the service on the node at host.
+func NewStream[RQ, RS any](n *Network, service string, host address.Address) *Stream[RQ, RS] {
+	return &Stream[RQ, RS]{net: n, service: service, host: host}
+}
+
+// String implements fmt.Stringer.
+func (s *Stream[RQ, RS]) String() string {
+	return fmt.Sprintf("mem.stream(%s@%s)", s.service, s.host)
+}
+
+type streamHandler[RQ, RS any] func(context.Context, transport.StreamServer[RQ, RS]) error
+
+// Stream implements transport.Stream.
+func (s *Stream[RQ, RS]) Stream(
+	ctx context.Context,
+	target address.Address,
+) (transport.StreamClient[RQ, RS], error) {
+	h, err := s.net.handler(s.service, target)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.net.reachable(s.host, target); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	var (
+		requests  = newPipe[RQ](s.net, s.host, target)
+		responses = newPipe[RS](s.net, target, s.host)
+		srv       = &server[RQ, RS]{requests: requests, responses: responses}
+	)
+	go func() {
+		defer cancel()
+		err := h.(streamHandler[RQ, RS])(ctx, srv)
+		responses.close(err)
+	}()
+	// Sever the stream if the network is partitioned or either node is killed.
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				requests.close(ctx.Err())
+				responses.close(ctx.Err())
+				return
+			case <-s.net.severed():
+				if err := s.net.reachable(s.host, target); err != nil {
+					requests.close(err)
+					responses.close(err)
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+	return &client[RQ, RS]{requests: requests, responses: responses}, nil
+}
+
+// Handle implements transport.Stream.
+func (s *Stream[RQ, RS]) Handle(handle func(context.Context, transport.StreamServer[RQ, RS]) error) {
+	s.net.register(s.service, s.host, streamHandler[RQ, RS](handle))
+}
+
+// pipe carries messages in one direction of a stream.
+type pipe[M any] struct {
+	net      *Network
+	from, to address.Address
+	messages chan M
+	done     chan struct{}
+	once     sync.Once
+	err      error
+}
+
+func newPipe[M any](n *Network, from, to address.Address) *pipe[M] {
+	return &pipe[M]{
+		net:      n,
+		from:     from,
+		to:       to,
+		messages: make(chan M, n.Buffer),
+		done:     make(chan struct{}),
+	}
+}
+
+func (p *pipe[M]) send(msg M) error {
+	dropped, err := p.net.transmit(p.from, p.to)
+	if err != nil || dropped {
+		return err
+	}
+	select {
+	case <-p.done:
+		if p.err != nil {
+			return p.err
+		}
+		return io.EOF
+	case p.messages <- msg:
+		return nil
+	}
+}
+
+func (p *pipe[M]) receive() (msg M, err error) {
+	// Drain buffered messages before reporting closure.
+	select {
+	case msg = <-p.messages:
+		return msg, nil
+	default:
+	}
+	select {
+	case msg = <-p.messages:
+		return msg, nil
+	case <-p.done:
+		select {
+		case msg = <-p.messages:
+			return msg, nil
+		default:
+		}
+		if p.err != nil {
+			return msg, p.err
+		}
+		return msg, io.EOF
+	}
+}
+
+// close closes the pipe. The first call to close determines the error returned to
+// the receiver. A nil error results in io.EOF.
+func (p *pipe[M]) close(err error) {
+	p.once.Do(func() {
+		p.err = err
+		close(p.done)
+	})
+}
+
+type client[RQ, RS any] struct {
+	requests  *pipe[RQ]
+	responses *pipe[RS]
+}
+
+// Send implements transport.StreamSender.
+func (c *client[RQ, RS]) Send(req RQ) error { return c.requests.send(req) }
+
+// CloseSend implements transport.StreamSenderCloser.
+func (c *client[RQ, RS]) CloseSend() error {
+	c.requests.close(nil)
+	return nil
+}
+
+// Receive implements transport.StreamReceiver.
+func (c *client[RQ, RS]) Receive() (RS, error) { return c.responses.receive() }
+
+type server[RQ, RS any] struct {
+	requests  *pipe[RQ]
+	responses *pipe[RS]
+}
+
+// Send implements transport.StreamSender.
+func (s *server[RQ, RS]) Send(res RS) error { return s.responses.send(res) }
+
+// Receive implements transport.StreamReceiver.
+func (s *server[RQ, RS]) Receive() (RQ, error) { return s.requests.receive() }
@@ -0,0 +1,56 @@
+package mem
+
+import (
+	"context"
+	"fmt"
+	"github.com/arya-analytics/x/address"
+	"github.com/arya-analytics/x/transport"
+)
+
+// Unary is an in-memory transport.Unary for a single node.
+type Unary[RQ, RS any] struct {
+	net     *Network
+	service string
+	host    address.Address
+}
+
+var _ transport.Unary[any, any] = (*Unary[any, any])(nil)
+
+// NewUnary opens a unary transport for the service on the node at host.
+func NewUnary[RQ, RS any](n *Network, service string, host address.Address) *Unary[RQ, RS] {
+	return &Unary[RQ, RS]{net: n, service: service, host: host}
+}
+
+// String implements fmt.Stringer.
+func (u *Unary[RQ, RS]) String() string {
+	return fmt.Sprintf("mem.unary(%s@%s)", u.service, u.host)
+}
+
+// Send implements transport.Unary.
+func (u *Unary[RQ, RS]) Send(ctx context.Context, target address.Address, req RQ) (res RS, err error) {
+	h, err := u.net.handler(u.service, target)
+	if err != nil {
+		return res, err
+	}
+	if err := u.transmit(u.host, target); err != nil {
+		return res, err
+	}
+	res, err = h.(func(context.Context, RQ) (RS, error))(ctx, req)
+	if tErr := u.transmit(target, u.host); tErr != nil {
+		return res, tErr
+	}
+	return res, err
+}
+
+func (u *Unary[RQ, RS]) transmit(from, to address.Address) error {
+	dropped, err := u.net.transmit(from, to)
+	if dropped {
+		return Dropped
+	}
+	return err
+}
+
+// Handle implements transport.Unary.
+func (u *Unary[RQ, RS]) Handle(handle func(context.Context, RQ) (RS, error)) {
+	u.net.register(u.service, u.host, handle)
+}
@@ -0,0 +1,113 @@
+package tcp_test
+
+import (
+	"github.com/arya-analytics/cesium"
+	"github.com/arya-analytics/delta/pkg/distribution/channel"
+	"github.com/arya-analytics/delta/pkg/distribution/mock"
+	"github.com/arya-analytics/delta/pkg/distribution/segment"
+	"github.com/arya-analytics/delta/pkg/distribution/segment/core"
+	"github.com/arya-analytics/delta/pkg/distribution/segment/writer"
+	"github.com/arya-analytics/delta/pkg/distribution/transport/tcp"
+	"github.com/arya-analytics/x/address"
+	"github.com/arya-analytics/x/gorp"
+	"github.com/arya-analytics/x/telem"
+	"github.com/cockroachdb/errors"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+)
+
+var _ = Describe("Cluster", Ordered, func() {
+	var (
+		builder  *mock.StorageBuilder
+		networks []*tcp.Network
+		channels []*channel.Service
+		segments []*segment.Service
+		key      channel.Key
+		data     = mock.Float64Data(1, 2, 3, 4, 5, 6, 7, 8, 9, 10)
+	)
+	BeforeAll(func() {
+		builder = mock.NewStorage()
+		tlsCfg := selfSignedTLS()
+		// The nodes listen on random loopback ports, so map the addresses aspen knows
+		// them by to the addresses their networks listen on.
+		addrs := make(map[address.Address]address.Address)
+		resolve := func(target address.Address) (address.Address, error) {
+			addr, ok := addrs[target]
+			if !ok {
+				return "", errors.Newf("no network for %s", target)
+			}
+			return addr, nil
+		}
+		for i := 0; i < 2; i++ {
+			store, err := builder.New(zap.NewNop())
+			Expect(err).ToNot(HaveOccurred())
+			clusterAddr, err := store.Aspen.Resolve(store.Aspen.HostID())
+			Expect(err).ToNot(HaveOccurred())
+			n, err := tcp.Listen(tcp.Config{
+				Address:     "localhost:0",
+				TLS:         tlsCfg,
+				VerifyPeers: true,
+				Resolve:     resolve,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			addrs[clusterAddr] = n.Addr()
+			ch := channel.New(
+				store.Aspen,
+				gorp.Wrap(store.Aspen),
+				store.Cesium,
+				tcp.ChannelTransport(n),
+			)
+			networks = append(networks, n)
+			channels = append(channels, ch)
+			segments = append(segments, segment.New(
+				ch,
+				store.Cesium,
+				tcp.NewSegmentTransport(n),
+				store.Aspen,
+			))
+		}
+		By("Creating a channel on the second node through the first")
+		ch, err := channels[0].NewCreate().
+			WithName("SG01").
+			WithDataRate(1 * telem.Hz).
+			WithSampleType(channel.Float64).
+			WithNodeID(2).
+			Exec(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		key = ch.Key()
+		Expect(key.NodeID()).To(BeEquivalentTo(2))
+		Eventually(func() error {
+			return core.ValidateChannelKeys(ctx, channels[0], channel.Keys{key})
+		}).Should(Succeed())
+	})
+	AfterAll(func() {
+		for _, n := range networks {
+			Expect(n.Close()).To(Succeed())
+		}
+		Expect(builder.Close()).To(Succeed())
+	})
+	It("Should write segments to a remote leaseholder", func() {
+		w, err := segments[0].NewCreate().WhereChannels(key).Write(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		w.Requests() <- writer.Request{Segments: []core.Segment{{
+			ChannelKey: key,
+			Segment:    cesium.Segment{ChannelKey: key.Cesium(), Start: 0, Data: data},
+		}}}
+		close(w.Requests())
+		for res := range w.Responses() {
+			Expect(res.Error).ToNot(HaveOccurred())
+		}
+		Expect(w.Close()).To(Succeed())
+	})
+	It("Should read segments from a remote leaseholder", func() {
+		iter, err := segments[0].NewRetrieve().WhereChannels(key).Iterate(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(iter.First()).To(BeTrue())
+		res := <-iter.Responses()
+		Expect(res.Error).ToNot(HaveOccurred())
+		Expect(res.Segments).To(HaveLen(1))
+		Expect(res.Segments[0].Segment.Data).To(Equal(data))
+		Expect(iter.Close()).To(Succeed())
+	})
+})
@@ -0,0 +1,170 @@
+package tcp
+
+import (
+	"context"
+	"encoding/gob"
+	"github.com/cockroachdb/errors"
+	"reflect"
+	"sync"
+)
+
+// Error is an error that was sent over the network. It holds the error encoded by
+// errors.EncodeError, which preserves the type, message, and causes of the original
+// error so that errors.Is and errors.As keep working after it is decoded on the
+// receiving node.
+type Error struct {
+	Encoded []byte
+}
+
+// Error implements error.
+func (e *Error) Error() string { return e.decode().Error() }
+
+func newError(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	if e, ok := err.(*Error); ok {
+		return e
+	}
+	enc := errors.EncodeError(context.Background(), err)
+	b, mErr := enc.Marshal()
+	if mErr != nil {
+		enc = errors.EncodeError(context.Background(), errors.Newf("%v", err))
+		b, _ = enc.Marshal()
+	}
+	return &Error{Encoded: b}
+}
+
+func (e *Error) decode() error {
+	if e == nil {
+		return errors.New("[transport.tcp] - peer sent an error frame without an error")
+	}
+	var enc errors.EncodedError
+	if err := enc.Unmarshal(e.Encoded); err != nil {
+		return errors.Wrap(err, "[transport.tcp] - failed to decode error")
+	}
+	return errors.DecodeError(context.Background(), enc)
+}
+
+func init() { gob.Register(&Error{}) }
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// encodeErrors returns a copy of msg with every non-nil error it contains replaced
+// with an *Error so that it can be encoded by gob, which can't encode unregistered
+// implementations of an interface. Errors are found in nested structs, pointers,
+// slices, arrays, and map values.
+func encodeErrors[M any](msg M) M {
+	return mapErrors(reflect.ValueOf(&msg).Elem(), func(err error) error {
+		return newError(err)
+	}).Interface().(M)
+}
+
+// decodeErrors reverses encodeErrors, replacing every *Error in msg with the error it
+// holds.
+func decodeErrors[M any](msg M) M {
+	return mapErrors(reflect.ValueOf(&msg).Elem(), func(err error) error {
+		if e, ok := err.(*Error); ok {
+			return e.decode()
+		}
+		return err
+	}).Interface().(M)
+}
+
+// mapErrors returns a copy of v with f applied to every non-nil error it contains.
+// Values that can't contain errors are returned as is, and the values v references
+// are never modified.
+func mapErrors(v reflect.Value, f func(error) error) reflect.Value {
+	t := v.Type()
+	if !containsErrors(t) {
+		return v
+	}
+	switch t.Kind() {
+	case reflect.Interface:
+		out := reflect.New(t).Elem()
+		if !v.IsNil() {
+			out.Set(reflect.ValueOf(f(v.Interface().(error))))
+		}
+		return out
+	case reflect.Struct:
+		out := reflect.New(t).Elem()
+		out.Set(v)
+		for i := 0; i < t.NumField(); i++ {
+			if t.Field(i).IsExported() {
+				out.Field(i).Set(mapErrors(v.Field(i), f))
+			}
+		}
+		return out
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.New(t.Elem())
+		out.Elem().Set(mapErrors(v.Elem(), f))
+		return out
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.MakeSlice(t, v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(mapErrors(v.Index(i), f))
+		}
+		return out
+	case reflect.Array:
+		out := reflect.New(t).Elem()
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(mapErrors(v.Index(i), f))
+		}
+		return out
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.MakeMapWithSize(t, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			out.SetMapIndex(iter.Key(), mapErrors(iter.Value(), f))
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+var errorTypes = struct {
+	sync.Mutex
+	contains map[reflect.Type]bool
+}{contains: make(map[reflect.Type]bool)}
+
+// containsErrors returns true if values of type t can hold an error in an exported
+// field. Map keys are not considered.
+func containsErrors(t reflect.Type) bool {
+	errorTypes.Lock()
+	contains, ok := errorTypes.contains[t]
+	if !ok {
+		// Assume recursive types contain errors until proven otherwise.
+		errorTypes.contains[t] = true
+	}
+	errorTypes.Unlock()
+	if ok {
+		return contains
+	}
+	switch t.Kind() {
+	case reflect.Interface:
+		contains = t == errorType
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if t.Field(i).IsExported() && containsErrors(t.Field(i).Type) {
+				contains = true
+				break
+			}
+		}
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		contains = containsErrors(t.Elem())
+	}
+	errorTypes.Lock()
+	errorTypes.contains[t] = contains
+	errorTypes.Unlock()
+	return contains
+}
@@ -0,0 +1,53 @@
+package tcp
+
+import (
+	"github.com/arya-analytics/delta/pkg/distribution/channel"
+	"github.com/arya-analytics/delta/pkg/distribution/resource"
+	"github.com/arya-analytics/delta/pkg/distribution/segment"
+	"github.com/arya-analytics/delta/pkg/distribution/segment/iterator"
+	"github.com/arya-analytics/delta/pkg/distribution/segment/plan"
+	"github.com/arya-analytics/delta/pkg/distribution/segment/stat"
+	"github.com/arya-analytics/delta/pkg/distribution/segment/writer"
+)
+
+// ChannelTransport returns a channel.CreateTransport on the Network.
+func ChannelTransport(n *Network) channel.CreateTransport {
+	return NewUnary[channel.CreateMessage, channel.CreateMessage](n, "channel.create")
+}
+
+// ResourceTransport returns a resource.Transport on the Network.
+func ResourceTransport(n *Network) resource.Transport {
+	return NewUnary[resource.Message, resource.Message](n, "resource.relay")
+}
+
+// SegmentTransport implements segment.Transport on a Network.
+type SegmentTransport struct {
+	iterator *Stream[iterator.Request, iterator.Response]
+	writer   *Stream[writer.Request, writer.Response]
+	stat     *Unary[stat.Request, stat.Response]
+	plan     *Stream[plan.Request, plan.Response]
+}
+
+var _ segment.Transport = (*SegmentTransport)(nil)
+
+// NewSegmentTransport opens the transports for the segment service on the Network.
+func NewSegmentTransport(n *Network) *SegmentTransport {
+	return &SegmentTransport{
+		iterator: NewStream[iterator.Request, iterator.Response](n, "segment.iterator"),
+		writer:   NewStream[writer.Request, writer.Response](n, "segment.writer"),
+		stat:     NewUnary[stat.Request, stat.Response](n, "segment.stat"),
+		plan:     NewStream[plan.Request, plan.Response](n, "segment.plan"),
+	}
+}
+
+// Iterator implements segment.Transport.
+func (t *SegmentTransport) Iterator() iterator.Transport { return t.iterator }
+
+// Writer implements segment.Transport.
+func (t *SegmentTransport) Writer() writer.Transport { return t.writer }
+
+// Stat implements segment.Transport.
+func (t *SegmentTransport) Stat() stat.Transport { return t.stat }
+
+// Plan implements segment.Transport.
+func (t *SegmentTransport) Plan() plan.Transport { return t.plan }
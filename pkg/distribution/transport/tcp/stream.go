@@ -0,0 +1,124 @@
+package tcp
+
+import (
+	"context"
+	"fmt"
+	"github.com/arya-analytics/x/address"
+	"github.com/arya-analytics/x/transport"
+	"io"
+)
+
+// Stream is a transport.Stream that opens a new connection for each stream.
+type Stream[RQ, RS any] struct {
+	net     *Network
+	service string
+}
+
+var _ transport.Stream[any, any] = (*Stream[any, any])(nil)
+
+// NewStream opens a streaming transport for the service on the Network. All nodes
+// must use the same service name for the transport.
+func NewStream[RQ, RS any](n *Network, service string) *Stream[RQ, RS] {
+	return &Stream[RQ, RS]{net: n, service: service}
+}
+
+// String implements fmt.Stringer.
+func (s *Stream[RQ, RS]) String() string { return fmt.Sprintf("tcp.stream(%s)", s.service) }
+
+// Stream implements transport.Stream.
+func (s *Stream[RQ, RS]) Stream(
+	ctx context.Context,
+	target address.Address,
+) (transport.StreamClient[RQ, RS], error) {
+	c, err := s.net.dial(ctx, target, s.service)
+	if err != nil {
+		return nil, err
+	}
+	return &client[RQ, RS]{conn: c, stop: c.closeOnDone(ctx)}, nil
+}
+
+// Handle implements transport.Stream.
+func (s *Stream[RQ, RS]) Handle(handle func(context.Context, transport.StreamServer[RQ, RS]) error) {
+	s.net.register(s.service, func(ctx context.Context, c *conn) {
+		srv := &server[RQ, RS]{conn: c}
+		f := frame[RS]{Type: closeFrame}
+		if err := handle(ctx, srv); err != nil {
+			f.Type, f.Error = errorFrame, newError(err)
+		}
+		_ = c.send(f)
+	})
+}
+
+// client is the client side of a stream.
+type client[RQ, RS any] struct {
+	conn *conn
+	stop func()
+	done bool
+}
+
+// Send implements transport.StreamSender.
+func (c *client[RQ, RS]) Send(req RQ) error {
+	return c.conn.send(frame[RQ]{Type: messageFrame, Message: encodeErrors(req)})
+}
+
+// CloseSend implements transport.StreamSenderCloser.
+func (c *client[RQ, RS]) CloseSend() error {
+	return c.conn.send(frame[RQ]{Type: closeFrame})
+}
+
+// Receive implements transport.StreamReceiver. Returns io.EOF once the server
+// handler exits without error.
+func (c *client[RQ, RS]) Receive() (res RS, err error) {
+	if c.done {
+		return res, io.EOF
+	}
+	var f frame[RS]
+	if err := c.conn.dec.Decode(&f); err != nil {
+		c.close()
+		return res, err
+	}
+	switch f.Type {
+	case messageFrame:
+		return decodeErrors(f.Message), nil
+	case errorFrame:
+		c.close()
+		return res, f.Error.decode()
+	default:
+		c.close()
+		return res, io.EOF
+	}
+}
+
+func (c *client[RQ, RS]) close() {
+	c.done = true
+	c.stop()
+	_ = c.conn.Close()
+}
+
+// server is the server side of a stream.
+type server[RQ, RS any] struct {
+	conn *conn
+	done bool
+}
+
+// Send implements transport.StreamSender.
+func (s *server[RQ, RS]) Send(res RS) error {
+	return s.conn.send(frame[RS]{Type: messageFrame, Message: encodeErrors(res)})
+}
+
+// Receive implements transport.StreamReceiver. Returns io.EOF once the client calls
+// CloseSend.
+func (s *server[RQ, RS]) Receive() (req RQ, err error) {
+	if s.done {
+		return req, io.EOF
+	}
+	var f frame[RQ]
+	if err := s.conn.dec.Decode(&f); err != nil {
+		return req, err
+	}
+	if f.Type != messageFrame {
+		s.done = true
+		return req, io.EOF
+	}
+	return decodeErrors(f.Message), nil
+}
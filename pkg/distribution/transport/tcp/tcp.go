@@ -0,0 +1,239 @@
+// Package tcp implements the transports used by the distribution layer over a framed
+// TCP protocol with optional TLS and mutual TLS authentication of peers.
+//
+// A Network listens on a single address and multiplexes any number of services over
+// it. Each unary request and each stream opens a new connection. The first frame
+// on a connection is a header naming the service it targets, after which request
+// and response frames are exchanged. Frames are encoded using encoding/gob, which
+// delimits frames on the wire.
+package tcp
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/gob"
+	"github.com/arya-analytics/x/address"
+	"github.com/cockroachdb/errors"
+	"go.uber.org/zap"
+	"net"
+	"sync"
+	"time"
+)
+
+// Config is the configuration for a Network.
+type Config struct {
+	// Address is the address to listen on. Use "localhost:0" to listen on a random
+	// loopback port.
+	Address address.Address
+	// TLS enables TLS for both the listener and outgoing connections when non-nil.
+	TLS *tls.Config
+	// VerifyPeers requires every peer that connects to the Network to present a
+	// certificate signed by a CA in TLS.ClientCAs, or in TLS.RootCAs if ClientCAs is
+	// nil. Connections from unverified peers are rejected during the handshake.
+	// Requires TLS to be set.
+	VerifyPeers bool
+	// Resolve maps the address a peer is known by in the cluster to the address its
+	// Network listens on. Defaults to dialing the cluster address as is.
+	Resolve func(target address.Address) (address.Address, error)
+	// DialTimeout is the maximum amount of time to wait for a connection to a peer
+	// to be established. Defaults to 5 seconds.
+	DialTimeout time.Duration
+	// Logger is the logger used by the Network. Defaults to a no-op logger.
+	Logger *zap.SugaredLogger
+}
+
+const defaultDialTimeout = 5 * time.Second
+
+// Network listens for and dials connections for the transports created on it.
+type Network struct {
+	Config
+	listener net.Listener
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	mu       struct {
+		sync.RWMutex
+		handlers map[string]func(ctx context.Context, conn *conn)
+	}
+}
+
+// Listen opens a Network listening on cfg.Address. The Network serves incoming
+// connections until Close is called.
+func Listen(cfg Config) (*Network, error) {
+	if cfg.DialTimeout == 0 {
+		cfg.DialTimeout = defaultDialTimeout
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = zap.NewNop().Sugar()
+	}
+	if cfg.Resolve == nil {
+		cfg.Resolve = func(target address.Address) (address.Address, error) { return target, nil }
+	}
+	if cfg.VerifyPeers && cfg.TLS == nil {
+		return nil, errors.New("[transport.tcp] - verifying peers requires TLS")
+	}
+	ln, err := net.Listen("tcp", string(cfg.Address))
+	if err != nil {
+		return nil, errors.Wrapf(err, "[transport.tcp] - failed to listen on %s", cfg.Address)
+	}
+	if cfg.TLS != nil {
+		ln = tls.NewListener(ln, serverTLS(cfg))
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	n := &Network{Config: cfg, listener: ln, ctx: ctx, cancel: cancel}
+	n.mu.handlers = make(map[string]func(ctx context.Context, conn *conn))
+	n.wg.Add(1)
+	go n.serve()
+	return n, nil
+}
+
+func serverTLS(cfg Config) *tls.Config {
+	if !cfg.VerifyPeers {
+		return cfg.TLS
+	}
+	c := cfg.TLS.Clone()
+	c.ClientAuth = tls.RequireAndVerifyClientCert
+	if c.ClientCAs == nil {
+		c.ClientCAs = c.RootCAs
+	}
+	return c
+}
+
+// Addr returns the address the Network is listening on. This differs from
+// Config.Address when listening on port 0.
+func (n *Network) Addr() address.Address { return address.Address(n.listener.Addr().String()) }
+
+// Close stops accepting connections, cancels all in-progress handlers, and waits for
+// them to exit.
+func (n *Network) Close() error {
+	n.cancel()
+	err := n.listener.Close()
+	n.wg.Wait()
+	return err
+}
+
+func (n *Network) serve() {
+	defer n.wg.Done()
+	for {
+		c, err := n.listener.Accept()
+		if err != nil {
+			if n.ctx.Err() == nil {
+				n.Logger.Errorw("[transport.tcp] - failed to accept connection", "error", err)
+			}
+			return
+		}
+		n.wg.Add(1)
+		go func() {
+			defer n.wg.Done()
+			n.handleConn(newConn(c))
+		}()
+	}
+}
+
+func (n *Network) handleConn(c *conn) {
+	defer func() { _ = c.Close() }()
+	var h header
+	if err := c.dec.Decode(&h); err != nil {
+		n.Logger.Debugw("[transport.tcp] - failed to read header", "error", err)
+		return
+	}
+	n.mu.RLock()
+	handle, ok := n.mu.handlers[h.Service]
+	n.mu.RUnlock()
+	if !ok {
+		_ = c.enc.Encode(frame[struct{}]{
+			Type:  errorFrame,
+			Error: newError(errors.Newf("[transport.tcp] - no handler for service %s", h.Service)),
+		})
+		return
+	}
+	ctx, cancel := context.WithCancel(n.ctx)
+	defer cancel()
+	// Close the connection if the Network is closed while the handler is running.
+	stop := c.closeOnDone(ctx)
+	defer stop()
+	handle(ctx, c)
+}
+
+func (n *Network) register(service string, handle func(ctx context.Context, conn *conn)) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.mu.handlers[service] = handle
+}
+
+func (n *Network) dial(ctx context.Context, target address.Address, service string) (*conn, error) {
+	addr, err := n.Resolve(target)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[transport.tcp] - failed to resolve %s", target)
+	}
+	dialer := &net.Dialer{Timeout: n.DialTimeout}
+	var c net.Conn
+	if n.TLS != nil {
+		c, err = (&tls.Dialer{NetDialer: dialer, Config: n.TLS}).DialContext(ctx, "tcp", string(addr))
+	} else {
+		c, err = dialer.DialContext(ctx, "tcp", string(addr))
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "[transport.tcp] - failed to dial %s", target)
+	}
+	cn := newConn(c)
+	if err := cn.enc.Encode(header{Service: service}); err != nil {
+		_ = cn.Close()
+		return nil, err
+	}
+	return cn, nil
+}
+
+// header is the first frame sent on every connection.
+type header struct {
+	Service string
+}
+
+type frameType uint8
+
+const (
+	// messageFrame carries a request or response.
+	messageFrame frameType = iota + 1
+	// closeFrame signals that the sender won't send any more messages.
+	closeFrame
+	// errorFrame carries an error returned by a handler. No further frames follow.
+	errorFrame
+)
+
+type frame[M any] struct {
+	Type    frameType
+	Message M
+	Error   *Error
+}
+
+// conn wraps a net.Conn with a gob encoder and decoder.
+type conn struct {
+	net.Conn
+	enc *gob.Encoder
+	dec *gob.Decoder
+	mu  sync.Mutex
+}
+
+func newConn(c net.Conn) *conn {
+	return &conn{Conn: c, enc: gob.NewEncoder(c), dec: gob.NewDecoder(c)}
+}
+
+func (c *conn) send(f any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.enc.Encode(f)
+}
+
+// closeOnDone closes the connection when ctx is done. The returned function stops
+// watching the context.
+func (c *conn) closeOnDone(ctx context.Context) func() {
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = c.Close()
+		case <-stop:
+		}
+	}()
+	return func() { close(stop) }
+}
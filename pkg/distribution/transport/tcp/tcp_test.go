@@ -0,0 +1,264 @@
+package tcp_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/arya-analytics/delta/pkg/distribution/transport/tcp"
+	"github.com/arya-analytics/x/transport"
+	"github.com/cockroachdb/errors"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"io"
+	"math/big"
+	"net"
+	"time"
+)
+
+type message struct {
+	Value  int
+	Error  error
+	Errors []error
+	ByKey  map[string]error
+	Nested *message
+}
+
+var sentinel = errors.New("sentinel")
+
+func selfSignedTLS() *tls.Config {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).ToNot(HaveOccurred())
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		DNSNames:              []string{"localhost"},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	Expect(err).ToNot(HaveOccurred())
+	cert, err := x509.ParseCertificate(der)
+	Expect(err).ToNot(HaveOccurred())
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+		RootCAs:      pool,
+		ServerName:   "localhost",
+	}
+}
+
+var _ = Describe("TCP", func() {
+	for _, useTLS := range []bool{false, true} {
+		useTLS := useTLS
+		name := "Plaintext"
+		if useTLS {
+			name = "TLS"
+		}
+		Context(name, func() {
+			var server, client *tcp.Network
+			BeforeEach(func() {
+				cfg := tcp.Config{Address: "localhost:0"}
+				if useTLS {
+					cfg.TLS = selfSignedTLS()
+				}
+				var err error
+				server, err = tcp.Listen(cfg)
+				Expect(err).ToNot(HaveOccurred())
+				client, err = tcp.Listen(cfg)
+				Expect(err).ToNot(HaveOccurred())
+			})
+			AfterEach(func() {
+				Expect(server.Close()).To(Succeed())
+				Expect(client.Close()).To(Succeed())
+			})
+			Describe("Unary", func() {
+				It("Should send a request and receive a response", func() {
+					tcp.NewUnary[message, message](server, "double").Handle(
+						func(_ context.Context, req message) (message, error) {
+							return message{Value: req.Value * 2}, nil
+						},
+					)
+					res, err := tcp.NewUnary[message, message](client, "double").
+						Send(ctx, server.Addr(), message{Value: 2})
+					Expect(err).ToNot(HaveOccurred())
+					Expect(res.Value).To(Equal(4))
+				})
+				It("Should return errors from the handler and in messages", func() {
+					tcp.NewUnary[message, message](server, "fail").Handle(
+						func(_ context.Context, req message) (message, error) {
+							if req.Value > 0 {
+								return message{}, errors.New("handler failed")
+							}
+							return message{Error: errors.New("message error")}, nil
+						},
+					)
+					t := tcp.NewUnary[message, message](client, "fail")
+					_, err := t.Send(ctx, server.Addr(), message{Value: 1})
+					Expect(err).To(MatchError("handler failed"))
+					res, err := t.Send(ctx, server.Addr(), message{})
+					Expect(err).ToNot(HaveOccurred())
+					Expect(res.Error).To(MatchError("message error"))
+				})
+				It("Should preserve the identity of errors", func() {
+					tcp.NewUnary[message, message](server, "wrap").Handle(
+						func(_ context.Context, req message) (message, error) {
+							return req, errors.Wrap(sentinel, "handler failed")
+						},
+					)
+					_, err := tcp.NewUnary[message, message](client, "wrap").
+						Send(ctx, server.Addr(), message{})
+					Expect(err).To(MatchError("handler failed: sentinel"))
+					Expect(errors.Is(err, sentinel)).To(BeTrue())
+				})
+				It("Should send errors in nested slices, maps, and pointers", func() {
+					tcp.NewUnary[message, message](server, "nested").Handle(
+						func(_ context.Context, req message) (message, error) { return req, nil },
+					)
+					req := message{
+						Errors: []error{nil, sentinel},
+						ByKey:  map[string]error{"key": sentinel},
+						Nested: &message{Errors: []error{nil, sentinel}},
+					}
+					res, err := tcp.NewUnary[message, message](client, "nested").
+						Send(ctx, server.Addr(), req)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(res.Errors[0]).To(BeNil())
+					Expect(errors.Is(res.Errors[1], sentinel)).To(BeTrue())
+					Expect(errors.Is(res.ByKey["key"], sentinel)).To(BeTrue())
+					Expect(errors.Is(res.Nested.Errors[1], sentinel)).To(BeTrue())
+					By("Leaving the sent message unmodified")
+					Expect(req.Errors[1]).To(BeIdenticalTo(sentinel))
+					Expect(req.ByKey["key"]).To(BeIdenticalTo(sentinel))
+					Expect(req.Nested.Errors[1]).To(BeIdenticalTo(sentinel))
+				})
+				It("Should return an error for an unknown service", func() {
+					_, err := tcp.NewUnary[message, message](client, "unknown").
+						Send(ctx, server.Addr(), message{})
+					Expect(err).To(HaveOccurred())
+				})
+			})
+			Describe("Stream", func() {
+				It("Should exchange messages until the client closes the stream", func() {
+					tcp.NewStream[message, message](server, "echo").Handle(
+						func(_ context.Context, srv transport.StreamServer[message, message]) error {
+							for {
+								req, err := srv.Receive()
+								if errors.Is(err, io.EOF) {
+									return nil
+								}
+								if err != nil {
+									return err
+								}
+								if err := srv.Send(req); err != nil {
+									return err
+								}
+							}
+						},
+					)
+					stream, err := tcp.NewStream[message, message](client, "echo").
+						Stream(ctx, server.Addr())
+					Expect(err).ToNot(HaveOccurred())
+					for i := 0; i < 10; i++ {
+						Expect(stream.Send(message{Value: i})).To(Succeed())
+						res, err := stream.Receive()
+						Expect(err).ToNot(HaveOccurred())
+						Expect(res.Value).To(Equal(i))
+					}
+					Expect(stream.CloseSend()).To(Succeed())
+					_, err = stream.Receive()
+					Expect(err).To(MatchError(io.EOF))
+				})
+				It("Should return the error the handler exits with", func() {
+					tcp.NewStream[message, message](server, "fail").Handle(
+						func(_ context.Context, srv transport.StreamServer[message, message]) error {
+							_, _ = srv.Receive()
+							return errors.New("stream failed")
+						},
+					)
+					stream, err := tcp.NewStream[message, message](client, "fail").
+						Stream(ctx, server.Addr())
+					Expect(err).ToNot(HaveOccurred())
+					Expect(stream.Send(message{})).To(Succeed())
+					_, err = stream.Receive()
+					Expect(err).To(MatchError("stream failed"))
+				})
+				It("Should close the stream when the context is cancelled", func() {
+					tcp.NewStream[message, message](server, "block").Handle(
+						func(_ context.Context, srv transport.StreamServer[message, message]) error {
+							_, err := srv.Receive()
+							return err
+						},
+					)
+					cCtx, cancel := context.WithCancel(ctx)
+					stream, err := tcp.NewStream[message, message](client, "block").
+						Stream(cCtx, server.Addr())
+					Expect(err).ToNot(HaveOccurred())
+					cancel()
+					_, err = stream.Receive()
+					Expect(err).To(HaveOccurred())
+				})
+			})
+		})
+	}
+})
+
+var _ = Describe("Verify Peers", func() {
+	var server *tcp.Network
+	BeforeEach(func() {
+		var err error
+		server, err = tcp.Listen(tcp.Config{
+			Address:     "localhost:0",
+			TLS:         selfSignedTLS(),
+			VerifyPeers: true,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		tcp.NewUnary[message, message](server, "echo").Handle(
+			func(_ context.Context, req message) (message, error) { return req, nil },
+		)
+	})
+	AfterEach(func() { Expect(server.Close()).To(Succeed()) })
+	It("Should accept requests from peers with a trusted certificate", func() {
+		client, err := tcp.Listen(tcp.Config{Address: "localhost:0", TLS: server.TLS})
+		Expect(err).ToNot(HaveOccurred())
+		defer func() { Expect(client.Close()).To(Succeed()) }()
+		res, err := tcp.NewUnary[message, message](client, "echo").
+			Send(ctx, server.Addr(), message{Value: 1})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.Value).To(Equal(1))
+	})
+	It("Should reject requests from peers without a certificate", func() {
+		client, err := tcp.Listen(tcp.Config{
+			Address: "localhost:0",
+			TLS:     &tls.Config{RootCAs: server.TLS.RootCAs, ServerName: "localhost"},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		defer func() { Expect(client.Close()).To(Succeed()) }()
+		_, err = tcp.NewUnary[message, message](client, "echo").
+			Send(ctx, server.Addr(), message{Value: 1})
+		Expect(err).To(HaveOccurred())
+	})
+	It("Should reject requests from peers with an untrusted certificate", func() {
+		untrusted := selfSignedTLS()
+		untrusted.RootCAs = server.TLS.RootCAs
+		client, err := tcp.Listen(tcp.Config{Address: "localhost:0", TLS: untrusted})
+		Expect(err).ToNot(HaveOccurred())
+		defer func() { Expect(client.Close()).To(Succeed()) }()
+		_, err = tcp.NewUnary[message, message](client, "echo").
+			Send(ctx, server.Addr(), message{Value: 1})
+		Expect(err).To(HaveOccurred())
+	})
+	It("Should require TLS", func() {
+		_, err := tcp.Listen(tcp.Config{Address: "localhost:0", VerifyPeers: true})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,67 @@
+package tcp
+
+import (
+	"context"
+	"fmt"
+	"github.com/arya-analytics/x/address"
+	"github.com/arya-analytics/x/transport"
+	"github.com/cockroachdb/errors"
+)
+
+// Unary is a transport.Unary that sends each request over a new connection.
+type Unary[RQ, RS any] struct {
+	net     *Network
+	service string
+}
+
+var _ transport.Unary[any, any] = (*Unary[any, any])(nil)
+
+// NewUnary opens a unary transport for the service on the Network. All nodes must
+// use the same service name for the transport.
+func NewUnary[RQ, RS any](n *Network, service string) *Unary[RQ, RS] {
+	return &Unary[RQ, RS]{net: n, service: service}
+}
+
+// String implements fmt.Stringer.
+func (u *Unary[RQ, RS]) String() string { return fmt.Sprintf("tcp.unary(%s)", u.service) }
+
+// Send implements transport.Unary.
+func (u *Unary[RQ, RS]) Send(ctx context.Context, target address.Address, req RQ) (res RS, err error) {
+	c, err := u.net.dial(ctx, target, u.service)
+	if err != nil {
+		return res, err
+	}
+	defer func() { _ = c.Close() }()
+	stop := c.closeOnDone(ctx)
+	defer stop()
+	if err := c.send(frame[RQ]{Type: messageFrame, Message: encodeErrors(req)}); err != nil {
+		return res, errors.Wrap(err, "[transport.tcp] - failed to send request")
+	}
+	var f frame[RS]
+	if err := c.dec.Decode(&f); err != nil {
+		if ctx.Err() != nil {
+			return res, ctx.Err()
+		}
+		return res, errors.Wrap(err, "[transport.tcp] - failed to receive response")
+	}
+	if f.Type == errorFrame {
+		return decodeErrors(f.Message), f.Error.decode()
+	}
+	return decodeErrors(f.Message), nil
+}
+
+// Handle implements transport.Unary.
+func (u *Unary[RQ, RS]) Handle(handle func(context.Context, RQ) (RS, error)) {
+	u.net.register(u.service, func(ctx context.Context, c *conn) {
+		var req frame[RQ]
+		if err := c.dec.Decode(&req); err != nil {
+			return
+		}
+		res, err := handle(ctx, decodeErrors(req.Message))
+		f := frame[RS]{Type: messageFrame, Message: encodeErrors(res)}
+		if err != nil {
+			f.Type, f.Error = errorFrame, newError(err)
+		}
+		_ = c.send(f)
+	})
+}
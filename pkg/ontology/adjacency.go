@@ -0,0 +1,177 @@
+package ontology
+
+import (
+	"fmt"
+	"github.com/arya-analytics/x/gorp"
+	"github.com/arya-analytics/x/query"
+	"github.com/cockroachdb/errors"
+)
+
+// adjacency indexes the relationships of a resource in a single direction. Forward
+// adjacencies hold the resources a resource has relationships to, and Backward
+// adjacencies hold the resources that have relationships to it. Traversals and cycle
+// checks read adjacencies instead of scanning every Relationship, so their cost is
+// proportional to the size of the neighbourhood they visit.
+type adjacency struct {
+	Resource  ID
+	Direction Direction
+	Edges     map[RelationshipType][]ID
+}
+
+func adjacencyKey(id ID, dir Direction) string { return fmt.Sprintf("%s:%d", id, dir) }
+
+// GorpKey implements the gorp.Entry interface.
+func (a adjacency) GorpKey() string { return adjacencyKey(a.Resource, a.Direction) }
+
+// SetOptions implements the gorp.Entry interface.
+func (a adjacency) SetOptions() []interface{} { return nil }
+
+func (a *adjacency) add(t RelationshipType, id ID) {
+	if a.Edges == nil {
+		a.Edges = make(map[RelationshipType][]ID)
+	}
+	for _, e := range a.Edges[t] {
+		if e == id {
+			return
+		}
+	}
+	a.Edges[t] = append(a.Edges[t], id)
+}
+
+func (a *adjacency) remove(t RelationshipType, id ID) {
+	edges := a.Edges[t]
+	for i, e := range edges {
+		if e == id {
+			a.Edges[t] = append(edges[:i], edges[i+1:]...)
+			break
+		}
+	}
+	if len(a.Edges[t]) == 0 {
+		delete(a.Edges, t)
+	}
+}
+
+func retrieveAdjacency(txn gorp.Txn, id ID, dir Direction) (adjacency, error) {
+	a := adjacency{Resource: id, Direction: dir}
+	err := gorp.NewRetrieve[string, adjacency]().
+		WhereKeys(adjacencyKey(id, dir)).
+		Entry(&a).
+		Exec(txn)
+	if errors.Is(err, query.NotFound) {
+		return adjacency{Resource: id, Direction: dir}, nil
+	}
+	return a, err
+}
+
+func writeAdjacency(txn gorp.Txn, a adjacency) error {
+	if len(a.Edges) == 0 {
+		return gorp.NewDelete[string, adjacency]().WhereKeys(a.GorpKey()).Exec(txn)
+	}
+	return gorp.NewCreate[string, adjacency]().Entry(&a).Exec(txn)
+}
+
+// retrieveAdjacent returns the IDs of the resources adjacent to the resource with the
+// given ID through relationships of type t in the given direction.
+func retrieveAdjacent(txn gorp.Txn, id ID, dir Direction, t RelationshipType) ([]ID, error) {
+	a, err := retrieveAdjacency(txn, id, dir)
+	return a.Edges[t], err
+}
+
+// indexRelationship adds the relationship to the adjacencies of both resources.
+func indexRelationship(txn gorp.Txn, rel Relationship) error {
+	return updateAdjacencies(txn, rel, (*adjacency).add)
+}
+
+// unindexRelationship removes the relationship from the adjacencies of both resources.
+func unindexRelationship(txn gorp.Txn, rel Relationship) error {
+	return updateAdjacencies(txn, rel, (*adjacency).remove)
+}
+
+func updateAdjacencies(
+	txn gorp.Txn,
+	rel Relationship,
+	update func(a *adjacency, t RelationshipType, id ID),
+) error {
+	fwd, err := retrieveAdjacency(txn, rel.From, Forward)
+	if err != nil {
+		return err
+	}
+	update(&fwd, rel.Type, rel.To)
+	if err := writeAdjacency(txn, fwd); err != nil {
+		return err
+	}
+	bwd, err := retrieveAdjacency(txn, rel.To, Backward)
+	if err != nil {
+		return err
+	}
+	update(&bwd, rel.Type, rel.From)
+	return writeAdjacency(txn, bwd)
+}
+
+// reachable returns true if target can be reached from the resource with the given
+// ID by following relationships of type t in the given direction.
+func reachable(txn gorp.Txn, from, target ID, dir Direction, t RelationshipType) (bool, error) {
+	visited := map[ID]struct{}{from: {}}
+	queue := []ID{from}
+	for len(queue) > 0 {
+		next, err := retrieveAdjacent(txn, queue[0], dir, t)
+		if err != nil {
+			return false, err
+		}
+		queue = queue[1:]
+		for _, id := range next {
+			if id == target {
+				return true, nil
+			}
+			if _, ok := visited[id]; !ok {
+				visited[id] = struct{}{}
+				queue = append(queue, id)
+			}
+		}
+	}
+	return false, nil
+}
+
+// adjacencyIndex marks a database whose relationships have all been indexed into
+// adjacencies. Databases written before adjacencies were introduced don't have one,
+// and are backfilled by backfillAdjacencies when the Ontology is opened.
+type adjacencyIndex struct {
+	Indexed bool
+}
+
+const adjacencyIndexKey = "adjacency_index"
+
+// GorpKey implements the gorp.Entry interface.
+func (a adjacencyIndex) GorpKey() string { return adjacencyIndexKey }
+
+// SetOptions implements the gorp.Entry interface.
+func (a adjacencyIndex) SetOptions() []interface{} { return nil }
+
+// backfillAdjacencies indexes every Relationship in the database into adjacencies if
+// the database hasn't been indexed yet.
+func backfillAdjacencies(db *gorp.DB) error {
+	indexed, err := gorp.NewRetrieve[string, adjacencyIndex]().
+		WhereKeys(adjacencyIndexKey).
+		Exists(db)
+	if err != nil || indexed {
+		return err
+	}
+	var relationships []Relationship
+	if err := gorp.NewRetrieve[string, Relationship]().
+		Entries(&relationships).
+		Exec(db); err != nil {
+		return err
+	}
+	txn := db.BeginTxn()
+	for _, rel := range relationships {
+		if err := indexRelationship(txn, rel); err != nil {
+			return errors.CombineErrors(err, txn.Close())
+		}
+	}
+	if err := gorp.NewCreate[string, adjacencyIndex]().
+		Entry(&adjacencyIndex{Indexed: true}).
+		Exec(txn); err != nil {
+		return errors.CombineErrors(err, txn.Close())
+	}
+	return txn.Commit()
+}
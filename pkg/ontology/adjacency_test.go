@@ -0,0 +1,58 @@
+package ontology_test
+
+import (
+	"github.com/arya-analytics/delta/pkg/ontology"
+	"github.com/arya-analytics/x/gorp"
+	"github.com/arya-analytics/x/kv/memkv"
+	"github.com/cockroachdb/errors"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Adjacency", func() {
+	Describe("Backfill", func() {
+		var (
+			db      *gorp.DB
+			o       *ontology.Ontology
+			a, b, c ontology.ID
+		)
+		BeforeEach(func() {
+			// Write resources and relationships directly, as a database written before
+			// relationships were indexed would have stored them.
+			db = gorp.Wrap(memkv.New())
+			a, b, c = newEmptyID("A"), newEmptyID("B"), newEmptyID("C")
+			for _, id := range []ontology.ID{a, b, c} {
+				Expect(gorp.NewCreate[ontology.ID, ontology.Resource]().
+					Entry(&ontology.Resource{ID: id}).
+					Exec(db)).To(Succeed())
+			}
+			for _, rel := range []ontology.Relationship{
+				{From: b, To: a, Type: ontology.Parent},
+				{From: c, To: b, Type: ontology.Parent},
+			} {
+				Expect(gorp.NewCreate[string, ontology.Relationship]().
+					Entry(&rel).
+					Exec(db)).To(Succeed())
+			}
+			var err error
+			o, err = ontology.Open(db)
+			Expect(err).ToNot(HaveOccurred())
+			o.RegisterService(&emptyService{})
+		})
+		AfterEach(func() { Expect(db.Close()).To(Succeed()) })
+		It("Should traverse relationships written before the database was opened", func() {
+			var res []ontology.Resource
+			Expect(o.NewRetrieve().
+				WhereIDs(a).
+				TraverseTo(ontology.Descendants).
+				Entries(&res).
+				Exec()).To(Succeed())
+			Expect(res).To(HaveLen(2))
+			Expect([]ontology.ID{res[0].ID, res[1].ID}).To(ConsistOf(b, c))
+		})
+		It("Should detect cycles through relationships written before the database was opened", func() {
+			err := o.NewWriter(db).DefineRelationship(a, c, ontology.Parent)
+			Expect(errors.Is(err, ontology.CyclicDependency)).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,112 @@
+package ontology_test
+
+import (
+	"github.com/arya-analytics/delta/pkg/ontology"
+	"github.com/arya-analytics/x/gorp"
+	"github.com/arya-analytics/x/kv/memkv"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+const (
+	benchResources = 100_000
+	benchFanout    = 10
+)
+
+var (
+	benchOnce sync.Once
+	benchDB   *gorp.DB
+	benchOtg  *ontology.Ontology
+)
+
+// openBench opens an ontology containing a tree of benchResources resources, where
+// each resource is a child of the resource at index i/benchFanout.
+func openBench(b *testing.B) (*gorp.DB, *ontology.Ontology) {
+	benchOnce.Do(func() {
+		benchDB = gorp.Wrap(memkv.New())
+		var err error
+		if benchOtg, err = ontology.Open(benchDB); err != nil {
+			b.Fatal(err)
+		}
+		benchOtg.RegisterService(&emptyService{})
+		txn := benchOtg.BeginTxn()
+		w := benchOtg.NewWriter(txn)
+		for i := 0; i < benchResources; i++ {
+			if err := w.DefineResource(benchID(i)); err != nil {
+				b.Fatal(err)
+			}
+			if i == 0 {
+				continue
+			}
+			if err := w.DefineRelationship(benchID(i), benchID(i/benchFanout), ontology.Parent); err != nil {
+				b.Fatal(err)
+			}
+		}
+		if err := txn.Commit(); err != nil {
+			b.Fatal(err)
+		}
+		if err := txn.Close(); err != nil {
+			b.Fatal(err)
+		}
+	})
+	return benchDB, benchOtg
+}
+
+func benchID(i int) ontology.ID { return newEmptyID(strconv.Itoa(i)) }
+
+func benchmarkTraverse(b *testing.B, t ontology.Traverser) {
+	_, otg := openBench(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var res []ontology.Resource
+		if err := otg.NewRetrieve().
+			WhereIDs(benchID(1)).
+			TraverseTo(t).
+			Entries(&res).
+			Exec(); err != nil {
+			b.Fatal(err)
+		}
+		if len(res) != benchFanout {
+			b.Fatalf("expected %d children, got %d", benchFanout, len(res))
+		}
+	}
+}
+
+// BenchmarkTraverseChildrenIndexed traverses using the adjacency index.
+func BenchmarkTraverseChildrenIndexed(b *testing.B) {
+	benchmarkTraverse(b, ontology.Children)
+}
+
+// BenchmarkTraverseChildrenScan traverses by scanning every relationship.
+func BenchmarkTraverseChildrenScan(b *testing.B) {
+	benchmarkTraverse(b, ontology.Traverser{
+		Filter: func(res *ontology.Resource, rel *ontology.Relationship) bool {
+			return rel.Type == ontology.Parent && rel.To == res.ID
+		},
+		Direction: ontology.Backward,
+	})
+}
+
+// BenchmarkDefineRelationship defines a relationship between two leaves of the tree,
+// which requires a cycle check from the new parent.
+func BenchmarkDefineRelationship(b *testing.B) {
+	_, otg := openBench(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		txn := otg.BeginTxn()
+		w := otg.NewWriter(txn)
+		if err := w.DefineRelationship(
+			benchID(benchResources-1),
+			benchID(benchResources-2),
+			ontology.Parent,
+		); err != nil {
+			b.Fatal(err)
+		}
+		if err := txn.Close(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package ontology
 
+import "github.com/arya-analytics/delta/pkg/ontology/schema"
+
 const (
 	BuiltIn   Type = "builtin"
 	RouteType Type = "route"
@@ -10,3 +12,46 @@ var Root = ID{Type: BuiltIn, Key: "root"}
 func RouteKey(path string) ID {
 	return ID{Type: RouteType, Key: path}
 }
+
+// |||||| BUILT IN ||||||
+
+var builtInSchema = &Schema{
+	Type:   BuiltIn,
+	Fields: map[string]schema.Field{"key": {Type: schema.String}},
+}
+
+// builtInService exposes the resources built into the ontology, such as Root.
+type builtInService struct{}
+
+var _ Service = builtInService{}
+
+// Schema implements the Service interface.
+func (builtInService) Schema() *Schema { return builtInSchema }
+
+// RetrieveEntity implements the Service interface.
+func (builtInService) RetrieveEntity(key string) (Entity, error) {
+	e := schema.NewEntity(builtInSchema)
+	return e, schema.Set(e, "key", key)
+}
+
+// |||||| ROUTE ||||||
+
+var routeSchema = &Schema{
+	Type:   RouteType,
+	Fields: map[string]schema.Field{"path": {Type: schema.String, Required: true}},
+}
+
+// routeService exposes API routes, which are defined as resources so that access to
+// them can be controlled.
+type routeService struct{}
+
+var _ Service = routeService{}
+
+// Schema implements the Service interface.
+func (routeService) Schema() *Schema { return routeSchema }
+
+// RetrieveEntity implements the Service interface.
+func (routeService) RetrieveEntity(path string) (Entity, error) {
+	e := schema.NewEntity(routeSchema)
+	return e, schema.Set(e, "path", path)
+}
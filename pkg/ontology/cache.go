@@ -0,0 +1,97 @@
+package ontology
+
+import (
+	"container/list"
+	"sync"
+)
+
+// WithEntityCache caches up to capacity entities in memory, so that repeated retrievals
+// of the same resources don't call their Services. Cached entities are invalidated
+// when they're updated or deleted through a Writer, and again when the Writer's Txn
+// commits. Services that write entities without using UpdateEntity must call
+// Writer.InvalidateEntities.
+//
+// The cache is local to the Ontology and isn't invalidated by writes made on other
+// nodes, so it's unsafe to use for an ontology shared across a cluster (such as one
+// opened WithRelay): other nodes would keep serving stale entities indefinitely.
+func WithEntityCache(capacity int) Option {
+	return func(o *Ontology) { o.retrieve.cache = newEntityCache(capacity) }
+}
+
+// InvalidateEntities removes the entities of the resources with the given IDs from the
+// entity cache. InvalidateEntities does nothing if the Ontology has no cache. Prefer
+// Writer.InvalidateEntities when writing in a transaction, as it invalidates the
+// entities again once the transaction commits.
+func (o *Ontology) InvalidateEntities(ids ...ID) { o.retrieve.cache.invalidate(ids...) }
+
+// entityCache is a least-recently-used cache of entities. A nil entityCache caches
+// nothing.
+type entityCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	items    map[ID]*list.Element
+}
+
+type cacheEntry struct {
+	id     ID
+	entity Entity
+}
+
+func newEntityCache(capacity int) *entityCache {
+	return &entityCache{
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[ID]*list.Element, capacity),
+	}
+}
+
+// get returns a copy of the cached entity, so that callers can't modify the cache.
+func (c *entityCache) get(id ID) (Entity, bool) {
+	if c == nil {
+		return Entity{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[id]
+	if !ok {
+		return Entity{}, false
+	}
+	c.order.MoveToFront(el)
+	e := el.Value.(*cacheEntry).entity
+	return e.Project(e.Keys()...), true
+}
+
+func (c *entityCache) put(id ID, e Entity) {
+	if c == nil || c.capacity <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e = e.Project(e.Keys()...)
+	if el, ok := c.items[id]; ok {
+		el.Value.(*cacheEntry).entity = e
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[id] = c.order.PushFront(&cacheEntry{id: id, entity: e})
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheEntry).id)
+	}
+}
+
+func (c *entityCache) invalidate(ids ...ID) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, id := range ids {
+		if el, ok := c.items[id]; ok {
+			c.order.Remove(el)
+			delete(c.items, id)
+		}
+	}
+}
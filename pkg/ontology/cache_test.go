@@ -0,0 +1,154 @@
+package ontology_test
+
+import (
+	"github.com/arya-analytics/delta/pkg/ontology"
+	"github.com/arya-analytics/delta/pkg/ontology/schema"
+	"github.com/arya-analytics/x/gorp"
+	"github.com/arya-analytics/x/kv/memkv"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const batchType ontology.Type = "batch"
+
+var batchSchema = &ontology.Schema{
+	Type: batchType,
+	Fields: map[string]schema.Field{
+		"key":  {Type: schema.String},
+		"name": {Type: schema.String},
+	},
+}
+
+// batchService is a mutable BatchService that counts the calls made to it.
+type batchService struct {
+	names        map[string]string
+	singleCalls  int
+	batchCalls   int
+	batchedCount int
+}
+
+func (s *batchService) Schema() *ontology.Schema { return batchSchema }
+
+func (s *batchService) entity(key string) ontology.Entity {
+	e := schema.NewEntity(batchSchema)
+	Expect(schema.Set(e, "key", key)).To(Succeed())
+	Expect(schema.Set(e, "name", s.names[key])).To(Succeed())
+	return e
+}
+
+func (s *batchService) RetrieveEntity(key string) (ontology.Entity, error) {
+	s.singleCalls++
+	return s.entity(key), nil
+}
+
+func (s *batchService) RetrieveEntities(keys []string) ([]ontology.Entity, error) {
+	s.batchCalls++
+	s.batchedCount += len(keys)
+	entities := make([]ontology.Entity, len(keys))
+	for i, k := range keys {
+		entities[i] = s.entity(k)
+	}
+	return entities, nil
+}
+
+func (s *batchService) CreateEntity(_ gorp.Txn, e ontology.Entity) (string, error) {
+	key, _ := schema.Get[string](e, "key")
+	s.names[key], _ = schema.Get[string](e, "name")
+	return key, nil
+}
+
+func (s *batchService) UpdateEntity(_ gorp.Txn, key string, e ontology.Entity) error {
+	s.names[key], _ = schema.Get[string](e, "name")
+	return nil
+}
+
+var _ = Describe("Entity Retrieval", func() {
+	var (
+		cacheDB *gorp.DB
+		o       *ontology.Ontology
+		svc     *batchService
+		ids     []ontology.ID
+	)
+	BeforeEach(func() {
+		cacheDB = gorp.Wrap(memkv.New())
+		var err error
+		o, err = ontology.Open(cacheDB, ontology.WithEntityCache(10))
+		Expect(err).ToNot(HaveOccurred())
+		svc = &batchService{names: make(map[string]string)}
+		o.RegisterService(&emptyService{})
+		o.RegisterService(svc)
+		ids = nil
+		w := o.NewWriter(cacheDB)
+		for _, key := range []string{"A", "B", "C"} {
+			e := schema.NewEntity(batchSchema)
+			Expect(schema.Set(e, "key", key)).To(Succeed())
+			Expect(schema.Set(e, "name", "name-"+key)).To(Succeed())
+			id, err := w.CreateEntity(e)
+			Expect(err).ToNot(HaveOccurred())
+			ids = append(ids, id)
+		}
+	})
+	AfterEach(func() { Expect(cacheDB.Close()).To(Succeed()) })
+	retrieveNames := func(ids ...ontology.ID) []string {
+		var res []ontology.Resource
+		Expect(o.NewRetrieve().WhereIDs(ids...).Entries(&res).Exec()).To(Succeed())
+		names := make([]string, len(res))
+		for i, r := range res {
+			names[i], _ = schema.Get[string](r.Entity(), "name")
+		}
+		return names
+	}
+	It("Should retrieve the entities of a type in a single batch", func() {
+		Expect(o.NewWriter(cacheDB).DefineResource(newEmptyID("A"))).To(Succeed())
+		Expect(retrieveNames(append(ids, newEmptyID("A"))...)).
+			To(ConsistOf("name-A", "name-B", "name-C", ""))
+		Expect(svc.batchCalls).To(Equal(1))
+		Expect(svc.singleCalls).To(Equal(0))
+	})
+	It("Should only retrieve the entities on the requested page", func() {
+		o.InvalidateEntities(ids...)
+		var res []ontology.Resource
+		Expect(o.NewRetrieve().WhereIDs(ids...).Limit(2).Entries(&res).Exec()).To(Succeed())
+		Expect(res).To(HaveLen(2))
+		Expect(svc.batchedCount).To(Equal(2))
+	})
+	It("Should serve repeated retrievals from the cache", func() {
+		retrieveNames(ids...)
+		retrieveNames(ids...)
+		Expect(svc.batchCalls).To(Equal(1))
+		Expect(svc.batchedCount).To(Equal(3))
+	})
+	It("Should invalidate entities updated through a Writer", func() {
+		retrieveNames(ids...)
+		e := schema.NewEntity(batchSchema)
+		Expect(schema.Set(e, "name", "updated")).To(Succeed())
+		Expect(o.NewWriter(cacheDB).UpdateEntity(ids[0], e)).To(Succeed())
+		Expect(retrieveNames(ids[0])).To(Equal([]string{"updated"}))
+	})
+	It("Should invalidate entities on request", func() {
+		retrieveNames(ids...)
+		svc.names["B"] = "changed"
+		Expect(retrieveNames(ids[1])).To(Equal([]string{"name-B"}))
+		o.InvalidateEntities(ids[1])
+		Expect(retrieveNames(ids[1])).To(Equal([]string{"changed"}))
+	})
+	It("Should invalidate entities through a Writer when its transaction commits", func() {
+		retrieveNames(ids...)
+		txn := o.BeginTxn()
+		svc.names["C"] = "changed"
+		o.NewWriter(txn).InvalidateEntities(ids[2])
+		// Entities retrieved before the transaction commits are cached again.
+		Expect(retrieveNames(ids[2])).To(Equal([]string{"changed"}))
+		svc.names["C"] = "committed"
+		Expect(retrieveNames(ids[2])).To(Equal([]string{"changed"}))
+		Expect(txn.Commit()).To(Succeed())
+		Expect(txn.Close()).To(Succeed())
+		Expect(retrieveNames(ids[2])).To(Equal([]string{"committed"}))
+	})
+	It("Should not let callers modify cached entities", func() {
+		var r ontology.Resource
+		Expect(o.NewRetrieve().WhereIDs(ids[0]).Entry(&r).Exec()).To(Succeed())
+		Expect(schema.Set(r.Entity(), "name", "modified")).To(Succeed())
+		Expect(retrieveNames(ids[0])).To(Equal([]string{"name-A"}))
+	})
+})
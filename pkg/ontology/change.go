@@ -0,0 +1,256 @@
+package ontology
+
+import (
+	"context"
+	"github.com/arya-analytics/x/gorp"
+	"github.com/cockroachdb/errors"
+	"sync"
+)
+
+// ChangeVariant is the kind of change made to the ontology.
+type ChangeVariant uint8
+
+const (
+	ResourceDefined ChangeVariant = iota + 1
+	ResourceDeleted
+	RelationshipDefined
+	RelationshipDeleted
+)
+
+func (v ChangeVariant) String() string {
+	switch v {
+	case ResourceDefined:
+		return "resource_defined"
+	case ResourceDeleted:
+		return "resource_deleted"
+	case RelationshipDefined:
+		return "relationship_defined"
+	case RelationshipDeleted:
+		return "relationship_deleted"
+	default:
+		return "unknown"
+	}
+}
+
+// Change is a single change made to the ontology by a Writer.
+type Change struct {
+	// Seq orders changes. Seq increases monotonically across all changes published by
+	// an Ontology.
+	Seq uint64
+	// Variant is the kind of change.
+	Variant ChangeVariant
+	// ID is the ID of the resource that was defined or deleted. ID is zero for
+	// relationship changes.
+	ID ID
+	// Relationship is the relationship that was defined or deleted. Relationship is
+	// zero for resource changes.
+	Relationship Relationship
+}
+
+// IDs returns the IDs of the resources affected by the change.
+func (c Change) IDs() []ID {
+	if c.Variant == RelationshipDefined || c.Variant == RelationshipDeleted {
+		return []ID{c.Relationship.From, c.Relationship.To}
+	}
+	return []ID{c.ID}
+}
+
+// Txn is a gorp.Txn that publishes the changes made by Writers opened on it once it
+// commits. Writes to the ontology must be made on a Txn, unless they're made directly
+// on the database.
+type Txn struct {
+	gorp.Txn
+	feed    *feed
+	mu      sync.Mutex
+	changes []Change
+	hooks   []func()
+	// relayed holds the graph changes made by Writers opened WithRelay, which are
+	// relayed when the transaction commits.
+	relay   Relay
+	relayed []Change
+}
+
+// BeginTxn begins a new Txn on the Ontology's database.
+func (o *Ontology) BeginTxn() *Txn { return &Txn{Txn: o.db.BeginTxn(), feed: o.feed} }
+
+// Commit commits the transaction and publishes its changes to Watch subscribers. If
+// the transaction holds relayed changes, they're relayed first, and the transaction
+// isn't committed if the Relay returns an error.
+func (t *Txn) Commit() error {
+	t.mu.Lock()
+	relay, relayed := t.relay, t.relayed
+	t.relayed = nil
+	t.mu.Unlock()
+	if len(relayed) > 0 {
+		if err := relay(relayed); err != nil {
+			return err
+		}
+	}
+	if err := t.Txn.Commit(); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	changes, hooks := t.changes, t.hooks
+	t.changes, t.hooks = nil, nil
+	t.mu.Unlock()
+	for _, h := range hooks {
+		h()
+	}
+	t.feed.publish(changes)
+	return nil
+}
+
+// Changes returns the changes made on the transaction so far, which are published to
+// Watch subscribers when it commits. Relayed changes aren't included.
+func (t *Txn) Changes() []Change {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	changes := make([]Change, len(t.changes))
+	copy(changes, t.changes)
+	return changes
+}
+
+// afterCommit registers a function to run after the transaction commits.
+func (t *Txn) afterCommit(h func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.hooks = append(t.hooks, h)
+}
+
+// queueRelay queues a change to be relayed when the transaction commits.
+func (t *Txn) queueRelay(r Relay, c Change) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.relay = r
+	t.relayed = append(t.relayed, c)
+}
+
+func (t *Txn) record(c Change) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.changes = append(t.changes, c)
+}
+
+// UnsupportedTxn is returned when writing to the ontology on a transaction whose
+// changes can't be published to Watch subscribers.
+var UnsupportedTxn = errors.New("[ontology] - unsupported transaction")
+
+// recorder returns a function that records changes made by a Writer opened on txn.
+// Changes made on a Txn are published when it commits, and changes made directly on
+// the database are published immediately. Returns UnsupportedTxn for any other
+// transaction, as there's no way to know when it commits.
+func (o *Ontology) recorder(txn gorp.Txn) (func(Change), error) {
+	switch t := txn.(type) {
+	case *Txn:
+		return t.record, nil
+	case *gorp.DB:
+		return func(c Change) { o.feed.publish([]Change{c}) }, nil
+	default:
+		return nil, errors.Wrapf(
+			UnsupportedTxn,
+			"[ontology] - writes must be made on a Txn opened with BeginTxn, not %T",
+			txn,
+		)
+	}
+}
+
+// DefaultWatchBuffer is the default maximum number of changes buffered for a single
+// Watch subscriber.
+const DefaultWatchBuffer = 1024
+
+// WithWatchBuffer sets the maximum number of changes buffered for each Watch
+// subscriber. A subscriber that falls further behind is dropped with
+// SubscriberTooSlow. Defaults to DefaultWatchBuffer.
+func WithWatchBuffer(capacity int) Option { return func(o *Ontology) { o.feed.capacity = capacity } }
+
+// SubscriberTooSlow is returned by Subscription.Err when a subscriber is dropped for
+// falling too far behind the changes published to it.
+var SubscriberTooSlow = errors.New("[ontology] - watch subscriber fell too far behind")
+
+// feed publishes committed changes to subscribers in order.
+type feed struct {
+	mu       sync.Mutex
+	seq      uint64
+	capacity int
+	subs     map[*subscriber]struct{}
+}
+
+func newFeed() *feed {
+	return &feed{capacity: DefaultWatchBuffer, subs: make(map[*subscriber]struct{})}
+}
+
+func (f *feed) publish(changes []Change) {
+	if len(changes) == 0 {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := range changes {
+		f.seq++
+		changes[i].Seq = f.seq
+	}
+	for sub := range f.subs {
+		if !sub.enqueue(changes, f.capacity) {
+			delete(f.subs, sub)
+		}
+	}
+}
+
+func (f *feed) subscribe(ctx context.Context) *subscriber {
+	sub := &subscriber{notify: make(chan struct{}, 1), out: make(chan Change)}
+	f.mu.Lock()
+	f.subs[sub] = struct{}{}
+	f.mu.Unlock()
+	go func() {
+		<-ctx.Done()
+		f.mu.Lock()
+		delete(f.subs, sub)
+		f.mu.Unlock()
+	}()
+	return sub
+}
+
+// subscriber buffers changes for a single subscriber so that slow subscribers never
+// block a committing transaction.
+type subscriber struct {
+	mu     sync.Mutex
+	queue  []Change
+	err    error
+	notify chan struct{}
+	out    chan Change
+}
+
+// enqueue adds changes to the queue. If the queue would grow beyond capacity, the
+// queued changes are discarded, the subscriber fails with SubscriberTooSlow, and
+// enqueue returns false.
+func (s *subscriber) enqueue(changes []Change, capacity int) bool {
+	s.mu.Lock()
+	ok := s.err == nil && len(s.queue)+len(changes) <= capacity
+	if ok {
+		s.queue = append(s.queue, changes...)
+	} else {
+		s.queue, s.err = nil, SubscriberTooSlow
+	}
+	s.mu.Unlock()
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+	return ok
+}
+
+// dequeue returns and clears the queued changes, or the error the subscriber failed
+// with.
+func (s *subscriber) dequeue() ([]Change, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q := s.queue
+	s.queue = nil
+	return q, s.err
+}
+
+func (s *subscriber) error() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
@@ -0,0 +1,155 @@
+package ontology
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"github.com/arya-analytics/delta/pkg/ontology/schema"
+	"io"
+	"sort"
+)
+
+// idJSON is the JSON representation of an ID.
+type idJSON struct {
+	Type Type   `json:"type"`
+	Key  string `json:"key"`
+}
+
+func newIDJSON(id ID) idJSON { return idJSON{Type: id.Type, Key: id.Key} }
+
+func (i idJSON) id() ID { return ID{Type: i.Type, Key: i.Key} }
+
+// resourceJSON is the JSON representation of a Resource.
+type resourceJSON struct {
+	ID     idJSON          `json:"id"`
+	Entity json.RawMessage `json:"entity,omitempty"`
+}
+
+// EncodeJSON encodes the resource as a JSON object with an 'id' holding its type and
+// key, and an 'entity' holding its entity encoded with schema.Entity.MarshalJSON.
+func EncodeJSON(r Resource) ([]byte, error) {
+	rj, err := encodeResourceJSON(r)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(rj)
+}
+
+func encodeResourceJSON(r Resource) (resourceJSON, error) {
+	rj := resourceJSON{ID: newIDJSON(r.ID)}
+	if r.entity.Schema() == nil {
+		return rj, nil
+	}
+	b, err := json.Marshal(r.entity)
+	rj.Entity = b
+	return rj, err
+}
+
+// DecodeJSON decodes a resource encoded with EncodeJSON, using the Schema of the
+// Service registered for its type to decode the entity.
+func (o *Ontology) DecodeJSON(data []byte) (Resource, error) {
+	var rj resourceJSON
+	if err := json.Unmarshal(data, &rj); err != nil {
+		return Resource{}, err
+	}
+	return o.decodeResourceJSON(rj)
+}
+
+func (o *Ontology) decodeResourceJSON(rj resourceJSON) (Resource, error) {
+	r := Resource{ID: rj.ID.id()}
+	if len(rj.Entity) == 0 || string(rj.Entity) == "null" {
+		return r, nil
+	}
+	s, err := o.schema(r.ID.Type)
+	if err != nil {
+		return r, err
+	}
+	r.entity, err = schema.DecodeJSON(s, rj.Entity)
+	return r, err
+}
+
+// EncodeBinary encodes the resource into a compact binary format. The entity is
+// encoded with schema.EncodeBinary.
+func EncodeBinary(r Resource) ([]byte, error) {
+	var buf bytes.Buffer
+	writeBytes(&buf, []byte(r.ID.Type))
+	writeBytes(&buf, []byte(r.ID.Key))
+	if r.entity.Schema() == nil {
+		return buf.Bytes(), nil
+	}
+	b, err := schema.EncodeBinary(r.entity)
+	if err != nil {
+		return nil, err
+	}
+	writeBytes(&buf, b)
+	return buf.Bytes(), nil
+}
+
+// DecodeBinary decodes a resource encoded with EncodeBinary, using the Schema of the
+// Service registered for its type to decode the entity.
+func (o *Ontology) DecodeBinary(data []byte) (Resource, error) {
+	var (
+		r   Resource
+		buf = bytes.NewReader(data)
+	)
+	t, err := readBytes(buf)
+	if err != nil {
+		return r, err
+	}
+	key, err := readBytes(buf)
+	if err != nil {
+		return r, err
+	}
+	r.ID = ID{Type: Type(t), Key: string(key)}
+	if buf.Len() == 0 {
+		return r, nil
+	}
+	b, err := readBytes(buf)
+	if err != nil {
+		return r, err
+	}
+	s, err := o.schema(r.ID.Type)
+	if err != nil {
+		return r, err
+	}
+	r.entity, err = schema.DecodeBinary(s, b)
+	return r, err
+}
+
+// Schemas returns the Schemas of all registered Services, sorted by Type. Clients can
+// use the Schemas to generate typed bindings for the entities of each Type.
+func (o *Ontology) Schemas() []*Schema {
+	schemas := make([]*Schema, 0, len(o.retrieve.services))
+	for _, svc := range o.retrieve.services {
+		schemas = append(schemas, svc.Schema())
+	}
+	sort.Slice(schemas, func(i, j int) bool { return schemas[i].Type < schemas[j].Type })
+	return schemas
+}
+
+func (o *Ontology) schema(t Type) (*Schema, error) {
+	svc, err := o.retrieve.services.get(t)
+	if err != nil {
+		return nil, err
+	}
+	return svc.Schema(), nil
+}
+
+func writeBytes(buf *bytes.Buffer, b []byte) {
+	var n [binary.MaxVarintLen64]byte
+	buf.Write(n[:binary.PutUvarint(n[:], uint64(len(b)))])
+	buf.Write(b)
+}
+
+func readBytes(r *bytes.Reader) ([]byte, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(r.Len()) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := make([]byte, n)
+	_, err = io.ReadFull(r, b)
+	return b, err
+}
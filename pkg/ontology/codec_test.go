@@ -0,0 +1,56 @@
+package ontology_test
+
+import (
+	"github.com/arya-analytics/delta/pkg/ontology"
+	"github.com/arya-analytics/delta/pkg/ontology/schema"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Codec", func() {
+	var r ontology.Resource
+	BeforeEach(func() {
+		w := otg.NewWriter(txn)
+		e := schema.NewEntity(mutableSchema)
+		Expect(schema.Set(e, "key", "codec")).To(Succeed())
+		Expect(schema.Set(e, "name", "foo")).To(Succeed())
+		id, err := w.CreateEntity(e)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(w.NewRetrieve().WhereIDs(id).Entry(&r).Exec()).To(Succeed())
+	})
+	It("Should encode and decode a resource as JSON", func() {
+		b, err := ontology.EncodeJSON(r)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(b)).To(Equal(
+			`{"id":{"type":"mutable","key":"codec"},"entity":{"key":"codec","name":"foo"}}`,
+		))
+		decoded, err := otg.DecodeJSON(b)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decoded.ID).To(Equal(r.ID))
+		Expect(decoded.Entity().Data()).To(Equal(r.Entity().Data()))
+	})
+	It("Should encode and decode a resource as binary", func() {
+		b, err := ontology.EncodeBinary(r)
+		Expect(err).ToNot(HaveOccurred())
+		decoded, err := otg.DecodeBinary(b)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decoded.ID).To(Equal(r.ID))
+		Expect(decoded.Entity().Data()).To(Equal(r.Entity().Data()))
+	})
+	It("Should return an error when decoding a resource of an unregistered type", func() {
+		_, err := otg.DecodeJSON([]byte(`{"id":{"type":"unknown","key":"a"},"entity":{}}`))
+		Expect(err).To(MatchError(ontology.UnknownType))
+	})
+	It("Should return the schemas of all registered types", func() {
+		var types []ontology.Type
+		for _, s := range otg.Schemas() {
+			types = append(types, s.Type)
+		}
+		Expect(types).To(Equal([]ontology.Type{
+			ontology.BuiltIn,
+			emptyType,
+			mutableType,
+			ontology.RouteType,
+		}))
+	})
+})
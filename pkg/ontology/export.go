@@ -0,0 +1,234 @@
+package ontology
+
+import (
+	"encoding/json"
+	"github.com/arya-analytics/x/gorp"
+	"github.com/cockroachdb/errors"
+	"io"
+	"sort"
+)
+
+// snapshotVersion is the version of the snapshot format written by Export. Import
+// rejects snapshots of any other version.
+const snapshotVersion = 1
+
+// snapshotJSON is the JSON representation of a snapshot of the ontology.
+type snapshotJSON struct {
+	Version       int                `json:"version"`
+	Resources     []resourceJSON     `json:"resources"`
+	Relationships []relationshipJSON `json:"relationships"`
+}
+
+// relationshipJSON is the JSON representation of a Relationship.
+type relationshipJSON struct {
+	From idJSON           `json:"from"`
+	To   idJSON           `json:"to"`
+	Type RelationshipType `json:"type"`
+}
+
+// |||||| EXPORT ||||||
+
+type ExportOption func(*exportOptions)
+
+type exportOptions struct {
+	entities bool
+}
+
+// WithEntities includes the entity of each resource in the snapshot. Returns an error
+// from Export if the entity of a resource can't be retrieved.
+func WithEntities() ExportOption { return func(o *exportOptions) { o.entities = true } }
+
+// Export writes a snapshot of every resource and relationship in the ontology to w as
+// JSON. The snapshot can be loaded into another ontology with Import.
+func (o *Ontology) Export(txn gorp.Txn, w io.Writer, opts ...ExportOption) error {
+	eo := &exportOptions{}
+	for _, opt := range opts {
+		opt(eo)
+	}
+	var (
+		resources     []Resource
+		relationships []Relationship
+	)
+	if err := gorp.NewRetrieve[ID, Resource]().Entries(&resources).Exec(txn); err != nil {
+		return err
+	}
+	if err := gorp.NewRetrieve[string, Relationship]().
+		Entries(&relationships).
+		Exec(txn); err != nil {
+		return err
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		return resources[i].ID.String() < resources[j].ID.String()
+	})
+	sort.Slice(relationships, func(i, j int) bool {
+		return relationships[i].GorpKey() < relationships[j].GorpKey()
+	})
+	snap := snapshotJSON{
+		Version:       snapshotVersion,
+		Resources:     make([]resourceJSON, len(resources)),
+		Relationships: make([]relationshipJSON, len(relationships)),
+	}
+	for i, r := range resources {
+		if eo.entities {
+			var err error
+			if r.entity, err = o.retrieve.services.RetrieveEntity(r.ID); err != nil {
+				return err
+			}
+		}
+		rj, err := encodeResourceJSON(r)
+		if err != nil {
+			return err
+		}
+		snap.Resources[i] = rj
+	}
+	for i, rel := range relationships {
+		snap.Relationships[i] = relationshipJSON{
+			From: newIDJSON(rel.From),
+			To:   newIDJSON(rel.To),
+			Type: rel.Type,
+		}
+	}
+	return json.NewEncoder(w).Encode(snap)
+}
+
+// |||||| IMPORT ||||||
+
+type ImportOption func(*importOptions)
+
+type importOptions struct {
+	merge bool
+}
+
+// MergeEntities updates the entities of resources that already exist with the
+// entities in the snapshot. By default, resources that already exist are skipped.
+func MergeEntities() ImportOption { return func(o *importOptions) { o.merge = true } }
+
+// ImportResult summarizes the changes made by Import.
+type ImportResult struct {
+	// Created is the number of resources that didn't exist before the import.
+	Created int
+	// Merged is the number of existing resources whose entities were updated.
+	Merged int
+	// Skipped is the number of existing resources that were left unchanged.
+	Skipped int
+	// Relationships is the number of relationships defined by the import.
+	Relationships int
+	// Remapped maps the IDs of resources in the snapshot to their IDs in the ontology,
+	// for entities that were assigned a new key when they were created.
+	Remapped map[ID]ID
+}
+
+// Import loads a snapshot written by Export into the ontology using the provided
+// transaction. Entities in the snapshot are created using the MutableService for
+// their type. If the Service assigns the entity a different key, relationships are
+// remapped to the new ID. Entities of read-only types are ignored, and only their
+// resources are defined.
+//
+// Import returns an error if the snapshot contains an invalid ID or an unregistered
+// relationship type, or if a relationship violates the constraints of its type (e.g.
+// it would create a cycle). The transaction is left partially written on error, so
+// it should be discarded instead of committed. If the Ontology was opened WithRelay,
+// graph changes are relayed when txn commits, and constraint violations are returned
+// by the commit instead of Import.
+func (o *Ontology) Import(txn gorp.Txn, r io.Reader, opts ...ImportOption) (ImportResult, error) {
+	iOpts := &importOptions{}
+	for _, opt := range opts {
+		opt(iOpts)
+	}
+	res := ImportResult{Remapped: make(map[ID]ID)}
+	var snap snapshotJSON
+	if err := json.NewDecoder(r).Decode(&snap); err != nil {
+		return res, err
+	}
+	if snap.Version != snapshotVersion {
+		return res, errors.Newf("[ontology] - unsupported snapshot version %d", snap.Version)
+	}
+	if err := o.validateSnapshot(snap); err != nil {
+		return res, err
+	}
+	w := o.newWriter(txn, o.relay)
+	for _, rj := range snap.Resources {
+		if err := o.importResource(w, rj, iOpts, &res); err != nil {
+			return res, err
+		}
+	}
+	for _, rj := range snap.Relationships {
+		from, to := remap(res.Remapped, rj.From.id()), remap(res.Remapped, rj.To.id())
+		if err := w.DefineRelationship(from, to, rj.Type); err != nil {
+			return res, errors.Wrapf(err, "[ontology] - relationship %s -> %s", from, to)
+		}
+		res.Relationships++
+	}
+	return res, nil
+}
+
+func (o *Ontology) validateSnapshot(snap snapshotJSON) error {
+	for _, rj := range snap.Resources {
+		if err := rj.ID.id().Validate(); err != nil {
+			return err
+		}
+	}
+	for _, rj := range snap.Relationships {
+		if err := rj.From.id().Validate(); err != nil {
+			return err
+		}
+		if err := rj.To.id().Validate(); err != nil {
+			return err
+		}
+		if _, err := o.relationships.get(rj.Type); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o *Ontology) importResource(
+	w dagWriter,
+	rj resourceJSON,
+	opts *importOptions,
+	res *ImportResult,
+) error {
+	id := rj.ID.id()
+	exists, err := w.resourceExists(id)
+	if err != nil {
+		return err
+	}
+	hasEntity := len(rj.Entity) > 0 && string(rj.Entity) != "null"
+	_, mutableErr := o.retrieve.services.mutable(id.Type)
+	if !hasEntity || mutableErr != nil {
+		if exists {
+			res.Skipped++
+			return nil
+		}
+		res.Created++
+		return w.DefineResource(id)
+	}
+	r, err := o.decodeResourceJSON(rj)
+	if err != nil {
+		return err
+	}
+	if exists {
+		if !opts.merge {
+			res.Skipped++
+			return nil
+		}
+		res.Merged++
+		return w.UpdateEntity(id, r.entity)
+	}
+	newID, err := w.CreateEntity(r.entity)
+	if err != nil {
+		return err
+	}
+	if newID != id {
+		res.Remapped[id] = newID
+	}
+	res.Created++
+	return nil
+}
+
+func remap(ids map[ID]ID, id ID) ID {
+	if newID, ok := ids[id]; ok {
+		return newID
+	}
+	return id
+}
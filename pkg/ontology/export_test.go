@@ -0,0 +1,142 @@
+package ontology_test
+
+import (
+	"bytes"
+	"github.com/arya-analytics/delta/pkg/ontology"
+	"github.com/arya-analytics/delta/pkg/ontology/schema"
+	"github.com/arya-analytics/x/gorp"
+	"github.com/arya-analytics/x/kv/memkv"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"strings"
+)
+
+func openExportOntology() (*gorp.DB, *ontology.Ontology, *mutableService) {
+	db := gorp.Wrap(memkv.New())
+	o, err := ontology.Open(db)
+	Expect(err).ToNot(HaveOccurred())
+	svc := &mutableService{entities: make(map[string]ontology.Entity)}
+	o.RegisterService(&emptyService{})
+	o.RegisterService(svc)
+	return db, o, svc
+}
+
+func newMutableEntity(key, name string) ontology.Entity {
+	e := schema.NewEntity(mutableSchema)
+	Expect(schema.Set(e, "key", key)).To(Succeed())
+	Expect(schema.Set(e, "name", name)).To(Succeed())
+	return e
+}
+
+var _ = Describe("Export", func() {
+	var (
+		srcDB, dstDB *gorp.DB
+		src, dst     *ontology.Ontology
+		dstSvc       *mutableService
+		a, b         ontology.ID
+	)
+	BeforeEach(func() {
+		srcDB, src, _ = openExportOntology()
+		dstDB, dst, dstSvc = openExportOntology()
+		w := src.NewWriter(srcDB)
+		a = newEmptyID("A")
+		Expect(w.DefineResource(a)).To(Succeed())
+		var err error
+		b, err = w.CreateEntity(newMutableEntity("B", "Bravo"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(w.DefineRelationship(a, b, ontology.Parent)).To(Succeed())
+		Expect(w.DefineRelationship(b, ontology.Root, ontology.Parent)).To(Succeed())
+	})
+	AfterEach(func() {
+		Expect(srcDB.Close()).To(Succeed())
+		Expect(dstDB.Close()).To(Succeed())
+	})
+	It("Should copy resources, relationships and entities into another ontology", func() {
+		var buf bytes.Buffer
+		Expect(src.Export(srcDB, &buf, ontology.WithEntities())).To(Succeed())
+		res, err := dst.Import(dstDB, &buf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.Created).To(Equal(2))
+		Expect(res.Skipped).To(Equal(1))
+		Expect(res.Relationships).To(Equal(2))
+		var r []ontology.Resource
+		Expect(dst.NewRetrieve().
+			WhereIDs(a).
+			TraverseTo(ontology.Ancestors).
+			Entries(&r).
+			Exec(),
+		).To(Succeed())
+		Expect(r).To(HaveLen(2))
+		name, ok := schema.Get[string](dstSvc.entities["B"], "name")
+		Expect(ok).To(BeTrue())
+		Expect(name).To(Equal("Bravo"))
+	})
+	It("Should omit entities unless they are requested", func() {
+		var buf bytes.Buffer
+		Expect(src.Export(srcDB, &buf)).To(Succeed())
+		Expect(buf.String()).ToNot(ContainSubstring("Bravo"))
+		_, err := dst.Import(dstDB, &buf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(dstSvc.entities).To(BeEmpty())
+	})
+	Describe("Existing Resources", func() {
+		var snapshot string
+		BeforeEach(func() {
+			var buf bytes.Buffer
+			Expect(src.Export(srcDB, &buf, ontology.WithEntities())).To(Succeed())
+			snapshot = buf.String()
+			_, err := dst.NewWriter(dstDB).CreateEntity(newMutableEntity("B", "Beta"))
+			Expect(err).ToNot(HaveOccurred())
+		})
+		It("Should skip existing resources by default", func() {
+			res, err := dst.Import(dstDB, strings.NewReader(snapshot))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res.Skipped).To(Equal(2))
+			name, _ := schema.Get[string](dstSvc.entities["B"], "name")
+			Expect(name).To(Equal("Beta"))
+		})
+		It("Should merge the entities of existing resources when requested", func() {
+			res, err := dst.Import(dstDB, strings.NewReader(snapshot), ontology.MergeEntities())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res.Merged).To(Equal(1))
+			name, _ := schema.Get[string](dstSvc.entities["B"], "name")
+			Expect(name).To(Equal("Bravo"))
+		})
+	})
+	Describe("Invalid Snapshots", func() {
+		It("Should reject a snapshot that would create a cycle", func() {
+			_, err := dst.Import(dstDB, strings.NewReader(`{
+				"version": 1,
+				"resources": [
+					{"id": {"type": "empty", "key": "A"}},
+					{"id": {"type": "empty", "key": "B"}}
+				],
+				"relationships": [
+					{"from": {"type": "empty", "key": "A"}, "to": {"type": "empty", "key": "B"}, "type": "parent"},
+					{"from": {"type": "empty", "key": "B"}, "to": {"type": "empty", "key": "A"}, "type": "parent"}
+				]
+			}`))
+			Expect(err).To(MatchError(ontology.CyclicDependency))
+		})
+		It("Should reject a snapshot with an invalid ID", func() {
+			_, err := dst.Import(dstDB, strings.NewReader(`{
+				"version": 1,
+				"resources": [{"id": {"type": "empty", "key": ""}}]
+			}`))
+			Expect(err).To(HaveOccurred())
+		})
+		It("Should reject a snapshot with an unknown relationship type", func() {
+			_, err := dst.Import(dstDB, strings.NewReader(`{
+				"version": 1,
+				"relationships": [
+					{"from": {"type": "empty", "key": "A"}, "to": {"type": "empty", "key": "B"}, "type": "unknown"}
+				]
+			}`))
+			Expect(err).To(MatchError(ontology.UnknownRelationshipType))
+		})
+		It("Should reject a snapshot of an unsupported version", func() {
+			_, err := dst.Import(dstDB, strings.NewReader(`{"version": 2}`))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,13 @@
+package fiber_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestFiber(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Ontology Fiber Suite")
+}
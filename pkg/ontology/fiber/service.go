@@ -0,0 +1,239 @@
+package fiber
+
+import (
+	"github.com/arya-analytics/delta/pkg/access"
+	fiberaccess "github.com/arya-analytics/delta/pkg/access/fiber"
+	"github.com/arya-analytics/delta/pkg/auth/apikey"
+	fiberauth "github.com/arya-analytics/delta/pkg/auth/fiber"
+	"github.com/arya-analytics/delta/pkg/auth/token"
+	"github.com/arya-analytics/delta/pkg/ontology"
+	"github.com/arya-analytics/x/query"
+	"github.com/cockroachdb/errors"
+	"github.com/gofiber/fiber/v2"
+	"net/url"
+	"strconv"
+)
+
+// MaxDepth is the maximum depth of a traversal request.
+const MaxDepth = 10
+
+// MaxQueryLimit is the maximum number of resources a query request can return. Queries
+// without a limit return at most MaxQueryLimit resources.
+const MaxQueryLimit = 1000
+
+// Service exposes the ontology over HTTP so that clients can browse resources and
+// the relationships between them. Resources the requesting user isn't allowed to
+// retrieve are omitted from responses.
+type Service struct {
+	Ontology *ontology.Ontology
+	Token    *token.Service
+	Enforcer access.Enforcer
+	// APIKeys authenticates requests made with API keys. Requests made with a key are
+	// enforced on behalf of the key's subject, within the key's scopes (see
+	// apikey.Enforcer). Optional.
+	APIKeys *apikey.Service
+}
+
+func (s *Service) BindTo(parent fiber.Router) {
+	router := parent.Group("/ontology")
+	router.Use(fiberauth.TokenMiddleware(s.Token, fiberauth.WithAPIKeys(s.APIKeys)))
+	router.Get("/resources/:type/:key", s.retrieve)
+	router.Get("/resources/:type/:key/children", s.traverse(ontology.Children))
+	router.Get("/resources/:type/:key/parents", s.traverse(ontology.Parents))
+	router.Get("/watch", s.watch)
+	router.Get("/schemas", s.schemas)
+	router.Post("/query", s.query)
+}
+
+// resourceResponse is the serialized form of an ontology.Resource. Data holds the
+// fields of the resource's entity as defined by its schema.Schema.
+type resourceResponse struct {
+	Type     ontology.Type      `json:"type"`
+	Key      string             `json:"key"`
+	Data     ontology.Entity    `json:"data"`
+	Children []resourceResponse `json:"children,omitempty"`
+}
+
+func newResourceResponse(r ontology.Resource) resourceResponse {
+	return resourceResponse{Type: r.ID.Type, Key: r.ID.Key, Data: r.Entity()}
+}
+
+// schemas returns the schemas of all resource types registered with the ontology.
+func (s *Service) schemas(c *fiber.Ctx) error { return c.JSON(s.Ontology.Schemas()) }
+
+// query executes the ontology.Query in the request body. Only resources the requesting
+// subject is allowed to retrieve are selected, traversed, filtered and returned.
+func (s *Service) query(c *fiber.Ctx) error {
+	var q ontology.Query
+	if err := c.BodyParser(&q); err != nil {
+		c.Status(fiber.StatusBadRequest)
+		return c.JSON(fiber.Map{"error": err.Error()})
+	}
+	if q.Limit > MaxQueryLimit {
+		c.Status(fiber.StatusBadRequest)
+		return c.JSON(fiber.Map{
+			"error": "limit must be at most " + strconv.Itoa(MaxQueryLimit),
+		})
+	}
+	if q.Limit == 0 {
+		q.Limit = MaxQueryLimit
+	}
+	subject, err := fiberaccess.GetSubject(c)
+	if err != nil {
+		return err
+	}
+	var enforceErr error
+	allow := func(id ontology.ID) bool {
+		err := s.enforce(subject, id)
+		if err != nil && !errors.Is(err, access.Denied) && enforceErr == nil {
+			enforceErr = err
+		}
+		return err == nil
+	}
+	r, err := s.Ontology.Compile(q, ontology.WithAllow(allow))
+	if err != nil {
+		c.Status(fiber.StatusBadRequest)
+		return c.JSON(fiber.Map{"error": err.Error()})
+	}
+	var resources []ontology.Resource
+	if err := r.Entries(&resources).Exec(); err != nil && !errors.Is(err, query.NotFound) {
+		if errors.Is(err, ontology.InvalidQuery) {
+			c.Status(fiber.StatusBadRequest)
+		} else {
+			c.Status(fiber.StatusInternalServerError)
+		}
+		return c.JSON(fiber.Map{"error": err.Error()})
+	}
+	if enforceErr != nil {
+		c.Status(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{"error": enforceErr.Error()})
+	}
+	res := make([]resourceResponse, len(resources))
+	for i, rsc := range resources {
+		res[i] = newResourceResponse(rsc)
+	}
+	return c.JSON(res)
+}
+
+func (s *Service) retrieve(c *fiber.Ctx) error {
+	res, ok, err := s.retrieveRequested(c)
+	if !ok {
+		return err
+	}
+	return c.JSON(newResourceResponse(res))
+}
+
+// traverse returns a handler that traverses the ontology from the requested
+// resource to the depth set by the 'depth' query parameter (defaults to 1).
+// Traversed resources are nested under the resource they were reached from. A
+// resource reachable through more than one path is nested under each of them, but
+// its own children are only traversed the first time it is reached.
+func (s *Service) traverse(t ontology.Traverser) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		depth, err := strconv.Atoi(c.Query("depth", "1"))
+		if err != nil || depth < 1 || depth > MaxDepth {
+			c.Status(fiber.StatusBadRequest)
+			return c.JSON(fiber.Map{
+				"error": "depth must be an integer between 1 and " + strconv.Itoa(MaxDepth),
+			})
+		}
+		res, ok, err := s.retrieveRequested(c)
+		if !ok {
+			return err
+		}
+		subject, err := fiberaccess.GetSubject(c)
+		if err != nil {
+			return err
+		}
+		tree, err := s.traverseFrom(subject, res, t, depth, make(map[ontology.ID]bool))
+		if err != nil {
+			c.Status(fiber.StatusInternalServerError)
+			return c.JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(tree)
+	}
+}
+
+func (s *Service) traverseFrom(
+	subject ontology.ID,
+	res ontology.Resource,
+	t ontology.Traverser,
+	depth int,
+	visited map[ontology.ID]bool,
+) (resourceResponse, error) {
+	r := newResourceResponse(res)
+	if depth == 0 || visited[res.ID] {
+		return r, nil
+	}
+	visited[res.ID] = true
+	var next []ontology.Resource
+	if err := s.Ontology.NewRetrieve().
+		WhereIDs(res.ID).
+		TraverseTo(t).
+		Entries(&next).
+		Exec(); err != nil && !errors.Is(err, query.NotFound) {
+		return r, err
+	}
+	for _, n := range next {
+		if err := s.enforce(subject, n.ID); errors.Is(err, access.Denied) {
+			continue
+		} else if err != nil {
+			return r, err
+		}
+		child, err := s.traverseFrom(subject, n, t, depth-1, visited)
+		if err != nil {
+			return r, err
+		}
+		r.Children = append(r.Children, child)
+	}
+	return r, nil
+}
+
+// retrieveRequested retrieves the resource identified by the request path, and
+// checks that the requesting subject is allowed to retrieve it. Returns false if the
+// resource can't be retrieved, in which case an error response has been written.
+func (s *Service) retrieveRequested(c *fiber.Ctx) (res ontology.Resource, ok bool, err error) {
+	key, err := url.PathUnescape(c.Params("key"))
+	if err != nil {
+		c.Status(fiber.StatusBadRequest)
+		return res, false, c.JSON(fiber.Map{"error": err.Error()})
+	}
+	id := ontology.ID{Type: ontology.Type(c.Params("type")), Key: key}
+	if err := id.Validate(); err != nil {
+		c.Status(fiber.StatusBadRequest)
+		return res, false, c.JSON(fiber.Map{"error": err.Error()})
+	}
+	subject, err := fiberaccess.GetSubject(c)
+	if err != nil {
+		return res, false, err
+	}
+	if err := s.enforce(subject, id); err != nil {
+		if errors.Is(err, access.Denied) {
+			c.Status(fiber.StatusForbidden)
+		} else {
+			c.Status(fiber.StatusInternalServerError)
+		}
+		return res, false, c.JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := s.Ontology.NewRetrieve().WhereIDs(id).Entry(&res).Exec(); err != nil {
+		if errors.Is(err, query.NotFound) {
+			c.Status(fiber.StatusNotFound)
+		} else {
+			c.Status(fiber.StatusInternalServerError)
+		}
+		return res, false, c.JSON(fiber.Map{"error": err.Error()})
+	}
+	return res, true, nil
+}
+
+func (s *Service) enforce(subject, object ontology.ID) error {
+	e := s.Enforcer
+	if s.APIKeys != nil {
+		e = &apikey.Enforcer{Enforcer: s.Enforcer, Keys: s.APIKeys}
+	}
+	return e.Enforce(access.Request{
+		Subject: subject,
+		Object:  object,
+		Action:  access.Retrieve,
+	})
+}
@@ -0,0 +1,387 @@
+package fiber_test
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"github.com/arya-analytics/delta/pkg/access"
+	"github.com/arya-analytics/delta/pkg/access/rbac"
+	"github.com/arya-analytics/delta/pkg/auth/apikey"
+	fiberauth "github.com/arya-analytics/delta/pkg/auth/fiber"
+	"github.com/arya-analytics/delta/pkg/auth/token"
+	"github.com/arya-analytics/delta/pkg/ontology"
+	ontologyfiber "github.com/arya-analytics/delta/pkg/ontology/fiber"
+	"github.com/arya-analytics/delta/pkg/user"
+	"github.com/arya-analytics/x/gorp"
+	"github.com/arya-analytics/x/kv/memkv"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+)
+
+// resource mirrors the JSON shape of a resource returned by the API.
+type resource struct {
+	Type     string                 `json:"type"`
+	Key      string                 `json:"key"`
+	Data     map[string]interface{} `json:"data"`
+	Children []resource             `json:"children"`
+}
+
+var _ = Describe("Service", Ordered, func() {
+	var (
+		db         *gorp.DB
+		otg        *ontology.Ontology
+		app        *fiber.App
+		users      *user.Service
+		apiKeys    *apikey.Service
+		legislator *rbac.Legislator
+		alice      = &user.User{Username: "alice"}
+		bob        = &user.User{Username: "bob"}
+		carol      = &user.User{Username: "carol"}
+		tk         string
+	)
+	// request sends a request authenticated as alice, and returns the status code and
+	// the body of the response.
+	request := func(method, path string, body interface{}) (int, []byte) {
+		var r io.Reader
+		if body != nil {
+			b, err := json.Marshal(body)
+			Expect(err).ToNot(HaveOccurred())
+			r = bytes.NewReader(b)
+		}
+		req := httptest.NewRequest(method, path, r)
+		req.Header.Set("Authorization", "Bearer "+tk)
+		req.Header.Set("Content-Type", "application/json")
+		res, err := app.Test(req)
+		Expect(err).ToNot(HaveOccurred())
+		b, err := io.ReadAll(res.Body)
+		Expect(err).ToNot(HaveOccurred())
+		return res.StatusCode, b
+	}
+	allow := func(subject, object ontology.ID) {
+		txn := db.BeginTxn()
+		Expect(legislator.Create(txn, rbac.Policy{
+			Subject: subject,
+			Object:  object,
+			Actions: []access.Action{access.Retrieve},
+			Effect:  access.Allow,
+		})).To(Succeed())
+		Expect(txn.Commit()).To(Succeed())
+		Expect(txn.Close()).To(Succeed())
+	}
+	BeforeAll(func() {
+		var err error
+		db = gorp.Wrap(memkv.New())
+		otg, err = ontology.Open(db)
+		Expect(err).ToNot(HaveOccurred())
+		users = user.New(db, otg)
+		otg.RegisterService(users)
+		apiKeys = apikey.New(db, otg)
+		otg.RegisterService(apiKeys)
+		txn := otg.BeginTxn()
+		for _, u := range []*user.User{alice, bob, carol} {
+			Expect(users.Create(txn, u)).To(Succeed())
+		}
+		w := otg.NewWriter(txn)
+		Expect(w.DefineRelationship(user.OntologyID(bob.Key), ontology.Root, ontology.Parent)).To(Succeed())
+		Expect(w.DefineRelationship(user.OntologyID(carol.Key), ontology.Root, ontology.Parent)).To(Succeed())
+		Expect(txn.Commit()).To(Succeed())
+		Expect(txn.Close()).To(Succeed())
+
+		legislator = &rbac.Legislator{DB: db}
+		allow(user.OntologyID(alice.Key), ontology.Root)
+		allow(user.OntologyID(alice.Key), user.OntologyID(bob.Key))
+
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).ToNot(HaveOccurred())
+		tokens := &token.Service{Secret: key, Expiration: time.Hour}
+		tk, err = tokens.New(alice.Key)
+		Expect(err).ToNot(HaveOccurred())
+
+		app = fiber.New()
+		(&ontologyfiber.Service{
+			Ontology: otg,
+			Token:    tokens,
+			Enforcer: &rbac.Enforcer{DefaultEffect: access.Deny, Legislator: legislator},
+			APIKeys:  apiKeys,
+		}).BindTo(app)
+	})
+	AfterAll(func() { Expect(db.Close()).To(Succeed()) })
+	It("Should reject requests without a token", func() {
+		res, err := app.Test(httptest.NewRequest("GET", "/ontology/schemas", nil))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.StatusCode).ToNot(Equal(fiber.StatusOK))
+	})
+	Describe("Retrieve", func() {
+		It("Should return a resource the subject is allowed to retrieve", func() {
+			status, body := request("GET", "/ontology/resources/user/"+bob.Key.String(), nil)
+			Expect(status).To(Equal(fiber.StatusOK))
+			var r resource
+			Expect(json.Unmarshal(body, &r)).To(Succeed())
+			Expect(r.Type).To(Equal("user"))
+			Expect(r.Key).To(Equal(bob.Key.String()))
+			Expect(r.Data).To(Equal(map[string]interface{}{
+				"key":      bob.Key.String(),
+				"username": "bob",
+			}))
+			Expect(r.Children).To(BeEmpty())
+		})
+		It("Should forbid retrieving a resource the subject isn't allowed to retrieve", func() {
+			status, body := request("GET", "/ontology/resources/user/"+carol.Key.String(), nil)
+			Expect(status).To(Equal(fiber.StatusForbidden))
+			Expect(string(body)).To(ContainSubstring(`"error"`))
+		})
+		It("Should return not found for a resource that doesn't exist", func() {
+			allow(user.OntologyID(alice.Key), ontology.ID{Type: "user", Key: "missing"})
+			status, _ := request("GET", "/ontology/resources/user/missing", nil)
+			Expect(status).To(Equal(fiber.StatusNotFound))
+		})
+	})
+	Describe("API Keys", func() {
+		// retrieve retrieves the given user with a new API key of alice's that has the
+		// given scopes, and returns the status code of the response.
+		retrieve := func(u *user.User, scopes ...access.Action) int {
+			txn := apiKeys.BeginTxn()
+			_, raw, err := apiKeys.Create(txn, user.OntologyID(alice.Key), "test", scopes, time.Time{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(txn.Commit()).To(Succeed())
+			Expect(txn.Close()).To(Succeed())
+			req := httptest.NewRequest("GET", "/ontology/resources/user/"+u.Key.String(), nil)
+			req.Header.Set(fiberauth.APIKeyHeader, raw)
+			res, err := app.Test(req)
+			Expect(err).ToNot(HaveOccurred())
+			return res.StatusCode
+		}
+		It("Should allow a key to retrieve what its subject is allowed to retrieve", func() {
+			Expect(retrieve(bob, access.Retrieve)).To(Equal(fiber.StatusOK))
+		})
+		It("Should forbid a key from retrieving what its subject isn't allowed to retrieve", func() {
+			Expect(retrieve(carol, access.Retrieve)).To(Equal(fiber.StatusForbidden))
+		})
+		It("Should forbid a key from retrieving outside of its scopes", func() {
+			Expect(retrieve(bob, access.Action("write"))).To(Equal(fiber.StatusForbidden))
+		})
+	})
+	Describe("Traverse", func() {
+		It("Should only return children the subject is allowed to retrieve", func() {
+			status, body := request("GET", "/ontology/resources/builtin/root/children", nil)
+			Expect(status).To(Equal(fiber.StatusOK))
+			var r resource
+			Expect(json.Unmarshal(body, &r)).To(Succeed())
+			Expect(r.Type).To(Equal("builtin"))
+			Expect(r.Key).To(Equal("root"))
+			Expect(r.Children).To(HaveLen(1))
+			Expect(r.Children[0].Key).To(Equal(bob.Key.String()))
+			Expect(r.Children[0].Data["username"]).To(Equal("bob"))
+		})
+		It("Should return the parents of a resource", func() {
+			status, body := request("GET", "/ontology/resources/user/"+bob.Key.String()+"/parents", nil)
+			Expect(status).To(Equal(fiber.StatusOK))
+			var r resource
+			Expect(json.Unmarshal(body, &r)).To(Succeed())
+			Expect(r.Children).To(HaveLen(1))
+			Expect(r.Children[0].Type).To(Equal("builtin"))
+			Expect(r.Children[0].Key).To(Equal("root"))
+		})
+		It("Should forbid traversing from a resource the subject isn't allowed to retrieve", func() {
+			status, _ := request("GET", "/ontology/resources/user/"+carol.Key.String()+"/parents", nil)
+			Expect(status).To(Equal(fiber.StatusForbidden))
+		})
+		It("Should reject an invalid depth", func() {
+			status, _ := request("GET", "/ontology/resources/builtin/root/children?depth=100", nil)
+			Expect(status).To(Equal(fiber.StatusBadRequest))
+		})
+	})
+	Describe("Schemas", func() {
+		It("Should return the schemas of the registered services", func() {
+			status, body := request("GET", "/ontology/schemas", nil)
+			Expect(status).To(Equal(fiber.StatusOK))
+			var schemas []ontology.Schema
+			Expect(json.Unmarshal(body, &schemas)).To(Succeed())
+			var types []ontology.Type
+			for _, s := range schemas {
+				types = append(types, s.Type)
+			}
+			Expect(types).To(ContainElements(ontology.BuiltIn, ontology.Type("user")))
+		})
+	})
+	Describe("Query", func() {
+		query := func(q ontology.Query) (int, []resource) {
+			status, body := request("POST", "/ontology/query", q)
+			var res []resource
+			if status == fiber.StatusOK {
+				Expect(json.Unmarshal(body, &res)).To(Succeed())
+			}
+			return status, res
+		}
+		It("Should only return resources the subject is allowed to retrieve", func() {
+			status, res := query(ontology.Query{
+				IDs:      []string{ontology.Root.String()},
+				Traverse: []ontology.Step{{Relationship: ontology.Parent, Direction: ontology.Backward}},
+			})
+			Expect(status).To(Equal(fiber.StatusOK))
+			Expect(res).To(HaveLen(1))
+			Expect(res[0].Type).To(Equal("user"))
+			Expect(res[0].Key).To(Equal(bob.Key.String()))
+			Expect(res[0].Data).To(Equal(map[string]interface{}{
+				"key":      bob.Key.String(),
+				"username": "bob",
+			}))
+		})
+		It("Should not filter steps on resources the subject isn't allowed to retrieve", func() {
+			status, res := query(ontology.Query{
+				IDs: []string{ontology.Root.String()},
+				Traverse: []ontology.Step{
+					{
+						Relationship: ontology.Parent,
+						Direction:    ontology.Backward,
+						Where:        []ontology.Filter{{Field: "username", Op: ontology.Eq, Value: "carol"}},
+					},
+					{Relationship: ontology.Parent},
+				},
+			})
+			Expect(status).To(Equal(fiber.StatusOK))
+			Expect(res).To(BeEmpty())
+		})
+		It("Should project the requested fields", func() {
+			status, res := query(ontology.Query{
+				Types:  []ontology.Type{"user"},
+				Fields: []string{"username"},
+			})
+			Expect(status).To(Equal(fiber.StatusOK))
+			Expect(res).To(HaveLen(1))
+			Expect(res[0].Data).To(Equal(map[string]interface{}{"username": "bob"}))
+		})
+		It("Should reject a limit above the maximum", func() {
+			status, _ := query(ontology.Query{Limit: ontologyfiber.MaxQueryLimit + 1})
+			Expect(status).To(Equal(fiber.StatusBadRequest))
+		})
+		It("Should reject an invalid query", func() {
+			status, _ := query(ontology.Query{
+				Where: []ontology.Filter{{Field: "username", Op: "like", Value: "b"}},
+			})
+			Expect(status).To(Equal(fiber.StatusBadRequest))
+		})
+	})
+	Describe("Watch", func() {
+		It("Should stream changes to resources the subject is allowed to retrieve", func() {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).ToNot(HaveOccurred())
+			go func() { _ = app.Listener(ln) }()
+			defer func() { Expect(ln.Close()).To(Succeed()) }()
+			req, err := http.NewRequest("GET", "http://"+ln.Addr().String()+"/ontology/watch?types=user", nil)
+			Expect(err).ToNot(HaveOccurred())
+			req.Header.Set("Authorization", "Bearer "+tk)
+			res, err := http.DefaultClient.Do(req)
+			Expect(err).ToNot(HaveOccurred())
+			defer func() { Expect(res.Body.Close()).To(Succeed()) }()
+			Expect(res.StatusCode).To(Equal(fiber.StatusOK))
+			Expect(res.Header.Get("Content-Type")).To(Equal("text/event-stream"))
+
+			// Alice isn't allowed to retrieve dave, so only erin's definition should be
+			// streamed.
+			dave := &user.User{Key: uuid.New(), Username: "dave"}
+			erin := &user.User{Key: uuid.New(), Username: "erin"}
+			allow(user.OntologyID(alice.Key), user.OntologyID(erin.Key))
+			for _, u := range []*user.User{dave, erin} {
+				txn := otg.BeginTxn()
+				Expect(users.Create(txn, u)).To(Succeed())
+				Expect(txn.Commit()).To(Succeed())
+				Expect(txn.Close()).To(Succeed())
+			}
+
+			lines := make(chan string)
+			go func() {
+				defer GinkgoRecover()
+				defer close(lines)
+				scanner := bufio.NewScanner(res.Body)
+				for scanner.Scan() {
+					lines <- scanner.Text()
+				}
+			}()
+			// Read the first event, skipping comments.
+			var event []string
+			for done := false; !done; {
+				select {
+				case line, ok := <-lines:
+					Expect(ok).To(BeTrue())
+					if strings.HasPrefix(line, ":") {
+						continue
+					}
+					if line == "" {
+						done = len(event) > 0
+						break
+					}
+					event = append(event, line)
+				case <-time.After(time.Second):
+					Fail("timed out waiting for change event")
+				}
+			}
+			Expect(event).To(HaveLen(3))
+			Expect(event[1]).To(Equal("event: resource_defined"))
+			Expect(strings.HasPrefix(event[2], "data: ")).To(BeTrue())
+			var change map[string]interface{}
+			Expect(json.Unmarshal([]byte(strings.TrimPrefix(event[2], "data: ")), &change)).To(Succeed())
+			Expect(change["variant"]).To(Equal("resource_defined"))
+			Expect(change["resource"]).To(Equal(map[string]interface{}{
+				"type": "user",
+				"key":  erin.Key.String(),
+			}))
+		})
+		It("Should reject an invalid subtree filter", func() {
+			status, _ := request("GET", "/ontology/watch?subtreeType=user", nil)
+			Expect(status).To(Equal(fiber.StatusBadRequest))
+		})
+	})
+	Describe("Traverse Shared Descendants", func() {
+		var x, y, z, w *user.User
+		BeforeAll(func() {
+			x, y, z, w = &user.User{Username: "x"}, &user.User{Username: "y"},
+				&user.User{Username: "z"}, &user.User{Username: "w"}
+			txn := otg.BeginTxn()
+			for _, u := range []*user.User{x, y, z, w} {
+				Expect(users.Create(txn, u)).To(Succeed())
+			}
+			wr := otg.NewWriter(txn)
+			for _, rel := range [][2]*user.User{{x, bob}, {y, bob}, {z, x}, {z, y}, {w, z}} {
+				Expect(wr.DefineRelationship(
+					user.OntologyID(rel[0].Key),
+					user.OntologyID(rel[1].Key),
+					ontology.Parent,
+				)).To(Succeed())
+			}
+			Expect(txn.Commit()).To(Succeed())
+			Expect(txn.Close()).To(Succeed())
+			for _, u := range []*user.User{x, y, z, w} {
+				allow(user.OntologyID(alice.Key), user.OntologyID(u.Key))
+			}
+		})
+		It("Should only traverse the children of a shared descendant once", func() {
+			status, body := request("GET", "/ontology/resources/user/"+bob.Key.String()+"/children?depth=3", nil)
+			Expect(status).To(Equal(fiber.StatusOK))
+			var r resource
+			Expect(json.Unmarshal(body, &r)).To(Succeed())
+			counts := make(map[string]int)
+			var count func(r resource)
+			count = func(r resource) {
+				counts[r.Key]++
+				for _, c := range r.Children {
+					count(c)
+				}
+			}
+			count(r)
+			Expect(r.Children).To(HaveLen(2))
+			Expect(counts[z.Key.String()]).To(Equal(2))
+			Expect(counts[w.Key.String()]).To(Equal(1))
+		})
+	})
+})
@@ -0,0 +1,149 @@
+package fiber
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	fiberaccess "github.com/arya-analytics/delta/pkg/access/fiber"
+	"github.com/arya-analytics/delta/pkg/ontology"
+	"github.com/gofiber/fiber/v2"
+	"strings"
+	"time"
+)
+
+// HeartbeatInterval is the interval at which the watch endpoint writes a comment to
+// idle streams, so that disconnected clients are detected.
+const HeartbeatInterval = 15 * time.Second
+
+type idResponse struct {
+	Type ontology.Type `json:"type"`
+	Key  string        `json:"key"`
+}
+
+func newIDResponse(id ontology.ID) idResponse { return idResponse{Type: id.Type, Key: id.Key} }
+
+type relationshipResponse struct {
+	From idResponse                `json:"from"`
+	To   idResponse                `json:"to"`
+	Type ontology.RelationshipType `json:"type"`
+}
+
+// changeResponse is the serialized form of an ontology.Change.
+type changeResponse struct {
+	Seq          uint64                `json:"seq"`
+	Variant      string                `json:"variant"`
+	Resource     *idResponse           `json:"resource,omitempty"`
+	Relationship *relationshipResponse `json:"relationship,omitempty"`
+}
+
+func newChangeResponse(c ontology.Change) changeResponse {
+	r := changeResponse{Seq: c.Seq, Variant: c.Variant.String()}
+	if c.Variant == ontology.ResourceDefined || c.Variant == ontology.ResourceDeleted {
+		id := newIDResponse(c.ID)
+		r.Resource = &id
+	} else {
+		r.Relationship = &relationshipResponse{
+			From: newIDResponse(c.Relationship.From),
+			To:   newIDResponse(c.Relationship.To),
+			Type: c.Relationship.Type,
+		}
+	}
+	return r
+}
+
+// watch streams ontology changes to the client as server-sent events. Changes can be
+// filtered with the 'types' query parameter (a comma separated list of resource
+// types) and the 'subtreeType' and 'subtreeKey' query parameters. Changes affecting
+// resources the requesting subject isn't allowed to retrieve are omitted.
+func (s *Service) watch(c *fiber.Ctx) error {
+	subject, err := fiberaccess.GetSubject(c)
+	if err != nil {
+		return err
+	}
+	var filter ontology.WatchFilter
+	if types := c.Query("types"); types != "" {
+		for _, t := range strings.Split(types, ",") {
+			filter.Types = append(filter.Types, ontology.Type(t))
+		}
+	}
+	if st, sk := c.Query("subtreeType"), c.Query("subtreeKey"); st != "" || sk != "" {
+		filter.Subtree = ontology.ID{Type: ontology.Type(st), Key: sk}
+		if err := filter.Subtree.Validate(); err != nil {
+			c.Status(fiber.StatusBadRequest)
+			return c.JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	sub, err := s.Ontology.Watch(ctx, filter)
+	if err != nil {
+		cancel()
+		c.Status(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{"error": err.Error()})
+	}
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer cancel()
+		// The headers are only sent along with the first chunk of the body, so write a
+		// comment right away to let the client know it's subscribed.
+		if _, err := w.WriteString(": subscribed\n\n"); err != nil {
+			return
+		}
+		if err := w.Flush(); err != nil {
+			return
+		}
+		heartbeat := time.NewTicker(HeartbeatInterval)
+		defer heartbeat.Stop()
+		for {
+			select {
+			case <-heartbeat.C:
+				if _, err := w.WriteString(": heartbeat\n\n"); err != nil {
+					return
+				}
+			case change, ok := <-sub.Changes:
+				if !ok {
+					if err := sub.Err(); err != nil {
+						b, _ := json.Marshal(fiber.Map{"error": err.Error()})
+						_, _ = fmt.Fprintf(w, "event: error\ndata: %s\n\n", b)
+						_ = w.Flush()
+					}
+					return
+				}
+				if !s.allowed(subject, change) {
+					continue
+				}
+				b, err := json.Marshal(newChangeResponse(change))
+				if err != nil {
+					return
+				}
+				if _, err := fmt.Fprintf(
+					w,
+					"id: %d\nevent: %s\ndata: %s\n\n",
+					change.Seq,
+					change.Variant,
+					b,
+				); err != nil {
+					return
+				}
+			}
+			// Flush fails once the client disconnects.
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	})
+	return nil
+}
+
+func (s *Service) allowed(subject ontology.ID, c ontology.Change) bool {
+	for _, id := range c.IDs() {
+		// Treat enforcement errors as denials, since there's no way to report them
+		// mid-stream.
+		if err := s.enforce(subject, id); err != nil {
+			return false
+		}
+	}
+	return true
+}
@@ -12,20 +12,39 @@ type (
 )
 
 type Ontology struct {
-	db       *gorp.DB
-	retrieve retrieve
+	db            *gorp.DB
+	retrieve      retrieve
+	search        *searchIndex
+	relationships relationshipTypes
+	feed          *feed
+	relay         Relay
 }
 
-// Open opens the ontology stored in the given database.
-func Open(db *gorp.DB) (*Ontology, error) {
+// Open opens the ontology stored in the given database. Relationships written before
+// the database was indexed are indexed when it is opened.
+func Open(db *gorp.DB, opts ...Option) (*Ontology, error) {
+	svc := services{BuiltIn: builtInService{}, RouteType: routeService{}}
 	o := &Ontology{
 		db:       db,
-		retrieve: retrieve{services: make(services)},
+		retrieve: retrieve{services: svc},
+		search:   newSearchIndex(svc),
+		feed:     newFeed(),
+		relationships: relationshipTypes{
+			Parent: {Type: Parent, Acyclic: true, Cardinality: ManyToMany},
+		},
 	}
-	if err := o.NewWriter(db).DefineResource(Root); err != nil {
-		return nil, err
+	for _, opt := range opts {
+		opt(o)
 	}
-	return o, nil
+	if o.relay == nil {
+		if err := backfillAdjacencies(db); err != nil {
+			return nil, err
+		}
+		if err := o.NewWriter(db).DefineResource(Root); err != nil {
+			return nil, err
+		}
+	}
+	return o, o.search.load(db)
 }
 
 type Writer interface {
@@ -38,11 +57,24 @@ type Writer interface {
 	DeleteResource(id ID) error
 	// DefineRelationship defines a directional relationship of type t between the
 	// resources with the given IDs. If the relationship already exists, DefineRelationship
-	// does nothing.
+	// does nothing. Returns an error if t hasn't been registered, or if the
+	// relationship violates the constraints of t.
 	DefineRelationship(from, to ID, t RelationshipType) error
 	// DeleteRelationship deletes the relationship with the given IDs and type. If the
 	// relationship does not exist, DeleteRelationship does nothing.
 	DeleteRelationship(from, to ID, t RelationshipType) error
+	// CreateEntity validates the entity against the Schema for its Type, creates it
+	// using the Type's MutableService, and defines a resource for it. Returns the ID
+	// of the new resource.
+	CreateEntity(e Entity) (ID, error)
+	// UpdateEntity validates the entity against the Schema for the resource's Type
+	// and updates the resource using the Type's MutableService. Only the fields set on
+	// the entity are validated and updated, so required fields may be omitted.
+	UpdateEntity(id ID, e Entity) error
+	// InvalidateEntities removes the entities of the resources with the given IDs from
+	// the Ontology's entity cache, and again when the Writer's transaction commits.
+	// Services that write entities without going through UpdateEntity must call it.
+	InvalidateEntities(ids ...ID)
 	// NewRetrieve opens a new Retrieve query that uses the Writers transaction.
 	NewRetrieve() Retrieve
 }
@@ -51,9 +83,33 @@ type Writer interface {
 func (o *Ontology) NewRetrieve() Retrieve { return newRetrieve(o.db, o.retrieve.exec) }
 
 // NewWriter opens a new Writer using the provided transaction. NewWriter will panic
-// if the transaction does not root from the same database as the Ontology.
-func (o *Ontology) NewWriter(txn gorp.Txn) Writer { return dagWriter{txn: txn, retrieve: o.retrieve} }
+// if the transaction does not root from the same database as the Ontology. The
+// transaction must either be opened with BeginTxn, or be the Ontology's database
+// itself, so that the Writer's changes can be published to Watch subscribers; writes
+// on any other transaction return UnsupportedTxn. If the Ontology was opened
+// WithRelay, graph changes are relayed when txn commits instead of being made on txn.
+func (o *Ontology) NewWriter(txn gorp.Txn) Writer { return o.newWriter(txn, o.relay) }
+
+func (o *Ontology) newWriter(txn gorp.Txn, relay Relay) dagWriter {
+	record, err := o.recorder(txn)
+	return dagWriter{
+		txn:           txn,
+		retrieve:      o.retrieve,
+		search:        o.search,
+		relationships: o.relationships,
+		record:        record,
+		relay:         relay,
+		err:           err,
+	}
+}
 
 func (o *Ontology) RegisterService(s Service) {
 	o.retrieve.services.Register(s)
 }
+
+// RegisterRelationshipType registers a type of relationship that can be defined
+// between resources. Parent is registered by default. RegisterRelationshipType panics
+// if the type is already registered.
+func (o *Ontology) RegisterRelationshipType(def RelationshipDefinition) {
+	o.relationships.Register(def)
+}
@@ -34,6 +34,48 @@ func (s *emptyService) RetrieveEntity(key string) (ontology.Entity, error) {
 	return e, nil
 }
 
+// mutableService stores entities in memory.
+type mutableService struct {
+	entities map[string]ontology.Entity
+}
+
+const mutableType ontology.Type = "mutable"
+
+var mutableSchema = &ontology.Schema{
+	Type: mutableType,
+	Fields: map[string]schema.Field{
+		"key":  {Type: schema.String},
+		"name": {Type: schema.String},
+	},
+}
+
+func (s *mutableService) Schema() *ontology.Schema { return mutableSchema }
+
+func (s *mutableService) RetrieveEntity(key string) (ontology.Entity, error) {
+	return s.entities[key], nil
+}
+
+func (s *mutableService) CreateEntity(_ gorp.Txn, e ontology.Entity) (string, error) {
+	key, _ := schema.Get[string](e, "key")
+	s.entities[key] = e
+	return key, nil
+}
+
+func (s *mutableService) UpdateEntity(_ gorp.Txn, key string, e ontology.Entity) error {
+	name, _ := schema.Get[string](e, "name")
+	schema.Set(s.entities[key], "name", name)
+	return nil
+}
+
+const (
+	// memberOf is a cyclic relationship where each resource can be a member of at
+	// most one other resource.
+	memberOf ontology.RelationshipType = "member_of"
+	// derivedFrom is an acyclic relationship where each resource can be the source of
+	// at most one derived resource.
+	derivedFrom ontology.RelationshipType = "derived_from"
+)
+
 var (
 	db  *gorp.DB
 	otg *ontology.Ontology
@@ -46,6 +88,16 @@ var _ = BeforeSuite(func() {
 	otg, err = ontology.Open(gorp.Wrap(db))
 	Expect(err).ToNot(HaveOccurred())
 	otg.RegisterService(&emptyService{})
+	otg.RegisterService(&mutableService{entities: make(map[string]ontology.Entity)})
+	otg.RegisterRelationshipType(ontology.RelationshipDefinition{
+		Type:        memberOf,
+		Cardinality: ontology.ManyToOne,
+	})
+	otg.RegisterRelationshipType(ontology.RelationshipDefinition{
+		Type:        derivedFrom,
+		Acyclic:     true,
+		Cardinality: ontology.OneToMany,
+	})
 })
 
 var _ = AfterSuite(func() {
@@ -53,7 +105,7 @@ var _ = AfterSuite(func() {
 })
 
 var _ = BeforeEach(func() {
-	txn = db.BeginTxn()
+	txn = otg.BeginTxn()
 })
 
 var _ = AfterEach(func() {
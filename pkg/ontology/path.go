@@ -0,0 +1,39 @@
+package ontology
+
+// Path is the chain of relationships traversed from a resource matched by the first
+// clause of a Retrieve query to a resource in its results.
+type Path struct {
+	// From is the ID of the resource the path starts at.
+	From ID
+	// To is the ID of the resource the path ends at.
+	To ID
+	// Relationships are the relationships traversed, in order. Relationships is empty
+	// when From and To are the same resource.
+	Relationships []Relationship
+}
+
+// IDs returns the IDs of the resources along the path, starting with From and ending
+// with To.
+func (p Path) IDs() []ID {
+	ids := make([]ID, 0, len(p.Relationships)+1)
+	ids = append(ids, p.From)
+	curr := p.From
+	for _, rel := range p.Relationships {
+		if rel.From == curr {
+			curr = rel.To
+		} else {
+			curr = rel.From
+		}
+		ids = append(ids, curr)
+	}
+	return ids
+}
+
+// Len returns the number of relationships in the path.
+func (p Path) Len() int { return len(p.Relationships) }
+
+func (p Path) extend(to ID, rel Relationship) Path {
+	rels := make([]Relationship, len(p.Relationships), len(p.Relationships)+1)
+	copy(rels, p.Relationships)
+	return Path{From: p.From, To: to, Relationships: append(rels, rel)}
+}
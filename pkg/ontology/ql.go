@@ -0,0 +1,416 @@
+package ontology
+
+import (
+	"github.com/arya-analytics/delta/pkg/ontology/schema"
+	"github.com/arya-analytics/x/query"
+	"github.com/cockroachdb/errors"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Query is a declarative query on the ontology that can be sent over the wire. A Query
+// selects a set of resources, filters them on the fields of their entities, traverses
+// from them along relationships, and then sorts, paginates and projects the resources
+// it reaches. Queries are compiled into a Retrieve with Compile. An example in JSON:
+//
+//	{
+//	  "types": ["node"],
+//	  "traverse": [{"relationship": "parent", "direction": "backward"}],
+//	  "where": [{"field": "dataRate", "op": "gte", "value": 25}],
+//	  "orderBy": [{"field": "name"}],
+//	  "fields": ["name", "dataRate"],
+//	  "limit": 10
+//	}
+type Query struct {
+	// IDs selects the resources with the given IDs, formatted as returned by
+	// ID.String.
+	IDs []string `json:"ids,omitempty"`
+	// Types selects all resources of the given types. If neither IDs nor Types are
+	// set, the Query selects every resource.
+	Types []Type `json:"types,omitempty"`
+	// Traverse moves from the selected resources along relationships, one Step at a
+	// time.
+	Traverse []Step `json:"traverse,omitempty"`
+	// Where filters the resources the Query returns; that is, the resources reached
+	// by the last Step, or the selected resources if there are no Steps.
+	Where []Filter `json:"where,omitempty"`
+	// OrderBy sorts the returned resources.
+	OrderBy []Order `json:"orderBy,omitempty"`
+	// Offset skips the first Offset returned resources.
+	Offset int `json:"offset,omitempty"`
+	// Limit returns at most Limit resources. Zero returns every resource.
+	Limit int `json:"limit,omitempty"`
+	// Fields limits the entities of the returned resources to the given fields. If
+	// Fields is empty, every field is returned.
+	Fields []string `json:"fields,omitempty"`
+}
+
+// Step is a single traversal in a Query.
+type Step struct {
+	// Relationship is the type of relationship to traverse.
+	Relationship RelationshipType `json:"relationship"`
+	// Direction is the direction to traverse the relationship in. Defaults to Forward.
+	Direction Direction `json:"direction,omitempty"`
+	// Depth is the maximum number of hops to traverse (see Traverser.Depth).
+	Depth int `json:"depth,omitempty"`
+	// Where filters the resources reached by the Step before the next Step.
+	Where []Filter `json:"where,omitempty"`
+}
+
+// Operator is a comparison made by a Filter.
+type Operator string
+
+const (
+	Eq  Operator = "eq"
+	Neq Operator = "neq"
+	Lt  Operator = "lt"
+	Lte Operator = "lte"
+	Gt  Operator = "gt"
+	Gte Operator = "gte"
+	// In matches if the field is equal to any element of the Filter's Value, which
+	// must be a slice.
+	In Operator = "in"
+	// Prefix matches string fields that start with the Filter's Value.
+	Prefix Operator = "prefix"
+	// Contains matches string fields that contain the Filter's Value.
+	Contains Operator = "contains"
+)
+
+// Filter matches resources whose entity field satisfies the comparison. Resources
+// whose entity doesn't have the field never match.
+type Filter struct {
+	Field string      `json:"field"`
+	Op    Operator    `json:"op"`
+	Value interface{} `json:"value"`
+}
+
+// Order sorts resources by the value of an entity field.
+type Order struct {
+	Field string `json:"field"`
+	Desc  bool   `json:"desc,omitempty"`
+}
+
+// InvalidQuery is returned when compiling a malformed Query.
+var InvalidQuery = errors.New("[ontology] - invalid query")
+
+// CompileOption configures how a Query is compiled.
+type CompileOption func(o *compileOptions)
+
+type compileOptions struct {
+	allow func(id ID) bool
+}
+
+// WithAllow restricts the resources a Query selects, and those reached by each of its
+// Steps, to the ones for which allow returns true. Steps don't traverse through
+// resources that aren't allowed, and filters, sorting and pagination only see allowed
+// resources, so a Query can't reveal anything about resources the caller isn't
+// allowed to retrieve.
+func WithAllow(allow func(id ID) bool) CompileOption {
+	return func(o *compileOptions) { o.allow = allow }
+}
+
+// Compile compiles the Query into a Retrieve. Bind the results with Entries and call
+// Exec to execute it.
+func (o *Ontology) Compile(q Query, opts ...CompileOption) (Retrieve, error) {
+	cOpts := &compileOptions{allow: func(ID) bool { return true }}
+	for _, opt := range opts {
+		opt(cOpts)
+	}
+	if err := q.validate(); err != nil {
+		return Retrieve{}, err
+	}
+	r := o.NewRetrieve()
+	if len(q.IDs) > 0 {
+		ids := make([]ID, len(q.IDs))
+		for i, s := range q.IDs {
+			id, err := ParseID(s)
+			if err != nil {
+				return r, errors.Wrap(InvalidQuery, err.Error())
+			}
+			ids[i] = id
+		}
+		r = r.WhereIDs(ids...)
+	}
+	r = r.Where(func(res *Resource) bool {
+		return q.selects(res.ID.Type) && cOpts.allow(res.ID)
+	})
+	for _, step := range q.Traverse {
+		dir := step.Direction
+		if dir == 0 {
+			dir = Forward
+		}
+		r = r.TraverseTo(Traverser{
+			Type:      step.Relationship,
+			Direction: dir,
+			Depth:     step.Depth,
+			Allow:     cOpts.allow,
+		}).WhereFields(step.Where...)
+	}
+	r = r.WhereFields(q.Where...).OrderBy(q.OrderBy...).Offset(q.Offset).Limit(q.Limit)
+	if len(q.Fields) > 0 {
+		r = r.Fields(q.Fields...)
+	}
+	return r, nil
+}
+
+// selects returns true if the Query selects resources of the given type.
+func (q Query) selects(t Type) bool {
+	if len(q.Types) == 0 {
+		return true
+	}
+	for _, qt := range q.Types {
+		if qt == t {
+			return true
+		}
+	}
+	return false
+}
+
+func (q Query) validate() error {
+	filters := append([]Filter{}, q.Where...)
+	for _, step := range q.Traverse {
+		if step.Relationship == "" {
+			return errors.Wrap(InvalidQuery, "[ontology] - traversal relationship is required")
+		}
+		if step.Depth < Unbounded {
+			return errors.Wrapf(InvalidQuery, "[ontology] - invalid traversal depth %d", step.Depth)
+		}
+		filters = append(filters, step.Where...)
+	}
+	for _, f := range filters {
+		if err := f.validate(); err != nil {
+			return err
+		}
+	}
+	if q.Offset < 0 || q.Limit < 0 {
+		return errors.Wrap(InvalidQuery, "[ontology] - offset and limit must be positive")
+	}
+	return nil
+}
+
+// ParseID parses an ID formatted as returned by ID.String.
+func ParseID(s string) (ID, error) {
+	i := strings.LastIndex(s, ":")
+	if i < 0 {
+		return ID{}, errors.Newf("[ontology] - invalid id %s", s)
+	}
+	id := ID{Key: s[:i], Type: Type(s[i+1:])}
+	return id, id.Validate()
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Direction) MarshalText() ([]byte, error) {
+	switch d {
+	case Forward:
+		return []byte("forward"), nil
+	case Backward:
+		return []byte("backward"), nil
+	default:
+		return nil, errors.Newf("[ontology] - unknown direction %d", d)
+	}
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *Direction) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "forward":
+		*d = Forward
+	case "backward":
+		*d = Backward
+	default:
+		return errors.Wrapf(InvalidQuery, "[ontology] - unknown direction %s", text)
+	}
+	return nil
+}
+
+// |||||| FILTERS ||||||
+
+const filtersOptKey = "filters"
+
+func setFilters(q query.Query, filters []Filter) { q.Set(filtersOptKey, filters) }
+
+func getFilters(q query.Query) []Filter {
+	v, ok := q.Get(filtersOptKey)
+	if !ok {
+		return nil
+	}
+	return v.([]Filter)
+}
+
+func (f Filter) validate() error {
+	if f.Field == "" {
+		return errors.Wrap(InvalidQuery, "[ontology] - filter field is required")
+	}
+	switch f.Op {
+	case Eq, Neq, Lt, Lte, Gt, Gte:
+	case In:
+		if v := reflect.ValueOf(f.Value); v.Kind() != reflect.Slice {
+			return errors.Wrapf(InvalidQuery, "[ontology] - %s filter on %s requires a list", f.Op, f.Field)
+		}
+	case Prefix, Contains:
+		if _, ok := f.Value.(string); !ok {
+			return errors.Wrapf(InvalidQuery, "[ontology] - %s filter on %s requires a string", f.Op, f.Field)
+		}
+	default:
+		return errors.Wrapf(InvalidQuery, "[ontology] - unknown operator %s", f.Op)
+	}
+	return nil
+}
+
+// filterResources returns the resources whose entities match every filter.
+func filterResources(resources []Resource, filters []Filter) ([]Resource, error) {
+	if len(filters) == 0 {
+		return resources, nil
+	}
+	matched := make([]Resource, 0, len(resources))
+	for _, res := range resources {
+		ok, err := matchFilters(res.entity, filters)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matched = append(matched, res)
+		}
+	}
+	return matched, nil
+}
+
+// matchFilters returns true if the entity matches every filter. Values that can't be
+// compared don't match.
+func matchFilters(e Entity, filters []Filter) (bool, error) {
+	for _, f := range filters {
+		if err := f.validate(); err != nil {
+			return false, err
+		}
+		v, ok := e.Value(f.Field)
+		if !ok || !f.match(v) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (f Filter) match(v interface{}) bool {
+	switch f.Op {
+	case In:
+		list := reflect.ValueOf(f.Value)
+		for i := 0; i < list.Len(); i++ {
+			if equal(v, list.Index(i).Interface()) {
+				return true
+			}
+		}
+		return false
+	case Prefix, Contains:
+		s, ok := v.(string)
+		if !ok {
+			return false
+		}
+		if f.Op == Prefix {
+			return strings.HasPrefix(s, f.Value.(string))
+		}
+		return strings.Contains(s, f.Value.(string))
+	case Eq:
+		return equal(v, f.Value)
+	case Neq:
+		return !equal(v, f.Value)
+	}
+	c, err := schema.Compare(v, f.Value)
+	if err != nil {
+		return false
+	}
+	switch f.Op {
+	case Lt:
+		return c < 0
+	case Lte:
+		return c <= 0
+	case Gt:
+		return c > 0
+	default:
+		return c >= 0
+	}
+}
+
+func equal(a, b interface{}) bool {
+	if c, err := schema.Compare(a, b); err == nil {
+		return c == 0
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// |||||| SHAPE ||||||
+
+const shapeOptKey = "shape"
+
+// shape sorts, paginates and projects the resources matched by a clause.
+type shape struct {
+	orders        []Order
+	offset, limit int
+	fields        []string
+}
+
+func setShape(q query.Query, update func(s *shape)) {
+	s := getShape(q)
+	update(&s)
+	q.Set(shapeOptKey, s)
+}
+
+func getShape(q query.Query) shape {
+	v, ok := q.Get(shapeOptKey)
+	if !ok {
+		return shape{}
+	}
+	return v.(shape)
+}
+
+func (s shape) apply(resources []Resource) []Resource {
+	if len(s.orders) > 0 {
+		sort.SliceStable(resources, func(i, j int) bool {
+			return s.less(resources[i].entity, resources[j].entity)
+		})
+	}
+	resources = s.paginate(resources)
+	if len(s.fields) > 0 {
+		for i := range resources {
+			resources[i].entity = resources[i].entity.Project(s.fields...)
+		}
+	}
+	return resources
+}
+
+// paginate applies the offset and limit of the shape to the resources.
+func (s shape) paginate(resources []Resource) []Resource {
+	if s.offset > 0 {
+		if s.offset > len(resources) {
+			s.offset = len(resources)
+		}
+		resources = resources[s.offset:]
+	}
+	if s.limit > 0 && s.limit < len(resources) {
+		resources = resources[:s.limit]
+	}
+	return resources
+}
+
+func (s shape) less(a, b Entity) bool {
+	for _, o := range s.orders {
+		av, aOk := a.Value(o.Field)
+		bv, bOk := b.Value(o.Field)
+		if !aOk || !bOk {
+			if aOk != bOk {
+				// Resources missing the field sort last.
+				return aOk
+			}
+			continue
+		}
+		c, err := schema.Compare(av, bv)
+		if err != nil || c == 0 {
+			continue
+		}
+		if o.Desc {
+			return c > 0
+		}
+		return c < 0
+	}
+	return false
+}
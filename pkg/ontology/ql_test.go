@@ -0,0 +1,157 @@
+package ontology_test
+
+import (
+	"encoding/json"
+	"github.com/arya-analytics/delta/pkg/ontology"
+	"github.com/arya-analytics/delta/pkg/ontology/schema"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Query", func() {
+	var (
+		w      ontology.Writer
+		parent ontology.ID
+	)
+	names := func(resources []ontology.Resource) []string {
+		out := make([]string, len(resources))
+		for i, r := range resources {
+			out[i], _ = schema.Get[string](r.Entity(), "name")
+		}
+		return out
+	}
+	exec := func(raw string, opts ...ontology.CompileOption) ([]ontology.Resource, error) {
+		var q ontology.Query
+		Expect(json.Unmarshal([]byte(raw), &q)).To(Succeed())
+		r, err := otg.Compile(q, opts...)
+		if err != nil {
+			return nil, err
+		}
+		var res []ontology.Resource
+		return res, r.Entries(&res).Exec()
+	}
+	BeforeEach(func() {
+		w = otg.NewWriter(txn)
+		parent = newEmptyID("QueryParent")
+		Expect(w.DefineResource(parent)).To(Succeed())
+		for _, name := range []string{"delta", "alpha", "charlie", "bravo"} {
+			e := newMutableEntity("query-"+name, name)
+			id, err := w.CreateEntity(e)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(w.DefineRelationship(id, parent, ontology.Parent)).To(Succeed())
+		}
+		Expect(txn.Commit()).To(Succeed())
+	})
+	It("Should traverse, filter, sort and paginate", func() {
+		res, err := exec(`{
+			"ids": ["QueryParent:empty"],
+			"traverse": [{"relationship": "parent", "direction": "backward"}],
+			"where": [{"field": "name", "op": "neq", "value": "charlie"}],
+			"orderBy": [{"field": "name", "desc": true}],
+			"offset": 1,
+			"limit": 2
+		}`)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(names(res)).To(Equal([]string{"bravo", "alpha"}))
+	})
+	It("Should select resources by type", func() {
+		res, err := exec(`{
+			"types": ["mutable"],
+			"where": [{"field": "key", "op": "prefix", "value": "query-"}],
+			"orderBy": [{"field": "name"}]
+		}`)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(names(res)).To(Equal([]string{"alpha", "bravo", "charlie", "delta"}))
+	})
+	It("Should filter with a list of values", func() {
+		res, err := exec(`{
+			"types": ["mutable"],
+			"where": [
+				{"field": "key", "op": "prefix", "value": "query-"},
+				{"field": "name", "op": "in", "value": ["alpha", "delta"]}
+			]
+		}`)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(names(res)).To(ConsistOf("alpha", "delta"))
+	})
+	It("Should project the requested fields", func() {
+		res, err := exec(`{"ids": ["query-alpha:mutable"], "fields": ["name"]}`)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res).To(HaveLen(1))
+		Expect(res[0].Entity().Keys()).To(ConsistOf("name"))
+	})
+	It("Should filter resources reached by an intermediate step", func() {
+		res, err := exec(`{
+			"ids": ["QueryParent:empty"],
+			"traverse": [
+				{"relationship": "parent", "direction": "backward", "where": [
+					{"field": "name", "op": "eq", "value": "alpha"}
+				]},
+				{"relationship": "parent"}
+			]
+		}`)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res).To(HaveLen(1))
+		Expect(res[0].ID).To(Equal(parent))
+	})
+	Describe("Allowed Resources", func() {
+		denyAlpha := ontology.WithAllow(func(id ontology.ID) bool {
+			return id.Key != "query-alpha"
+		})
+		It("Should omit resources that aren't allowed", func() {
+			res, err := exec(`{
+				"ids": ["QueryParent:empty"],
+				"traverse": [{"relationship": "parent", "direction": "backward"}],
+				"orderBy": [{"field": "name"}],
+				"limit": 2
+			}`, denyAlpha)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(names(res)).To(Equal([]string{"bravo", "charlie"}))
+		})
+		It("Should not filter intermediate steps on resources that aren't allowed", func() {
+			res, err := exec(`{
+				"ids": ["QueryParent:empty"],
+				"traverse": [
+					{"relationship": "parent", "direction": "backward", "where": [
+						{"field": "name", "op": "eq", "value": "alpha"}
+					]},
+					{"relationship": "parent"}
+				]
+			}`, denyAlpha)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res).To(BeEmpty())
+		})
+		It("Should not traverse through resources that aren't allowed", func() {
+			w := otg.NewWriter(db)
+			grandchild := newEmptyID("QueryGrandchild")
+			Expect(w.DefineResource(grandchild)).To(Succeed())
+			Expect(w.DefineRelationship(
+				grandchild,
+				ontology.ID{Key: "query-alpha", Type: mutableType},
+				ontology.Parent,
+			)).To(Succeed())
+			res, err := exec(`{
+				"ids": ["QueryParent:empty"],
+				"traverse": [{"relationship": "parent", "direction": "backward", "depth": -1}]
+			}`, denyAlpha)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(names(res)).To(ConsistOf("bravo", "charlie", "delta"))
+		})
+		It("Should not select resources that aren't allowed", func() {
+			res, err := exec(`{"ids": ["query-alpha:mutable"]}`, denyAlpha)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res).To(BeEmpty())
+		})
+	})
+	DescribeTable("Invalid Queries",
+		func(raw string) {
+			_, err := exec(raw)
+			Expect(err).To(MatchError(ontology.InvalidQuery))
+		},
+		Entry("unknown operator", `{"where": [{"field": "name", "op": "like", "value": "a"}]}`),
+		Entry("in without a list", `{"where": [{"field": "name", "op": "in", "value": "a"}]}`),
+		Entry("missing relationship", `{"traverse": [{"direction": "forward"}]}`),
+		Entry("negative limit", `{"limit": -1}`),
+		Entry("malformed id", `{"ids": ["invalid"]}`),
+	)
+})
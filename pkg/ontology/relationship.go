@@ -11,6 +11,83 @@ const (
 	Parent RelationshipType = "parent"
 )
 
+// Forward returns a Traverser that follows relationships of type t from a resource
+// to the resources it has relationships to.
+func (t RelationshipType) Forward() Traverser {
+	return Traverser{Type: t, Direction: Forward}
+}
+
+// Backward returns a Traverser that follows relationships of type t from a resource
+// to the resources that have relationships to it.
+func (t RelationshipType) Backward() Traverser {
+	return Traverser{Type: t, Direction: Backward}
+}
+
+// Cardinality limits the number of relationships of a single type a resource can
+// have.
+type Cardinality uint8
+
+const (
+	// ManyToMany places no limit on the number of relationships.
+	ManyToMany Cardinality = iota
+	// ManyToOne allows each resource at most one outgoing relationship.
+	ManyToOne
+	// OneToMany allows each resource at most one incoming relationship.
+	OneToMany
+	// OneToOne allows each resource at most one incoming and one outgoing
+	// relationship.
+	OneToOne
+)
+
+func (c Cardinality) String() string {
+	switch c {
+	case ManyToOne:
+		return "many-to-one"
+	case OneToMany:
+		return "one-to-many"
+	case OneToOne:
+		return "one-to-one"
+	default:
+		return "many-to-many"
+	}
+}
+
+// RelationshipDefinition describes a type of relationship and the constraints the
+// ontology enforces on it.
+type RelationshipDefinition struct {
+	Type RelationshipType
+	// Acyclic prevents relationships of Type from forming cycles.
+	Acyclic bool
+	// Cardinality limits the number of relationships of Type each resource can have.
+	Cardinality Cardinality
+}
+
+var (
+	// CardinalityViolation is returned when defining a relationship would exceed the
+	// Cardinality of its type.
+	CardinalityViolation = errors.New("[ontology] - cardinality violation")
+	// UnknownRelationshipType is returned when defining a relationship whose type
+	// hasn't been registered.
+	UnknownRelationshipType = errors.New("[ontology] - unknown relationship type")
+)
+
+type relationshipTypes map[RelationshipType]RelationshipDefinition
+
+func (r relationshipTypes) Register(def RelationshipDefinition) {
+	if _, ok := r[def.Type]; ok {
+		panic("[ontology] - relationship type already registered")
+	}
+	r[def.Type] = def
+}
+
+func (r relationshipTypes) get(t RelationshipType) (RelationshipDefinition, error) {
+	def, ok := r[t]
+	if !ok {
+		return def, errors.Wrapf(UnknownRelationshipType, "[ontology] - type %s", t)
+	}
+	return def, nil
+}
+
 type Relationship struct {
 	From ID
 	To   ID
@@ -0,0 +1,95 @@
+package ontology
+
+import (
+	"github.com/arya-analytics/x/gorp"
+	"github.com/cockroachdb/errors"
+)
+
+// Relay applies the graph changes made by Writers (defining and deleting resources and
+// relationships) instead of the Writer's transaction. Relays are used to apply changes
+// somewhere other than the local transaction, such as on the node holding the lease on
+// a cluster-wide ontology. Changes passed to a Relay don't have a Seq.
+//
+// Changes made on a Txn are queued and relayed as a single batch when the Txn commits,
+// before the Txn itself is committed. A Relay should apply each batch atomically, so
+// that a batch it rejects (e.g. because it would create a cycle) leaves no trace.
+// Changes made directly on the database are relayed immediately.
+type Relay func(changes []Change) error
+
+// Option configures an Ontology opened with Open.
+type Option func(o *Ontology)
+
+// WithRelay routes the graph changes made by the Ontology's Writers through the given
+// Relay. The Relay is responsible for applying the changes (typically with Apply), so
+// Open doesn't define Root.
+func WithRelay(r Relay) Option { return func(o *Ontology) { o.relay = r } }
+
+// Apply applies the changes to the ontology using the provided transaction, bypassing
+// the Ontology's Relay. The changes are validated in the same way as changes made by a
+// Writer.
+func (o *Ontology) Apply(txn gorp.Txn, changes ...Change) error {
+	w := o.newWriter(txn, nil)
+	if w.err != nil {
+		return w.err
+	}
+	for _, c := range changes {
+		if err := w.apply(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Observe updates the Ontology with graph changes that were applied to its database by
+// another Ontology, such as the one on the node holding the lease on a cluster-wide
+// ontology. The resources are re-indexed for search, their cached entities are
+// invalidated, and the changes are published to Watch subscribers. Observe doesn't
+// write to the database, so the changes must already be visible in it.
+func (o *Ontology) Observe(changes ...Change) {
+	published := make([]Change, len(changes))
+	copy(published, changes)
+	for _, c := range published {
+		switch c.Variant {
+		case ResourceDefined:
+			o.search.define(c.ID)
+			o.retrieve.cache.invalidate(c.ID)
+		case ResourceDeleted:
+			o.search.delete(c.ID)
+			o.retrieve.cache.invalidate(c.ID)
+		}
+	}
+	o.feed.publish(published)
+}
+
+func (d dagWriter) apply(c Change) error {
+	rel := c.Relationship
+	switch c.Variant {
+	case ResourceDefined:
+		return d.defineResource(c.ID)
+	case ResourceDeleted:
+		return d.deleteResource(c.ID)
+	case RelationshipDefined:
+		return d.defineRelationship(rel.From, rel.To, rel.Type)
+	case RelationshipDeleted:
+		return d.deleteRelationship(rel.From, rel.To, rel.Type)
+	default:
+		return errors.Newf("[ontology] - unknown change variant %d", c.Variant)
+	}
+}
+
+// maybeRelay relays the change if the writer has a Relay, returning true if it did.
+// Changes made on a Txn are queued until it commits. Also returns true if the writer's
+// transaction isn't supported, along with the error.
+func (d dagWriter) maybeRelay(c Change) (bool, error) {
+	if d.err != nil {
+		return true, d.err
+	}
+	if d.relay == nil {
+		return false, nil
+	}
+	if t, ok := d.txn.(*Txn); ok {
+		t.queueRelay(d.relay, c)
+		return true, nil
+	}
+	return true, d.relay([]Change{c})
+}
@@ -0,0 +1,130 @@
+package ontology_test
+
+import (
+	"context"
+	"github.com/arya-analytics/delta/pkg/ontology"
+	"github.com/arya-analytics/x/gorp"
+	"github.com/arya-analytics/x/kv/memkv"
+	"github.com/cockroachdb/errors"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Relay", func() {
+	var (
+		relayDB *gorp.DB
+		relayed *ontology.Ontology
+		target  *ontology.Ontology
+	)
+	BeforeEach(func() {
+		relayDB = gorp.Wrap(memkv.New())
+		var err error
+		relayed, err = ontology.Open(relayDB, ontology.WithRelay(func(changes []ontology.Change) error {
+			t := target.BeginTxn()
+			if err := target.Apply(t, changes...); err != nil {
+				return errors.CombineErrors(err, t.Close())
+			}
+			return errors.CombineErrors(t.Commit(), t.Close())
+		}))
+		Expect(err).ToNot(HaveOccurred())
+		target = otg
+	})
+	AfterEach(func() { Expect(relayDB.Close()).To(Succeed()) })
+	It("Should apply graph changes through the relay instead of the transaction", func() {
+		a, b := newEmptyID("RelayA"), newEmptyID("RelayB")
+		w := relayed.NewWriter(relayDB)
+		Expect(w.DefineResource(a)).To(Succeed())
+		Expect(w.DefineResource(b)).To(Succeed())
+		Expect(w.DefineRelationship(a, b, ontology.Parent)).To(Succeed())
+		var r ontology.Resource
+		Expect(otg.NewRetrieve().
+			WhereIDs(a).
+			TraverseTo(ontology.Parents).
+			Entry(&r).
+			Exec(),
+		).To(Succeed())
+		Expect(r.ID).To(Equal(b))
+		exists, err := gorp.NewRetrieve[ontology.ID, ontology.Resource]().
+			WhereKeys(a).
+			Exists(relayDB)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(exists).To(BeFalse())
+		Expect(w.DeleteResource(a)).To(Succeed())
+		Expect(w.DeleteResource(b)).To(Succeed())
+	})
+	It("Should return the constraint violations reported by the relay", func() {
+		a, b := newEmptyID("RelayA"), newEmptyID("RelayB")
+		w := relayed.NewWriter(relayDB)
+		Expect(w.DefineResource(a)).To(Succeed())
+		Expect(w.DefineResource(b)).To(Succeed())
+		Expect(w.DefineRelationship(a, b, ontology.Parent)).To(Succeed())
+		err := w.DefineRelationship(b, a, ontology.Parent)
+		Expect(err).To(MatchError(ontology.CyclicDependency))
+		Expect(w.DeleteResource(a)).To(Succeed())
+		Expect(w.DeleteResource(b)).To(Succeed())
+	})
+	Describe("Transactions", func() {
+		var a, b ontology.ID
+		BeforeEach(func() { a, b = newEmptyID("RelayA"), newEmptyID("RelayB") })
+		AfterEach(func() {
+			w := otg.NewWriter(db)
+			Expect(w.DeleteResource(a)).To(Succeed())
+			Expect(w.DeleteResource(b)).To(Succeed())
+		})
+		exists := func(id ontology.ID) bool {
+			ok, err := gorp.NewRetrieve[ontology.ID, ontology.Resource]().
+				WhereKeys(id).
+				Exists(db)
+			Expect(err).ToNot(HaveOccurred())
+			return ok
+		}
+		It("Should relay graph changes when the transaction commits", func() {
+			t := relayed.BeginTxn()
+			w := relayed.NewWriter(t)
+			Expect(w.DefineResource(a)).To(Succeed())
+			Expect(w.DefineResource(b)).To(Succeed())
+			Expect(w.DefineRelationship(a, b, ontology.Parent)).To(Succeed())
+			Expect(exists(a)).To(BeFalse())
+			Expect(t.Commit()).To(Succeed())
+			Expect(t.Close()).To(Succeed())
+			Expect(exists(a)).To(BeTrue())
+			Expect(exists(b)).To(BeTrue())
+		})
+		It("Should not relay graph changes when the transaction is discarded", func() {
+			t := relayed.BeginTxn()
+			Expect(relayed.NewWriter(t).DefineResource(a)).To(Succeed())
+			Expect(t.Close()).To(Succeed())
+			Expect(exists(a)).To(BeFalse())
+		})
+		It("Should return the constraint violations reported by the relay on commit", func() {
+			w := relayed.NewWriter(relayDB)
+			Expect(w.DefineResource(a)).To(Succeed())
+			Expect(w.DefineResource(b)).To(Succeed())
+			Expect(w.DefineRelationship(a, b, ontology.Parent)).To(Succeed())
+			t := relayed.BeginTxn()
+			Expect(relayed.NewWriter(t).DefineRelationship(b, a, ontology.Parent)).To(Succeed())
+			Expect(t.Commit()).To(MatchError(ontology.CyclicDependency))
+			Expect(t.Close()).To(Succeed())
+		})
+	})
+	It("Should publish observed changes to Watch subscribers", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		sub, err := relayed.Watch(ctx, ontology.WatchFilter{})
+		Expect(err).ToNot(HaveOccurred())
+		a := newEmptyID("RelayA")
+		relayed.Observe(ontology.Change{Variant: ontology.ResourceDefined, ID: a})
+		var c ontology.Change
+		Eventually(sub.Changes).Should(Receive(&c))
+		Expect(c.Variant).To(Equal(ontology.ResourceDefined))
+		Expect(c.ID).To(Equal(a))
+		Expect(c.Seq).ToNot(BeZero())
+	})
+	It("Should not define the root resource when opened with a relay", func() {
+		exists, err := gorp.NewRetrieve[ontology.ID, ontology.Resource]().
+			WhereKeys(ontology.Root).
+			Exists(relayDB)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(exists).To(BeFalse())
+	})
+})
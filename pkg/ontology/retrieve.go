@@ -3,6 +3,7 @@ package ontology
 import (
 	"github.com/arya-analytics/x/gorp"
 	"github.com/arya-analytics/x/query"
+	"github.com/cockroachdb/errors"
 )
 
 type Retrieve struct {
@@ -32,6 +33,38 @@ func (r Retrieve) Where(filter func(r *Resource) bool) Retrieve {
 	return r
 }
 
+// WhereFields filters resources by the values of their entity fields. A resource
+// matches if its entity matches every filter.
+func (r Retrieve) WhereFields(filters ...Filter) Retrieve {
+	setFilters(r.query.Current(), append(getFilters(r.query.Current()), filters...))
+	return r
+}
+
+// OrderBy sorts resources by the values of their entity fields. Resources missing a
+// field are sorted after those that have it.
+func (r Retrieve) OrderBy(orders ...Order) Retrieve {
+	setShape(r.query.Current(), func(s *shape) { s.orders = append(s.orders, orders...) })
+	return r
+}
+
+// Offset skips the first offset resources (after sorting).
+func (r Retrieve) Offset(offset int) Retrieve {
+	setShape(r.query.Current(), func(s *shape) { s.offset = offset })
+	return r
+}
+
+// Limit returns at most limit resources (after sorting and applying Offset).
+func (r Retrieve) Limit(limit int) Retrieve {
+	setShape(r.query.Current(), func(s *shape) { s.limit = limit })
+	return r
+}
+
+// Fields limits the entities of resources to the given fields.
+func (r Retrieve) Fields(fields ...string) Retrieve {
+	setShape(r.query.Current(), func(s *shape) { s.fields = append(s.fields, fields...) })
+	return r
+}
+
 type Direction uint8
 
 const (
@@ -39,24 +72,46 @@ const (
 	Backward Direction = 2
 )
 
+// Traverser defines how a Retrieve query moves from a set of resources to their
+// related resources.
 type Traverser struct {
-	Filter    func(res *Resource, rel *Relationship) bool
+	// Depth is the maximum number of hops to traverse. Zero traverses a single hop,
+	// and Unbounded traverses until no more resources can be reached. A traversal
+	// of more than one hop returns every resource reached, not only those at the
+	// maximum depth.
+	Depth int
+	// Type is the type of relationship to traverse. When Type is set, the traversal
+	// reads the adjacency index for each resource instead of scanning every
+	// relationship, and Filter, if set, is only applied to relationships of Type.
+	Type RelationshipType
+	// Filter returns true if the traversal should follow the relationship from the
+	// resource. A Traverser without a Type must have a Filter.
+	Filter func(res *Resource, rel *Relationship) bool
+	// Allow, if set, returns true if the traversal may reach the resource. Resources
+	// that aren't allowed are neither returned nor traversed through, so a traversal
+	// of more than one hop never reaches a resource through one that isn't allowed.
+	Allow     func(id ID) bool
 	Direction Direction
 }
 
+// Unbounded is a Traverser Depth that traverses until no more resources can be
+// reached.
+const Unbounded = -1
+
+// Recursive returns a copy of the Traverser with an Unbounded Depth.
+func (t Traverser) Recursive() Traverser { return t.ToDepth(Unbounded) }
+
+// ToDepth returns a copy of the Traverser that traverses up to depth hops.
+func (t Traverser) ToDepth(depth int) Traverser {
+	t.Depth = depth
+	return t
+}
+
 var (
-	Children = Traverser{
-		Filter: func(res *Resource, rel *Relationship) bool {
-			return rel.Type == Parent && rel.To == res.ID
-		},
-		Direction: Backward,
-	}
-	Parents = Traverser{
-		Filter: func(res *Resource, rel *Relationship) bool {
-			return rel.Type == Parent && rel.From == res.ID
-		},
-		Direction: Forward,
-	}
+	Children    = Parent.Backward()
+	Parents     = Parent.Forward()
+	Descendants = Children.Recursive()
+	Ancestors   = Parents.Recursive()
 )
 
 // TraverseTo traverses to the provided relationship type. All filtering methods will
@@ -71,6 +126,7 @@ func (r Retrieve) TraverseTo(t Traverser) Retrieve {
 // override all previous calls to Entries or Entry.
 func (r Retrieve) Entry(res *Resource) Retrieve {
 	r.query.Current().Entry(res)
+	r.query.Current().Set(entriesOptKey, nil)
 	return r
 }
 
@@ -78,11 +134,37 @@ func (r Retrieve) Entry(res *Resource) Retrieve {
 // override all previous calls to Entries or Entry.
 func (r Retrieve) Entries(res *[]Resource) Retrieve {
 	r.query.Current().Entries(res)
+	r.query.Current().Set(entriesOptKey, res)
+	return r
+}
+
+// Paths binds a slice that the Query will fill with the path from a resource matched
+// by the first clause to each resource in the results, in the same order as the
+// results.
+func (r Retrieve) Paths(paths *[]Path) Retrieve {
+	r.query.Clauses[0].Set(pathsOptKey, paths)
 	return r
 }
 
 func (r Retrieve) Exec() error { return r.exec(r) }
 
+const (
+	pathsOptKey   = "paths"
+	entriesOptKey = "entries"
+)
+
+func setPaths(r Retrieve, resources []Resource, paths map[ID]Path) {
+	v, ok := r.query.Clauses[0].Get(pathsOptKey)
+	if !ok {
+		return
+	}
+	out := v.(*[]Path)
+	*out = make([]Path, len(resources))
+	for i, res := range resources {
+		(*out)[i] = paths[res.ID]
+	}
+}
+
 const traverseOptKey = "traverse"
 
 func setTraverser(q query.Query, f Traverser) {
@@ -95,10 +177,14 @@ func getTraverser(q query.Query) Traverser {
 
 type retrieve struct {
 	services services
+	cache    *entityCache
 }
 
 func (r retrieve) exec(q Retrieve) error {
-	var nextIDs []ID
+	var (
+		nextIDs []ID
+		paths   map[ID]Path
+	)
 	for i, clause := range q.query.Clauses {
 		if i != 0 {
 			clause.WhereKeys(nextIDs...)
@@ -107,43 +193,171 @@ func (r retrieve) exec(q Retrieve) error {
 			return err
 		}
 		entries := gorp.GetEntries[ID, Resource](clause)
-		resources := entries.All()
-		for i, res := range resources {
-			data, err := r.services.RetrieveEntity(res.ID)
-			if err != nil {
-				return err
+		resources, filters, shape := entries.All(), getFilters(clause), getShape(clause)
+		if len(filters) == 0 && len(shape.orders) == 0 {
+			// The page doesn't depend on the entities, so only load the ones on it.
+			resources = shape.paginate(resources)
+			shape.offset, shape.limit = 0, 0
+		}
+		resources, err := r.loadEntities(resources)
+		if err != nil {
+			return err
+		}
+		if resources, err = filterResources(resources, filters); err != nil {
+			return err
+		}
+		resources = shape.apply(resources)
+		out, bound := clause.Get(entriesOptKey)
+		if out != nil {
+			*out.(*[]Resource) = resources
+		} else if bound && len(resources) == 0 {
+			// The only resource matched by an Entry query was filtered out.
+			return query.NotFound
+		} else {
+			for i, res := range resources {
+				entries.Set(i, res)
+			}
+		}
+		if i == 0 {
+			paths = make(map[ID]Path, len(resources))
+			for _, res := range resources {
+				paths[res.ID] = Path{From: res.ID, To: res.ID}
 			}
-			res.entity = data
-			entries.Set(i, res)
 		}
 		if len(resources) == 0 {
 			break
 		}
 		if atLast := len(q.query.Clauses) == i+1; atLast {
+			setPaths(q, resources, paths)
 			return nil
 		}
-		var err error
-		if nextIDs, err = r.traverse(q.txn, getTraverser(clause), resources); err != nil {
+		nextIDs, paths, err = r.traverse(q.txn, getTraverser(clause), resources, paths)
+		if err != nil {
 			return err
 		}
+		if len(nextIDs) == 0 {
+			break
+		}
 	}
+	setPaths(q, nil, nil)
 	return nil
 }
 
+// loadEntities retrieves the entities of the resources in a single batch.
+func (r retrieve) loadEntities(resources []Resource) ([]Resource, error) {
+	ids := make([]ID, len(resources))
+	for i, res := range resources {
+		ids[i] = res.ID
+	}
+	entities, err := r.retrieveEntities(ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range resources {
+		resources[i].entity = entities[i]
+	}
+	return resources, nil
+}
+
+// retrieveEntities retrieves the entities of the resources with the given IDs, in the
+// same order, reading through the cache if the Ontology has one.
+func (r retrieve) retrieveEntities(ids []ID) ([]Entity, error) {
+	entities := make([]Entity, len(ids))
+	missing := make([]ID, 0, len(ids))
+	missingIdx := make([]int, 0, len(ids))
+	for i, id := range ids {
+		if e, ok := r.cache.get(id); ok {
+			entities[i] = e
+			continue
+		}
+		missing = append(missing, id)
+		missingIdx = append(missingIdx, i)
+	}
+	if len(missing) == 0 {
+		return entities, nil
+	}
+	retrieved, err := r.services.RetrieveEntities(missing)
+	if err != nil {
+		return nil, err
+	}
+	for i, e := range retrieved {
+		entities[missingIdx[i]] = e
+		r.cache.put(missing[i], e)
+	}
+	return entities, nil
+}
+
+// hop is a single step of a traversal along a relationship.
+type hop struct {
+	from, to ID
+	rel      Relationship
+}
+
+// traverse follows the Traverser from the given resources for up to its Depth, and
+// returns the IDs of the resources reached along with the path to each of them.
+// Resources reachable through multiple paths are returned once, with the shortest
+// path.
 func (r retrieve) traverse(
 	txn gorp.Txn,
 	traverse Traverser,
 	resources []Resource,
-) ([]ID, error) {
-	var nextIDs []ID
-	return nextIDs, gorp.NewRetrieve[string, Relationship]().
+	paths map[ID]Path,
+) ([]ID, map[ID]Path, error) {
+	var (
+		nextIDs   []ID
+		nextPaths = make(map[ID]Path)
+		visited   = make(map[ID]struct{}, len(resources))
+		frontier  = resources
+	)
+	for _, res := range resources {
+		visited[res.ID] = struct{}{}
+	}
+	for depth := 0; len(frontier) > 0; depth++ {
+		if traverse.Depth != Unbounded && depth >= max(traverse.Depth, 1) {
+			break
+		}
+		hops, err := r.step(txn, traverse, frontier)
+		if err != nil {
+			return nil, nil, err
+		}
+		frontier = nil
+		for _, h := range hops {
+			if _, ok := visited[h.to]; ok {
+				continue
+			}
+			visited[h.to] = struct{}{}
+			if traverse.Allow != nil && !traverse.Allow(h.to) {
+				continue
+			}
+			frontier = append(frontier, Resource{ID: h.to})
+			nextIDs = append(nextIDs, h.to)
+			prev, ok := paths[h.from]
+			if !ok {
+				prev = nextPaths[h.from]
+			}
+			nextPaths[h.to] = prev.extend(h.to, h.rel)
+		}
+	}
+	return nextIDs, nextPaths, nil
+}
+
+// step moves a single hop from each of the given resources.
+func (r retrieve) step(txn gorp.Txn, traverse Traverser, resources []Resource) ([]hop, error) {
+	if traverse.Type != "" {
+		return r.stepIndexed(txn, traverse, resources)
+	}
+	if traverse.Filter == nil {
+		return nil, errors.New("[ontology] - traverser must have a relationship type or a filter")
+	}
+	var hops []hop
+	return hops, gorp.NewRetrieve[string, Relationship]().
 		Where(func(rel *Relationship) bool {
 			for _, resource := range resources {
 				if traverse.Filter(&resource, rel) {
 					if traverse.Direction == Forward {
-						nextIDs = append(nextIDs, rel.To)
+						hops = append(hops, hop{from: resource.ID, to: rel.To, rel: *rel})
 					} else {
-						nextIDs = append(nextIDs, rel.From)
+						hops = append(hops, hop{from: resource.ID, to: rel.From, rel: *rel})
 					}
 					break
 				}
@@ -151,3 +365,35 @@ func (r retrieve) traverse(
 			return false
 		}).Exec(txn)
 }
+
+func (r retrieve) stepIndexed(
+	txn gorp.Txn,
+	traverse Traverser,
+	resources []Resource,
+) ([]hop, error) {
+	var hops []hop
+	for _, resource := range resources {
+		adjacent, err := retrieveAdjacent(txn, resource.ID, traverse.Direction, traverse.Type)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range adjacent {
+			rel := Relationship{From: resource.ID, To: id, Type: traverse.Type}
+			if traverse.Direction == Backward {
+				rel = Relationship{From: id, To: resource.ID, Type: traverse.Type}
+			}
+			if traverse.Filter != nil && !traverse.Filter(&resource, &rel) {
+				continue
+			}
+			hops = append(hops, hop{from: resource.ID, to: id, rel: rel})
+		}
+	}
+	return hops, nil
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -106,5 +106,138 @@ var _ = Describe("RetrieveEntity", func() {
 				Expect(v).To(Equal("C"))
 			})
 		})
+		Describe("Recursive Traversal", func() {
+			var a, b, c, d ontology.ID
+			BeforeEach(func() {
+				a, b, c, d = newEmptyID("A"), newEmptyID("B"), newEmptyID("C"), newEmptyID("D")
+				for _, id := range []ontology.ID{a, b, c, d} {
+					Expect(w.DefineResource(id)).To(Succeed())
+				}
+				Expect(w.DefineRelationship(a, b, ontology.Parent)).To(Succeed())
+				Expect(w.DefineRelationship(b, c, ontology.Parent)).To(Succeed())
+				Expect(w.DefineRelationship(c, d, ontology.Parent)).To(Succeed())
+			})
+			resourceIDs := func(res []ontology.Resource) []ontology.ID {
+				ids := make([]ontology.ID, len(res))
+				for i, r := range res {
+					ids[i] = r.ID
+				}
+				return ids
+			}
+			It("Should retrieve all ancestors of a resource", func() {
+				var res []ontology.Resource
+				Expect(w.NewRetrieve().
+					WhereIDs(a).
+					TraverseTo(ontology.Ancestors).
+					Entries(&res).
+					Exec(),
+				).To(Succeed())
+				Expect(resourceIDs(res)).To(ConsistOf(b, c, d))
+			})
+			It("Should retrieve descendants up to a depth", func() {
+				var res []ontology.Resource
+				Expect(w.NewRetrieve().
+					WhereIDs(d).
+					TraverseTo(ontology.Children.ToDepth(2)).
+					Entries(&res).
+					Exec(),
+				).To(Succeed())
+				Expect(resourceIDs(res)).To(ConsistOf(b, c))
+			})
+			It("Should return the path to each resource", func() {
+				var (
+					res   []ontology.Resource
+					paths []ontology.Path
+				)
+				Expect(w.NewRetrieve().
+					WhereIDs(d).
+					TraverseTo(ontology.Descendants).
+					Entries(&res).
+					Paths(&paths).
+					Exec(),
+				).To(Succeed())
+				Expect(paths).To(HaveLen(3))
+				for i, p := range paths {
+					Expect(p.From).To(Equal(d))
+					Expect(p.To).To(Equal(res[i].ID))
+					if p.To == a {
+						Expect(p.IDs()).To(Equal([]ontology.ID{d, c, b, a}))
+						Expect(p.Relationships[0]).To(Equal(ontology.Relationship{
+							From: c,
+							To:   d,
+							Type: ontology.Parent,
+						}))
+					}
+				}
+			})
+			It("Should return an empty result when there is nothing to traverse to", func() {
+				var res []ontology.Resource
+				Expect(w.NewRetrieve().
+					WhereIDs(a).
+					TraverseTo(ontology.Descendants).
+					Entries(&res).
+					Exec(),
+				).To(Succeed())
+				Expect(res).To(BeEmpty())
+			})
+		})
+		Describe("Custom Traversal", func() {
+			It("Should traverse relationships matching a filter", func() {
+				a := newEmptyID("A")
+				b := newEmptyID("B")
+				Expect(w.DefineResource(a)).To(Succeed())
+				Expect(w.DefineResource(b)).To(Succeed())
+				Expect(w.DefineRelationship(a, b, ontology.Parent)).To(Succeed())
+				var r ontology.Resource
+				Expect(w.NewRetrieve().
+					WhereIDs(b).
+					TraverseTo(ontology.Traverser{
+						Filter: func(res *ontology.Resource, rel *ontology.Relationship) bool {
+							return rel.Type == ontology.Parent && rel.To == res.ID
+						},
+						Direction: ontology.Backward,
+					}).
+					Entry(&r).
+					Exec(),
+				).To(Succeed())
+				Expect(r.ID).To(Equal(a))
+			})
+			It("Should return an error when the traverser has no type or filter", func() {
+				a := newEmptyID("A")
+				Expect(w.DefineResource(a)).To(Succeed())
+				var r ontology.Resource
+				Expect(w.NewRetrieve().
+					WhereIDs(a).
+					TraverseTo(ontology.Traverser{Direction: ontology.Forward}).
+					Entry(&r).
+					Exec(),
+				).To(HaveOccurred())
+			})
+		})
+	})
+	Describe("Built In Types", func() {
+		It("Should retrieve the root resource", func() {
+			var r ontology.Resource
+			Expect(w.NewRetrieve().WhereIDs(ontology.Root).Entry(&r).Exec()).To(Succeed())
+			v, ok := schema.Get[string](r.Entity(), "key")
+			Expect(ok).To(BeTrue())
+			Expect(v).To(Equal("root"))
+		})
+		It("Should retrieve a route resource", func() {
+			id := ontology.RouteKey("/auth/protected")
+			Expect(w.DefineResource(id)).To(Succeed())
+			var r ontology.Resource
+			Expect(w.NewRetrieve().WhereIDs(id).Entry(&r).Exec()).To(Succeed())
+			v, ok := schema.Get[string](r.Entity(), "path")
+			Expect(ok).To(BeTrue())
+			Expect(v).To(Equal("/auth/protected"))
+		})
+		It("Should return an error when retrieving a resource of an unknown type", func() {
+			id := ontology.ID{Type: "unknown", Key: "A"}
+			Expect(w.DefineResource(id)).To(Succeed())
+			var r ontology.Resource
+			Expect(w.NewRetrieve().WhereIDs(id).Entry(&r).Exec()).
+				To(MatchError(ontology.UnknownType))
+		})
 	})
 })
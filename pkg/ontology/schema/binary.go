@@ -0,0 +1,266 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"github.com/arya-analytics/x/telem"
+	"github.com/cockroachdb/errors"
+	"github.com/google/uuid"
+	"io"
+	"math"
+)
+
+// EncodeBinary encodes the Entity into a compact binary format. Fields are written in
+// sorted order, and values are written using the type of their field in the Entity's
+// Schema, so the same Schema must be used to decode them with DecodeBinary. Object
+// fields without Fields and Array fields without an Elem are written as JSON.
+func EncodeBinary(e Entity) ([]byte, error) {
+	if e.schema == nil {
+		return nil, errors.New("[schema] - entity has no schema")
+	}
+	w := &binaryWriter{}
+	if err := w.fields(e.schema.Fields, e.data); err != nil {
+		return nil, err
+	}
+	return w.buf.Bytes(), nil
+}
+
+// DecodeBinary decodes an Entity encoded with EncodeBinary using the given Schema.
+func DecodeBinary(s *Schema, data []byte) (Entity, error) {
+	e := NewEntity(s)
+	r := &binaryReader{r: bytes.NewReader(data)}
+	values, err := r.fields(s.Fields)
+	if err != nil {
+		return e, errors.Wrap(err, "[schema] - failed to decode entity")
+	}
+	e.data = values
+	return e, nil
+}
+
+type binaryWriter struct{ buf bytes.Buffer }
+
+func (w *binaryWriter) uvarint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	w.buf.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+func (w *binaryWriter) varint(v int64) {
+	var b [binary.MaxVarintLen64]byte
+	w.buf.Write(b[:binary.PutVarint(b[:], v)])
+}
+
+func (w *binaryWriter) bytes(b []byte) {
+	w.uvarint(uint64(len(b)))
+	w.buf.Write(b)
+}
+
+func (w *binaryWriter) fields(fields map[string]Field, values map[string]interface{}) error {
+	w.uvarint(uint64(len(values)))
+	for _, k := range sortedKeys(values) {
+		f, ok := fields[k]
+		if !ok {
+			return errors.Newf("[schema] - field %s not defined in schema", k)
+		}
+		w.bytes([]byte(k))
+		if err := w.value(f, values[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *binaryWriter) value(f Field, v interface{}) error {
+	if !f.Type.AssertValue(v) {
+		return errors.Newf("[schema] - expected %s, received %T", f.Type, v)
+	}
+	switch f.Type {
+	case String:
+		w.bytes([]byte(v.(string)))
+	case Bool:
+		if v.(bool) {
+			w.buf.WriteByte(1)
+		} else {
+			w.buf.WriteByte(0)
+		}
+	case UUID:
+		u := v.(uuid.UUID)
+		w.buf.Write(u[:])
+	case Int:
+		w.varint(int64(v.(int)))
+	case Int8:
+		w.varint(int64(v.(int8)))
+	case Int16:
+		w.varint(int64(v.(int16)))
+	case Int32:
+		w.varint(int64(v.(int32)))
+	case Int64:
+		w.varint(v.(int64))
+	case Uint8:
+		w.uvarint(uint64(v.(uint8)))
+	case Uint16:
+		w.uvarint(uint64(v.(uint16)))
+	case Uint32:
+		w.uvarint(uint64(v.(uint32)))
+	case Uint64:
+		w.uvarint(v.(uint64))
+	case Float32:
+		var b [4]byte
+		binary.LittleEndian.PutUint32(b[:], math.Float32bits(v.(float32)))
+		w.buf.Write(b[:])
+	case Float64:
+		var b [8]byte
+		binary.LittleEndian.PutUint64(b[:], math.Float64bits(v.(float64)))
+		w.buf.Write(b[:])
+	case TimeStamp:
+		w.varint(int64(v.(telem.TimeStamp)))
+	case TimeRange:
+		tr := v.(telem.TimeRange)
+		w.varint(int64(tr.Start))
+		w.varint(int64(tr.End))
+	case Object:
+		if f.Fields == nil {
+			return w.json(v)
+		}
+		return w.fields(f.Fields, v.(map[string]interface{}))
+	case Array:
+		if f.Elem == nil {
+			return w.json(v)
+		}
+		arr := v.([]interface{})
+		w.uvarint(uint64(len(arr)))
+		for _, elem := range arr {
+			if err := w.value(*f.Elem, elem); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (w *binaryWriter) json(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	w.bytes(b)
+	return nil
+}
+
+type binaryReader struct{ r *bytes.Reader }
+
+func (r *binaryReader) uvarint() (uint64, error) { return binary.ReadUvarint(r.r) }
+
+func (r *binaryReader) varint() (int64, error) { return binary.ReadVarint(r.r) }
+
+func (r *binaryReader) bytes() ([]byte, error) {
+	n, err := r.uvarint()
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(r.r.Len()) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := make([]byte, n)
+	_, err = io.ReadFull(r.r, b)
+	return b, err
+}
+
+func (r *binaryReader) fields(fields map[string]Field) (map[string]interface{}, error) {
+	n, err := r.uvarint()
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(r.r.Len()) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	values := make(map[string]interface{}, n)
+	for i := uint64(0); i < n; i++ {
+		k, err := r.bytes()
+		if err != nil {
+			return nil, err
+		}
+		f, ok := fields[string(k)]
+		if !ok {
+			return nil, errors.Newf("[schema] - field %s not defined in schema", k)
+		}
+		if values[string(k)], err = r.value(f); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+func (r *binaryReader) value(f Field) (interface{}, error) {
+	switch f.Type {
+	case String:
+		b, err := r.bytes()
+		return string(b), err
+	case Bool:
+		b, err := r.r.ReadByte()
+		return b == 1, err
+	case UUID:
+		var u uuid.UUID
+		_, err := io.ReadFull(r.r, u[:])
+		return u, err
+	case Int, Int8, Int16, Int32, Int64:
+		n, err := r.varint()
+		return convertInt(f.Type, n), err
+	case Uint8, Uint16, Uint32, Uint64:
+		n, err := r.uvarint()
+		return convertUint(f.Type, n), err
+	case Float32:
+		var b [4]byte
+		_, err := io.ReadFull(r.r, b[:])
+		return math.Float32frombits(binary.LittleEndian.Uint32(b[:])), err
+	case Float64:
+		var b [8]byte
+		_, err := io.ReadFull(r.r, b[:])
+		return math.Float64frombits(binary.LittleEndian.Uint64(b[:])), err
+	case TimeStamp:
+		n, err := r.varint()
+		return telem.TimeStamp(n), err
+	case TimeRange:
+		start, err := r.varint()
+		if err != nil {
+			return nil, err
+		}
+		end, err := r.varint()
+		return telem.TimeRange{Start: telem.TimeStamp(start), End: telem.TimeStamp(end)}, err
+	case Object:
+		if f.Fields == nil {
+			var v map[string]interface{}
+			return v, r.json(&v)
+		}
+		return r.fields(f.Fields)
+	case Array:
+		if f.Elem == nil {
+			var v []interface{}
+			return v, r.json(&v)
+		}
+		n, err := r.uvarint()
+		if err != nil {
+			return nil, err
+		}
+		if n > uint64(r.r.Len()) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		arr := make([]interface{}, n)
+		for i := range arr {
+			if arr[i], err = r.value(*f.Elem); err != nil {
+				return nil, err
+			}
+		}
+		return arr, nil
+	default:
+		return nil, errors.Newf("[schema] - unknown field type %d", f.Type)
+	}
+}
+
+func (r *binaryReader) json(v interface{}) error {
+	b, err := r.bytes()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
@@ -0,0 +1,105 @@
+package schema_test
+
+import (
+	"encoding/json"
+	"github.com/arya-analytics/delta/pkg/ontology/schema"
+	"github.com/arya-analytics/x/telem"
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var codecSchema = &schema.Schema{
+	Type: "codec",
+	Fields: map[string]schema.Field{
+		"key":     {Type: schema.UUID, Required: true},
+		"name":    {Type: schema.String},
+		"count":   {Type: schema.Int16},
+		"rate":    {Type: schema.Float32},
+		"active":  {Type: schema.Bool},
+		"created": {Type: schema.TimeStamp},
+		"range":   {Type: schema.TimeRange},
+		"owner": {
+			Type:   schema.Object,
+			Fields: map[string]schema.Field{"id": {Type: schema.Uint32}},
+		},
+		"tags":  {Type: schema.Array, Elem: &schema.Field{Type: schema.String}},
+		"extra": {Type: schema.Object},
+	},
+}
+
+func newCodecEntity() schema.Entity {
+	e := schema.NewEntity(codecSchema)
+	Expect(schema.Set(e, "key", uuid.New())).To(Succeed())
+	Expect(schema.Set(e, "name", "foo")).To(Succeed())
+	Expect(schema.Set(e, "count", int16(-3))).To(Succeed())
+	Expect(schema.Set(e, "rate", float32(1.5))).To(Succeed())
+	Expect(schema.Set(e, "active", true)).To(Succeed())
+	Expect(schema.Set(e, "created", telem.TimeStamp(42))).To(Succeed())
+	Expect(schema.Set(e, "range", telem.TimeRange{Start: 1, End: 2})).To(Succeed())
+	Expect(schema.Set(e, "owner", map[string]interface{}{"id": uint32(7)})).To(Succeed())
+	Expect(schema.Set(e, "tags", []interface{}{"a", "b"})).To(Succeed())
+	Expect(schema.Set(e, "extra", map[string]interface{}{"note": "bar"})).To(Succeed())
+	return e
+}
+
+var _ = Describe("Codec", func() {
+	Describe("JSON", func() {
+		It("Should encode and decode an entity", func() {
+			e := newCodecEntity()
+			b, err := json.Marshal(e)
+			Expect(err).ToNot(HaveOccurred())
+			decoded, err := schema.DecodeJSON(codecSchema, b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decoded.Data()).To(Equal(e.Data()))
+		})
+		It("Should encode time ranges as objects", func() {
+			e := schema.NewEntity(codecSchema)
+			Expect(schema.Set(e, "range", telem.TimeRange{Start: 1, End: 2})).To(Succeed())
+			b, err := json.Marshal(e)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(b)).To(Equal(`{"range":{"start":1,"end":2}}`))
+		})
+		It("Should return validation errors for values that don't match the schema", func() {
+			_, err := schema.DecodeJSON(codecSchema, []byte(`{"count":"foo","owner":{"id":-1}}`))
+			verr := validationErrors(err)
+			Expect(verr).To(HaveLen(2))
+			Expect(verr[0].Field).To(Equal("count"))
+			Expect(verr[1].Field).To(Equal("owner.id"))
+		})
+		It("Should decode into an entity created with a schema", func() {
+			e := schema.NewEntity(codecSchema)
+			Expect(json.Unmarshal([]byte(`{"count":12}`), &e)).To(Succeed())
+			count, ok := schema.Get[int16](e, "count")
+			Expect(ok).To(BeTrue())
+			Expect(count).To(Equal(int16(12)))
+		})
+		It("Should encode a schema", func() {
+			b, err := json.Marshal(&schema.Schema{
+				Type:   "foo",
+				Fields: map[string]schema.Field{"name": {Type: schema.String, Required: true}},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(b)).To(Equal(`{"type":"foo","fields":{"name":{"type":"string","required":true}}}`))
+			var s schema.Schema
+			Expect(json.Unmarshal(b, &s)).To(Succeed())
+			Expect(s.Fields["name"].Type).To(Equal(schema.String))
+		})
+	})
+	Describe("Binary", func() {
+		It("Should encode and decode an entity", func() {
+			e := newCodecEntity()
+			b, err := schema.EncodeBinary(e)
+			Expect(err).ToNot(HaveOccurred())
+			decoded, err := schema.DecodeBinary(codecSchema, b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decoded.Data()).To(Equal(e.Data()))
+		})
+		It("Should return an error for truncated data", func() {
+			b, err := schema.EncodeBinary(newCodecEntity())
+			Expect(err).ToNot(HaveOccurred())
+			_, err = schema.DecodeBinary(codecSchema, b[:len(b)/2])
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
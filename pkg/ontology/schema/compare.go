@@ -0,0 +1,90 @@
+package schema
+
+import (
+	"github.com/cockroachdb/errors"
+	"github.com/google/uuid"
+	"math"
+	"reflect"
+)
+
+// Incomparable is returned by Compare when two values can't be ordered.
+var Incomparable = errors.New("[schema] - values are incomparable")
+
+// Compare orders two field values, returning -1 if a < b, 0 if a == b, and 1 if
+// a > b. Numeric values of any type (including TimeStamps) are compared by value, so a
+// float64 decoded from JSON can be compared with an int field. Strings and bools are
+// compared with each other, and UUIDs are compared as strings. Returns Incomparable
+// for any other combination of values.
+func Compare(a, b interface{}) (int, error) {
+	if u, ok := a.(uuid.UUID); ok {
+		a = u.String()
+	}
+	if u, ok := b.(uuid.UUID); ok {
+		b = u.String()
+	}
+	av, bv := reflect.ValueOf(a), reflect.ValueOf(b)
+	if !av.IsValid() || !bv.IsValid() {
+		return 0, Incomparable
+	}
+	switch {
+	case isNumeric(av.Kind()) && isNumeric(bv.Kind()):
+		return compareNumbers(av, bv), nil
+	case av.Kind() == reflect.String && bv.Kind() == reflect.String:
+		return compareOrdered(av.String(), bv.String()), nil
+	case av.Kind() == reflect.Bool && bv.Kind() == reflect.Bool:
+		return compareOrdered(boolToInt(av.Bool()), boolToInt(bv.Bool())), nil
+	default:
+		return 0, errors.Wrapf(Incomparable, "[schema] - %T and %T", a, b)
+	}
+}
+
+func isNumeric(k reflect.Kind) bool {
+	return isInt(k) || isUint(k) || k == reflect.Float32 || k == reflect.Float64
+}
+
+func isInt(k reflect.Kind) bool { return k >= reflect.Int && k <= reflect.Int64 }
+
+func isUint(k reflect.Kind) bool { return k >= reflect.Uint && k <= reflect.Uint64 }
+
+func compareNumbers(a, b reflect.Value) int {
+	switch {
+	case isInt(a.Kind()) && isInt(b.Kind()):
+		return compareOrdered(a.Int(), b.Int())
+	case isUint(a.Kind()) && isUint(b.Kind()):
+		return compareOrdered(a.Uint(), b.Uint())
+	default:
+		return compareOrdered(toFloat(a), toFloat(b))
+	}
+}
+
+func toFloat(v reflect.Value) float64 {
+	switch {
+	case isInt(v.Kind()):
+		return float64(v.Int())
+	case isUint(v.Kind()):
+		return float64(v.Uint())
+	default:
+		if f := v.Float(); !math.IsNaN(f) {
+			return f
+		}
+		return math.Inf(-1)
+	}
+}
+
+func compareOrdered[T int64 | uint64 | float64 | string | int](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,32 @@
+package schema_test
+
+import (
+	"github.com/arya-analytics/delta/pkg/ontology/schema"
+	"github.com/arya-analytics/x/telem"
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Compare", func() {
+	DescribeTable("Comparable values",
+		func(a, b interface{}, expected int) {
+			c, err := schema.Compare(a, b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c).To(Equal(expected))
+		},
+		Entry("ints", 1, 2, -1),
+		Entry("an int and a float decoded from JSON", int16(3), 3.0, 0),
+		Entry("a timestamp and an int", telem.TimeStamp(10), int64(5), 1),
+		Entry("a uint and a negative int", uint32(1), -1, 1),
+		Entry("strings", "b", "a", 1),
+		Entry("bools", false, true, -1),
+		Entry("a uuid and a string", uuid.Nil, uuid.Nil.String(), 0),
+	)
+	It("Should return an error for incomparable values", func() {
+		_, err := schema.Compare("a", 1)
+		Expect(err).To(MatchError(schema.Incomparable))
+		_, err = schema.Compare(nil, 1)
+		Expect(err).To(MatchError(schema.Incomparable))
+	})
+})
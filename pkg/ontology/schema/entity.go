@@ -1,31 +1,100 @@
 package schema
 
+import "github.com/cockroachdb/errors"
+
 type Entity struct {
 	schema *Schema
 	data   map[string]interface{}
 }
 
+// Get returns the value of the field with the given key. Returns false if the field
+// isn't set, or if its value isn't of type V.
 func Get[V Value](d Entity, k string) (v V, ok bool) {
 	rv, ok := d.data[k]
 	if !ok {
 		return v, false
 	}
 	v, ok = rv.(V)
-	if !ok {
-		panic("[schema] - invalid field type")
-	}
-	return v, true
+	return v, ok
 }
 
-func Set[V Value](D Entity, k string, v V) {
+// Set sets the value of the field with the given key. Returns a ValidationErrors if
+// the field isn't defined in the Entity's schema, or if the value is invalid for the
+// field. The field is left unchanged if Set returns an error.
+func Set[V Value](D Entity, k string, v V) error {
 	f, ok := D.schema.Fields[k]
 	if !ok {
-		panic("[schema] - field not found")
+		return ValidationErrors{{Field: k, Message: "field not defined in schema"}}
 	}
-	if !f.Type.AssertValue(v) {
-		panic("[schema] - invalid field type")
+	if errs := f.Validate(k, v); len(errs) > 0 {
+		return errs
 	}
 	D.data[k] = v
+	return nil
+}
+
+// Schema returns the schema the Entity was created with.
+func (e Entity) Schema() *Schema { return e.schema }
+
+// Keys returns the names of the fields that are set on the Entity.
+func (e Entity) Keys() []string {
+	keys := make([]string, 0, len(e.data))
+	for k := range e.data {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// Data returns a copy of the field values set on the Entity.
+func (e Entity) Data() map[string]interface{} {
+	data := make(map[string]interface{}, len(e.data))
+	for k, v := range e.data {
+		data[k] = v
+	}
+	return data
+}
+
+// Value returns the value of the field with the given key. Returns false if the field
+// isn't set.
+func (e Entity) Value(k string) (interface{}, bool) {
+	v, ok := e.data[k]
+	return v, ok
+}
+
+// Project returns a copy of the Entity that only holds the given fields. Fields that
+// aren't set on the Entity are ignored.
+func (e Entity) Project(fields ...string) Entity {
+	p := Entity{schema: e.schema, data: make(map[string]interface{}, len(fields))}
+	for _, k := range fields {
+		if v, ok := e.data[k]; ok {
+			p.data[k] = v
+		}
+	}
+	return p
+}
+
+// Validate validates that the Entity conforms to the given Schema. The Entity must
+// have the same Type as the Schema, every required field in the Schema must be set,
+// and every field set on the Entity must be defined in the Schema with a valid value.
+// Invalid fields are reported as ValidationErrors.
+func (e Entity) Validate(s *Schema) error { return e.validate(s, false) }
+
+// ValidatePartial validates the Entity like Validate, but allows required fields to
+// be missing. ValidatePartial is used to validate updates that only set the fields
+// being changed.
+func (e Entity) ValidatePartial(s *Schema) error { return e.validate(s, true) }
+
+func (e Entity) validate(s *Schema, partial bool) error {
+	if e.schema == nil {
+		return errors.New("[schema] - entity has no schema")
+	}
+	if e.schema.Type != s.Type {
+		return errors.Newf("[schema] - expected entity of type %s, received %s", s.Type, e.schema.Type)
+	}
+	if errs := validateFields("", s.Fields, e.data, partial); len(errs) > 0 {
+		return errs
+	}
+	return nil
 }
 
 func NewEntity(schema *Schema) Entity {
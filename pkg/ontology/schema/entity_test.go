@@ -0,0 +1,105 @@
+package schema_test
+
+import (
+	"github.com/arya-analytics/delta/pkg/ontology/schema"
+	"github.com/arya-analytics/x/telem"
+	"github.com/cockroachdb/errors"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var s = &schema.Schema{
+	Type: "test",
+	Fields: map[string]schema.Field{
+		"name":    {Type: schema.String, Required: true},
+		"count":   {Type: schema.Int16},
+		"created": {Type: schema.TimeStamp},
+		"range":   {Type: schema.TimeRange},
+		"owner": {
+			Type: schema.Object,
+			Fields: map[string]schema.Field{
+				"name": {Type: schema.String, Required: true},
+			},
+		},
+		"tags": {Type: schema.Array, Elem: &schema.Field{Type: schema.String}},
+	},
+}
+
+func validationErrors(err error) schema.ValidationErrors {
+	var verr schema.ValidationErrors
+	Expect(errors.As(err, &verr)).To(BeTrue())
+	return verr
+}
+
+var _ = Describe("Entity", func() {
+	var e schema.Entity
+	BeforeEach(func() {
+		e = schema.NewEntity(s)
+		Expect(schema.Set(e, "name", "foo")).To(Succeed())
+	})
+	Describe("Set", func() {
+		It("Should set scalar fields", func() {
+			Expect(schema.Set(e, "count", int16(3))).To(Succeed())
+			Expect(schema.Set(e, "created", telem.TimeStamp(10))).To(Succeed())
+			count, ok := schema.Get[int16](e, "count")
+			Expect(ok).To(BeTrue())
+			Expect(count).To(Equal(int16(3)))
+		})
+		It("Should return an error for a value of the wrong type", func() {
+			verr := validationErrors(schema.Set(e, "count", 3.0))
+			Expect(verr[0].Field).To(Equal("count"))
+			_, ok := schema.Get[int16](e, "count")
+			Expect(ok).To(BeFalse())
+		})
+		It("Should return an error for an undefined field", func() {
+			verr := validationErrors(schema.Set(e, "undefined", "foo"))
+			Expect(verr[0].Field).To(Equal("undefined"))
+		})
+		It("Should return an error for an invalid time range", func() {
+			verr := validationErrors(schema.Set(e, "range", telem.TimeRange{Start: 10, End: 5}))
+			Expect(verr[0].Field).To(Equal("range"))
+		})
+	})
+	Describe("Get", func() {
+		It("Should return false for a value of a different type", func() {
+			_, ok := schema.Get[int](e, "name")
+			Expect(ok).To(BeFalse())
+		})
+	})
+	Describe("Project", func() {
+		It("Should return a copy holding only the given fields", func() {
+			Expect(schema.Set(e, "count", int16(3))).To(Succeed())
+			p := e.Project("count", "range")
+			Expect(p.Keys()).To(ConsistOf("count"))
+			Expect(p.Schema()).To(Equal(s))
+			Expect(e.Keys()).To(ConsistOf("name", "count"))
+		})
+	})
+	Describe("Validate", func() {
+		It("Should validate nested objects and arrays", func() {
+			Expect(schema.Set(e, "owner", map[string]interface{}{"name": "bar"})).To(Succeed())
+			Expect(schema.Set(e, "tags", []interface{}{"a", "b"})).To(Succeed())
+			Expect(e.Validate(s)).To(Succeed())
+		})
+		It("Should return errors for invalid nested values", func() {
+			verr := validationErrors(schema.Set(e, "owner", map[string]interface{}{"age": 1}))
+			Expect(verr).To(HaveLen(2))
+			Expect(verr[0].Field).To(Equal("owner.name"))
+			Expect(verr[1].Field).To(Equal("owner.age"))
+			verr = validationErrors(schema.Set(e, "tags", []interface{}{"a", 1}))
+			Expect(verr[0].Field).To(Equal("tags[1]"))
+		})
+		It("Should return an error for a missing required field", func() {
+			verr := validationErrors(schema.NewEntity(s).Validate(s))
+			Expect(verr[0].Field).To(Equal("name"))
+			Expect(verr[0].Message).To(Equal("field is required"))
+		})
+		It("Should allow missing required fields when validating partially", func() {
+			Expect(schema.NewEntity(s).ValidatePartial(s)).To(Succeed())
+		})
+		It("Should return an error for an entity of a different type", func() {
+			other := schema.NewEntity(&schema.Schema{Type: "other"})
+			Expect(other.Validate(s)).ToNot(Succeed())
+		})
+	})
+})
@@ -1,9 +1,17 @@
 package schema
 
-import "github.com/google/uuid"
+import (
+	"fmt"
+	"github.com/arya-analytics/x/telem"
+	"github.com/google/uuid"
+	"strconv"
+)
 
 type FieldType uint8
 
+// AssertValue returns true if v is a valid value for the FieldType. AssertValue only
+// checks the Go type of v; use Field.Validate to also validate the contents of
+// Object and Array values.
 func (f FieldType) AssertValue(v interface{}) bool {
 	switch f {
 	case String:
@@ -13,7 +21,7 @@ func (f FieldType) AssertValue(v interface{}) bool {
 	case Int8:
 		return assertValueType[int8](v)
 	case Int16:
-		return assertValueType[float64](v)
+		return assertValueType[int16](v)
 	case Int32:
 		return assertValueType[int32](v)
 	case Int64:
@@ -34,9 +42,24 @@ func (f FieldType) AssertValue(v interface{}) bool {
 		return assertValueType[bool](v)
 	case UUID:
 		return assertValueType[uuid.UUID](v)
+	case TimeStamp:
+		return assertValueType[telem.TimeStamp](v)
+	case TimeRange:
+		return assertValueType[telem.TimeRange](v)
+	case Object:
+		return assertValueType[map[string]interface{}](v)
+	case Array:
+		return assertValueType[[]interface{}](v)
 	default:
-		panic("[FieldType]")
+		return false
+	}
+}
+
+func (f FieldType) String() string {
+	if int(f) < len(fieldTypeNames) {
+		return fieldTypeNames[f]
 	}
+	return "unknown"
 }
 
 const (
@@ -54,8 +77,38 @@ const (
 	Float64
 	Bool
 	UUID
+	// TimeStamp fields hold a telem.TimeStamp.
+	TimeStamp
+	// TimeRange fields hold a telem.TimeRange.
+	TimeRange
+	// Object fields hold a map[string]interface{} whose entries are defined by
+	// Field.Fields.
+	Object
+	// Array fields hold a []interface{} whose elements are defined by Field.Elem.
+	Array
 )
 
+var fieldTypeNames = []string{
+	"string",
+	"int",
+	"int8",
+	"int16",
+	"int32",
+	"int64",
+	"uint8",
+	"uint16",
+	"uint32",
+	"uint64",
+	"float32",
+	"float64",
+	"bool",
+	"uuid",
+	"timestamp",
+	"timerange",
+	"object",
+	"array",
+}
+
 type Value interface {
 	string |
 		int |
@@ -70,11 +123,54 @@ type Value interface {
 		float32 |
 		float64 |
 		bool |
-		uuid.UUID
+		uuid.UUID |
+		telem.TimeStamp |
+		telem.TimeRange |
+		map[string]interface{} |
+		[]interface{}
 }
 
 func assertValueType[V Value](v interface{}) bool { _, ok := v.(V); return ok }
 
 type Field struct {
-	Type FieldType
+	Type FieldType `json:"type"`
+	// Required fields must be set on an Entity for it to be valid.
+	Required bool `json:"required,omitempty"`
+	// Fields defines the entries of an Object field. Entries not defined in Fields are
+	// invalid. If Fields is nil, the Object can hold any entries.
+	Fields map[string]Field `json:"fields,omitempty"`
+	// Elem defines the elements of an Array field.
+	Elem *Field `json:"elem,omitempty"`
+}
+
+// Validate returns the errors that make v an invalid value for the Field. path is
+// the location of the value within the Entity, and is used to build the errors.
+func (f Field) Validate(path string, v interface{}) ValidationErrors {
+	if !f.Type.AssertValue(v) {
+		return ValidationErrors{{
+			Field:   path,
+			Message: fmt.Sprintf("expected %s, received %T", f.Type, v),
+		}}
+	}
+	switch f.Type {
+	case TimeRange:
+		if tr := v.(telem.TimeRange); tr.End < tr.Start {
+			return ValidationErrors{{Field: path, Message: "time range end is before start"}}
+		}
+	case Object:
+		if f.Fields == nil {
+			return nil
+		}
+		return validateFields(path+".", f.Fields, v.(map[string]interface{}), false)
+	case Array:
+		if f.Elem == nil {
+			return nil
+		}
+		var errs ValidationErrors
+		for i, elem := range v.([]interface{}) {
+			errs = append(errs, f.Elem.Validate(path+"["+strconv.Itoa(i)+"]", elem)...)
+		}
+		return errs
+	}
+	return nil
 }
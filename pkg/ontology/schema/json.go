@@ -0,0 +1,258 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/arya-analytics/x/telem"
+	"github.com/cockroachdb/errors"
+	"github.com/google/uuid"
+	"strconv"
+)
+
+// MarshalText implements encoding.TextMarshaler.
+func (f FieldType) MarshalText() ([]byte, error) {
+	if int(f) >= len(fieldTypeNames) {
+		return nil, errors.Newf("[schema] - unknown field type %d", f)
+	}
+	return []byte(fieldTypeNames[f]), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (f *FieldType) UnmarshalText(text []byte) error {
+	for i, name := range fieldTypeNames {
+		if name == string(text) {
+			*f = FieldType(i)
+			return nil
+		}
+	}
+	return errors.Newf("[schema] - unknown field type %s", text)
+}
+
+// jsonTimeRange is the JSON representation of a telem.TimeRange.
+type jsonTimeRange struct {
+	Start telem.TimeStamp `json:"start"`
+	End   telem.TimeStamp `json:"end"`
+}
+
+// MarshalJSON implements json.Marshaler. The Entity is encoded as an object mapping
+// field names to values. TimeStamps are encoded as integers, TimeRanges as objects
+// with 'start' and 'end' keys, and UUIDs as strings. The zero Entity is encoded as
+// null.
+func (e Entity) MarshalJSON() ([]byte, error) {
+	if e.schema == nil {
+		return []byte("null"), nil
+	}
+	out := make(map[string]interface{}, len(e.data))
+	for k, v := range e.data {
+		out[k] = toJSON(e.schema.Fields[k], v)
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON implements json.Unmarshaler. The Entity must have been created with
+// NewEntity, as its Schema is used to convert each JSON value to the Go type of its
+// field. Returns a ValidationErrors if the JSON doesn't conform to the Schema. Missing
+// required fields aren't reported; use Validate to check them.
+func (e *Entity) UnmarshalJSON(data []byte) error {
+	if e.schema == nil {
+		return errors.New("[schema] - entity has no schema")
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	values := make(map[string]interface{}, len(raw))
+	var errs ValidationErrors
+	for _, k := range sortedKeys(raw) {
+		f, ok := e.schema.Fields[k]
+		if !ok {
+			errs = append(errs, ValidationError{Field: k, Message: "field not defined in schema"})
+			continue
+		}
+		v, err := fromJSON(k, f, raw[k])
+		if err != nil {
+			errs = append(errs, asValidationErrors(k, err)...)
+			continue
+		}
+		values[k] = v
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	// Required fields are allowed to be missing, so that partial entities can be
+	// decoded for updates.
+	if errs := validateFields("", e.schema.Fields, values, true); len(errs) > 0 {
+		return errs
+	}
+	e.data = values
+	return nil
+}
+
+// DecodeJSON decodes an Entity encoded with Entity.MarshalJSON using the given Schema.
+func DecodeJSON(s *Schema, data []byte) (Entity, error) {
+	e := NewEntity(s)
+	return e, e.UnmarshalJSON(data)
+}
+
+func toJSON(f Field, v interface{}) interface{} {
+	switch f.Type {
+	case TimeRange:
+		tr := v.(telem.TimeRange)
+		return jsonTimeRange{Start: tr.Start, End: tr.End}
+	case Object:
+		obj := v.(map[string]interface{})
+		if f.Fields == nil {
+			return obj
+		}
+		out := make(map[string]interface{}, len(obj))
+		for k, ev := range obj {
+			out[k] = toJSON(f.Fields[k], ev)
+		}
+		return out
+	case Array:
+		arr := v.([]interface{})
+		if f.Elem == nil {
+			return arr
+		}
+		out := make([]interface{}, len(arr))
+		for i, ev := range arr {
+			out[i] = toJSON(*f.Elem, ev)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+func fromJSON(path string, f Field, raw json.RawMessage) (interface{}, error) {
+	switch f.Type {
+	case String:
+		var v string
+		return v, json.Unmarshal(raw, &v)
+	case Bool:
+		var v bool
+		return v, json.Unmarshal(raw, &v)
+	case UUID:
+		var v uuid.UUID
+		return v, json.Unmarshal(raw, &v)
+	case TimeStamp:
+		n, err := parseInt(raw, 64)
+		return telem.TimeStamp(n), err
+	case TimeRange:
+		var v jsonTimeRange
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return nil, err
+		}
+		return telem.TimeRange{Start: v.Start, End: v.End}, nil
+	case Int, Int8, Int16, Int32, Int64:
+		n, err := parseInt(raw, bitSize(f.Type))
+		return convertInt(f.Type, n), err
+	case Uint8, Uint16, Uint32, Uint64:
+		n, err := strconv.ParseUint(string(raw), 10, bitSize(f.Type))
+		return convertUint(f.Type, n), err
+	case Float32:
+		n, err := strconv.ParseFloat(string(raw), 32)
+		return float32(n), err
+	case Float64:
+		return strconv.ParseFloat(string(raw), 64)
+	case Object:
+		if f.Fields == nil {
+			var v map[string]interface{}
+			return v, json.Unmarshal(raw, &v)
+		}
+		e := NewEntity(&Schema{Fields: f.Fields})
+		if err := e.UnmarshalJSON(raw); err != nil {
+			return nil, prefixErrors(path+".", err)
+		}
+		return e.data, nil
+	case Array:
+		if f.Elem == nil {
+			var v []interface{}
+			return v, json.Unmarshal(raw, &v)
+		}
+		var elems []json.RawMessage
+		if err := json.Unmarshal(raw, &elems); err != nil {
+			return nil, err
+		}
+		out := make([]interface{}, len(elems))
+		for i, elem := range elems {
+			elemPath := path + "[" + strconv.Itoa(i) + "]"
+			v, err := fromJSON(elemPath, *f.Elem, elem)
+			if err != nil {
+				return nil, asValidationErrors(elemPath, err)
+			}
+			out[i] = v
+		}
+		return out, nil
+	default:
+		return nil, errors.Newf("[schema] - unknown field type %d", f.Type)
+	}
+}
+
+func parseInt(raw json.RawMessage, bitSize int) (int64, error) {
+	return strconv.ParseInt(string(bytes.TrimSpace(raw)), 10, bitSize)
+}
+
+func bitSize(t FieldType) int {
+	switch t {
+	case Int8, Uint8:
+		return 8
+	case Int16, Uint16:
+		return 16
+	case Int32, Uint32:
+		return 32
+	default:
+		return 64
+	}
+}
+
+func convertInt(t FieldType, n int64) interface{} {
+	switch t {
+	case Int8:
+		return int8(n)
+	case Int16:
+		return int16(n)
+	case Int32:
+		return int32(n)
+	case Int64:
+		return n
+	default:
+		return int(n)
+	}
+}
+
+func convertUint(t FieldType, n uint64) interface{} {
+	switch t {
+	case Uint8:
+		return uint8(n)
+	case Uint16:
+		return uint16(n)
+	case Uint32:
+		return uint32(n)
+	default:
+		return n
+	}
+}
+
+// asValidationErrors returns err if it's a ValidationErrors, and otherwise wraps it
+// in a ValidationErrors for the field at path.
+func asValidationErrors(path string, err error) ValidationErrors {
+	var errs ValidationErrors
+	if errors.As(err, &errs) {
+		return errs
+	}
+	return ValidationErrors{{Field: path, Message: err.Error()}}
+}
+
+// prefixErrors prefixes the field paths of nested validation errors.
+func prefixErrors(prefix string, err error) error {
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		return err
+	}
+	out := make(ValidationErrors, len(errs))
+	for i, e := range errs {
+		out[i] = ValidationError{Field: prefix + e.Field, Message: e.Message}
+	}
+	return out
+}
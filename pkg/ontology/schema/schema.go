@@ -3,6 +3,6 @@ package schema
 type Type string
 
 type Schema struct {
-	Type   Type
-	Fields map[string]Field
+	Type   Type             `json:"type"`
+	Fields map[string]Field `json:"fields"`
 }
@@ -0,0 +1,68 @@
+package schema
+
+import (
+	"sort"
+	"strings"
+)
+
+// ValidationError describes why a single field of an Entity is invalid.
+type ValidationError struct {
+	// Field is the path to the invalid field. Entries of Object fields are separated
+	// by '.', and elements of Array fields are indexed with '[i]'.
+	Field   string
+	Message string
+}
+
+// Error implements the error interface.
+func (v ValidationError) Error() string {
+	return "[schema] - field " + v.Field + ": " + v.Message
+}
+
+// ValidationErrors are the errors returned when validating an Entity or value against
+// a Schema.
+type ValidationErrors []ValidationError
+
+// Error implements the error interface.
+func (v ValidationErrors) Error() string {
+	msgs := make([]string, len(v))
+	for i, e := range v {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// validateFields validates the values against the field definitions, returning errors
+// for missing required fields, undefined fields, and invalid values. Missing required
+// fields are allowed when partial is true. Fields are validated in sorted order so
+// that errors are deterministic.
+func validateFields(
+	prefix string,
+	fields map[string]Field,
+	values map[string]interface{},
+	partial bool,
+) ValidationErrors {
+	var errs ValidationErrors
+	for _, k := range sortedKeys(fields) {
+		if _, ok := values[k]; !ok && fields[k].Required && !partial {
+			errs = append(errs, ValidationError{Field: prefix + k, Message: "field is required"})
+		}
+	}
+	for _, k := range sortedKeys(values) {
+		f, ok := fields[k]
+		if !ok {
+			errs = append(errs, ValidationError{Field: prefix + k, Message: "field not defined in schema"})
+			continue
+		}
+		errs = append(errs, f.Validate(prefix+k, values[k])...)
+	}
+	return errs
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,133 @@
+package ontology
+
+import (
+	"fmt"
+	"github.com/arya-analytics/delta/pkg/ontology/search"
+	"github.com/arya-analytics/x/gorp"
+	"sort"
+	"sync"
+)
+
+// Search returns the resources whose entities match the query, ordered by descending
+// relevance. See search.Parse for the query syntax.
+func (o *Ontology) Search(q search.Query) ([]Resource, error) {
+	if err := o.search.refresh(o.db); err != nil {
+		return nil, err
+	}
+	results := o.search.Search(q)
+	if len(results) == 0 {
+		return nil, nil
+	}
+	rank := make(map[ID]int, len(results))
+	for i, r := range results {
+		rank[o.search.id(r.ID)] = i
+	}
+	var resources []Resource
+	if err := o.NewRetrieve().Where(func(r *Resource) bool {
+		_, ok := rank[r.ID]
+		return ok
+	}).Entries(&resources).Exec(); err != nil {
+		return nil, err
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		return rank[resources[i].ID] < rank[resources[j].ID]
+	})
+	return resources, nil
+}
+
+// searchIndex keeps a search.Index of the entities of every resource in the ontology.
+// Because a resource is often defined before its Service stores the entity, resources
+// are marked stale when they're defined or updated, and indexed before the next
+// search.
+type searchIndex struct {
+	*search.Index
+	services services
+	mu       sync.Mutex
+	ids      map[string]ID
+	stale    map[ID]struct{}
+}
+
+func newSearchIndex(services services) *searchIndex {
+	return &searchIndex{
+		Index:    search.New(),
+		services: services,
+		ids:      make(map[string]ID),
+		stale:    make(map[ID]struct{}),
+	}
+}
+
+// define marks the resource with the given ID as needing to be indexed.
+func (s *searchIndex) define(id ID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stale[id] = struct{}{}
+}
+
+// delete removes the resource with the given ID from the index.
+func (s *searchIndex) delete(id ID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.stale, id)
+	delete(s.ids, id.String())
+	s.Index.Delete(id.String())
+}
+
+// load marks every resource persisted in the database as needing to be indexed.
+func (s *searchIndex) load(db *gorp.DB) error {
+	var resources []Resource
+	if err := gorp.NewRetrieve[ID, Resource]().Entries(&resources).Exec(db); err != nil {
+		return err
+	}
+	for _, r := range resources {
+		s.define(r.ID)
+	}
+	return nil
+}
+
+// refresh indexes the entities of all stale resources. Resources that no longer exist
+// are removed from the index. Resources whose Service hasn't been registered, or
+// whose entity can't be retrieved yet, remain stale.
+func (s *searchIndex) refresh(db *gorp.DB) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id := range s.stale {
+		if _, ok := s.services[id.Type]; !ok {
+			continue
+		}
+		exists, err := gorp.NewRetrieve[ID, Resource]().WhereKeys(id).Exists(db)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			// Resources are only marked stale once they've been committed, so the
+			// resource was deleted by a transaction that didn't call DeleteResource.
+			delete(s.stale, id)
+			delete(s.ids, id.String())
+			s.Index.Delete(id.String())
+			continue
+		}
+		e, err := s.services.RetrieveEntity(id)
+		if err != nil {
+			continue
+		}
+		s.Index.Index(id.String(), document(e))
+		s.ids[id.String()] = id
+		delete(s.stale, id)
+	}
+	return nil
+}
+
+func (s *searchIndex) id(key string) ID {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ids[key]
+}
+
+func document(e Entity) search.Document {
+	data := e.Data()
+	doc := make(search.Document, len(data))
+	for k, v := range data {
+		doc[k] = fmt.Sprint(v)
+	}
+	return doc
+}
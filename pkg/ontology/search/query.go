@@ -0,0 +1,117 @@
+package search
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Term is a single term in a Query.
+type Term struct {
+	// Value is the lowercase value to match.
+	Value string
+	// Field restricts matches to a single field when non-empty.
+	Field string
+	// Prefix matches any indexed term starting with Value.
+	Prefix bool
+	// Fuzzy matches any indexed term within the given edit distance of Value.
+	Fuzzy int
+}
+
+// Query is a parsed search query. A document matches the Query if it matches every
+// Term.
+type Query struct {
+	Terms []Term
+	// Limit is the maximum number of results to return. Zero means no limit.
+	Limit int
+}
+
+// Parse parses a query string. Terms are separated by whitespace and use the
+// following syntax:
+//
+//	foo       matches the term 'foo' in any field.
+//	name:foo  matches the term 'foo' in the 'name' field.
+//	foo*      matches any term starting with 'foo'.
+//	foo~      matches any term within an edit distance of 1 of 'foo'.
+//	foo~2     matches any term within an edit distance of 2 of 'foo'.
+//
+// Terms that contain punctuation are split into multiple terms, all of which must
+// match.
+func Parse(s string) Query {
+	var q Query
+	for _, raw := range strings.Fields(s) {
+		var base Term
+		if i := strings.Index(raw, ":"); i > 0 {
+			base.Field = strings.ToLower(raw[:i])
+			raw = raw[i+1:]
+		}
+		if strings.HasSuffix(raw, "*") {
+			base.Prefix = true
+			raw = strings.TrimSuffix(raw, "*")
+		} else if i := strings.LastIndex(raw, "~"); i >= 0 {
+			base.Fuzzy = 1
+			if n, err := strconv.Atoi(raw[i+1:]); err == nil && n > 0 {
+				base.Fuzzy = n
+			}
+			raw = raw[:i]
+		}
+		tokens := Tokenize(raw)
+		for i, tok := range tokens {
+			t := base
+			t.Value = tok
+			// Only the last token of a prefix term is a prefix.
+			t.Prefix = base.Prefix && i == len(tokens)-1
+			q.Terms = append(q.Terms, t)
+		}
+	}
+	return q
+}
+
+// distance returns the Levenshtein distance between a and b. Returns max+1 as soon
+// as the distance is known to exceed max.
+func distance(a, b string, max int) int {
+	ra, rb := []rune(a), []rune(b)
+	if abs(len(ra)-len(rb)) > max {
+		return max + 1
+	}
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if curr[j] < rowMin {
+				rowMin = curr[j]
+			}
+		}
+		if rowMin > max {
+			return max + 1
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+func min(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,182 @@
+// Package search implements an in-memory inverted index for full-text search over
+// documents made up of named string fields. Queries support exact, prefix, fuzzy, and
+// field-scoped terms, and results are ranked by relevance.
+package search
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Document is a set of named string fields to index.
+type Document map[string]string
+
+// Result is a single search result.
+type Result struct {
+	// ID is the ID of the matching document.
+	ID string
+	// Score is the relevance of the document to the query. Higher is better.
+	Score float64
+}
+
+// Index is an in-memory inverted index. Index is safe for concurrent use.
+type Index struct {
+	mu sync.RWMutex
+	// postings maps a term to the documents containing it, and the fields of each
+	// document the term appears in.
+	postings map[string]map[string]map[string]struct{}
+	// docs maps a document ID to the terms it contains.
+	docs map[string][]string
+}
+
+// New creates a new, empty Index.
+func New() *Index {
+	return &Index{
+		postings: make(map[string]map[string]map[string]struct{}),
+		docs:     make(map[string][]string),
+	}
+}
+
+// Index adds the document to the index, replacing any existing document with the
+// same ID.
+func (idx *Index) Index(id string, doc Document) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.deleteLocked(id)
+	var terms []string
+	for field, value := range doc {
+		field = strings.ToLower(field)
+		for _, term := range Tokenize(value) {
+			docs, ok := idx.postings[term]
+			if !ok {
+				docs = make(map[string]map[string]struct{})
+				idx.postings[term] = docs
+			}
+			fields, ok := docs[id]
+			if !ok {
+				fields = make(map[string]struct{})
+				docs[id] = fields
+				terms = append(terms, term)
+			}
+			fields[field] = struct{}{}
+		}
+	}
+	idx.docs[id] = terms
+}
+
+// Delete removes the document with the given ID from the index. Delete does nothing
+// if the document isn't indexed.
+func (idx *Index) Delete(id string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.deleteLocked(id)
+}
+
+func (idx *Index) deleteLocked(id string) {
+	for _, term := range idx.docs[id] {
+		delete(idx.postings[term], id)
+		if len(idx.postings[term]) == 0 {
+			delete(idx.postings, term)
+		}
+	}
+	delete(idx.docs, id)
+}
+
+// Len returns the number of documents in the index.
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.docs)
+}
+
+const (
+	exactWeight  = 1.0
+	prefixWeight = 0.75
+	fuzzyWeight  = 0.5
+	// rarityWeight is the maximum bonus for matching a rare term. It's kept small
+	// enough that exact matches always rank above prefix matches, and prefix matches
+	// above fuzzy matches.
+	rarityWeight = 0.2
+)
+
+// Search returns the documents matching every term in the query, ordered by
+// descending score. Ties are broken by ID.
+func (idx *Index) Search(q Query) []Result {
+	if len(q.Terms) == 0 {
+		return nil
+	}
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	var scores map[string]float64
+	for _, t := range q.Terms {
+		termScores := idx.match(t)
+		if scores == nil {
+			scores = termScores
+			continue
+		}
+		for id, s := range scores {
+			ts, ok := termScores[id]
+			if !ok {
+				delete(scores, id)
+				continue
+			}
+			scores[id] = s + ts
+		}
+	}
+	results := make([]Result, 0, len(scores))
+	for id, s := range scores {
+		results = append(results, Result{ID: id, Score: s})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].ID < results[j].ID
+	})
+	if q.Limit > 0 && len(results) > q.Limit {
+		results = results[:q.Limit]
+	}
+	return results
+}
+
+// match returns the best score of every document matching the term.
+func (idx *Index) match(t Term) map[string]float64 {
+	scores := make(map[string]float64)
+	apply := func(term string, weight float64) {
+		docs := idx.postings[term]
+		w := weight + rarityWeight/float64(len(docs))
+		for id, fields := range docs {
+			if t.Field != "" {
+				if _, ok := fields[t.Field]; !ok {
+					continue
+				}
+			}
+			if w > scores[id] {
+				scores[id] = w
+			}
+		}
+	}
+	for term := range idx.postings {
+		switch {
+		case term == t.Value:
+			apply(term, exactWeight)
+		case t.Prefix && strings.HasPrefix(term, t.Value):
+			apply(term, prefixWeight)
+		case t.Fuzzy > 0:
+			if d := distance(term, t.Value, t.Fuzzy); d <= t.Fuzzy {
+				apply(term, fuzzyWeight/float64(d))
+			}
+		}
+	}
+	return scores
+}
+
+// Tokenize splits s into lowercase terms on any character that isn't a letter or
+// number.
+func Tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
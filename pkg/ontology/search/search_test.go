@@ -0,0 +1,94 @@
+package search_test
+
+import (
+	"github.com/arya-analytics/delta/pkg/ontology/search"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func ids(results []search.Result) []string {
+	out := make([]string, len(results))
+	for i, r := range results {
+		out[i] = r.ID
+	}
+	return out
+}
+
+var _ = Describe("Search", func() {
+	var idx *search.Index
+	BeforeEach(func() {
+		idx = search.New()
+		idx.Index("1", search.Document{"name": "Engine Temperature", "unit": "celsius"})
+		idx.Index("2", search.Document{"name": "Engine Pressure", "unit": "psi"})
+		idx.Index("3", search.Document{"name": "cabin_temp", "owner": "engine team"})
+	})
+	Describe("Parse", func() {
+		It("Should parse field-scoped, prefix, and fuzzy terms", func() {
+			q := search.Parse("name:Eng* pressur~ temp~2")
+			Expect(q.Terms).To(Equal([]search.Term{
+				{Value: "eng", Field: "name", Prefix: true},
+				{Value: "pressur", Fuzzy: 1},
+				{Value: "temp", Fuzzy: 2},
+			}))
+		})
+		It("Should split terms on punctuation", func() {
+			q := search.Parse("cabin_temp")
+			Expect(q.Terms).To(HaveLen(2))
+		})
+	})
+	Describe("Exact", func() {
+		It("Should return every document containing all terms", func() {
+			Expect(ids(idx.Search(search.Parse("engine")))).To(ConsistOf("1", "2", "3"))
+			Expect(ids(idx.Search(search.Parse("engine pressure")))).To(Equal([]string{"2"}))
+		})
+		It("Should be case insensitive", func() {
+			Expect(ids(idx.Search(search.Parse("CELSIUS")))).To(Equal([]string{"1"}))
+		})
+	})
+	Describe("Prefix", func() {
+		It("Should match terms starting with the prefix", func() {
+			Expect(ids(idx.Search(search.Parse("temp*")))).To(ConsistOf("1", "3"))
+		})
+		It("Should rank exact matches above prefix matches", func() {
+			idx.Index("4", search.Document{"name": "temp"})
+			Expect(ids(idx.Search(search.Parse("temp*")))).To(Equal([]string{"3", "4", "1"}))
+		})
+	})
+	Describe("Fuzzy", func() {
+		It("Should match terms within the edit distance", func() {
+			Expect(ids(idx.Search(search.Parse("presure~")))).To(Equal([]string{"2"}))
+			Expect(idx.Search(search.Parse("presur~"))).To(BeEmpty())
+			Expect(ids(idx.Search(search.Parse("presur~2")))).To(Equal([]string{"2"}))
+		})
+	})
+	Describe("Field", func() {
+		It("Should only match terms in the given field", func() {
+			Expect(ids(idx.Search(search.Parse("name:engine")))).To(ConsistOf("1", "2"))
+			Expect(ids(idx.Search(search.Parse("owner:engine")))).To(Equal([]string{"3"}))
+		})
+	})
+	Describe("Ranking", func() {
+		It("Should rank documents matching rarer terms higher", func() {
+			res := idx.Search(search.Parse("engine* temp*"))
+			Expect(ids(res)).To(ConsistOf("1", "3"))
+			Expect(res[0].Score).To(BeNumerically(">=", res[1].Score))
+		})
+		It("Should limit the number of results", func() {
+			q := search.Parse("engine")
+			q.Limit = 2
+			Expect(idx.Search(q)).To(HaveLen(2))
+		})
+	})
+	Describe("Delete", func() {
+		It("Should remove the document from the index", func() {
+			idx.Delete("2")
+			Expect(idx.Len()).To(Equal(2))
+			Expect(idx.Search(search.Parse("pressure"))).To(BeEmpty())
+		})
+		It("Should replace a re-indexed document", func() {
+			idx.Index("2", search.Document{"name": "Fuel Flow"})
+			Expect(idx.Search(search.Parse("pressure"))).To(BeEmpty())
+			Expect(ids(idx.Search(search.Parse("fuel")))).To(Equal([]string{"2"}))
+		})
+	})
+})
@@ -0,0 +1,101 @@
+package ontology_test
+
+import (
+	"github.com/arya-analytics/delta/pkg/ontology"
+	"github.com/arya-analytics/delta/pkg/ontology/schema"
+	"github.com/arya-analytics/delta/pkg/ontology/search"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Search", func() {
+	var (
+		w         ontology.Writer
+		idA, idB  ontology.ID
+		newEntity = func(key, name string) ontology.Entity {
+			e := schema.NewEntity(mutableSchema)
+			schema.Set(e, "key", key)
+			schema.Set(e, "name", name)
+			return e
+		}
+		resourceIDs = func(resources []ontology.Resource) []ontology.ID {
+			ids := make([]ontology.ID, len(resources))
+			for i, r := range resources {
+				ids[i] = r.ID
+			}
+			return ids
+		}
+	)
+	BeforeEach(func() {
+		w = otg.NewWriter(txn)
+		var err error
+		idA, err = w.CreateEntity(newEntity("search-a", "Engine Temperature"))
+		Expect(err).ToNot(HaveOccurred())
+		idB, err = w.CreateEntity(newEntity("search-b", "Engine Pressure"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(txn.Commit()).To(Succeed())
+	})
+	AfterEach(func() {
+		delTxn := otg.BeginTxn()
+		dw := otg.NewWriter(delTxn)
+		Expect(dw.DeleteResource(idA)).To(Succeed())
+		Expect(dw.DeleteResource(idB)).To(Succeed())
+		Expect(delTxn.Commit()).To(Succeed())
+		Expect(delTxn.Close()).To(Succeed())
+	})
+	It("Should return resources matching the query", func() {
+		res, err := otg.Search(search.Parse("engine"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resourceIDs(res)).To(ConsistOf(idA, idB))
+		name, _ := schema.Get[string](res[0].Entity(), "name")
+		Expect(name).To(HavePrefix("Engine"))
+	})
+	It("Should support prefix, fuzzy, and field-scoped queries", func() {
+		res, err := otg.Search(search.Parse("name:temp*"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resourceIDs(res)).To(Equal([]ontology.ID{idA}))
+		res, err = otg.Search(search.Parse("presure~"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resourceIDs(res)).To(Equal([]ontology.ID{idB}))
+		res, err = otg.Search(search.Parse("key:pressure"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res).To(BeEmpty())
+	})
+	It("Should reflect updated entities", func() {
+		updTxn := otg.BeginTxn()
+		Expect(otg.NewWriter(updTxn).UpdateEntity(idB, newEntity("search-b", "Fuel Flow"))).To(Succeed())
+		Expect(updTxn.Commit()).To(Succeed())
+		Expect(updTxn.Close()).To(Succeed())
+		res, err := otg.Search(search.Parse("fuel"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resourceIDs(res)).To(Equal([]ontology.ID{idB}))
+	})
+	It("Should not return deleted resources", func() {
+		delTxn := otg.BeginTxn()
+		Expect(otg.NewWriter(delTxn).DeleteResource(idA)).To(Succeed())
+		Expect(delTxn.Commit()).To(Succeed())
+		Expect(delTxn.Close()).To(Succeed())
+		res, err := otg.Search(search.Parse("engine"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resourceIDs(res)).To(Equal([]ontology.ID{idB}))
+	})
+	Context("Aborted Transactions", func() {
+		It("Should not index resources created by an aborted transaction", func() {
+			abortTxn := otg.BeginTxn()
+			_, err := otg.NewWriter(abortTxn).CreateEntity(newEntity("search-c", "Engine Speed"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(abortTxn.Close()).To(Succeed())
+			res, err := otg.Search(search.Parse("speed"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res).To(BeEmpty())
+		})
+		It("Should keep resources deleted by an aborted transaction", func() {
+			abortTxn := otg.BeginTxn()
+			Expect(otg.NewWriter(abortTxn).DeleteResource(idA)).To(Succeed())
+			Expect(abortTxn.Close()).To(Succeed())
+			res, err := otg.Search(search.Parse("engine"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resourceIDs(res)).To(ConsistOf(idA, idB))
+		})
+	})
+})
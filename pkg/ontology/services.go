@@ -1,10 +1,46 @@
 package ontology
 
+import (
+	"github.com/arya-analytics/x/gorp"
+	"github.com/cockroachdb/errors"
+)
+
 type Service interface {
 	Schema() *Schema
 	RetrieveEntity(key string) (Entity, error)
 }
 
+// MutableService is a Service whose resources can be created and updated through
+// the ontology. The ontology validates entities against the Service's Schema before
+// calling CreateEntity or UpdateEntity.
+type MutableService interface {
+	Service
+	// CreateEntity creates a new resource from the fields set on the entity, and
+	// returns the key of the new resource.
+	CreateEntity(txn gorp.Txn, e Entity) (key string, err error)
+	// UpdateEntity updates the resource with the given key, replacing the values of
+	// the fields set on the entity.
+	UpdateEntity(txn gorp.Txn, key string, e Entity) error
+}
+
+// BatchService is a Service that can retrieve the entities of many resources at once.
+// The ontology uses RetrieveEntities instead of RetrieveEntity when it retrieves more
+// than one resource of the Service's Type.
+type BatchService interface {
+	Service
+	// RetrieveEntities retrieves the entities of the resources with the given keys, in
+	// the same order as the keys.
+	RetrieveEntities(keys []string) ([]Entity, error)
+}
+
+// ReadOnly is returned when writing an entity whose Service doesn't implement
+// MutableService.
+var ReadOnly = errors.New("[ontology] - resource type is read only")
+
+// UnknownType is returned when retrieving or writing a resource whose type doesn't
+// have a registered Service.
+var UnknownType = errors.New("[ontology] - unknown resource type")
+
 type services map[Type]Service
 
 func (s services) Register(svc Service) {
@@ -16,9 +52,83 @@ func (s services) Register(svc Service) {
 }
 
 func (s services) RetrieveEntity(key ID) (Entity, error) {
-	svc, ok := s[key.Type]
-	if !ok {
-		panic("[ontology] - service not found")
+	svc, err := s.get(key.Type)
+	if err != nil {
+		return Entity{}, err
 	}
 	return svc.RetrieveEntity(key.Key)
 }
+
+// RetrieveEntities retrieves the entities of the resources with the given IDs, in the
+// same order. Entities of each Type are retrieved in a single batch if the Type's
+// Service implements BatchService.
+func (s services) RetrieveEntities(ids []ID) ([]Entity, error) {
+	var (
+		entities = make([]Entity, len(ids))
+		keys     = make(map[Type][]string)
+		indexes  = make(map[Type][]int)
+		types    []Type
+	)
+	for i, id := range ids {
+		if _, ok := keys[id.Type]; !ok {
+			types = append(types, id.Type)
+		}
+		keys[id.Type] = append(keys[id.Type], id.Key)
+		indexes[id.Type] = append(indexes[id.Type], i)
+	}
+	for _, t := range types {
+		svc, err := s.get(t)
+		if err != nil {
+			return nil, err
+		}
+		batch, err := retrieveBatch(svc, keys[t])
+		if err != nil {
+			return nil, err
+		}
+		for i, e := range batch {
+			entities[indexes[t][i]] = e
+		}
+	}
+	return entities, nil
+}
+
+func retrieveBatch(svc Service, keys []string) ([]Entity, error) {
+	if bSvc, ok := svc.(BatchService); ok && len(keys) > 1 {
+		entities, err := bSvc.RetrieveEntities(keys)
+		if err == nil && len(entities) != len(keys) {
+			err = errors.Newf(
+				"[ontology] - service for type %s returned %d entities for %d keys",
+				svc.Schema().Type, len(entities), len(keys),
+			)
+		}
+		return entities, err
+	}
+	entities := make([]Entity, len(keys))
+	for i, key := range keys {
+		var err error
+		if entities[i], err = svc.RetrieveEntity(key); err != nil {
+			return nil, err
+		}
+	}
+	return entities, nil
+}
+
+func (s services) get(t Type) (Service, error) {
+	svc, ok := s[t]
+	if !ok {
+		return nil, errors.Wrapf(UnknownType, "[ontology] - no service registered for type %s", t)
+	}
+	return svc, nil
+}
+
+func (s services) mutable(t Type) (MutableService, error) {
+	svc, err := s.get(t)
+	if err != nil {
+		return nil, err
+	}
+	mSvc, ok := svc.(MutableService)
+	if !ok {
+		return nil, errors.Wrapf(ReadOnly, "[ontology] - type %s", t)
+	}
+	return mSvc, nil
+}
@@ -0,0 +1,205 @@
+package ontology
+
+import (
+	"context"
+	"github.com/arya-analytics/x/gorp"
+)
+
+// WatchFilter selects the changes delivered to a Watch subscriber. The zero value
+// matches every change.
+type WatchFilter struct {
+	// Types restricts changes to those affecting at least one resource of the given
+	// types.
+	Types []Type
+	// Subtree restricts changes to those affecting the resource with the given ID or
+	// one of its descendants.
+	Subtree ID
+}
+
+// Subscription streams the changes matched by a Watch.
+type Subscription struct {
+	// Changes receives matching changes in order. Changes is closed when the context
+	// passed to Watch is cancelled, or when the subscriber falls too far behind.
+	Changes <-chan Change
+	sub     *subscriber
+}
+
+// Err returns SubscriberTooSlow if the Subscription was dropped for falling too far
+// behind, and nil otherwise. Err should be checked once Changes is closed.
+func (s Subscription) Err() error { return s.sub.error() }
+
+// Watch streams changes published after Watch is called that match the filter. If the
+// Ontology was opened WithRelay, its graph changes are published by the Ontology that
+// applies them, and only published by this one once they're passed to Observe.
+func (o *Ontology) Watch(ctx context.Context, f WatchFilter) (Subscription, error) {
+	w := &watcher{db: o.db, filter: f}
+	// Subscribe before loading the subtree so that no changes are missed in between.
+	sub := o.feed.subscribe(ctx)
+	if w.filter.Subtree != (ID{}) {
+		if err := w.loadSubtree(); err != nil {
+			return Subscription{}, err
+		}
+	}
+	go w.deliver(ctx, sub)
+	return Subscription{Changes: sub.out, sub: sub}, nil
+}
+
+type watcher struct {
+	db     *gorp.DB
+	filter WatchFilter
+	// subtree holds the IDs of the descendants of filter.Subtree.
+	subtree map[ID]struct{}
+	// departed holds the IDs of resources that left the subtree in the current batch
+	// of changes, so that a following ResourceDeleted change still matches.
+	departed map[ID]struct{}
+}
+
+func (w *watcher) deliver(ctx context.Context, sub *subscriber) {
+	defer close(sub.out)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sub.notify:
+		}
+		changes, err := sub.dequeue()
+		if err != nil {
+			return
+		}
+		w.departed = make(map[ID]struct{})
+		for _, c := range changes {
+			ok, err := w.match(c)
+			if err != nil || !ok {
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case sub.out <- c:
+			}
+		}
+	}
+}
+
+func (w *watcher) match(c Change) (bool, error) {
+	if w.filter.Subtree != (ID{}) {
+		ok, err := w.matchSubtree(c)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return w.matchTypes(c), nil
+}
+
+func (w *watcher) matchTypes(c Change) bool {
+	if len(w.filter.Types) == 0 {
+		return true
+	}
+	for _, id := range c.IDs() {
+		for _, t := range w.filter.Types {
+			if id.Type == t {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchSubtree returns true if the change affects the subtree, updating the subtree
+// as resources join and leave it. Changes are matched after the transaction that made
+// them commits, so the database reflects the outcome of the entire transaction.
+func (w *watcher) matchSubtree(c Change) (bool, error) {
+	switch c.Variant {
+	case ResourceDefined:
+		return w.inSubtree(c.ID), nil
+	case ResourceDeleted:
+		_, departed := w.departed[c.ID]
+		ok := departed || w.inSubtree(c.ID)
+		delete(w.subtree, c.ID)
+		return ok, nil
+	}
+	rel := c.Relationship
+	fromIn, toIn := w.inSubtree(rel.From), w.inSubtree(rel.To)
+	if rel.Type != Parent {
+		return fromIn || toIn, nil
+	}
+	if c.Variant == RelationshipDefined && toIn && !fromIn {
+		return true, w.join(rel.From)
+	}
+	if c.Variant == RelationshipDeleted && toIn && fromIn {
+		return true, w.reloadSubtree()
+	}
+	return toIn, nil
+}
+
+func (w *watcher) inSubtree(id ID) bool {
+	if id == w.filter.Subtree {
+		return true
+	}
+	_, ok := w.subtree[id]
+	return ok
+}
+
+// join adds the resource with the given ID and its descendants to the subtree.
+func (w *watcher) join(id ID) error {
+	descendants, err := descendantIDs(w.db, id)
+	if err != nil {
+		return err
+	}
+	w.subtree[id] = struct{}{}
+	for _, d := range descendants {
+		w.subtree[d] = struct{}{}
+	}
+	return nil
+}
+
+// reloadSubtree reloads the subtree from the database, marking any resources that
+// are no longer in it as departed.
+func (w *watcher) reloadSubtree() error {
+	prev := w.subtree
+	if err := w.loadSubtree(); err != nil {
+		return err
+	}
+	for id := range prev {
+		if _, ok := w.subtree[id]; !ok {
+			w.departed[id] = struct{}{}
+		}
+	}
+	return nil
+}
+
+func (w *watcher) loadSubtree() error {
+	descendants, err := descendantIDs(w.db, w.filter.Subtree)
+	if err != nil {
+		return err
+	}
+	w.subtree = make(map[ID]struct{}, len(descendants))
+	for _, id := range descendants {
+		w.subtree[id] = struct{}{}
+	}
+	return nil
+}
+
+// descendantIDs returns the IDs of all descendants of the resource with the given ID.
+func descendantIDs(txn gorp.Txn, id ID) ([]ID, error) {
+	var (
+		ids      []ID
+		visited  = map[ID]struct{}{id: {}}
+		frontier = []ID{id}
+	)
+	for len(frontier) > 0 {
+		children, err := retrieveAdjacent(txn, frontier[0], Backward, Parent)
+		if err != nil {
+			return nil, err
+		}
+		frontier = frontier[1:]
+		for _, c := range children {
+			if _, ok := visited[c]; !ok {
+				visited[c] = struct{}{}
+				ids = append(ids, c)
+				frontier = append(frontier, c)
+			}
+		}
+	}
+	return ids, nil
+}
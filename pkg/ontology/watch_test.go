@@ -0,0 +1,144 @@
+package ontology_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/arya-analytics/delta/pkg/ontology"
+	"github.com/arya-analytics/x/gorp"
+	"github.com/arya-analytics/x/kv/memkv"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Watch", func() {
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+		root   ontology.ID
+		write  = func(f func(w ontology.Writer)) {
+			t := otg.BeginTxn()
+			f(otg.NewWriter(t))
+			Expect(t.Commit()).To(Succeed())
+			Expect(t.Close()).To(Succeed())
+		}
+	)
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+		root = newEmptyID("watch-root")
+		write(func(w ontology.Writer) { Expect(w.DefineResource(root)).To(Succeed()) })
+	})
+	AfterEach(func() {
+		cancel()
+		write(func(w ontology.Writer) { Expect(w.DeleteResource(root)).To(Succeed()) })
+	})
+	It("Should publish changes in order once the transaction commits", func() {
+		sub, err := otg.Watch(ctx, ontology.WatchFilter{})
+		Expect(err).ToNot(HaveOccurred())
+		child := newEmptyID("watch-child")
+		t := otg.BeginTxn()
+		w := otg.NewWriter(t)
+		Expect(w.DefineResource(child)).To(Succeed())
+		Expect(w.DefineRelationship(child, root, ontology.Parent)).To(Succeed())
+		Consistently(sub.Changes).ShouldNot(Receive())
+		Expect(t.Commit()).To(Succeed())
+		Expect(t.Close()).To(Succeed())
+		var c1, c2 ontology.Change
+		Eventually(sub.Changes).Should(Receive(&c1))
+		Eventually(sub.Changes).Should(Receive(&c2))
+		Expect(c1.Variant).To(Equal(ontology.ResourceDefined))
+		Expect(c1.ID).To(Equal(child))
+		Expect(c2.Variant).To(Equal(ontology.RelationshipDefined))
+		Expect(c2.Relationship.From).To(Equal(child))
+		Expect(c2.Seq).To(BeNumerically(">", c1.Seq))
+		write(func(w ontology.Writer) { Expect(w.DeleteResource(child)).To(Succeed()) })
+		Eventually(sub.Changes).Should(Receive(&c1))
+		Expect(c1.Variant).To(Equal(ontology.RelationshipDeleted))
+		Eventually(sub.Changes).Should(Receive(&c1))
+		Expect(c1.Variant).To(Equal(ontology.ResourceDeleted))
+	})
+	It("Should not publish changes from a transaction that doesn't commit", func() {
+		sub, err := otg.Watch(ctx, ontology.WatchFilter{})
+		Expect(err).ToNot(HaveOccurred())
+		t := otg.BeginTxn()
+		Expect(otg.NewWriter(t).DefineResource(newEmptyID("watch-abort"))).To(Succeed())
+		Expect(t.Close()).To(Succeed())
+		Consistently(sub.Changes).ShouldNot(Receive())
+	})
+	It("Should reject writes on transactions that can't be published", func() {
+		t := db.BeginTxn()
+		defer func() { Expect(t.Close()).To(Succeed()) }()
+		err := otg.NewWriter(t).DefineResource(newEmptyID("watch-unsupported"))
+		Expect(err).To(MatchError(ontology.UnsupportedTxn))
+	})
+	It("Should filter changes by type", func() {
+		sub, err := otg.Watch(ctx, ontology.WatchFilter{Types: []ontology.Type{mutableType}})
+		Expect(err).ToNot(HaveOccurred())
+		other := newEmptyID("watch-other")
+		write(func(w ontology.Writer) { Expect(w.DefineResource(other)).To(Succeed()) })
+		Consistently(sub.Changes).ShouldNot(Receive())
+		write(func(w ontology.Writer) { Expect(w.DeleteResource(other)).To(Succeed()) })
+	})
+	It("Should filter changes by subtree", func() {
+		sub, err := otg.Watch(ctx, ontology.WatchFilter{Subtree: root})
+		Expect(err).ToNot(HaveOccurred())
+		child, grandchild, outside := newEmptyID("watch-child"),
+			newEmptyID("watch-grandchild"),
+			newEmptyID("watch-outside")
+		write(func(w ontology.Writer) {
+			Expect(w.DefineResource(child)).To(Succeed())
+			Expect(w.DefineResource(grandchild)).To(Succeed())
+			Expect(w.DefineResource(outside)).To(Succeed())
+			Expect(w.DefineRelationship(child, root, ontology.Parent)).To(Succeed())
+		})
+		var c ontology.Change
+		Eventually(sub.Changes).Should(Receive(&c))
+		Expect(c.Relationship.From).To(Equal(child))
+		write(func(w ontology.Writer) {
+			Expect(w.DefineRelationship(grandchild, child, ontology.Parent)).To(Succeed())
+			Expect(w.DefineRelationship(outside, ontology.Root, ontology.Parent)).To(Succeed())
+		})
+		Eventually(sub.Changes).Should(Receive(&c))
+		Expect(c.Relationship.From).To(Equal(grandchild))
+		Consistently(sub.Changes).ShouldNot(Receive())
+		write(func(w ontology.Writer) {
+			Expect(w.DeleteResource(grandchild)).To(Succeed())
+			Expect(w.DeleteResource(child)).To(Succeed())
+			Expect(w.DeleteResource(outside)).To(Succeed())
+		})
+		var variants []ontology.ChangeVariant
+		for i := 0; i < 4; i++ {
+			Eventually(sub.Changes).Should(Receive(&c))
+			variants = append(variants, c.Variant)
+		}
+		Expect(variants).To(Equal([]ontology.ChangeVariant{
+			ontology.RelationshipDeleted,
+			ontology.ResourceDeleted,
+			ontology.RelationshipDeleted,
+			ontology.ResourceDeleted,
+		}))
+		Consistently(sub.Changes).ShouldNot(Receive())
+	})
+	It("Should drop a subscriber that falls too far behind", func() {
+		slowDB := gorp.Wrap(memkv.New())
+		defer func() { Expect(slowDB.Close()).To(Succeed()) }()
+		slow, err := ontology.Open(slowDB, ontology.WithWatchBuffer(2))
+		Expect(err).ToNot(HaveOccurred())
+		slow.RegisterService(&emptyService{})
+		sub, err := slow.Watch(ctx, ontology.WatchFilter{})
+		Expect(err).ToNot(HaveOccurred())
+		for i := 0; i < 10; i++ {
+			Expect(slow.NewWriter(slowDB).DefineResource(newEmptyID(fmt.Sprintf("watch-slow-%d", i)))).
+				To(Succeed())
+		}
+		received := 0
+		Eventually(func() bool {
+			_, ok := <-sub.Changes
+			if ok {
+				received++
+			}
+			return ok
+		}).Should(BeFalse())
+		Expect(received).To(BeNumerically("<", 10))
+		Expect(sub.Err()).To(MatchError(ontology.SubscriberTooSlow))
+	})
+})
@@ -9,134 +9,260 @@ import (
 // dagWriter is a key-value backed directed acyclic graph that implements the Writer
 // interface.
 type dagWriter struct {
-	txn      gorp.Txn
-	retrieve retrieve
+	txn           gorp.Txn
+	retrieve      retrieve
+	search        *searchIndex
+	relationships relationshipTypes
+	record        func(Change)
+	relay         Relay
+	// err is returned by every write if the writer's transaction isn't supported.
+	err error
 }
 
 var CyclicDependency = errors.New("[ontology] cyclic dependency")
 
 // DefineResource implements the Writer interface.
 func (d dagWriter) DefineResource(tk ID) error {
+	if ok, err := d.maybeRelay(Change{Variant: ResourceDefined, ID: tk}); ok {
+		return err
+	}
+	return d.defineResource(tk)
+}
+
+func (d dagWriter) defineResource(tk ID) error {
 	if err := tk.Validate(); err != nil {
 		return err
 	}
-	return gorp.NewCreate[ID, Resource]().
+	exists, err := d.resourceExists(tk)
+	if err != nil || exists {
+		return err
+	}
+	if err := gorp.NewCreate[ID, Resource]().
 		Entry(&Resource{ID: tk}).
-		Exec(d.txn)
+		Exec(d.txn); err != nil {
+		return err
+	}
+	d.afterCommit(func() { d.search.define(tk) })
+	d.record(Change{Variant: ResourceDefined, ID: tk})
+	return nil
 }
 
 // DeleteResource implements the Writer interface.
 func (d dagWriter) DeleteResource(tk ID) error {
-	if err := d.deleteIncomingRelationships(tk); err != nil {
+	if ok, err := d.maybeRelay(Change{Variant: ResourceDeleted, ID: tk}); ok {
 		return err
 	}
-	if err := d.deleteOutgoingRelationships(tk); err != nil {
+	return d.deleteResource(tk)
+}
+
+func (d dagWriter) deleteResource(tk ID) error {
+	exists, err := d.resourceExists(tk)
+	if err != nil || !exists {
 		return err
 	}
-	return gorp.NewDelete[ID, Resource]().WhereKeys(tk).Exec(d.txn)
+	if err := d.deleteRelationships(tk); err != nil {
+		return err
+	}
+	if err := gorp.NewDelete[ID, Resource]().WhereKeys(tk).Exec(d.txn); err != nil {
+		return err
+	}
+	d.afterCommit(func() { d.search.delete(tk) })
+	d.invalidate(tk)
+	d.record(Change{Variant: ResourceDeleted, ID: tk})
+	return nil
 }
 
 // DefineRelationship implements the Writer interface.
 func (d dagWriter) DefineRelationship(from, to ID, t RelationshipType) error {
+	if ok, err := d.maybeRelay(Change{
+		Variant:      RelationshipDefined,
+		Relationship: Relationship{From: from, To: to, Type: t},
+	}); ok {
+		return err
+	}
+	return d.defineRelationship(from, to, t)
+}
+
+func (d dagWriter) defineRelationship(from, to ID, t RelationshipType) error {
+	def, err := d.relationships.get(t)
+	if err != nil {
+		return err
+	}
 	rel := Relationship{From: from, To: to, Type: t}
-	exists, err := d.checkRelationshipExists(rel)
+	if def.Acyclic && from == to {
+		return CyclicDependency
+	}
+	if err := rel.Validate(); err != nil {
+		return err
+	}
+	exists, err := d.checkRelationshipExists(rel, def.Acyclic)
 	if err != nil || exists {
 		return err
 	}
 	if err := d.validateResourcesExist(from, to); err != nil {
 		return err
 	}
-	descendants, err := d.retrieveDescendants(to)
-	if err != nil {
+	if err := d.checkCardinality(rel, def.Cardinality); err != nil {
 		return err
 	}
-	if _, exists := descendants[from]; exists {
-		return CyclicDependency
+	if def.Acyclic {
+		cyclic, err := reachable(d.txn, to, from, Forward, t)
+		if err != nil {
+			return err
+		}
+		if cyclic {
+			return CyclicDependency
+		}
 	}
-	return gorp.NewCreate[string, Relationship]().Entry(&rel).Exec(d.txn)
-
+	if err := gorp.NewCreate[string, Relationship]().Entry(&rel).Exec(d.txn); err != nil {
+		return err
+	}
+	if err := indexRelationship(d.txn, rel); err != nil {
+		return err
+	}
+	d.record(Change{Variant: RelationshipDefined, Relationship: rel})
+	return nil
 }
 
 // DeleteRelationship implements the Writer interface.
 func (d dagWriter) DeleteRelationship(from, to ID, t RelationshipType) error {
-	return gorp.NewDelete[string, Relationship]().
-		WhereKeys(Relationship{From: from, To: to, Type: t}.GorpKey()).
-		Exec(d.txn)
+	if ok, err := d.maybeRelay(Change{
+		Variant:      RelationshipDeleted,
+		Relationship: Relationship{From: from, To: to, Type: t},
+	}); ok {
+		return err
+	}
+	return d.deleteRelationship(from, to, t)
 }
 
-// NewRetrieve implements the Writer interface.
-func (d dagWriter) NewRetrieve() Retrieve { return newRetrieve(d.txn, d.retrieve.exec) }
+func (d dagWriter) deleteRelationship(from, to ID, t RelationshipType) error {
+	rel := Relationship{From: from, To: to, Type: t}
+	exists, err := gorp.NewRetrieve[string, Relationship]().
+		WhereKeys(rel.GorpKey()).
+		Exists(d.txn)
+	if err != nil || !exists {
+		return err
+	}
+	if err := gorp.NewDelete[string, Relationship]().
+		WhereKeys(rel.GorpKey()).
+		Exec(d.txn); err != nil {
+		return err
+	}
+	if err := unindexRelationship(d.txn, rel); err != nil {
+		return err
+	}
+	d.record(Change{Variant: RelationshipDeleted, Relationship: rel})
+	return nil
+}
 
-func (d dagWriter) retrieveOutgoingRelationships(key ID) ([]Resource, error) {
-	relationships, err := d.retrieveRelationships(func(rel *Relationship) bool {
-		return rel.From == key
-	})
+// CreateEntity implements the Writer interface.
+func (d dagWriter) CreateEntity(e Entity) (ID, error) {
+	if d.err != nil {
+		return ID{}, d.err
+	}
+	if e.Schema() == nil {
+		return ID{}, errors.New("[ontology] - entity has no schema")
+	}
+	svc, err := d.retrieve.services.mutable(e.Schema().Type)
 	if err != nil {
-		return nil, err
+		return ID{}, err
+	}
+	if err := e.Validate(svc.Schema()); err != nil {
+		return ID{}, err
 	}
-	var keys []ID
-	for _, rel := range relationships {
-		keys = append(keys, rel.To)
+	key, err := svc.CreateEntity(d.txn, e)
+	if err != nil {
+		return ID{}, err
 	}
-	return d.retrieveResources(keys)
+	id := ID{Key: key, Type: svc.Schema().Type}
+	return id, d.DefineResource(id)
 }
 
-func (d dagWriter) retrieveRelationships(matcher func(*Relationship) bool) ([]Relationship, error) {
-	var relationships []Relationship
-	return relationships, gorp.NewRetrieve[string, Relationship]().
-		Where(matcher).
-		Entries(&relationships).
-		Exec(d.txn)
+// UpdateEntity implements the Writer interface.
+func (d dagWriter) UpdateEntity(id ID, e Entity) error {
+	if d.err != nil {
+		return d.err
+	}
+	svc, err := d.retrieve.services.mutable(id.Type)
+	if err != nil {
+		return err
+	}
+	if err := e.ValidatePartial(svc.Schema()); err != nil {
+		return err
+	}
+	if err := d.validateResourcesExist(id); err != nil {
+		return err
+	}
+	if err := svc.UpdateEntity(d.txn, id.Key, e); err != nil {
+		return err
+	}
+	d.invalidate(id)
+	d.afterCommit(func() { d.search.define(id) })
+	return nil
 }
 
-func (d dagWriter) retrieveResources(keys []ID) ([]Resource, error) {
-	var resources []Resource
-	return resources, gorp.NewRetrieve[ID, Resource]().
-		WhereKeys(keys...).
-		Entries(&resources).
-		Exec(d.txn)
+// InvalidateEntities implements the Writer interface.
+func (d dagWriter) InvalidateEntities(ids ...ID) {
+	for _, id := range ids {
+		d.invalidate(id)
+	}
 }
 
-func (d dagWriter) retrieveDescendants(key ID) (map[ID]Resource, error) {
-	descendants := make(map[ID]Resource)
-	children, err := d.retrieveOutgoingRelationships(key)
-	if err != nil {
-		return nil, err
+// NewRetrieve implements the Writer interface.
+func (d dagWriter) NewRetrieve() Retrieve { return newRetrieve(d.txn, d.retrieve.exec) }
+
+// afterCommit runs f once the writer's transaction commits. Changes to in-memory
+// state, such as the search index, must only be made after the commit, or an aborted
+// transaction would leave them out of sync with the database.
+func (d dagWriter) afterCommit(f func()) {
+	if t, ok := d.txn.(*Txn); ok {
+		t.afterCommit(f)
+		return
 	}
-	if len(children) == 0 {
-		return nil, nil
+	f()
+}
+
+// invalidate removes the entity of the resource from the cache. The entity is
+// invalidated again when the transaction commits, in case it was cached from a read
+// made before the commit.
+func (d dagWriter) invalidate(id ID) {
+	cache := d.retrieve.cache
+	if cache == nil {
+		return
 	}
-	for _, child := range children {
-		childDescendants, err := d.retrieveDescendants(child.ID)
+	cache.invalidate(id)
+	d.afterCommit(func() { cache.invalidate(id) })
+}
+
+// deleteRelationships deletes all incoming and outgoing relationships of the resource
+// with the given ID.
+func (d dagWriter) deleteRelationships(tk ID) error {
+	for _, dir := range []Direction{Forward, Backward} {
+		a, err := retrieveAdjacency(d.txn, tk, dir)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		for k, v := range childDescendants {
-			descendants[k] = v
+		for t, ids := range a.Edges {
+			for _, id := range ids {
+				rel := Relationship{From: tk, To: id, Type: t}
+				if dir == Backward {
+					rel = Relationship{From: id, To: tk, Type: t}
+				}
+				if err := d.deleteRelationship(rel.From, rel.To, rel.Type); err != nil {
+					return err
+				}
+			}
 		}
-		descendants[child.ID] = child
 	}
-	return descendants, nil
-}
-
-func (d dagWriter) deleteIncomingRelationships(tk ID) error {
-	return gorp.NewDelete[string, Relationship]().Where(func(rel *Relationship) bool {
-		return rel.To == tk
-	}).Exec(d.txn)
-}
-
-func (d dagWriter) deleteOutgoingRelationships(tk ID) error {
-	return gorp.NewDelete[string, Relationship]().Where(func(rel *Relationship) bool {
-		return rel.From == tk
-	}).Exec(d.txn)
+	return nil
 }
 
-func (d dagWriter) checkRelationshipExists(rel Relationship) (bool, error) {
+func (d dagWriter) checkRelationshipExists(rel Relationship, acyclic bool) (bool, error) {
 	exists, err := gorp.NewRetrieve[string, Relationship]().
 		WhereKeys(rel.GorpKey()).
 		Exists(d.txn)
-	if err != nil {
-		return false, err
+	if err != nil || exists || !acyclic {
+		return exists, err
 	}
 	reverseRel := Relationship{From: rel.To, To: rel.From, Type: rel.Type}
 	reverseExists, err := gorp.NewRetrieve[string, Relationship]().
@@ -151,6 +277,40 @@ func (d dagWriter) checkRelationshipExists(rel Relationship) (bool, error) {
 	return exists, nil
 }
 
+func (d dagWriter) checkCardinality(rel Relationship, c Cardinality) error {
+	if c == ManyToOne || c == OneToOne {
+		to, err := retrieveAdjacent(d.txn, rel.From, Forward, rel.Type)
+		if err != nil {
+			return err
+		}
+		if len(to) > 0 {
+			return errors.Wrapf(
+				CardinalityViolation,
+				"[ontology] - %s already has a %s relationship (%s)",
+				rel.From, rel.Type, c,
+			)
+		}
+	}
+	if c == OneToMany || c == OneToOne {
+		from, err := retrieveAdjacent(d.txn, rel.To, Backward, rel.Type)
+		if err != nil {
+			return err
+		}
+		if len(from) > 0 {
+			return errors.Wrapf(
+				CardinalityViolation,
+				"[ontology] - %s already has an incoming %s relationship (%s)",
+				rel.To, rel.Type, c,
+			)
+		}
+	}
+	return nil
+}
+
+func (d dagWriter) resourceExists(id ID) (bool, error) {
+	return gorp.NewRetrieve[ID, Resource]().WhereKeys(id).Exists(d.txn)
+}
+
 func (d dagWriter) validateResourcesExist(ids ...ID) error {
 	ok, err := gorp.NewRetrieve[ID, Resource]().WhereKeys(ids...).Exists(d.txn)
 	if err != nil {
@@ -2,6 +2,7 @@ package ontology_test
 
 import (
 	"github.com/arya-analytics/delta/pkg/ontology"
+	"github.com/arya-analytics/delta/pkg/ontology/schema"
 	"github.com/arya-analytics/x/query"
 	"github.com/cockroachdb/errors"
 	. "github.com/onsi/ginkgo/v2"
@@ -77,6 +78,10 @@ var _ = Describe("Writer", func() {
 				})
 			})
 			Context("Cyclic violations", func() {
+				It("Should return an error if a resource is related to itself", func() {
+					err := w.DefineRelationship(idOne, idOne, ontology.Parent)
+					Expect(errors.Is(err, ontology.CyclicDependency)).To(BeTrue())
+				})
 				It(
 					"Should return an error if a relationship is defined in two directions",
 					func() {
@@ -111,6 +116,23 @@ var _ = Describe("Writer", func() {
 				Expect(res).To(HaveLen(0))
 			})
 		})
+		Describe("Deleting a Resource", func() {
+			It("Should delete the relationships of the resource", func() {
+				idThree := newEmptyID("baz")
+				Expect(w.DefineResource(idThree)).To(Succeed())
+				Expect(w.DefineRelationship(idOne, idThree, ontology.Parent)).To(Succeed())
+				Expect(w.DefineRelationship(idThree, idTwo, ontology.Parent)).To(Succeed())
+				Expect(w.DeleteResource(idThree)).To(Succeed())
+				var res []ontology.Resource
+				Expect(w.NewRetrieve().
+					WhereIDs(idTwo).
+					TraverseTo(ontology.Children).
+					Entries(&res).
+					Exec()).To(Succeed())
+				Expect(res).To(HaveLen(0))
+				Expect(w.DefineRelationship(idTwo, idOne, ontology.Parent)).To(Succeed())
+			})
+		})
 		Describe("Idempotency", func() {
 			Specify("Defining a relationship should be idempotent", func() {
 				Expect(w.DefineRelationship(idOne, idTwo, ontology.Parent)).To(Succeed())
@@ -118,4 +140,108 @@ var _ = Describe("Writer", func() {
 			})
 		})
 	})
+	Describe("Relationship Types", func() {
+		var a, b, c ontology.ID
+		BeforeEach(func() {
+			a, b, c = newEmptyID("a"), newEmptyID("b"), newEmptyID("c")
+			for _, id := range []ontology.ID{a, b, c} {
+				Expect(w.DefineResource(id)).To(Succeed())
+			}
+		})
+		It("Should return an error for an unregistered relationship type", func() {
+			err := w.DefineRelationship(a, b, "unknown")
+			Expect(errors.Is(err, ontology.UnknownRelationshipType)).To(BeTrue())
+		})
+		It("Should allow cycles for cyclic relationship types", func() {
+			Expect(w.DefineRelationship(a, b, memberOf)).To(Succeed())
+			Expect(w.DefineRelationship(b, a, memberOf)).To(Succeed())
+		})
+		It("Should prevent cycles for acyclic relationship types", func() {
+			Expect(w.DefineRelationship(a, b, derivedFrom)).To(Succeed())
+			Expect(w.DefineRelationship(b, c, derivedFrom)).To(Succeed())
+			err := w.DefineRelationship(c, a, derivedFrom)
+			Expect(errors.Is(err, ontology.CyclicDependency)).To(BeTrue())
+		})
+		It("Should enforce many-to-one cardinality", func() {
+			Expect(w.DefineRelationship(a, b, memberOf)).To(Succeed())
+			Expect(w.DefineRelationship(c, b, memberOf)).To(Succeed())
+			err := w.DefineRelationship(a, c, memberOf)
+			Expect(errors.Is(err, ontology.CardinalityViolation)).To(BeTrue())
+		})
+		It("Should enforce one-to-many cardinality", func() {
+			Expect(w.DefineRelationship(a, b, derivedFrom)).To(Succeed())
+			Expect(w.DefineRelationship(a, c, derivedFrom)).To(Succeed())
+			err := w.DefineRelationship(c, b, derivedFrom)
+			Expect(errors.Is(err, ontology.CardinalityViolation)).To(BeTrue())
+		})
+		It("Should not count relationships of other types", func() {
+			Expect(w.DefineRelationship(a, b, memberOf)).To(Succeed())
+			Expect(w.DefineRelationship(a, c, ontology.Parent)).To(Succeed())
+		})
+		It("Should traverse relationships of a custom type", func() {
+			Expect(w.DefineRelationship(a, c, memberOf)).To(Succeed())
+			Expect(w.DefineRelationship(b, c, memberOf)).To(Succeed())
+			var res []ontology.Resource
+			Expect(w.NewRetrieve().
+				WhereIDs(c).
+				TraverseTo(memberOf.Backward()).
+				Entries(&res).
+				Exec()).To(Succeed())
+			Expect(res).To(HaveLen(2))
+			var r ontology.Resource
+			Expect(w.NewRetrieve().
+				WhereIDs(a).
+				TraverseTo(memberOf.Forward()).
+				Entry(&r).
+				Exec()).To(Succeed())
+			Expect(r.ID).To(Equal(c))
+		})
+	})
+	Describe("Entities", func() {
+		newMutable := func(key, name string) ontology.Entity {
+			e := schema.NewEntity(mutableSchema)
+			schema.Set(e, "key", key)
+			schema.Set(e, "name", name)
+			return e
+		}
+		It("Should create an entity and define its resource", func() {
+			id, err := w.CreateEntity(newMutable("a", "foo"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(id).To(Equal(ontology.ID{Key: "a", Type: mutableType}))
+			var r ontology.Resource
+			Expect(w.NewRetrieve().WhereIDs(id).Entry(&r).Exec()).To(Succeed())
+			name, ok := schema.Get[string](r.Entity(), "name")
+			Expect(ok).To(BeTrue())
+			Expect(name).To(Equal("foo"))
+		})
+		It("Should update an entity", func() {
+			id, err := w.CreateEntity(newMutable("b", "foo"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(w.UpdateEntity(id, newMutable("b", "bar"))).To(Succeed())
+			var r ontology.Resource
+			Expect(w.NewRetrieve().WhereIDs(id).Entry(&r).Exec()).To(Succeed())
+			name, ok := schema.Get[string](r.Entity(), "name")
+			Expect(ok).To(BeTrue())
+			Expect(name).To(Equal("bar"))
+		})
+		It("Should return an error when updating a resource that doesn't exist", func() {
+			err := w.UpdateEntity(ontology.ID{Key: "c", Type: mutableType}, newMutable("c", "foo"))
+			Expect(errors.Is(err, query.NotFound)).To(BeTrue())
+		})
+		It("Should return an error if the entity doesn't match the schema", func() {
+			e := schema.NewEntity(&ontology.Schema{
+				Type:   mutableType,
+				Fields: map[string]schema.Field{"name": {Type: schema.Int}},
+			})
+			schema.Set(e, "name", 1)
+			_, err := w.CreateEntity(e)
+			Expect(err).To(HaveOccurred())
+		})
+		It("Should return an error if the service is read only", func() {
+			e := schema.NewEntity((&emptyService{}).Schema())
+			schema.Set(e, "key", "d")
+			_, err := w.CreateEntity(e)
+			Expect(errors.Is(err, ontology.ReadOnly)).To(BeTrue())
+		})
+	})
 })
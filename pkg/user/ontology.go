@@ -3,6 +3,9 @@ package user
 import (
 	"github.com/arya-analytics/delta/pkg/ontology"
 	"github.com/arya-analytics/delta/pkg/ontology/schema"
+	"github.com/arya-analytics/x/gorp"
+	"github.com/arya-analytics/x/query"
+	"github.com/cockroachdb/errors"
 	"github.com/google/uuid"
 )
 
@@ -16,11 +19,11 @@ var _schema = &ontology.Schema{
 	Type: ontologyType,
 	Fields: map[string]schema.Field{
 		"key":      {Type: schema.UUID},
-		"username": {Type: schema.String},
+		"username": {Type: schema.String, Required: true},
 	},
 }
 
-var _ ontology.Service = (*Service)(nil)
+var _ ontology.MutableService = (*Service)(nil)
 
 // Schema implements the ontology.Service interface.
 func (s *Service) Schema() *schema.Schema { return _schema }
@@ -35,9 +38,74 @@ func (s *Service) RetrieveEntity(key string) (schema.Entity, error) {
 	return newEntity(u), err
 }
 
+// CreateEntity implements the ontology.MutableService interface. Returns an error
+// wrapping query.UniqueViolation if a user with the key or username already exists.
+func (s *Service) CreateEntity(txn gorp.Txn, e schema.Entity) (string, error) {
+	username, ok := schema.Get[string](e, "username")
+	if !ok || username == "" {
+		return "", errors.New("[user] - username is required")
+	}
+	key, _ := schema.Get[uuid.UUID](e, "key")
+	if key != uuid.Nil {
+		exists, err := gorp.NewRetrieve[uuid.UUID, User]().WhereKeys(key).Exists(txn)
+		if err != nil {
+			return "", err
+		}
+		if exists {
+			return "", errors.Wrapf(query.UniqueViolation, "[user] - user %s already exists", key)
+		}
+	}
+	if err := checkUsername(txn, key, username); err != nil {
+		return "", err
+	}
+	u := &User{Key: key, Username: username}
+	// Create assigns a key to the user if one wasn't provided, so we need to read it
+	// afterwards.
+	if err := s.Create(txn, u); err != nil {
+		return "", err
+	}
+	return u.Key.String(), nil
+}
+
+// UpdateEntity implements the ontology.MutableService interface. Returns an error
+// wrapping query.UniqueViolation if the user is renamed to a username that belongs to
+// another user.
+func (s *Service) UpdateEntity(txn gorp.Txn, key string, e schema.Entity) error {
+	uuidKey, err := uuid.Parse(key)
+	if err != nil {
+		return err
+	}
+	var u User
+	if err := gorp.NewRetrieve[uuid.UUID, User]().WhereKeys(uuidKey).Entry(&u).Exec(txn); err != nil {
+		return err
+	}
+	if username, ok := schema.Get[string](e, "username"); ok && username != u.Username {
+		if err := checkUsername(txn, u.Key, username); err != nil {
+			return err
+		}
+		u.Username = username
+	}
+	return gorp.NewCreate[uuid.UUID, User]().Entry(&u).Exec(txn)
+}
+
+// checkUsername returns an error if a user other than the one with the given key has
+// the username.
+func checkUsername(txn gorp.Txn, key uuid.UUID, username string) error {
+	taken, err := gorp.NewRetrieve[uuid.UUID, User]().
+		Where(func(u *User) bool { return u.Username == username && u.Key != key }).
+		Exists(txn)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return errors.Wrapf(query.UniqueViolation, "[user] - username %s is taken", username)
+	}
+	return nil
+}
+
 func newEntity(u User) schema.Entity {
 	e := schema.NewEntity(_schema)
-	schema.Set(e, "key", u.Key.String())
+	schema.Set(e, "key", u.Key)
 	schema.Set(e, "username", u.Username)
 	return e
 }
@@ -0,0 +1,164 @@
+package user_test
+
+import (
+	"context"
+	"github.com/arya-analytics/delta/pkg/ontology"
+	"github.com/arya-analytics/delta/pkg/ontology/schema"
+	"github.com/arya-analytics/delta/pkg/user"
+	"github.com/arya-analytics/x/gorp"
+	"github.com/arya-analytics/x/kv/memkv"
+	"github.com/arya-analytics/x/query"
+	"github.com/cockroachdb/errors"
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Ontology", func() {
+	var (
+		db  *gorp.DB
+		otg *ontology.Ontology
+		svc *user.Service
+	)
+	BeforeEach(func() {
+		var err error
+		db = gorp.Wrap(memkv.New())
+		otg, err = ontology.Open(db)
+		Expect(err).ToNot(HaveOccurred())
+		svc = user.New(db, otg)
+		otg.RegisterService(svc)
+	})
+	AfterEach(func() { Expect(db.Close()).To(Succeed()) })
+	It("Should return entities that conform to the service's schema", func() {
+		txn := svc.BeginTxn()
+		u := &user.User{Username: "alice"}
+		Expect(svc.Create(txn, u)).To(Succeed())
+		Expect(txn.Commit()).To(Succeed())
+		Expect(txn.Close()).To(Succeed())
+		e, err := svc.RetrieveEntity(u.Key.String())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(e.Validate(svc.Schema())).To(Succeed())
+		username, ok := schema.Get[string](e, "username")
+		Expect(ok).To(BeTrue())
+		Expect(username).To(Equal("alice"))
+	})
+	It("Should create a user through the ontology writer", func() {
+		txn := svc.BeginTxn()
+		e := schema.NewEntity(svc.Schema())
+		schema.Set(e, "username", "bob")
+		id, err := otg.NewWriter(txn).CreateEntity(e)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(txn.Commit()).To(Succeed())
+		Expect(txn.Close()).To(Succeed())
+		Expect(id.Key).ToNot(Equal(uuid.Nil.String()))
+		u, err := svc.RetrieveByUsername("bob")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(id).To(Equal(user.OntologyID(u.Key)))
+		var resources []ontology.Resource
+		Expect(otg.NewRetrieve().
+			Where(func(r *ontology.Resource) bool { return r.ID.Type == id.Type }).
+			Entries(&resources).
+			Exec()).To(Succeed())
+		Expect(resources).To(HaveLen(1))
+		Expect(resources[0].ID).To(Equal(id))
+	})
+	It("Should publish the resources of created users to watchers", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		sub, err := otg.Watch(ctx, ontology.WatchFilter{})
+		Expect(err).ToNot(HaveOccurred())
+		txn := svc.BeginTxn()
+		u := &user.User{Username: "carol"}
+		Expect(svc.Create(txn, u)).To(Succeed())
+		Expect(txn.Commit()).To(Succeed())
+		Expect(txn.Close()).To(Succeed())
+		var c ontology.Change
+		Eventually(sub.Changes).Should(Receive(&c))
+		Expect(c.Variant).To(Equal(ontology.ResourceDefined))
+		Expect(c.ID).To(Equal(user.OntologyID(u.Key)))
+	})
+	It("Should return an error when creating a user on an unsupported transaction", func() {
+		txn := db.BeginTxn()
+		defer func() { Expect(txn.Close()).To(Succeed()) }()
+		err := svc.Create(txn, &user.User{Username: "dave"})
+		Expect(err).To(MatchError(ontology.UnsupportedTxn))
+	})
+	It("Should invalidate the cached entity of an overwritten user", func() {
+		cacheDB := gorp.Wrap(memkv.New())
+		defer func() { Expect(cacheDB.Close()).To(Succeed()) }()
+		cached, err := ontology.Open(cacheDB, ontology.WithEntityCache(10))
+		Expect(err).ToNot(HaveOccurred())
+		cachedSvc := user.New(cacheDB, cached)
+		cached.RegisterService(cachedSvc)
+		create := func(u *user.User) {
+			txn := cachedSvc.BeginTxn()
+			Expect(cachedSvc.Create(txn, u)).To(Succeed())
+			Expect(txn.Commit()).To(Succeed())
+			Expect(txn.Close()).To(Succeed())
+		}
+		username := func(key uuid.UUID) string {
+			var r ontology.Resource
+			Expect(cached.NewRetrieve().WhereIDs(user.OntologyID(key)).Entry(&r).Exec()).To(Succeed())
+			v, _ := schema.Get[string](r.Entity(), "username")
+			return v
+		}
+		u := &user.User{Username: "erin"}
+		create(u)
+		Expect(username(u.Key)).To(Equal("erin"))
+		create(&user.User{Key: u.Key, Username: "frank"})
+		Expect(username(u.Key)).To(Equal("frank"))
+	})
+	It("Should return a validation error when creating a user without a username", func() {
+		txn := svc.BeginTxn()
+		defer func() { Expect(txn.Close()).To(Succeed()) }()
+		_, err := otg.NewWriter(txn).CreateEntity(schema.NewEntity(svc.Schema()))
+		var verr schema.ValidationErrors
+		Expect(errors.As(err, &verr)).To(BeTrue())
+		Expect(verr[0].Field).To(Equal("username"))
+	})
+	Describe("Conflicts", func() {
+		var alice *user.User
+		BeforeEach(func() {
+			alice = &user.User{Username: "alice"}
+			txn := svc.BeginTxn()
+			Expect(svc.Create(txn, alice)).To(Succeed())
+			Expect(svc.Create(txn, &user.User{Username: "bob"})).To(Succeed())
+			Expect(txn.Commit()).To(Succeed())
+			Expect(txn.Close()).To(Succeed())
+		})
+		entity := func(key uuid.UUID, username string) schema.Entity {
+			e := schema.NewEntity(svc.Schema())
+			if key != uuid.Nil {
+				schema.Set(e, "key", key)
+			}
+			schema.Set(e, "username", username)
+			return e
+		}
+		It("Should not create a user with the key of an existing user", func() {
+			txn := svc.BeginTxn()
+			defer func() { Expect(txn.Close()).To(Succeed()) }()
+			_, err := otg.NewWriter(txn).CreateEntity(entity(alice.Key, "mallory"))
+			Expect(err).To(MatchError(query.UniqueViolation))
+			u, err := svc.Retrieve(alice.Key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(u.Username).To(Equal("alice"))
+		})
+		It("Should not create a user with a taken username", func() {
+			txn := svc.BeginTxn()
+			defer func() { Expect(txn.Close()).To(Succeed()) }()
+			_, err := otg.NewWriter(txn).CreateEntity(entity(uuid.Nil, "alice"))
+			Expect(err).To(MatchError(query.UniqueViolation))
+		})
+		It("Should not rename a user to a taken username", func() {
+			txn := svc.BeginTxn()
+			defer func() { Expect(txn.Close()).To(Succeed()) }()
+			w := otg.NewWriter(txn)
+			Expect(w.UpdateEntity(user.OntologyID(alice.Key), entity(uuid.Nil, "bob"))).
+				To(MatchError(query.UniqueViolation))
+			Expect(w.UpdateEntity(user.OntologyID(alice.Key), entity(uuid.Nil, "alice"))).
+				To(Succeed())
+			Expect(w.UpdateEntity(user.OntologyID(alice.Key), entity(uuid.Nil, "carol"))).
+				To(Succeed())
+		})
+	})
+})
@@ -11,6 +11,18 @@ type Service struct {
 	resources *ontology.Ontology
 }
 
+// New creates a new Service that stores users in the given database and defines them
+// as resources in the given ontology. The Service must be registered with the
+// ontology before user entities can be retrieved through it.
+func New(db *gorp.DB, resources *ontology.Ontology) *Service {
+	return &Service{db: db, resources: resources}
+}
+
+// BeginTxn begins a transaction for creating users. Users must be created on a
+// transaction opened with BeginTxn, or on one opened with the ontology's BeginTxn,
+// so that their resources are published to ontology Watch subscribers.
+func (s *Service) BeginTxn() gorp.Txn { return s.resources.BeginTxn() }
+
 func (s *Service) Retrieve(key uuid.UUID) (User, error) {
 	var u User
 	return u, gorp.NewRetrieve[uuid.UUID, User]().
@@ -27,13 +39,17 @@ func (s *Service) RetrieveByUsername(username string) (User, error) {
 		Exec(s.db)
 }
 
+// Create creates the user, assigning it a key if it doesn't have one. txn must be
+// opened with BeginTxn.
 func (s *Service) Create(txn gorp.Txn, u *User) error {
 	if u.Key == uuid.Nil {
 		u.Key = uuid.New()
 	}
-	if err := s.resources.NewWriter(txn).
-		DefineResource(OntologyID(u.Key)); err != nil {
+	w := s.resources.NewWriter(txn)
+	if err := w.DefineResource(OntologyID(u.Key)); err != nil {
 		return err
 	}
+	// Create overwrites any existing user with the same key.
+	w.InvalidateEntities(OntologyID(u.Key))
 	return gorp.NewCreate[uuid.UUID, User]().Entry(u).Exec(txn)
 }